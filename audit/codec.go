@@ -0,0 +1,85 @@
+package audit
+
+import "time"
+
+// Actions recorded in the audit log
+const (
+	ActionRepositoryCreate = "repository.create"
+	ActionRepositoryDelete = "repository.delete"
+	ActionGroupCreate      = "group.create"
+	ActionGroupDelete      = "group.delete"
+	ActionTagCreate        = "tag.create"
+	ActionTagDelete        = "tag.delete"
+	ActionGitPush          = "git.push"
+	ActionGitFetch         = "git.fetch"
+	ActionLFSUpload        = "lfs.upload"
+)
+
+// Outcomes of audited operations
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Entry is a single record of the audit log
+// @Description Audit log entry
+type Entry struct {
+	Time       time.Time `json:"time" example:"2024-01-01T00:00:00Z"`
+	Actor      string    `json:"actor,omitempty" example:"john"`
+	Action     string    `json:"action" example:"tag.create"`
+	Repository string    `json:"repository,omitempty" example:"myorg/myrepo"`
+	Target     string    `json:"target,omitempty" example:"v1.0.0"`
+	RemoteAddr string    `json:"remote_addr,omitempty" example:"192.0.2.10"`
+	Outcome    string    `json:"outcome" example:"success"`
+	Status     int       `json:"status,omitempty" example:"201"`
+	Error      string    `json:"error,omitempty" example:""`
+} // @name AuditEntry
+
+// Filter selects audit log entries. Zero fields match all entries.
+type Filter struct {
+	// Actor matches the actor exactly
+	Actor string
+
+	// Repository matches a repository or group and everything below it
+	Repository string
+
+	// Action matches the action exactly
+	Action string
+
+	// Since and Until bound the entry time, both inclusive
+	Since time.Time
+	Until time.Time
+
+	// Limit caps the number of returned entries, newest first
+	Limit int
+}
+
+// Matches reports whether an entry is selected by the filter
+func (f Filter) Matches(e Entry) bool {
+	if f.Actor != "" && e.Actor != f.Actor {
+		return false
+	}
+
+	if f.Action != "" && e.Action != f.Action {
+		return false
+	}
+
+	if f.Repository != "" && e.Repository != f.Repository && !hasPathPrefix(e.Repository, f.Repository) {
+		return false
+	}
+
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+
+	if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return false
+	}
+
+	return true
+}
+
+// hasPathPrefix reports whether name lies below the group prefix
+func hasPathPrefix(name, prefix string) bool {
+	return len(name) > len(prefix) && name[len(prefix)] == '/' && name[:len(prefix)] == prefix
+}
@@ -0,0 +1,207 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Record appends an entry to the audit log. Entries without time are stamped with the current time.
+// A nil *AuditLog is valid and drops all entries, so modules can treat the audit log as optional.
+// Failures are logged, auditing never fails the audited operation.
+func (m *AuditLog) Record(entry Entry) {
+	if m == nil {
+		return
+	}
+
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	entry.Time = entry.Time.UTC()
+
+	if entry.Outcome == "" {
+		entry.Outcome = OutcomeSuccess
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		m.logger.Error("Failed to encode audit entry", zap.Error(err))
+		return
+	}
+	data = append(data, '\n')
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		m.logger.Warn("Audit log is closed, dropping entry", zap.String("action", entry.Action))
+		return
+	}
+
+	if m.file != nil && m.maxSize > 0 && m.size > 0 && m.size+int64(len(data)) > m.maxSize {
+		if err := m.rotate(); err != nil {
+			m.logger.Error("Failed to rotate audit log", zap.Error(err))
+		}
+	}
+
+	// The file is not open if opening or rotating it failed, which is retried with every entry
+	if m.file == nil {
+		if err := m.openFile(); err != nil {
+			m.logger.Error("Failed to open audit log, dropping entry", zap.String("action", entry.Action), zap.Error(err))
+			return
+		}
+	}
+
+	n, err := m.file.Write(data)
+	m.size += int64(n)
+	if err != nil {
+		m.logger.Error("Failed to write audit entry", zap.Error(err))
+	}
+}
+
+// Query returns the entries matching a filter, newest first.
+// The current log file and all rotated files are searched.
+// Files are read without holding the lock of Record, so queries do not delay audited operations.
+func (m *AuditLog) Query(filter Filter) ([]Entry, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultQueryLimit
+	}
+
+	files, err := m.openFiles()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	entries := make([]Entry, 0)
+
+	// Files from newest to oldest
+	for _, f := range files {
+		if len(entries) >= filter.Limit {
+			break
+		}
+
+		found, err := readEntries(f, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		// Entries of a file are stored oldest first
+		for j := len(found) - 1; j >= 0 && len(entries) < filter.Limit; j-- {
+			entries = append(entries, found[j])
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.After(entries[j].Time)
+	})
+
+	return entries, nil
+}
+
+// openFiles opens the current file and the rotated files for reading, from newest to oldest.
+// They are opened at once while no rotation runs, and stay readable when rotated later on.
+func (m *AuditLog) openFiles() ([]*os.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	files := make([]*os.File, 0, m.maxBackups+1)
+	for i := 0; i <= m.maxBackups; i++ {
+		f, err := os.Open(m.backupPath(i))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			for _, f := range files {
+				f.Close()
+			}
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+		files = append(files, f)
+	}
+
+	return files, nil
+}
+
+// openFile opens the log file for appending
+func (m *AuditLog) openFile() error {
+	f, err := os.OpenFile(m.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat audit log: %w", err)
+	}
+
+	m.file = f
+	m.size = info.Size()
+	return nil
+}
+
+// rotate shifts the rotated files by one, moves the current file to <path>.1 and starts a new file.
+// The oldest file is removed once max_backups files exist. Without backups the current file is
+// kept and grows further, entries are never discarded without being rotated first.
+func (m *AuditLog) rotate() error {
+	if m.maxBackups < 1 {
+		return nil
+	}
+
+	if err := m.file.Close(); err != nil {
+		return err
+	}
+	m.file = nil
+
+	os.Remove(m.backupPath(m.maxBackups))
+	for i := m.maxBackups - 1; i >= 0; i-- {
+		if err := os.Rename(m.backupPath(i), m.backupPath(i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return m.openFile()
+}
+
+// backupPath returns the path of the n-th rotated file, 0 being the current file
+func (m *AuditLog) backupPath(n int) string {
+	if n == 0 {
+		return m.path
+	}
+	return fmt.Sprintf("%s.%d", m.path, n)
+}
+
+// readEntries reads the matching entries of a log file.
+// Lines that cannot be decoded, e.g. a partially written last line, are skipped.
+func readEntries(f io.Reader, filter Filter) ([]Entry, error) {
+	entries := make([]Entry, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if filter.Matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	return entries, nil
+}
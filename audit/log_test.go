@@ -0,0 +1,163 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+func setupTestAuditLog(t *testing.T, maxSize int64, maxBackups int) *AuditLog {
+	tmpDir := t.TempDir()
+
+	m := &AuditLog{
+		logger:     zap.NewNop(),
+		path:       filepath.Join(tmpDir, "audit.log"),
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+	if err := m.openFile(); err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+	t.Cleanup(func() { m.file.Close() })
+
+	return m
+}
+
+// Test filtering of audit entries by actor, repository, action and time
+func TestQuery(t *testing.T) {
+	m := setupTestAuditLog(t, 0, 0)
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.Record(Entry{Time: base, Actor: "john", Action: ActionRepositoryCreate, Repository: "org/app"})
+	m.Record(Entry{Time: base.Add(time.Hour), Actor: "jane", Action: ActionGitPush, Repository: "org/app", Outcome: OutcomeFailure})
+	m.Record(Entry{Time: base.Add(2 * time.Hour), Actor: "john", Action: ActionTagCreate, Repository: "org/lib", Target: "v1"})
+	m.Record(Entry{Time: base.Add(3 * time.Hour), Actor: "john", Action: ActionGroupCreate, Repository: "organization"})
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   int
+	}{
+		{"all", Filter{}, 4},
+		{"actor", Filter{Actor: "john"}, 3},
+		{"group includes repositories", Filter{Repository: "org"}, 3},
+		{"repository", Filter{Repository: "org/app"}, 2},
+		{"action", Filter{Action: ActionGitPush}, 1},
+		{"time range", Filter{Since: base.Add(time.Hour), Until: base.Add(2 * time.Hour)}, 2},
+		{"limit", Filter{Limit: 2}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := m.Query(tt.filter)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if len(entries) != tt.want {
+				t.Errorf("Expected %d entries, got %d: %+v", tt.want, len(entries), entries)
+			}
+			for i := 1; i < len(entries); i++ {
+				if entries[i].Time.After(entries[i-1].Time) {
+					t.Errorf("Expected newest entries first, got %+v", entries)
+				}
+			}
+		})
+	}
+
+	entries, _ := m.Query(Filter{Action: ActionRepositoryCreate})
+	if len(entries) != 1 || entries[0].Outcome != OutcomeSuccess {
+		t.Errorf("Expected outcome to default to success, got %+v", entries)
+	}
+}
+
+// Test that the log is rotated and old files are removed
+func TestRotation(t *testing.T) {
+	m := setupTestAuditLog(t, 200, 2)
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 20; i++ {
+		m.Record(Entry{Time: base.Add(time.Duration(i) * time.Minute), Actor: "john", Action: ActionGitFetch, Target: fmt.Sprint(i)})
+	}
+
+	if _, err := os.Stat(m.path + ".2"); err != nil {
+		t.Errorf("Expected second rotated file: %v", err)
+	}
+	if _, err := os.Stat(m.path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected at most 2 rotated files, got %v", err)
+	}
+
+	entries, err := m.Query(Filter{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) == 0 || len(entries) >= 20 {
+		t.Fatalf("Expected only the retained entries, got %d", len(entries))
+	}
+	if entries[0].Target != "19" {
+		t.Errorf("Expected newest entry first, got %+v", entries[0])
+	}
+}
+
+// Test that a log without backups is never truncated and that the module refuses to rotate without backups
+func TestRotation_NoBackups(t *testing.T) {
+	m := setupTestAuditLog(t, 200, 0)
+
+	for i := 0; i < 20; i++ {
+		m.Record(Entry{Actor: "john", Action: ActionGitFetch, Target: fmt.Sprint(i)})
+	}
+
+	entries, err := m.Query(Filter{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 20 {
+		t.Errorf("Expected all 20 entries to be kept, got %d", len(entries))
+	}
+
+	viper.Set("audit.path", filepath.Join(t.TempDir(), "audit.log"))
+	viper.Set("audit.max_backups", 0)
+	t.Cleanup(func() { viper.Set("audit.max_backups", DefaultMaxBackups) })
+
+	module := &AuditLog{logger: zap.NewNop(), scope: "audit"}
+	module.initDefaultConfigs()
+	if err := module.onStart(context.Background()); err == nil {
+		module.onStop(context.Background())
+		t.Error("Expected max_backups 0 with rotation to be refused")
+	}
+}
+
+// Test that entries are written again once the log file can be reopened after a failed rotation
+func TestRotation_Failure(t *testing.T) {
+	m := setupTestAuditLog(t, 100, 1)
+	dir := filepath.Dir(m.path)
+
+	m.Record(Entry{Actor: "john", Action: ActionGitFetch, Target: "before"})
+
+	// Rotation cannot reopen the file while its directory is missing
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatalf("Failed to remove log directory: %v", err)
+	}
+	m.Record(Entry{Actor: "john", Action: ActionGitFetch, Target: "dropped"})
+	if m.file != nil {
+		t.Fatal("Expected the log file to be closed after the failed rotation")
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create log directory: %v", err)
+	}
+	m.Record(Entry{Actor: "john", Action: ActionGitFetch, Target: "after"})
+
+	entries, err := m.Query(Filter{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Target != "after" {
+		t.Errorf("Expected the entry recorded after recovery, got %+v", entries)
+	}
+}
@@ -0,0 +1,125 @@
+// Package audit provides a persistent, append-only audit trail of management and git operations.
+//
+// Entries are written as JSON lines to a log file which is rotated once it exceeds
+// a configured size. Rotated files are kept as <path>.1 (newest) to <path>.<max_backups>.
+// A max_size of 0 disables rotation. Otherwise max_backups must be at least 1, as rotating
+// without backups would discard the whole trail; only the oldest rotated file is ever removed.
+package audit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	ModuleName        = "AuditLog"
+	DefaultPath       = "./git/audit.log"
+	DefaultMaxSize    = 10 * 1024 * 1024
+	DefaultMaxBackups = 5
+	DefaultQueryLimit = 100
+)
+
+type AuditLog struct {
+	params     Params
+	logger     *zap.Logger
+	scope      string
+	path       string
+	maxSize    int64
+	maxBackups int
+	mu         sync.Mutex
+	file       *os.File
+	size       int64
+	closed     bool
+}
+
+type Params struct {
+	fx.In
+
+	Lifecycle fx.Lifecycle
+	Logger    *zap.Logger
+}
+
+func Module(scope string) fx.Option {
+
+	var m *AuditLog
+
+	return fx.Module(
+		scope,
+		fx.Provide(func(p Params) *AuditLog {
+			a := &AuditLog{
+				params: p,
+				logger: p.Logger.Named(scope),
+				scope:  scope,
+			}
+
+			a.initDefaultConfigs()
+
+			return a
+		}),
+		fx.Populate(&m),
+		fx.Invoke(func(p Params) {
+
+			p.Lifecycle.Append(
+				fx.Hook{
+					OnStart: m.onStart,
+					OnStop:  m.onStop,
+				},
+			)
+		}),
+	)
+
+}
+
+func (m *AuditLog) onStart(ctx context.Context) error {
+	m.logger.Info("Starting " + ModuleName)
+
+	m.path = viper.GetString(m.getConfigPath("path"))
+	m.maxSize = viper.GetInt64(m.getConfigPath("max_size"))
+	m.maxBackups = viper.GetInt(m.getConfigPath("max_backups"))
+
+	if m.maxSize > 0 && m.maxBackups < 1 {
+		return fmt.Errorf("invalid audit log configuration: %s must be at least 1 when %s is set", m.getConfigPath("max_backups"), m.getConfigPath("max_size"))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.openFile()
+}
+
+func (m *AuditLog) onStop(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.file != nil {
+		if err := m.file.Close(); err != nil {
+			m.logger.Warn("Failed to close audit log", zap.Error(err))
+		}
+		m.file = nil
+	}
+	m.closed = true
+
+	m.logger.Info("Stopped " + ModuleName)
+	return nil
+}
+
+func (m *AuditLog) getConfigPath(key string) string {
+	return fmt.Sprintf("%s.%s", m.scope, key)
+}
+
+func (m *AuditLog) initDefaultConfigs() {
+	viper.SetDefault(m.getConfigPath("path"), DefaultPath)
+	viper.SetDefault(m.getConfigPath("max_size"), DefaultMaxSize)
+	viper.SetDefault(m.getConfigPath("max_backups"), DefaultMaxBackups)
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrUnauthenticated indicates missing or invalid credentials.
+	// Transports answer it with a challenge so that clients ask for credentials.
+	ErrUnauthenticated = errors.New("authentication required")
+
+	// ErrForbidden indicates valid credentials without permission for the operation
+	ErrForbidden = errors.New("permission denied")
+)
+
+// Operation is the kind of access a request needs
+type Operation string
+
+const (
+	// OperationRead covers clones and fetches (git-upload-pack)
+	OperationRead Operation = "read"
+
+	// OperationWrite covers pushes (git-receive-pack)
+	OperationWrite Operation = "write"
+)
+
+// Credentials are the credentials sent with a request.
+// Basic authentication fills Username and Password, bearer authentication fills Token.
+type Credentials struct {
+	Username string
+	Password string
+	Token    string
+}
+
+// IsEmpty reports whether no credentials were sent
+func (c Credentials) IsEmpty() bool {
+	return c.Username == "" && c.Password == "" && c.Token == ""
+}
+
+// Request describes an access to a repository to be authenticated
+type Request struct {
+	Repository  string
+	Operation   Operation
+	Credentials Credentials
+}
+
+// Authenticator verifies the credentials of a request and decides whether the operation is allowed.
+// It returns the identity of the user, or nil to allow anonymous access.
+// ErrUnauthenticated asks the client for (other) credentials, ErrForbidden denies the operation.
+type Authenticator interface {
+	Authenticate(ctx context.Context, req Request) (*Identity, error)
+}
+
+// AuthenticatorFunc adapts a function to the Authenticator interface
+type AuthenticatorFunc func(ctx context.Context, req Request) (*Identity, error)
+
+// Authenticate calls f
+func (f AuthenticatorFunc) Authenticate(ctx context.Context, req Request) (*Identity, error) {
+	return f(ctx, req)
+}
+
+// CredentialsFromRequest extracts Basic or Bearer credentials from the Authorization header
+func CredentialsFromRequest(r *http.Request) Credentials {
+	if username, password, ok := r.BasicAuth(); ok {
+		return Credentials{Username: username, Password: password}
+	}
+
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return Credentials{Token: strings.TrimSpace(token)}
+	}
+
+	return Credentials{}
+}
+
+// TeamResolver returns the teams of a user for credentials that only name the user,
+// such as SSH keys, so that roles granted to teams apply to them as well
+type TeamResolver interface {
+	Teams(ctx context.Context, user string) ([]string, error)
+}
+
+// TeamResolverFunc adapts a function to the TeamResolver interface
+type TeamResolverFunc func(ctx context.Context, user string) ([]string, error)
+
+// Teams calls f
+func (f TeamResolverFunc) Teams(ctx context.Context, user string) ([]string, error) {
+	return f(ctx, user)
+}
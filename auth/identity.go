@@ -0,0 +1,65 @@
+// Package auth carries the identity of the user behind a request between
+// the HTTP transports, the management APIs and the repository policies.
+package auth
+
+import (
+	"context"
+	"strings"
+)
+
+// Identity describes an authenticated user
+type Identity struct {
+	// Name is the unique user name used in protection rules and logs
+	Name string
+
+	// Teams lists the teams the user belongs to, roles granted to a team apply to its members
+	Teams []string
+
+	// Token restricts an identity that authenticated with an access token, nil for other credentials
+	Token *TokenScope
+}
+
+// TokenScope describes what an access token may be used for
+type TokenScope struct {
+	// Write allows pushes and other changes, tokens without it can only read
+	Write bool
+
+	// Prefix limits the token to a repository, or to a group and everything below it.
+	// Empty prefixes cover every repository.
+	Prefix string
+
+	// Deploy marks repository deploy tokens, which are not bound to a user
+	// and grant their access on their own, regardless of roles
+	Deploy bool
+}
+
+// Covers reports whether a repository or group is within the prefix of the token
+func (s *TokenScope) Covers(name string) bool {
+	return s.Prefix == "" || name == s.Prefix || strings.HasPrefix(name, s.Prefix+"/")
+}
+
+// Allows reports whether the token may be used for an operation
+func (s *TokenScope) Allows(op Operation) bool {
+	return op == OperationRead || s.Write
+}
+
+type identityContextKey struct{}
+
+// WithIdentity returns a copy of ctx carrying the identity
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityContextKey{}, identity)
+}
+
+// IdentityFromContext returns the identity stored in ctx, or nil for anonymous requests
+func IdentityFromContext(ctx context.Context) *Identity {
+	identity, _ := ctx.Value(identityContextKey{}).(*Identity)
+	return identity
+}
+
+// NameFromContext returns the name of the identity stored in ctx, or an empty string for anonymous requests
+func NameFromContext(ctx context.Context) string {
+	if identity := IdentityFromContext(ctx); identity != nil {
+		return identity.Name
+	}
+	return ""
+}
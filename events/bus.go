@@ -0,0 +1,123 @@
+package events
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// subscriptionBufferSize is the number of events queued per subscriber before events are dropped
+const subscriptionBufferSize = 256
+
+// Handler receives the events of a subscription
+type Handler func(event Event)
+
+// Bus distributes events to subscribers.
+// Every subscriber receives the events in publishing order on its own goroutine, so publishers
+// never run subscriber code and subscribers may call back into the publishing modules.
+// Publishers never wait for subscribers either: events are dropped for subscribers that fall
+// more than subscriptionBufferSize events behind.
+// A nil *Bus is valid and drops all events.
+type Bus struct {
+	logger        *zap.Logger
+	mu            sync.RWMutex
+	subscriptions map[*subscription]struct{}
+	closed        bool
+	wg            sync.WaitGroup
+}
+
+type subscription struct {
+	events chan Event
+	once   sync.Once
+}
+
+// NewBus creates an event bus logging dropped events to logger
+func NewBus(logger *zap.Logger) *Bus {
+	return &Bus{
+		logger:        logger,
+		subscriptions: make(map[*subscription]struct{}),
+	}
+}
+
+// Subscribe registers a handler for all events published after the call
+// and returns a function cancelling the subscription
+func (b *Bus) Subscribe(handler Handler) func() {
+	if b == nil {
+		return func() {}
+	}
+
+	s := &subscription{events: make(chan Event, subscriptionBufferSize)}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return func() {}
+	}
+	b.subscriptions[s] = struct{}{}
+	b.wg.Add(1)
+	b.mu.Unlock()
+
+	go func() {
+		defer b.wg.Done()
+		for event := range s.events {
+			handler(event)
+		}
+	}()
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, ok := b.subscriptions[s]; ok {
+			delete(b.subscriptions, s)
+			s.close()
+		}
+	}
+}
+
+// Publish queues an event for all subscribers, dropping it for subscribers whose queue is full
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	dropped := 0
+	for s := range b.subscriptions {
+		select {
+		case s.events <- event:
+		default:
+			dropped++
+		}
+	}
+
+	if dropped > 0 {
+		b.logger.Warn("Dropped event for slow subscribers",
+			zap.String("event", event.EventName()),
+			zap.Int("subscribers", dropped),
+		)
+	}
+}
+
+// Close stops accepting subscriptions and waits until all queued events have been handled
+func (b *Bus) Close() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	b.closed = true
+	for s := range b.subscriptions {
+		delete(b.subscriptions, s)
+		s.close()
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+}
+
+func (s *subscription) close() {
+	s.once.Do(func() { close(s.events) })
+}
@@ -0,0 +1,88 @@
+package events
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Test that subscribers receive the events in publishing order until they unsubscribe
+func TestBus(t *testing.T) {
+	bus := NewBus(zap.NewNop())
+
+	var mu sync.Mutex
+	var received []string
+	unsubscribe := bus.Subscribe(func(event Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, event.(GroupCreated).Group)
+	})
+
+	bus.Publish(GroupCreated{Group: "a"})
+	bus.Publish(GroupCreated{Group: "b"})
+	unsubscribe()
+	unsubscribe()
+	bus.Publish(GroupCreated{Group: "c"})
+	bus.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 || received[0] != "a" || received[1] != "b" {
+		t.Errorf("Expected events a and b, got %v", received)
+	}
+
+	// Closed and nil buses drop events
+	bus.Subscribe(func(event Event) { t.Error("Unexpected event after Close") })()
+	bus.Publish(GroupCreated{Group: "d"})
+
+	var nilBus *Bus
+	nilBus.Subscribe(func(event Event) {})()
+	nilBus.Publish(GroupCreated{Group: "e"})
+	nilBus.Close()
+}
+
+// Test that a full subscriber does not block publishers, and that other subscribers
+// may publish and unsubscribe while it is stalled
+func TestBus_SlowSubscriber(t *testing.T) {
+	bus := NewBus(zap.NewNop())
+
+	release := make(chan struct{})
+	bus.Subscribe(func(event Event) { <-release })
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 2*subscriptionBufferSize; i++ {
+			bus.Publish(GroupCreated{Group: "group"})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Publish blocked on a full subscriber")
+	}
+
+	republished := make(chan struct{})
+	var unsubscribe func()
+	unsubscribe = bus.Subscribe(func(event Event) {
+		if _, ok := event.(GroupCreated); !ok {
+			return
+		}
+		bus.Publish(RepositoryCreated{Repository: "republished"})
+		unsubscribe()
+		close(republished)
+	})
+	bus.Publish(GroupCreated{Group: "last"})
+
+	select {
+	case <-republished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Subscriber could not publish and unsubscribe while another subscriber was stalled")
+	}
+
+	close(release)
+	bus.Close()
+}
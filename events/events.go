@@ -0,0 +1,90 @@
+package events
+
+import (
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Event names
+const (
+	NameRepositoryCreated = "repository.created"
+	NameRepositoryDeleted = "repository.deleted"
+	NameGroupCreated      = "group.created"
+	NameTagCreated        = "tag.created"
+	NameTagDeleted        = "tag.deleted"
+	NameRefsPushed        = "refs.pushed"
+)
+
+// Event is implemented by all events published on the bus.
+// Subscribers use a type switch to handle the events they are interested in.
+type Event interface {
+	EventName() string
+}
+
+// RepositoryCreated is published after a repository has been created
+type RepositoryCreated struct {
+	Repository string
+	Time       time.Time
+}
+
+func (RepositoryCreated) EventName() string { return NameRepositoryCreated }
+
+// RepositoryDeleted is published after a repository has been deleted
+type RepositoryDeleted struct {
+	Repository string
+	Time       time.Time
+}
+
+func (RepositoryDeleted) EventName() string { return NameRepositoryDeleted }
+
+// GroupCreated is published after a group has been created
+type GroupCreated struct {
+	Group string
+	Time  time.Time
+}
+
+func (GroupCreated) EventName() string { return NameGroupCreated }
+
+// TagCreated is published after a tag has been created or replaced, through the API or by a push.
+// Hash is the tag object for annotated tags and the commit for lightweight tags.
+type TagCreated struct {
+	Repository string
+	Tag        string
+	Hash       plumbing.Hash
+	Actor      string
+	Time       time.Time
+}
+
+func (TagCreated) EventName() string { return NameTagCreated }
+
+// TagDeleted is published after a tag has been deleted, through the API or by a push
+type TagDeleted struct {
+	Repository string
+	Tag        string
+	Hash       plumbing.Hash
+	Actor      string
+	Time       time.Time
+}
+
+func (TagDeleted) EventName() string { return NameTagDeleted }
+
+// RefUpdate describes a reference moved from OldHash to NewHash.
+// A zero OldHash creates the reference, a zero NewHash deletes it.
+type RefUpdate struct {
+	Name    plumbing.ReferenceName
+	OldHash plumbing.Hash
+	NewHash plumbing.Hash
+}
+
+// RefsPushed is published after references of a repository have been updated,
+// by a git push or by API operations such as commits and merges.
+// Pusher is empty when the actor is unknown.
+type RefsPushed struct {
+	Repository string
+	Pusher     string
+	Updates    []RefUpdate
+	Time       time.Time
+}
+
+func (RefsPushed) EventName() string { return NameRefsPushed }
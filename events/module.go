@@ -0,0 +1,60 @@
+// Package events provides an in-process event bus for repository lifecycle and push events.
+//
+// The bus is provided through fx so that other modules can subscribe without depending
+// on the publishers:
+//
+//	fx.Invoke(func(bus *events.Bus) {
+//		bus.Subscribe(func(event events.Event) {
+//			switch e := event.(type) {
+//			case events.TagCreated:
+//				...
+//			}
+//		})
+//	})
+package events
+
+import (
+	"context"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	ModuleName = "EventBus"
+)
+
+type Params struct {
+	fx.In
+
+	Lifecycle fx.Lifecycle
+	Logger    *zap.Logger
+}
+
+func Module(scope string) fx.Option {
+
+	return fx.Module(
+		scope,
+		fx.Provide(func(p Params) *Bus {
+			logger := p.Logger.Named(scope)
+			bus := NewBus(logger)
+
+			p.Lifecycle.Append(
+				fx.Hook{
+					OnStart: func(ctx context.Context) error {
+						logger.Info("Starting " + ModuleName)
+						return nil
+					},
+					OnStop: func(ctx context.Context) error {
+						bus.Close()
+						logger.Info("Stopped " + ModuleName)
+						return nil
+					},
+				},
+			)
+
+			return bus
+		}),
+	)
+
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/weedbox/git-modules/audit"
 	"go.uber.org/zap"
 )
 
@@ -32,7 +33,7 @@ func (m *GitHTTP) handleGitProtocolOrAPI(c *gin.Context) {
 	c.JSON(http.StatusBadRequest, gin.H{"error": "invalid git protocol path, expected path with .git"})
 }
 
-// handleGitProtocol handles all Git HTTP protocol requests by delegating to gitkit
+// handleGitProtocol handles all Git HTTP protocol requests by delegating to the configured backend
 // Supports multi-level paths like "username/repo.git/info/refs"
 func (m *GitHTTP) handleGitProtocol(c *gin.Context, fullPath string) {
 	// Extract repository name from path (remove .git suffix and everything after)
@@ -40,6 +41,13 @@ func (m *GitHTTP) handleGitProtocol(c *gin.Context, fullPath string) {
 	// - "username/repo.git/info/refs" -> "username/repo"
 	// - "hello.git/info/refs" -> "hello"
 	// - "org/team/project.git" -> "org/team/project"
+	// Dot segments would let a path be authorized for one repository and served from another
+	if hasDotSegment(fullPath) {
+		m.logger.Warn("Git protocol path with dot segments", zap.String("fullPath", fullPath))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid git protocol path"})
+		return
+	}
+
 	var repoName string
 	var gitPath string
 
@@ -57,8 +65,14 @@ func (m *GitHTTP) handleGitProtocol(c *gin.Context, fullPath string) {
 		return
 	}
 
+	// Authenticate before revealing whether the repository exists
+	op := gitOperation(c.Request, gitPath)
+	if !m.authenticate(c, repoName, op) {
+		return
+	}
+
 	// Verify repository exists
-	_, err := m.params.RepositoryManager.GetRepository(repoName)
+	repo, err := m.params.RepositoryManager.GetRepository(repoName)
 	if err != nil {
 		m.logger.Warn("Repository not found for git operation",
 			zap.String("repoName", repoName),
@@ -72,18 +86,34 @@ func (m *GitHTTP) handleGitProtocol(c *gin.Context, fullPath string) {
 		return
 	}
 
-	// Use http.StripPrefix to remove url_prefix, then delegate to gitkit
-	// This ensures gitkit sees clean paths like /hello.git/info/refs
+	if !m.authorize(c, repoName, op) {
+		return
+	}
+
+	// Git LFS clients transfer large files next to the repository
+	if lfsPath, ok := strings.CutPrefix(gitPath, lfsPathPrefix); ok {
+		m.handleLFS(c, repoName, lfsPath)
+		return
+	}
+
+	// Clients that do not request a smart service read the repository files with the dumb protocol
+	if isDumbRequest(c.Request, gitPath) {
+		m.serveDumbFile(c, repoName, repo.Path, gitPath)
+		return
+	}
+
+	// Use http.StripPrefix to remove url_prefix, then delegate to the backend (git or go-git)
+	// This ensures the backend sees clean paths like /hello.git/info/refs
 	//
 	// Example:
 	//   - url_prefix = "/git/repos"
 	//   - Original request: /git/repos/hello.git/info/refs
 	//   - After StripPrefix: /hello.git/info/refs
-	//   - gitkit finds repo at: data/git-repos/hello.git ✓
+	//   - backend finds repo at: data/git-repos/hello.git ✓
 
 	originalPath := c.Request.URL.Path
 
-	m.logger.Info("Delegating to gitkit",
+	m.logger.Info("Delegating to git backend",
 		zap.String("repo", repoName),
 		zap.String("method", c.Request.Method),
 		zap.String("urlPrefix", m.urlPrefix),
@@ -93,7 +123,36 @@ func (m *GitHTTP) handleGitProtocol(c *gin.Context, fullPath string) {
 		zap.String("queryString", c.Request.URL.RawQuery),
 	)
 
-	// Use StripPrefix to remove url_prefix before passing to gitkit
+	// Use StripPrefix to remove url_prefix before passing to the backend
 	handler := http.StripPrefix(m.urlPrefix, m.gitService)
+
+	// Check pushes against the repository policies and hooks before the backend applies them
+	if c.Request.Method == http.MethodPost && gitPath == "/git-receive-pack" {
+		m.handleReceivePack(c, repoName, handler)
+		return
+	}
+
+	if c.Request.Method != http.MethodPost || gitPath != "/git-upload-pack" {
+		handler.ServeHTTP(c.Writer, c.Request)
+		return
+	}
+
+	// Clones and fetches, protocol v2 clients also list references with ls-refs which is not recorded
+	command := m.protocolV2Command(c.Request)
+
 	handler.ServeHTTP(c.Writer, c.Request)
+
+	if command == "" || command == "fetch" {
+		m.recordAudit(c, audit.ActionGitFetch, repoName, "", nil)
+	}
+}
+
+// hasDotSegment reports whether a slash separated path contains "." or ".." segments
+func hasDotSegment(path string) bool {
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "." || segment == ".." {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,47 @@
+package git_http
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weedbox/git-modules/audit"
+	"github.com/weedbox/git-modules/auth"
+	"github.com/weedbox/git-modules/repository_manager"
+)
+
+// recordAudit writes an audit entry for a git operation once it has been answered.
+// The operation failed if err is set or the response status is an error.
+func (m *GitHTTP) recordAudit(c *gin.Context, action, repoName, target string, err error) {
+	status := c.Writer.Status()
+
+	entry := audit.Entry{
+		Actor:      auth.NameFromContext(c.Request.Context()),
+		Action:     action,
+		Repository: repoName,
+		Target:     target,
+		RemoteAddr: c.ClientIP(),
+		Outcome:    audit.OutcomeSuccess,
+		Status:     status,
+	}
+
+	switch {
+	case err != nil:
+		entry.Outcome = audit.OutcomeFailure
+		entry.Error = err.Error()
+	case status >= http.StatusBadRequest:
+		entry.Outcome = audit.OutcomeFailure
+		entry.Error = http.StatusText(status)
+	}
+
+	m.params.AuditLog.Record(entry)
+}
+
+// referenceNames joins the names of the references of a push for the audit log
+func referenceNames(updates []repository_manager.ReferenceUpdate) string {
+	names := make([]string, 0, len(updates))
+	for _, update := range updates {
+		names = append(names, update.Name.String())
+	}
+	return strings.Join(names, ",")
+}
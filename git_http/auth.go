@@ -0,0 +1,141 @@
+package git_http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weedbox/git-modules/audit"
+	"github.com/weedbox/git-modules/auth"
+	"github.com/weedbox/git-modules/repository_manager"
+	"github.com/weedbox/git-modules/tokens"
+	"go.uber.org/zap"
+)
+
+// gitOperation returns the access a git HTTP request needs.
+// The ref advertisement of git-receive-pack already requires write access,
+// so clients are challenged before they upload a packfile.
+func gitOperation(r *http.Request, gitPath string) auth.Operation {
+	if lfsPath, ok := strings.CutPrefix(gitPath, lfsPathPrefix); ok {
+		return lfsOperation(r, lfsPath)
+	}
+	if gitPath == "/git-receive-pack" || r.URL.Query().Get("service") == "git-receive-pack" {
+		return auth.OperationWrite
+	}
+	return auth.OperationRead
+}
+
+// contextKeyAuthenticated marks requests whose identity was set by authenticate
+const contextKeyAuthenticated = "git_http.authenticated"
+
+// authenticate checks a git request with the Authenticator, if one is provided, and stores the
+// authenticated identity in the request context for policies and hooks.
+// Requests carrying an access token are authenticated by the token store instead.
+// Requests passed on by other transports already carry an identity and are not authenticated again,
+// requests authenticated here are, e.g. Git LFS batch requests found to upload objects.
+// It returns false if access is denied and the request has been answered.
+func (m *GitHTTP) authenticate(c *gin.Context, repoName string, op auth.Operation) bool {
+	if auth.IdentityFromContext(c.Request.Context()) != nil && !c.GetBool(contextKeyAuthenticated) {
+		return true
+	}
+
+	creds := auth.CredentialsFromRequest(c.Request)
+
+	authenticator := m.params.Authenticator
+	if m.params.Tokens != nil && tokens.IsToken(tokens.SecretFromCredentials(creds)) {
+		authenticator = m.params.Tokens
+	}
+	if authenticator == nil {
+		return true
+	}
+
+	identity, err := authenticator.Authenticate(c.Request.Context(), auth.Request{
+		Repository:  repoName,
+		Operation:   op,
+		Credentials: creds,
+	})
+
+	switch {
+	case err == nil:
+		if identity != nil {
+			c.Request = c.Request.WithContext(auth.WithIdentity(c.Request.Context(), identity))
+			c.Set(contextKeyAuthenticated, true)
+		}
+		return true
+
+	case errors.Is(err, auth.ErrForbidden) && !creds.IsEmpty():
+		m.deny(c, http.StatusForbidden, err)
+
+	case errors.Is(err, auth.ErrUnauthenticated), errors.Is(err, auth.ErrForbidden):
+		m.deny(c, http.StatusUnauthorized, auth.ErrUnauthenticated)
+
+	default:
+		m.logger.Error("Failed to authenticate git request", zap.String("repo", repoName), zap.Error(err))
+		m.deny(c, http.StatusInternalServerError, errors.New("authentication failed"))
+	}
+
+	// Anonymous requests answered with a challenge are part of the normal git flow
+	if !creds.IsEmpty() {
+		m.recordAudit(c, auditAction(op), repoName, "", err)
+	}
+
+	return false
+}
+
+// authorize checks that the identity of a git request holds the role an operation requires
+// on the repository, when role enforcement is enabled: read to fetch and write to push.
+// It returns false if access is denied and the request has been answered.
+func (m *GitHTTP) authorize(c *gin.Context, repoName string, op auth.Operation) bool {
+	if !m.enforceRoles {
+		return true
+	}
+
+	required := repository_manager.RoleRead
+	if op == auth.OperationWrite {
+		required = repository_manager.RoleWrite
+	}
+
+	identity := auth.IdentityFromContext(c.Request.Context())
+	err := m.params.RepositoryManager.Authorize(repoName, identity, required)
+
+	var forbidden *repository_manager.ForbiddenError
+	switch {
+	case err == nil:
+		return true
+
+	case errors.As(err, &forbidden) && identity == nil:
+		m.deny(c, http.StatusUnauthorized, auth.ErrUnauthenticated)
+		return false
+
+	case errors.As(err, &forbidden):
+		m.deny(c, http.StatusForbidden, err)
+
+	default:
+		m.logger.Error("Failed to authorize git request", zap.String("repo", repoName), zap.Error(err))
+		m.deny(c, http.StatusInternalServerError, errors.New("authorization failed"))
+	}
+
+	m.recordAudit(c, auditAction(op), repoName, "", err)
+	return false
+}
+
+// deny answers a git request with a plain text error, which git clients show to the user.
+// Unauthorized responses carry the challenges that make git ask for credentials.
+func (m *GitHTTP) deny(c *gin.Context, status int, err error) {
+	if status == http.StatusUnauthorized {
+		c.Header("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", m.realm))
+		c.Writer.Header().Add("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q", m.realm))
+	}
+
+	c.String(status, "%s\n", err.Error())
+}
+
+// auditAction returns the audit action of a git operation
+func auditAction(op auth.Operation) string {
+	if op == auth.OperationWrite {
+		return audit.ActionGitPush
+	}
+	return audit.ActionGitFetch
+}
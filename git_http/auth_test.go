@@ -0,0 +1,312 @@
+package git_http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp/capability"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/spf13/viper"
+	"github.com/weedbox/git-modules/auth"
+	"github.com/weedbox/git-modules/repository_manager"
+	"github.com/weedbox/git-modules/tokens"
+	"go.uber.org/fx"
+)
+
+// gitRequest sends a request to a git path of a repository URL, with Basic credentials if a password is given.
+// It returns the response status and headers.
+func gitRequest(t *testing.T, method, url, password string) (int, http.Header) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	if password != "" {
+		req.SetBasicAuth("git", password)
+	}
+
+	return gitRequestAs(t, req)
+}
+
+// gitRequestAs sends a prepared request and returns the response status and headers
+func gitRequestAs(t *testing.T, req *http.Request) (int, http.Header) {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send %s %s: %v", req.Method, req.URL, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	return resp.StatusCode, resp.Header
+}
+
+// enforceRoles enables role enforcement for the git_http servers started by a test
+func enforceRoles(t *testing.T) {
+	viper.Set("git_http.enforce_roles", true)
+	t.Cleanup(func() { viper.Set("git_http.enforce_roles", false) })
+}
+
+// Test that enforced roles decide between fetching and pushing, and challenge anonymous clients
+func TestEnforceRoles(t *testing.T) {
+	enforceRoles(t)
+
+	var store *tokens.TokenStore
+	url, manager := setupTestServerWith(t, false, fx.Populate(&store))
+
+	token, err := store.CreatePersonalToken("bob", tokens.Options{Name: "test", Scope: tokens.ScopeWrite})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	uploadPack := url + "/info/refs?service=git-upload-pack"
+	receivePack := url + "/info/refs?service=git-receive-pack"
+
+	status, header := gitRequest(t, http.MethodGet, uploadPack, "")
+	if status != http.StatusUnauthorized || len(header.Values("WWW-Authenticate")) != 2 {
+		t.Errorf("Expected anonymous fetches to be challenged with 401, got %d %v", status, header.Values("WWW-Authenticate"))
+	}
+	if status, _ := gitRequest(t, http.MethodGet, uploadPack, token.Secret); status != http.StatusForbidden {
+		t.Errorf("Expected 403 without role, got %d", status)
+	}
+
+	if _, err := manager.SetPermission("org/team", repository_manager.Permission{User: "bob", Role: repository_manager.RoleRead}); err != nil {
+		t.Fatalf("Failed to grant role: %v", err)
+	}
+	if status, _ := gitRequest(t, http.MethodGet, uploadPack, token.Secret); status != http.StatusOK {
+		t.Errorf("Expected the read role to allow fetches, got %d", status)
+	}
+	if status, _ := gitRequest(t, http.MethodGet, receivePack, token.Secret); status != http.StatusForbidden {
+		t.Errorf("Expected the read role not to allow pushes, got %d", status)
+	}
+	if status, _ := gitRequest(t, http.MethodPost, url+"/git-receive-pack", token.Secret); status != http.StatusForbidden {
+		t.Errorf("Expected pushes to need the write role, got %d", status)
+	}
+
+	if _, err := manager.SetPermission("org/team/app", repository_manager.Permission{User: "bob", Role: repository_manager.RoleWrite}); err != nil {
+		t.Fatalf("Failed to grant role: %v", err)
+	}
+	if status, _ := gitRequest(t, http.MethodGet, receivePack, token.Secret); status != http.StatusOK {
+		t.Errorf("Expected the write role to allow pushes, got %d", status)
+	}
+}
+
+// Test that access tokens are limited to their repository or group and to their scope
+func TestTokens(t *testing.T) {
+	var store *tokens.TokenStore
+	url, manager := setupTestServerWith(t, false, fx.Populate(&store))
+	if _, err := manager.CreateRepository("other/app", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	otherURL := strings.Replace(url, "/org/team/app.git", "/other/app.git", 1)
+
+	deploy, err := store.CreateDeployToken("org/team/app", tokens.Options{Name: "ci", Scope: tokens.ScopeRead})
+	if err != nil {
+		t.Fatalf("Failed to create deploy token: %v", err)
+	}
+	group, err := store.CreatePersonalToken("bob", tokens.Options{Name: "org", Scope: tokens.ScopeWrite, Group: "org"})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		url    string
+		secret string
+		want   int
+	}{
+		{"deploy token fetch", url + "/info/refs?service=git-upload-pack", deploy.Secret, http.StatusOK},
+		{"read-only deploy token push", url + "/info/refs?service=git-receive-pack", deploy.Secret, http.StatusForbidden},
+		{"deploy token of another repository", otherURL + "/info/refs?service=git-upload-pack", deploy.Secret, http.StatusForbidden},
+		{"group token push", url + "/info/refs?service=git-receive-pack", group.Secret, http.StatusOK},
+		{"group token outside its group", otherURL + "/info/refs?service=git-upload-pack", group.Secret, http.StatusForbidden},
+		{"unknown token", url + "/info/refs?service=git-upload-pack", "gitpat_invalid", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		if status, _ := gitRequest(t, http.MethodGet, tt.url, tt.secret); status != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, status)
+		}
+	}
+
+	// Revoked tokens are refused
+	if err := store.RevokeDeployToken("org/team/app", deploy.ID); err != nil {
+		t.Fatalf("Failed to revoke token: %v", err)
+	}
+	if status, _ := gitRequest(t, http.MethodGet, url+"/info/refs?service=git-upload-pack", deploy.Secret); status != http.StatusUnauthorized {
+		t.Errorf("Expected revoked token to be refused with 401, got %d", status)
+	}
+}
+
+// Test that pushes need write access, including the ref advertisement of git-receive-pack
+func TestGitOperation(t *testing.T) {
+	tests := []struct {
+		method  string
+		gitPath string
+		query   string
+		want    auth.Operation
+	}{
+		{http.MethodGet, "/info/refs", "service=git-upload-pack", auth.OperationRead},
+		{http.MethodPost, "/git-upload-pack", "", auth.OperationRead},
+		{http.MethodGet, "/info/refs", "service=git-receive-pack", auth.OperationWrite},
+		{http.MethodPost, "/git-receive-pack", "", auth.OperationWrite},
+		{http.MethodGet, "/info/refs", "", auth.OperationRead},
+		{http.MethodGet, "/HEAD", "", auth.OperationRead},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, "/git/org/app.git"+tt.gitPath+"?"+tt.query, nil)
+		if got := gitOperation(req, tt.gitPath); got != tt.want {
+			t.Errorf("%s %s?%s: got %v, want %v", tt.method, tt.gitPath, tt.query, got, tt.want)
+		}
+	}
+}
+
+// testAuthenticator lets anyone read org/team/app, alice write to it and nobody access org/team/secret
+var testAuthenticator = auth.AuthenticatorFunc(func(ctx context.Context, req auth.Request) (*auth.Identity, error) {
+	if req.Credentials.IsEmpty() {
+		if req.Repository == "org/team/app" && req.Operation == auth.OperationRead {
+			return nil, nil
+		}
+		return nil, auth.ErrUnauthenticated
+	}
+
+	if req.Credentials.Username != "alice" || req.Credentials.Password != "secret" {
+		return nil, auth.ErrUnauthenticated
+	}
+	if req.Repository != "org/team/app" {
+		return nil, auth.ErrForbidden
+	}
+	return &auth.Identity{Name: "alice"}, nil
+})
+
+// setupAuthenticatedServer starts git_http with testAuthenticator and the repositories org/team/app and org/team/secret
+func setupAuthenticatedServer(t *testing.T) string {
+	url, manager := setupTestServerWith(t, false, fx.Supply(fx.Annotate(testAuthenticator, fx.As(new(auth.Authenticator)))))
+	if _, err := manager.CreateRepository("org/team/secret", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	return url
+}
+
+// Test that the Authenticator challenges missing or wrong credentials with 401 and refuses valid ones with 403
+func TestAuthenticator(t *testing.T) {
+	url := setupAuthenticatedServer(t)
+	secretURL := strings.Replace(url, "/app.git", "/secret.git", 1)
+
+	tests := []struct {
+		name      string
+		url       string
+		password  string
+		want      int
+		challenge bool
+	}{
+		{"anonymous fetch", url + "/info/refs?service=git-upload-pack", "", http.StatusOK, false},
+		{"anonymous push", url + "/info/refs?service=git-receive-pack", "", http.StatusUnauthorized, true},
+		{"wrong password", url + "/info/refs?service=git-receive-pack", "wrong", http.StatusUnauthorized, true},
+		{"push", url + "/info/refs?service=git-receive-pack", "secret", http.StatusOK, false},
+		{"anonymous fetch of private repository", secretURL + "/info/refs?service=git-upload-pack", "", http.StatusUnauthorized, true},
+		{"forbidden repository", secretURL + "/info/refs?service=git-upload-pack", "secret", http.StatusForbidden, false},
+		{"missing repository is not revealed", strings.Replace(url, "/app.git", "/missing.git", 1) + "/info/refs", "", http.StatusUnauthorized, true},
+	}
+
+	for _, tt := range tests {
+		req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		if tt.password != "" {
+			req.SetBasicAuth("alice", tt.password)
+		}
+
+		status, header := gitRequestAs(t, req)
+		if status != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, status)
+		}
+		if challenged := len(header.Values("WWW-Authenticate")) > 0; challenged != tt.challenge {
+			t.Errorf("%s: expected challenge %v, got %v", tt.name, tt.challenge, header.Values("WWW-Authenticate"))
+		}
+	}
+}
+
+// Test that dot segments cannot authorize a request for one repository and serve another
+func TestPathTraversal(t *testing.T) {
+	url := setupAuthenticatedServer(t)
+
+	for _, path := range []string{
+		"/../secret.git/git-upload-pack",
+		"/../secret.git/info/refs?service=git-upload-pack",
+		"/./info/refs?service=git-upload-pack",
+		"/%2e%2e/secret.git/HEAD",
+	} {
+		if status, _ := gitRequest(t, http.MethodGet, url+path, ""); status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, status)
+		}
+	}
+
+	prefix := strings.TrimSuffix(url, "/org/team/app.git")
+	if status, _ := gitRequest(t, http.MethodGet, prefix+"/org/team/../team/secret.git/HEAD", ""); status != http.StatusBadRequest {
+		t.Errorf("Expected dot segments in the repository name to be refused, got %d", status)
+	}
+}
+
+// Test that only identities stored by other transports through ReceivePack skip the Authenticator,
+// pushes over HTTP without valid credentials are refused
+func TestReceivePackIdentity(t *testing.T) {
+	var m *GitHTTP
+	url, _ := setupTestServerWith(t, false,
+		fx.Supply(fx.Annotate(testAuthenticator, fx.As(new(auth.Authenticator)))),
+		fx.Populate(&m),
+	)
+
+	out, _ := runGit(t, t.TempDir(), "ls-remote", url, "refs/heads/master")
+	head := plumbing.NewHash(strings.Fields(out)[0])
+
+	pushRequest := func(branch string) *bytes.Buffer {
+		req := packp.NewReferenceUpdateRequest()
+		req.Capabilities.Add(capability.ReportStatus)
+		req.Commands = []*packp.Command{{Name: plumbing.NewBranchReferenceName(branch), New: head}}
+
+		buf := &bytes.Buffer{}
+		if err := req.Encode(buf); err != nil {
+			t.Fatalf("Failed to encode request: %v", err)
+		}
+		// The commit already exists, so an empty packfile follows the commands
+		if _, err := packfile.NewEncoder(buf, memory.NewStorage(), false).Encode(nil, 0); err != nil {
+			t.Fatalf("Failed to encode packfile: %v", err)
+		}
+		return buf
+	}
+
+	// Over HTTP the request context never carries an identity, whatever the request sends
+	req, _ := http.NewRequest(http.MethodPost, url+"/git-receive-pack", pushRequest("http"))
+	req.Header.Set("Content-Type", "application/x-git-receive-pack-request")
+	req.Header.Set("X-Remote-User", "alice")
+	if status, _ := gitRequestAs(t, req); status != http.StatusUnauthorized {
+		t.Errorf("Expected anonymous HTTP push to be challenged, got %d", status)
+	}
+
+	// ReceivePack without an identity is authenticated like any HTTP request
+	w := httptest.NewRecorder()
+	m.ReceivePack(context.Background(), w, "org/team/app", "127.0.0.1:1234", pushRequest("anonymous"))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected ReceivePack without identity to be challenged, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	ctx := auth.WithIdentity(context.Background(), &auth.Identity{Name: "alice"})
+	m.ReceivePack(ctx, w, "org/team/app", "127.0.0.1:1234", pushRequest("transport"))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "ok refs/heads/transport") {
+		t.Errorf("Expected push with a transport identity to be accepted, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if out, _ := runGit(t, t.TempDir(), "ls-remote", url, "refs/heads/*"); strings.Contains(out, "refs/heads/http") || strings.Contains(out, "refs/heads/anonymous") {
+		t.Errorf("Expected only the transport push to be applied, got %s", out)
+	}
+}
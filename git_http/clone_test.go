@@ -0,0 +1,91 @@
+package git_http
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/weedbox/git-modules/repository_manager"
+)
+
+// commitTestFiles adds commits to the default branch of the test repository
+func commitTestFiles(t *testing.T, manager *repository_manager.RepositoryManager, paths ...string) {
+	for _, path := range paths {
+		_, err := manager.CommitFiles("org/team/app", repository_manager.CommitRequest{
+			Message: "Add " + path,
+			Actions: []repository_manager.FileAction{{Action: repository_manager.FileActionCreate, Path: path, Content: []byte(path + "\n")}},
+		})
+		if err != nil {
+			t.Fatalf("Failed to commit: %v", err)
+		}
+	}
+}
+
+// Test shallow clones and deepening them
+func TestShallowClone(t *testing.T) {
+	url, manager := setupTestServer(t, true)
+	commitTestFiles(t, manager, "a.txt", "b.txt")
+	dir := t.TempDir()
+
+	for _, protocol := range []string{"0", "2"} {
+		t.Run("protocol v"+protocol, func(t *testing.T) {
+			repoDir := filepath.Join(dir, "shallow-v"+protocol)
+			runGit(t, dir, "-c", "protocol.version="+protocol, "clone", "-q", "--depth=1", url, repoDir)
+
+			if out, _ := runGit(t, repoDir, "rev-list", "--count", "HEAD"); strings.TrimSpace(out) != "1" {
+				t.Errorf("Expected 1 commit in a depth 1 clone, got %s", out)
+			}
+
+			runGit(t, repoDir, "-c", "protocol.version="+protocol, "fetch", "-q", "--deepen=1")
+			if out, _ := runGit(t, repoDir, "rev-list", "--count", "HEAD"); strings.TrimSpace(out) != "2" {
+				t.Errorf("Expected 2 commits after deepening, got %s", out)
+			}
+
+			runGit(t, repoDir, "-c", "protocol.version="+protocol, "fetch", "-q", "--shallow-since=2000-01-01")
+			if out, _ := runGit(t, repoDir, "rev-list", "--count", "HEAD"); strings.TrimSpace(out) != "3" {
+				t.Errorf("Expected 3 commits since 2000, got %s", out)
+			}
+		})
+	}
+	// Shallow clones can push on top of their history
+	repoDir := filepath.Join(dir, "push")
+	runGit(t, dir, "clone", "-q", "--depth=1", url, repoDir)
+	runGit(t, repoDir, "-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "Shallow push")
+	runGit(t, repoDir, "push", "-q", "origin", "HEAD")
+}
+
+// Test partial clones and the filter policy of a repository
+func TestPartialClone(t *testing.T) {
+	url, manager := setupTestServer(t, true)
+	commitTestFiles(t, manager, "a.txt", "b.txt")
+	dir := t.TempDir()
+
+	// Blobs are left out of the clone, then fetched on demand by the checkout
+	for _, protocol := range []string{"0", "2"} {
+		repoDir := filepath.Join(dir, "partial-v"+protocol)
+		runGit(t, dir, "-c", "protocol.version="+protocol, "clone", "-q", "--filter=blob:none", "--no-checkout", url, repoDir)
+
+		out, _ := runGit(t, repoDir, "rev-list", "--objects", "--all", "--missing=print")
+		if missing := strings.Count(out, "\n?"); missing != 3 {
+			t.Errorf("Expected 3 missing blobs over protocol v%s, got %d", protocol, missing)
+		}
+
+		runGit(t, repoDir, "-c", "protocol.version="+protocol, "checkout", "-q", "HEAD")
+		if out, _ := runGit(t, repoDir, "show", "HEAD:b.txt"); out != "b.txt\n" {
+			t.Errorf("Expected b.txt to be fetched on checkout over protocol v%s, got %q", protocol, out)
+		}
+	}
+
+	if _, err := manager.SetFilterPolicy("org/team/app", repository_manager.FilterPolicy{Deny: []string{repository_manager.FilterBlobNone}}); err != nil {
+		t.Fatalf("Failed to set filter policy: %v", err)
+	}
+
+	cmd := exec.Command("git", "clone", "-q", "--bare", "--filter=blob:none", url, "denied.git")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(out), "filter 'blob:none' not supported") {
+		t.Errorf("Expected denied filter to be refused, got %v: %s", err, out)
+	}
+
+	runGit(t, dir, "clone", "-q", "--bare", "--filter=blob:limit=1k", url, "limit.git")
+}
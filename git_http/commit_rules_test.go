@@ -0,0 +1,55 @@
+package git_http
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/weedbox/git-modules/repository_manager"
+)
+
+// Test pushes violating the commit rules of a protected branch inherited from a group
+func TestCommitRulesPush(t *testing.T) {
+	url, manager := setupTestServer(t, true)
+	dir := t.TempDir()
+	repoDir := filepath.Join(dir, "app")
+	runGit(t, dir, "clone", "-q", url, repoDir)
+
+	if _, err := manager.CreateBranchProtectionRule("org", repository_manager.BranchProtectionRule{
+		Pattern:              "*",
+		CommitMessagePattern: `^(feat|fix|docs): `,
+		RequireSignoff:       true,
+		AllowedEmailDomains:  []string{"example.com"},
+	}); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	runGit(t, repoDir, "-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-s", "-m", "docs: start")
+	runGit(t, repoDir, "-c", "user.name=Bob", "-c", "user.email=bob@other.org", "commit", "-q", "--allow-empty", "-m", "wip")
+
+	cmd := exec.Command("git", "push", "origin", "HEAD")
+	cmd.Dir = repoDir
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("Expected push to be rejected: %s", out)
+	}
+
+	expected := []string{
+		"commit message does not match ^(feat|fix|docs): ",
+		"missing Signed-off-by of the author bob@other.org",
+		"email bob@other.org is not in an allowed domain",
+	}
+	for _, violation := range expected {
+		if !strings.Contains(string(out), violation) {
+			t.Errorf("Expected violation %q to be reported, got: %s", violation, out)
+		}
+	}
+	if strings.Contains(string(out), "test@example.com") {
+		t.Errorf("Expected the compliant commit not to be reported, got: %s", out)
+	}
+
+	// Compliant pushes are accepted
+	runGit(t, repoDir, "reset", "-q", "--hard", "HEAD~1")
+	runGit(t, repoDir, "push", "-q", "origin", "HEAD")
+}
@@ -0,0 +1,105 @@
+package git_http
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weedbox/git-modules/audit"
+	"go.uber.org/zap"
+)
+
+// dumbFile is a repository file served to clients of the dumb HTTP protocol
+type dumbFile struct {
+	pattern     *regexp.Regexp
+	contentType string
+
+	// immutable files are named after their content and may be cached forever
+	immutable bool
+}
+
+// dumbFiles are the files read by dumb clients, as served by git http-backend
+var dumbFiles = []dumbFile{
+	{regexp.MustCompile(`^/HEAD$`), "text/plain", false},
+	{regexp.MustCompile(`^/info/refs$`), "text/plain", false},
+	{regexp.MustCompile(`^/objects/info/packs$`), "text/plain; charset=utf-8", false},
+	{regexp.MustCompile(`^/objects/[0-9a-f]{2}/[0-9a-f]{38}$`), "application/x-git-loose-object", true},
+	{regexp.MustCompile(`^/objects/pack/pack-[0-9a-f]{40}\.pack$`), "application/x-git-packed-objects", true},
+	{regexp.MustCompile(`^/objects/pack/pack-[0-9a-f]{40}\.idx$`), "application/x-git-packed-objects-toc", true},
+}
+
+// isDumbRequest reports whether a request reads a repository file with the dumb HTTP protocol.
+// Smart clients request info/refs with a service parameter, dumb clients without.
+func isDumbRequest(r *http.Request, gitPath string) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if gitPath == "/info/refs" && r.URL.Query().Get("service") != "" {
+		return false
+	}
+
+	_, ok := findDumbFile(gitPath)
+	return ok
+}
+
+func findDumbFile(gitPath string) (dumbFile, bool) {
+	for _, file := range dumbFiles {
+		if file.pattern.MatchString(gitPath) {
+			return file, true
+		}
+	}
+	return dumbFile{}, false
+}
+
+// serveDumbFile serves a repository file to a dumb client.
+// The server info files are regenerated if missing, e.g. for repositories created before
+// they were maintained. Missing objects are answered with 404, dumb clients then look for
+// them in the packfiles.
+func (m *GitHTTP) serveDumbFile(c *gin.Context, repoName, repoPath, gitPath string) {
+	file, _ := findDumbFile(gitPath)
+	path := filepath.Join(repoPath, filepath.FromSlash(strings.TrimPrefix(gitPath, "/")))
+
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) && (gitPath == "/info/refs" || gitPath == "/objects/info/packs") {
+		if err := m.params.RepositoryManager.UpdateServerInfo(repoName); err != nil {
+			m.logger.Warn("Failed to update server info", zap.String("repo", repoName), zap.Error(err))
+		}
+		f, err = os.Open(path)
+	}
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			c.String(http.StatusNotFound, "Not Found")
+		} else {
+			m.logger.Error("Failed to open repository file", zap.String("repo", repoName), zap.String("gitPath", gitPath), zap.Error(err))
+			c.String(http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		c.String(http.StatusNotFound, "Not Found")
+		return
+	}
+
+	c.Header("Content-Type", file.contentType)
+	if file.immutable {
+		c.Header("Cache-Control", "public, max-age=31536000")
+	} else {
+		c.Header("Cache-Control", "no-cache")
+	}
+
+	// ServeContent handles range requests used to resume packfile downloads
+	http.ServeContent(c.Writer, c.Request, "", info.ModTime(), f)
+
+	// Every dumb clone or fetch starts by reading info/refs
+	if gitPath == "/info/refs" {
+		m.recordAudit(c, audit.ActionGitFetch, repoName, "", nil)
+	}
+}
@@ -0,0 +1,46 @@
+package git_http
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test cloning and fetching with the dumb HTTP protocol
+func TestDumbProtocol(t *testing.T) {
+	url, manager := setupTestServer(t, true)
+	dir := t.TempDir()
+
+	resp, err := http.Get(url + "/info/refs")
+	if err != nil {
+		t.Fatalf("Failed to get info/refs: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/plain" {
+		t.Fatalf("Expected plain info/refs, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	// Git clients only use the dumb protocol when smart HTTP is disabled
+	t.Setenv("GIT_SMART_HTTP", "0")
+
+	_, trace := runGit(t, dir, "clone", "--bare", url, "app.git")
+	if trace != "" {
+		t.Errorf("Expected dumb clone without smart protocol packets, got trace:\n%s", trace)
+	}
+	repoDir := filepath.Join(dir, "app.git")
+
+	out, _ := runGit(t, repoDir, "tag")
+	if tags := strings.Fields(out); len(tags) != testTagCount {
+		t.Errorf("Expected %d tags, got %d", testTagCount, len(tags))
+	}
+
+	// Deleted tags disappear from info/refs
+	if err := manager.DeleteTag("org/team/app", "v1.0.0"); err != nil {
+		t.Fatalf("Failed to delete tag: %v", err)
+	}
+	out, _ = runGit(t, repoDir, "ls-remote", "--tags", url)
+	if strings.Contains(out, "refs/tags/v1.0.0\n") {
+		t.Errorf("Expected deleted tag to be removed from info/refs, got:\n%s", out)
+	}
+}
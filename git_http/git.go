@@ -0,0 +1,211 @@
+package git_http
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/pktline"
+	"github.com/sosedoff/gitkit"
+	"github.com/weedbox/git-modules/repository_manager"
+	"go.uber.org/zap"
+)
+
+// gitServer serves the git smart HTTP protocol next to gitkit. It runs git upload-pack itself,
+// like git http-backend, and hands receive-pack requests to gitkit. It expects paths with the
+// URL prefix removed, e.g. /org/app.git/info/refs.
+//
+// When protocol v2 is enabled, the Git-Protocol header of a request is passed to git as
+// GIT_PROTOCOL, so clients asking for version=2 are served the ls-refs and fetch commands.
+// Pushes always use protocol v0, as git receive-pack does not support v2.
+//
+// Shallow clones are served by git upload-pack, partial clones with the filters allowed by
+// the filter policy of the repository.
+type gitServer struct {
+	logger      *zap.Logger
+	manager     *repository_manager.RepositoryManager
+	reposPath   string
+	protocolV2  bool
+	receivePack http.Handler
+}
+
+func newGitServer(logger *zap.Logger, manager *repository_manager.RepositoryManager, protocolV2 bool) *gitServer {
+	return &gitServer{
+		logger:     logger,
+		manager:    manager,
+		reposPath:  manager.GetReposPath(),
+		protocolV2: protocolV2,
+		receivePack: gitkit.New(gitkit.Config{
+			Dir:        manager.GetReposPath(),
+			AutoCreate: false,
+			AutoHooks:  false,
+			Auth:       false, // Requests are authenticated by the Authenticator before reaching gitkit
+		}),
+	}
+}
+
+func (s *gitServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	repoName, gitPath, ok := splitGitPath(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	var err error
+	switch {
+	case r.Method == http.MethodGet && gitPath == "/info/refs" && r.URL.Query().Get("service") == serviceReceivePack,
+		r.Method == http.MethodPost && gitPath == "/git-receive-pack":
+		s.receivePack.ServeHTTP(w, r)
+		return
+	case r.Method == http.MethodGet && gitPath == "/info/refs":
+		err = s.serveInfoRefs(w, r, repoName)
+	case r.Method == http.MethodPost && gitPath == "/git-upload-pack":
+		err = s.serveRPC(w, r, serviceUploadPack, repoName)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if err != nil {
+		s.logger.Warn("Failed to serve git request",
+			zap.String("repo", repoName),
+			zap.String("gitPath", gitPath),
+			zap.Error(err),
+		)
+	}
+}
+
+// serveInfoRefs writes the reference advertisement of git upload-pack.
+// Protocol v2 advertisements are capabilities only and are not preceded by the service line.
+func (s *gitServer) serveInfoRefs(w http.ResponseWriter, r *http.Request, repoName string) error {
+	service := r.URL.Query().Get("service")
+	if service != serviceUploadPack {
+		http.Error(w, "only the smart HTTP protocol is supported", http.StatusForbidden)
+		return nil
+	}
+
+	cmd, err := s.command(r, service, repoName, "--advertise-refs")
+	if err != nil {
+		http.Error(w, "failed to read references", http.StatusInternalServerError)
+		return err
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		http.Error(w, "failed to read references", http.StatusInternalServerError)
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	w.Header().Set("Content-Type", "application/x-"+service+"-advertisement")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	if protocolVersion(s.gitProtocol(r)) != 2 {
+		enc := pktline.NewEncoder(w)
+		if err := enc.Encodef("# service=%s\n", service); err != nil {
+			return err
+		}
+		if err := enc.Flush(); err != nil {
+			return err
+		}
+	}
+
+	_, err = w.Write(out)
+	return err
+}
+
+// serveRPC runs a service on the request body and streams its output to the client
+func (s *gitServer) serveRPC(w http.ResponseWriter, r *http.Request, service, repoName string) error {
+	body, err := requestBody(r)
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return err
+	}
+	defer body.Close()
+
+	cmd, err := s.command(r, service, repoName)
+	if err != nil {
+		http.Error(w, "failed to run "+service, http.StatusInternalServerError)
+		return err
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stdin = body
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		http.Error(w, "failed to run "+service, http.StatusInternalServerError)
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		http.Error(w, "failed to run "+service, http.StatusInternalServerError)
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/x-"+service+"-result")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	_, copyErr := io.Copy(&flushWriter{w: w}, stdout)
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return copyErr
+}
+
+// command prepares a git service on a repository for a request, which is stopped if the client goes away
+func (s *gitServer) command(r *http.Request, service, repoName string, args ...string) (*exec.Cmd, error) {
+	var gitArgs []string
+	if service == serviceUploadPack {
+		config, err := s.manager.UploadPackConfig(repoName)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range config {
+			gitArgs = append(gitArgs, "-c", entry)
+		}
+	}
+
+	gitArgs = append(gitArgs, strings.TrimPrefix(service, "git-"), "--stateless-rpc")
+	gitArgs = append(gitArgs, args...)
+	gitArgs = append(gitArgs, filepath.Join(s.reposPath, repoName+".git"))
+
+	cmd := exec.CommandContext(r.Context(), "git", gitArgs...)
+	cmd.Env = os.Environ()
+	if protocol := s.gitProtocol(r); protocol != "" {
+		cmd.Env = append(cmd.Env, "GIT_PROTOCOL="+protocol)
+	}
+
+	return cmd, nil
+}
+
+// gitProtocol returns the Git-Protocol header of a request, empty if protocol v2 is disabled
+func (s *gitServer) gitProtocol(r *http.Request) string {
+	if !s.protocolV2 {
+		return ""
+	}
+	return r.Header.Get(headerGitProtocol)
+}
+
+// flushWriter flushes every write, so clients receive progress and packfiles as they are produced
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	if f, ok := fw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return n, err
+}
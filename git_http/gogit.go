@@ -0,0 +1,495 @@
+package git_http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/plumbing/format/pktline"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp/capability"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"go.uber.org/zap"
+)
+
+const (
+	serviceUploadPack  = "git-upload-pack"
+	serviceReceivePack = "git-receive-pack"
+)
+
+// capabilityNoThin asks clients not to send thin packs. Pushed packfiles are stored as sent,
+// so they must not contain deltas against objects outside the pack.
+const capabilityNoThin capability.Capability = "no-thin"
+
+// packWindow is the number of objects compared to find deltas when encoding packfiles
+const packWindow = 10
+
+// goGitServer serves the git smart HTTP protocol with go-git instead of the git binary.
+// Like gitServer, it expects paths with the URL prefix removed, e.g. /org/app.git/info/refs.
+//
+// Fetches are negotiated with multi_ack_detailed as the protocol is stateless over HTTP:
+// every round acknowledges the common commits among the haves of the client, which sends them
+// again with the next round, until the client is done and receives a packfile of the objects
+// it is missing. Only advertised references can be wanted. Shallow and partial clones and protocol v2 are not supported, clients
+// requesting v2 are answered with v0 and fall back to it.
+type goGitServer struct {
+	logger    *zap.Logger
+	reposPath string
+}
+
+func newGoGitServer(logger *zap.Logger, reposPath string) *goGitServer {
+	return &goGitServer{
+		logger:    logger,
+		reposPath: reposPath,
+	}
+}
+
+func (s *goGitServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	repoName, gitPath, ok := splitGitPath(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	fs := osfs.New(filepath.Join(s.reposPath, repoName+".git"))
+	sto := filesystem.NewStorage(fs, cache.NewObjectLRUDefault())
+	defer sto.Close()
+
+	var err error
+	switch {
+	case r.Method == http.MethodGet && gitPath == "/info/refs":
+		err = s.serveInfoRefs(w, r, sto)
+	case r.Method == http.MethodPost && gitPath == "/git-upload-pack":
+		err = s.serveUploadPack(w, r, sto)
+	case r.Method == http.MethodPost && gitPath == "/git-receive-pack":
+		err = s.serveReceivePack(w, r, sto)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if err != nil {
+		s.logger.Warn("Failed to serve git request",
+			zap.String("repo", repoName),
+			zap.String("gitPath", gitPath),
+			zap.Error(err),
+		)
+	}
+}
+
+// serveInfoRefs writes the reference advertisement of a service
+func (s *goGitServer) serveInfoRefs(w http.ResponseWriter, r *http.Request, sto *filesystem.Storage) error {
+	service := r.URL.Query().Get("service")
+	if service != serviceUploadPack && service != serviceReceivePack {
+		http.Error(w, "only the smart HTTP protocol is supported", http.StatusForbidden)
+		return nil
+	}
+
+	adv, err := advertiseReferences(sto, service)
+	if err != nil {
+		http.Error(w, "failed to read references", http.StatusInternalServerError)
+		return err
+	}
+	adv.Prefix = [][]byte{[]byte("# service=" + service), pktline.Flush}
+
+	w.Header().Set("Content-Type", "application/x-"+service+"-advertisement")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	return adv.Encode(w)
+}
+
+// serveUploadPack answers a round of fetch negotiation, with a packfile once the client is done
+func (s *goGitServer) serveUploadPack(w http.ResponseWriter, r *http.Request, sto *filesystem.Storage) error {
+	body, err := requestBody(r)
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return err
+	}
+	defer body.Close()
+
+	req := packp.NewUploadPackRequest()
+	if err := req.UploadRequest.Decode(body); err != nil {
+		http.Error(w, "invalid upload-pack request", http.StatusBadRequest)
+		return err
+	}
+	if len(req.Shallows) > 0 || !req.Depth.IsZero() {
+		http.Error(w, "shallow clones are not supported", http.StatusBadRequest)
+		return nil
+	}
+
+	done, err := decodeHaves(body, &req.UploadHaves)
+	if err != nil {
+		http.Error(w, "invalid upload-pack request", http.StatusBadRequest)
+		return err
+	}
+
+	// Only advertised references and the objects their tags point to can be fetched,
+	// like git upload-pack without uploadpack.allowAnySHA1InWant
+	adv, err := advertiseReferences(sto, serviceUploadPack)
+	if err != nil {
+		http.Error(w, "failed to read references", http.StatusInternalServerError)
+		return err
+	}
+	tips := make(map[plumbing.Hash]bool, len(adv.References)+len(adv.Peeled))
+	for _, hash := range adv.References {
+		tips[hash] = true
+	}
+	for _, hash := range adv.Peeled {
+		tips[hash] = true
+	}
+
+	// Haves the repository does not have are unknown to the negotiation
+	var common []plumbing.Hash
+	for _, have := range req.Haves {
+		if _, err := sto.EncodedObject(plumbing.AnyObject, have); err == nil {
+			common = append(common, have)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/x-git-upload-pack-result")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	enc := pktline.NewEncoder(w)
+	for _, want := range req.Wants {
+		if !tips[want] {
+			return enc.Encodef("ERR upload-pack: not our ref %s\n", want)
+		}
+	}
+
+	if !done {
+		// Acknowledge the common commits of this round, the client sends them again with the next request
+		for _, hash := range common {
+			if err := enc.Encodef("ACK %s common\n", hash); err != nil {
+				return err
+			}
+		}
+		return enc.Encodef("NAK\n")
+	}
+
+	if len(common) > 0 {
+		err = enc.Encodef("ACK %s\n", common[len(common)-1])
+	} else {
+		err = enc.Encodef("NAK\n")
+	}
+	if err != nil {
+		return err
+	}
+
+	objects, err := missingObjects(sto, req.Wants, common)
+	if err != nil {
+		return err
+	}
+
+	_, err = packfile.NewEncoder(w, sto, false).Encode(objects, packWindow)
+	return err
+}
+
+// missingObjects returns the objects reachable from wants that a client having the common commits misses.
+// The history is walked from the wants down to the common commits only, and the client is assumed
+// to have the trees of the common commits, so fetches cost in proportion to what changed.
+// Commits reachable from the common commits through other paths may be sent again.
+func missingObjects(sto storer.EncodedObjectStorer, wants, common []plumbing.Hash) ([]plumbing.Hash, error) {
+	seen := make(map[plumbing.Hash]bool)
+	for _, hash := range common {
+		seen[hash] = true
+		commit, err := object.GetCommit(sto, hash)
+		if err != nil {
+			continue
+		}
+		if err := walkTree(sto, commit.TreeHash, seen, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	var objects []plumbing.Hash
+	pending := append([]plumbing.Hash(nil), wants...)
+	for len(pending) > 0 {
+		hash := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if seen[hash] {
+			continue
+		}
+
+		obj, err := sto.EncodedObject(plumbing.AnyObject, hash)
+		if err != nil {
+			return nil, err
+		}
+
+		switch obj.Type() {
+		case plumbing.CommitObject:
+			seen[hash] = true
+			objects = append(objects, hash)
+			commit, err := object.DecodeCommit(sto, obj)
+			if err != nil {
+				return nil, err
+			}
+			if err := walkTree(sto, commit.TreeHash, seen, &objects); err != nil {
+				return nil, err
+			}
+			pending = append(pending, commit.ParentHashes...)
+		case plumbing.TagObject:
+			seen[hash] = true
+			objects = append(objects, hash)
+			tag, err := object.DecodeTag(sto, obj)
+			if err != nil {
+				return nil, err
+			}
+			pending = append(pending, tag.Target)
+		case plumbing.TreeObject:
+			if err := walkTree(sto, hash, seen, &objects); err != nil {
+				return nil, err
+			}
+		default:
+			seen[hash] = true
+			objects = append(objects, hash)
+		}
+	}
+
+	return objects, nil
+}
+
+// walkTree marks a tree and the trees and blobs below it as seen, adding the ones not seen before to objects if given.
+// Submodule commits are not part of the repository and are skipped.
+func walkTree(sto storer.EncodedObjectStorer, hash plumbing.Hash, seen map[plumbing.Hash]bool, objects *[]plumbing.Hash) error {
+	if seen[hash] {
+		return nil
+	}
+	seen[hash] = true
+	if objects != nil {
+		*objects = append(*objects, hash)
+	}
+
+	tree, err := object.GetTree(sto, hash)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range tree.Entries {
+		switch {
+		case entry.Mode == filemode.Submodule:
+		case entry.Mode == filemode.Dir:
+			if err := walkTree(sto, entry.Hash, seen, objects); err != nil {
+				return err
+			}
+		case !seen[entry.Hash]:
+			seen[entry.Hash] = true
+			if objects != nil {
+				*objects = append(*objects, entry.Hash)
+			}
+		}
+	}
+
+	return nil
+}
+
+// serveReceivePack stores the pushed objects and updates the references of a push.
+// References are only updated if they still point at the old hash sent by the client.
+func (s *goGitServer) serveReceivePack(w http.ResponseWriter, r *http.Request, sto *filesystem.Storage) error {
+	body, err := requestBody(r)
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return err
+	}
+	defer body.Close()
+
+	req, err := DecodeReferenceUpdateRequest(body)
+	if err != nil {
+		http.Error(w, "invalid receive-pack request", http.StatusBadRequest)
+		return err
+	}
+
+	report := packp.NewReportStatus()
+	report.UnpackStatus = "ok"
+
+	// Pushes deleting references only carry no packfile
+	var unpackErr error
+	for _, cmd := range req.Commands {
+		if !cmd.New.IsZero() {
+			unpackErr = packfile.UpdateObjectStorage(sto, req.Packfile)
+			break
+		}
+	}
+	if unpackErr != nil {
+		report.UnpackStatus = unpackErr.Error()
+	}
+
+	for _, cmd := range req.Commands {
+		status := "ok"
+		switch {
+		case unpackErr != nil:
+			status = "unpacker error"
+		default:
+			if err := updateReference(sto, cmd); err != nil {
+				status = err.Error()
+			}
+		}
+		report.CommandStatuses = append(report.CommandStatuses, &packp.CommandStatus{
+			ReferenceName: cmd.Name,
+			Status:        status,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/x-git-receive-pack-result")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	if req.Capabilities.Supports(capability.ReportStatus) {
+		if err := report.Encode(w); err != nil {
+			return err
+		}
+	}
+
+	return unpackErr
+}
+
+// advertiseReferences lists the references of a repository with the capabilities of a service
+func advertiseReferences(sto *filesystem.Storage, service string) (*packp.AdvRefs, error) {
+	adv := packp.NewAdvRefs()
+
+	caps := adv.Capabilities
+	if err := caps.Set(capability.Agent, capability.DefaultAgent()); err != nil {
+		return nil, err
+	}
+	if err := caps.Set(capability.OFSDelta); err != nil {
+		return nil, err
+	}
+
+	if service == serviceUploadPack {
+		if err := caps.Set(capability.MultiACKDetailed); err != nil {
+			return nil, err
+		}
+	}
+
+	if service == serviceReceivePack {
+		for _, c := range []capability.Capability{capability.ReportStatus, capability.DeleteRefs, capabilityNoThin} {
+			if err := caps.Set(c); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	refs, err := sto.IterReferences()
+	if err != nil {
+		return nil, err
+	}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() != plumbing.HashReference {
+			return nil
+		}
+
+		adv.References[ref.Name().String()] = ref.Hash()
+		if ref.Name().IsTag() {
+			if target, ok := peelTag(sto, ref.Hash()); ok {
+				adv.Peeled[ref.Name().String()] = target
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if service == serviceUploadPack {
+		head, err := sto.Reference(plumbing.HEAD)
+		if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return nil, err
+		}
+		if head != nil && head.Type() == plumbing.SymbolicReference {
+			if resolved, err := storer.ResolveReference(sto, plumbing.HEAD); err == nil {
+				hash := resolved.Hash()
+				adv.Head = &hash
+				if err := caps.Add(capability.SymRef, "HEAD:"+head.Target().String()); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return adv, nil
+}
+
+// peelTag returns the object an annotated tag points to, following nested tags
+func peelTag(sto *filesystem.Storage, hash plumbing.Hash) (plumbing.Hash, bool) {
+	peeled := false
+	for {
+		tag, err := object.GetTag(sto, hash)
+		if err != nil {
+			return hash, peeled
+		}
+		hash = tag.Target
+		peeled = true
+	}
+}
+
+// decodeHaves reads the have lines following the wants of an upload-pack request.
+// It returns true if the client ended the negotiation with done.
+func decodeHaves(r io.Reader, haves *packp.UploadHaves) (bool, error) {
+	scanner := pktline.NewScanner(r)
+	for scanner.Scan() {
+		line := bytes.TrimSuffix(scanner.Bytes(), []byte("\n"))
+		switch {
+		case len(line) == 0:
+			// Flush between rounds of haves
+		case bytes.Equal(line, []byte("done")):
+			return true, nil
+		case bytes.HasPrefix(line, []byte("have ")):
+			hash := string(line[len("have "):])
+			if !plumbing.IsHash(hash) {
+				return false, fmt.Errorf("invalid have line: %q", line)
+			}
+			haves.Haves = append(haves.Haves, plumbing.NewHash(hash))
+		default:
+			return false, fmt.Errorf("unexpected line: %q", line)
+		}
+	}
+
+	return false, scanner.Err()
+}
+
+// updateReference applies a command of a push if the reference still has the expected old value
+func updateReference(sto *filesystem.Storage, cmd *packp.Command) error {
+	current, err := sto.Reference(cmd.Name)
+	if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return err
+	}
+
+	var currentHash plumbing.Hash
+	if current != nil {
+		currentHash = current.Hash()
+	}
+	if currentHash != cmd.Old {
+		return errors.New("failed to lock: reference has changed")
+	}
+
+	if cmd.Action() == packp.Delete {
+		return sto.RemoveReference(cmd.Name)
+	}
+
+	if _, err := sto.EncodedObject(plumbing.AnyObject, cmd.New); err != nil {
+		return errors.New("missing necessary objects")
+	}
+
+	return sto.CheckAndSetReference(plumbing.NewHashReference(cmd.Name, cmd.New), current)
+}
+
+// requestBody returns the body of a request, decompressing gzip encoded bodies
+func requestBody(r *http.Request) (io.ReadCloser, error) {
+	if r.Header.Get("Content-Encoding") != "gzip" {
+		return r.Body, nil
+	}
+
+	return gzip.NewReader(r.Body)
+}
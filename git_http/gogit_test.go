@@ -0,0 +1,85 @@
+package git_http
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/format/pktline"
+	"github.com/spf13/viper"
+	"github.com/weedbox/git-modules/repository_manager"
+)
+
+// useGoGitBackend serves the git servers started by a test with go-git
+func useGoGitBackend(t *testing.T) {
+	viper.Set("git_http.backend", BackendGoGit)
+	t.Cleanup(func() { viper.Set("git_http.backend", DefaultBackend) })
+}
+
+// Test cloning, fetching and pushing with the go-git backend
+func TestGoGitBackend(t *testing.T) {
+	useGoGitBackend(t)
+	url, manager := setupTestServer(t, false)
+	dir := t.TempDir()
+
+	cloneDir := filepath.Join(dir, "clone")
+	runGit(t, dir, "clone", "-q", url, cloneDir)
+	if out, _ := runGit(t, cloneDir, "tag"); strings.Count(out, "\n") != testTagCount {
+		t.Errorf("Expected %d tags, got %q", testTagCount, out)
+	}
+
+	// Pushes are checked by the same policies as with the git backend
+	if _, err := manager.CreateBranchProtectionRule("org", repository_manager.BranchProtectionRule{Pattern: "master", DenyDeletion: true}); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	cmd := exec.Command("git", "push", "origin", ":master")
+	cmd.Dir = cloneDir
+	if out, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(out), "deletion is not allowed") {
+		t.Errorf("Expected deletion to be rejected, got %v: %s", err, out)
+	}
+
+	runGit(t, cloneDir, "-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "Pushed with go-git")
+	runGit(t, cloneDir, "push", "-q", "origin", "HEAD")
+	pushed, _ := runGit(t, cloneDir, "rev-parse", "HEAD")
+
+	// Fetches only transfer what changed since the common commits
+	commitTestFiles(t, manager, "fetched.txt")
+	runGit(t, cloneDir, "fetch", "-q", "origin")
+	if out, _ := runGit(t, cloneDir, "show", "origin/master:fetched.txt"); out != "fetched.txt\n" {
+		t.Errorf("Expected fetched file, got %q", out)
+	}
+	if out, _ := runGit(t, cloneDir, "rev-parse", "origin/master~1"); out != pushed {
+		t.Errorf("Expected fetched commit on top of %s, got %s", pushed, out)
+	}
+	runGit(t, cloneDir, "fsck", "--no-progress")
+}
+
+// Test that the go-git backend only serves objects reachable from advertised references
+func TestGoGitBackend_Wants(t *testing.T) {
+	useGoGitBackend(t)
+	url, _ := setupTestServer(t, false)
+
+	// The blob of README.md exists in the repository but is not a reference
+	blob := "ce013625030ba8dba906f756967f9e9ca394464a"
+
+	var body bytes.Buffer
+	enc := pktline.NewEncoder(&body)
+	enc.Encodef("want %s multi_ack_detailed ofs-delta\n", blob)
+	enc.Flush()
+	enc.Encodef("done\n")
+
+	resp, err := http.Post(url+"/git-upload-pack", "application/x-git-upload-pack-request", &body)
+	if err != nil {
+		t.Fatalf("Failed to send upload-pack request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(data), "ERR upload-pack: not our ref "+blob) {
+		t.Errorf("Expected unadvertised want to be refused, got %d %q", resp.StatusCode, data)
+	}
+}
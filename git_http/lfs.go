@@ -0,0 +1,248 @@
+package git_http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weedbox/git-modules/audit"
+	"github.com/weedbox/git-modules/auth"
+	"github.com/weedbox/git-modules/lfs"
+	"github.com/weedbox/git-modules/repository_manager"
+	"go.uber.org/zap"
+)
+
+// maxLFSRequestSize limits the JSON bodies of batch and verify requests
+const maxLFSRequestSize = 10 << 20
+
+// lfsPathPrefix starts the git paths of the Git LFS API of a repository
+const lfsPathPrefix = "/info/lfs/"
+
+// lfsOperation returns the access a Git LFS request needs: uploads and their verification
+// need write access. Batch requests need read access, the operation in their body is only
+// read once the request has been authenticated, see handleLFSBatch.
+func lfsOperation(r *http.Request, lfsPath string) auth.Operation {
+	if r.Method == http.MethodPut && strings.HasPrefix(lfsPath, "objects/") ||
+		r.Method == http.MethodPost && lfsPath == "objects/verify" {
+		return auth.OperationWrite
+	}
+
+	return auth.OperationRead
+}
+
+// handleLFS serves the Git LFS batch API and the basic transfer of a repository.
+// The request has already been authenticated and authorized for its operation.
+func (m *GitHTTP) handleLFS(c *gin.Context, repoName, lfsPath string) {
+	if m.params.LFS == nil {
+		m.lfsError(c, http.StatusNotFound, errors.New("Git LFS is not enabled on this server"))
+		return
+	}
+
+	method := c.Request.Method
+	oid, isObject := strings.CutPrefix(lfsPath, "objects/")
+
+	switch {
+	case method == http.MethodPost && lfsPath == "objects/batch":
+		m.handleLFSBatch(c, repoName)
+	case method == http.MethodPost && lfsPath == "objects/verify":
+		m.handleLFSVerify(c, repoName)
+	case isObject && (method == http.MethodGet || method == http.MethodHead):
+		m.handleLFSDownload(c, repoName, oid)
+	case isObject && method == http.MethodPut:
+		m.handleLFSUpload(c, repoName, oid)
+	default:
+		m.lfsError(c, http.StatusNotFound, fmt.Errorf("unsupported Git LFS request: %s %s", method, lfsPath))
+	}
+}
+
+// handleLFSBatch answers a batch request with the transfer actions of every object.
+// Downloads are offered for objects uploaded to the repository, uploads for objects it does not have yet
+// and that fit in the quotas of the repository. Upload requests are checked again for write access.
+func (m *GitHTTP) handleLFSBatch(c *gin.Context, repoName string) {
+	var req lfs.BatchRequest
+	if err := json.NewDecoder(io.LimitReader(c.Request.Body, maxLFSRequestSize)).Decode(&req); err != nil {
+		m.lfsError(c, http.StatusUnprocessableEntity, fmt.Errorf("invalid batch request: %w", err))
+		return
+	}
+
+	switch {
+	case req.Operation != lfs.OperationDownload && req.Operation != lfs.OperationUpload:
+		m.lfsError(c, http.StatusUnprocessableEntity, fmt.Errorf("invalid batch operation %q: must be %s or %s", req.Operation, lfs.OperationDownload, lfs.OperationUpload))
+		return
+	case req.HashAlgo != "" && req.HashAlgo != lfs.HashAlgoSHA256:
+		m.lfsError(c, http.StatusConflict, fmt.Errorf("hash algorithm %q is not supported", req.HashAlgo))
+		return
+	case len(req.Transfers) > 0 && !slices.Contains(req.Transfers, lfs.TransferBasic):
+		m.lfsError(c, http.StatusUnprocessableEntity, errors.New("only the basic transfer is supported"))
+		return
+	}
+
+	if req.Operation == lfs.OperationUpload {
+		if !m.authenticate(c, repoName, auth.OperationWrite) || !m.authorize(c, repoName, auth.OperationWrite) {
+			return
+		}
+	}
+
+	// Clients send their own credentials with transfers, the server hands out none
+	authenticated := auth.IdentityFromContext(c.Request.Context()) != nil
+	var pending int64
+
+	resp := lfs.BatchResponse{
+		Transfer: lfs.TransferBasic,
+		Objects:  make([]lfs.ObjectResponse, 0, len(req.Objects)),
+		HashAlgo: lfs.HashAlgoSHA256,
+	}
+	for _, pointer := range req.Objects {
+		object := lfs.ObjectResponse{OID: pointer.OID, Size: pointer.Size, Authenticated: authenticated}
+		if !lfs.IsValidOID(pointer.OID) || pointer.Size < 0 {
+			object.Error = &lfs.ObjectError{Code: http.StatusUnprocessableEntity, Message: lfs.ErrObjectInvalid.Error()}
+			resp.Objects = append(resp.Objects, object)
+			continue
+		}
+
+		stored, err := m.params.LFS.Stat(repoName, pointer.OID)
+		if err != nil && !errors.Is(err, lfs.ErrObjectNotFound) {
+			m.logger.Error("Failed to look up LFS object", zap.String("repo", repoName), zap.String("oid", pointer.OID), zap.Error(err))
+			m.lfsError(c, http.StatusInternalServerError, errors.New("failed to look up LFS objects"))
+			return
+		}
+
+		switch {
+		case req.Operation == lfs.OperationDownload && stored == nil:
+			object.Error = &lfs.ObjectError{Code: http.StatusNotFound, Message: lfs.ErrObjectNotFound.Error()}
+		case req.Operation == lfs.OperationDownload:
+			object.Size = stored.Size
+			object.Actions = map[string]*lfs.Action{
+				"download": {Href: lfsHref(c.Request, "objects/"+pointer.OID)},
+			}
+		case stored != nil:
+			// Objects already uploaded to the repository are not transferred again
+		default:
+			if err := m.params.LFS.CheckSize(pointer.Size); err != nil {
+				object.Error = &lfs.ObjectError{Code: http.StatusUnprocessableEntity, Message: err.Error()}
+				break
+			}
+
+			// The objects uploaded by a batch must fit in the quotas together
+			var exceeded *repository_manager.QuotaExceededError
+			err := m.params.RepositoryManager.CheckQuotaSize(repoName, pending+pointer.Size)
+			if errors.As(err, &exceeded) {
+				object.Error = &lfs.ObjectError{Code: http.StatusUnprocessableEntity, Message: err.Error()}
+				break
+			}
+			if err != nil {
+				m.logger.Error("Failed to check quota", zap.String("repo", repoName), zap.Error(err))
+				m.lfsError(c, http.StatusInternalServerError, errors.New("failed to check quota"))
+				return
+			}
+			pending += pointer.Size
+
+			object.Actions = map[string]*lfs.Action{
+				"upload": {Href: lfsHref(c.Request, "objects/"+pointer.OID)},
+				"verify": {Href: lfsHref(c.Request, "objects/verify")},
+			}
+		}
+
+		resp.Objects = append(resp.Objects, object)
+	}
+
+	m.lfsJSON(c, http.StatusOK, resp)
+}
+
+// handleLFSUpload stores the content of an object uploaded with the basic transfer
+func (m *GitHTTP) handleLFSUpload(c *gin.Context, repoName, oid string) {
+	_, err := m.params.LFS.Put(repoName, oid, c.Request.Body)
+	switch status := statusCodeForLFSError(err); {
+	case err == nil:
+		c.Status(http.StatusOK)
+	case status == http.StatusInternalServerError:
+		m.logger.Error("Failed to store LFS object", zap.String("repo", repoName), zap.String("oid", oid), zap.Error(err))
+		m.lfsError(c, status, errors.New("failed to store LFS object"))
+	default:
+		m.lfsError(c, status, err)
+	}
+
+	m.recordAudit(c, audit.ActionLFSUpload, repoName, oid, err)
+}
+
+// handleLFSVerify confirms that an uploaded object was stored with the expected size
+func (m *GitHTTP) handleLFSVerify(c *gin.Context, repoName string) {
+	var pointer lfs.Pointer
+	if err := json.NewDecoder(io.LimitReader(c.Request.Body, maxLFSRequestSize)).Decode(&pointer); err != nil {
+		m.lfsError(c, http.StatusUnprocessableEntity, fmt.Errorf("invalid verify request: %w", err))
+		return
+	}
+
+	if err := m.params.LFS.Verify(repoName, pointer.OID, pointer.Size); err != nil {
+		m.lfsError(c, statusCodeForLFSError(err), err)
+		return
+	}
+
+	m.lfsJSON(c, http.StatusOK, struct{}{})
+}
+
+// handleLFSDownload serves the content of an object with the basic transfer, ranges are supported
+func (m *GitHTTP) handleLFSDownload(c *gin.Context, repoName, oid string) {
+	if !lfs.IsValidOID(oid) {
+		m.lfsError(c, http.StatusNotFound, lfs.ErrObjectNotFound)
+		return
+	}
+
+	f, object, err := m.params.LFS.Open(repoName, oid)
+	if err != nil {
+		m.lfsError(c, statusCodeForLFSError(err), err)
+		return
+	}
+	defer f.Close()
+
+	c.Header("Content-Type", "application/octet-stream")
+	http.ServeContent(c.Writer, c.Request, "", object.CreatedAt, f)
+}
+
+// lfsJSON writes a Git LFS API response
+func (m *GitHTTP) lfsJSON(c *gin.Context, status int, body any) {
+	c.Header("Content-Type", lfs.MediaType)
+	c.JSON(status, body)
+}
+
+// lfsError writes a Git LFS API error, which git-lfs shows to the user
+func (m *GitHTTP) lfsError(c *gin.Context, status int, err error) {
+	m.lfsJSON(c, status, lfs.ErrorResponse{Message: err.Error()})
+}
+
+// lfsHref returns the absolute URL of a Git LFS API path of the repository a request addresses
+func lfsHref(r *http.Request, lfsPath string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ","); proto != "" {
+		scheme = strings.TrimSpace(proto)
+	}
+
+	repoPath := r.URL.Path[:strings.Index(r.URL.Path, lfsPathPrefix)]
+	return scheme + "://" + r.Host + repoPath + lfsPathPrefix + lfsPath
+}
+
+// statusCodeForLFSError maps LFS store errors to HTTP status codes
+func statusCodeForLFSError(err error) int {
+	var notFound *repository_manager.NotFoundError
+	var exceeded *repository_manager.QuotaExceededError
+	switch {
+	case errors.Is(err, lfs.ErrObjectNotFound), errors.As(err, &notFound):
+		return http.StatusNotFound
+	case errors.As(err, &exceeded):
+		return http.StatusInsufficientStorage
+	case errors.Is(err, lfs.ErrObjectTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, lfs.ErrObjectInvalid), errors.Is(err, lfs.ErrObjectHashMismatch), errors.Is(err, lfs.ErrObjectSizeMismatch):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,264 @@
+package git_http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/weedbox/git-modules/auth"
+	"github.com/weedbox/git-modules/lfs"
+	"github.com/weedbox/git-modules/repository_manager"
+)
+
+// lfsRequest sends a Git LFS API request and decodes the JSON response into out if given
+func lfsRequest(t *testing.T, method, url string, body io.Reader, out any) int {
+	return lfsRequestAs(t, "", method, url, body, out)
+}
+
+// lfsRequestAs is lfsRequest with the Basic credentials of alice if a password is given
+func lfsRequestAs(t *testing.T, password, method, url string, body io.Reader, out any) int {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	if password != "" {
+		req.SetBasicAuth("alice", password)
+	}
+	req.Header.Set("Accept", lfs.MediaType)
+	req.Header.Set("Content-Type", lfs.MediaType)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send %s %s: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("Failed to decode %s %s response: %v", method, url, err)
+		}
+	}
+
+	return resp.StatusCode
+}
+
+// lfsBatch sends a batch request for one object
+func lfsBatch(t *testing.T, url, operation, oid string, size int64) (int, lfs.BatchResponse) {
+	return lfsBatchAs(t, "", url, operation, oid, size)
+}
+
+// lfsBatchAs is lfsBatch with the Basic credentials of alice if a password is given.
+// The response is only decoded for successful requests.
+func lfsBatchAs(t *testing.T, password, url, operation, oid string, size int64) (int, lfs.BatchResponse) {
+	body, _ := json.Marshal(lfs.BatchRequest{
+		Operation: operation,
+		Transfers: []string{lfs.TransferBasic},
+		Objects:   []lfs.Pointer{{OID: oid, Size: size}},
+	})
+
+	req, err := http.NewRequest(http.MethodPost, url+"/info/lfs/objects/batch", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	if password != "" {
+		req.SetBasicAuth("alice", password)
+	}
+	req.Header.Set("Accept", lfs.MediaType)
+	req.Header.Set("Content-Type", lfs.MediaType)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send batch request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var batch lfs.BatchResponse
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
+			t.Fatalf("Failed to decode batch response: %v", err)
+		}
+	}
+	return resp.StatusCode, batch
+}
+
+// testLFSObject returns the OID and size of an object content
+func testLFSObject(content []byte) (string, int64) {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), int64(len(content))
+}
+
+// Test uploading, verifying and downloading an object with the batch API and the basic transfer
+func TestLFS(t *testing.T) {
+	url, manager := setupTestServer(t, true)
+	if _, err := manager.CreateRepository("org/team/other", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	otherURL := strings.Replace(url, "/app.git", "/other.git", 1)
+
+	content := []byte("designer asset")
+	oid, size := testLFSObject(content)
+
+	// Objects that were never uploaded cannot be downloaded
+	status, batch := lfsBatch(t, url, lfs.OperationDownload, oid, size)
+	if status != http.StatusOK || len(batch.Objects) != 1 || batch.Objects[0].Error == nil || batch.Objects[0].Error.Code != http.StatusNotFound {
+		t.Fatalf("Expected object error 404, got %d %+v", status, batch)
+	}
+
+	status, batch = lfsBatch(t, url, lfs.OperationUpload, oid, size)
+	if status != http.StatusOK || batch.Transfer != lfs.TransferBasic || len(batch.Objects) != 1 {
+		t.Fatalf("Unexpected upload batch response: %d %+v", status, batch)
+	}
+	upload, verify := batch.Objects[0].Actions["upload"], batch.Objects[0].Actions["verify"]
+	if upload == nil || verify == nil || upload.Href != url+"/info/lfs/objects/"+oid {
+		t.Fatalf("Expected upload and verify actions, got %+v", batch.Objects[0].Actions)
+	}
+
+	var lfsErr lfs.ErrorResponse
+	if status := lfsRequest(t, http.MethodPut, upload.Href, strings.NewReader("tampered"), &lfsErr); status != http.StatusUnprocessableEntity {
+		t.Errorf("Expected tampered upload to be rejected with 422, got %d %+v", status, lfsErr)
+	}
+	if status := lfsRequest(t, http.MethodPut, upload.Href, bytes.NewReader(content), nil); status != http.StatusOK {
+		t.Fatalf("Expected upload to succeed, got %d", status)
+	}
+
+	pointer, _ := json.Marshal(lfs.Pointer{OID: oid, Size: size})
+	if status := lfsRequest(t, http.MethodPost, verify.Href, bytes.NewReader(pointer), nil); status != http.StatusOK {
+		t.Errorf("Expected verify to succeed, got %d", status)
+	}
+
+	// Uploaded objects are not transferred again
+	if _, batch := lfsBatch(t, url, lfs.OperationUpload, oid, size); batch.Objects[0].Actions != nil || batch.Objects[0].Error != nil {
+		t.Errorf("Expected no actions for an uploaded object, got %+v", batch.Objects[0])
+	}
+
+	_, batch = lfsBatch(t, url, lfs.OperationDownload, oid, size)
+	download := batch.Objects[0].Actions["download"]
+	if download == nil {
+		t.Fatalf("Expected download action, got %+v", batch.Objects[0])
+	}
+	resp, err := http.Get(download.Href)
+	if err != nil {
+		t.Fatalf("Failed to download object: %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(data, content) {
+		t.Errorf("Expected object content, got %d %q", resp.StatusCode, data)
+	}
+
+	// Objects are only served to the repositories they were uploaded to
+	if status := lfsRequest(t, http.MethodGet, otherURL+"/info/lfs/objects/"+oid, nil, &lfsErr); status != http.StatusNotFound {
+		t.Errorf("Expected object of another repository to be hidden, got %d", status)
+	}
+
+	body := `{"operation":"download","objects":[],"hash_algo":"sha512"}`
+	if status := lfsRequest(t, http.MethodPost, url+"/info/lfs/objects/batch", strings.NewReader(body), &lfsErr); status != http.StatusConflict {
+		t.Errorf("Expected unsupported hash algorithm to be rejected with 409, got %d", status)
+	}
+}
+
+// Test that LFS uploads and their verification need write access,
+// and that batch requests are authenticated before their body is read
+func TestLFSOperation(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   auth.Operation
+	}{
+		{http.MethodPost, "/info/lfs/objects/batch", auth.OperationRead},
+		{http.MethodPost, "/info/lfs/objects/verify", auth.OperationWrite},
+		{http.MethodPut, "/info/lfs/objects/abc", auth.OperationWrite},
+		{http.MethodGet, "/info/lfs/objects/abc", auth.OperationRead},
+	}
+
+	for _, tt := range tests {
+		body := &bytes.Buffer{}
+		req, _ := http.NewRequest(tt.method, "/app.git"+tt.path, io.TeeReader(strings.NewReader(`{"operation":"upload"}`), body))
+		if got := gitOperation(req, tt.path); got != tt.want {
+			t.Errorf("%s %s: got %v, want %v", tt.method, tt.path, got, tt.want)
+		}
+		if body.Len() > 0 {
+			t.Errorf("%s %s: expected the body not to be read", tt.method, tt.path)
+		}
+	}
+}
+
+// Test that upload batch requests are checked for write access and that transfers carry no credentials
+func TestLFSBatchAuthorization(t *testing.T) {
+	url := setupAuthenticatedServer(t)
+	oid, size := testLFSObject([]byte("asset"))
+
+	if status, _ := lfsBatchAs(t, "", url, lfs.OperationDownload, oid, size); status != http.StatusOK {
+		t.Errorf("Expected anonymous download batch to be allowed, got %d", status)
+	}
+	if status, _ := lfsBatchAs(t, "", url, lfs.OperationUpload, oid, size); status != http.StatusUnauthorized {
+		t.Errorf("Expected anonymous upload batch to be challenged, got %d", status)
+	}
+
+	status, batch := lfsBatchAs(t, "secret", url, lfs.OperationUpload, oid, size)
+	if status != http.StatusOK || len(batch.Objects) != 1 || batch.Objects[0].Actions["upload"] == nil {
+		t.Fatalf("Expected upload batch to be allowed, got %d %+v", status, batch)
+	}
+	for name, action := range batch.Objects[0].Actions {
+		if len(action.Header) > 0 {
+			t.Errorf("Expected %s action without headers, got %v", name, action.Header)
+		}
+	}
+}
+
+// Test that LFS objects count towards quotas and that uploads must fit in them
+func TestLFSQuota(t *testing.T) {
+	url, manager := setupTestServer(t, true)
+
+	// The quota is stored with the group and counts towards its usage
+	if _, err := manager.SetQuota("org", repository_manager.Quota{MaxSize: 1 << 20}); err != nil {
+		t.Fatalf("Failed to set quota: %v", err)
+	}
+	usage, err := manager.GetUsage("org")
+	if err != nil {
+		t.Fatalf("Failed to get usage: %v", err)
+	}
+	repoUsage, err := manager.GetUsage("org/team/app")
+	if err != nil {
+		t.Fatalf("Failed to get usage: %v", err)
+	}
+	if _, err := manager.SetQuota("org", repository_manager.Quota{MaxSize: usage.Size + 100}); err != nil {
+		t.Fatalf("Failed to set quota: %v", err)
+	}
+
+	small, smallSize := testLFSObject(bytes.Repeat([]byte("s"), 60))
+	large, largeSize := testLFSObject(bytes.Repeat([]byte("l"), 200))
+
+	_, batch := lfsBatch(t, url, lfs.OperationUpload, large, largeSize)
+	if len(batch.Objects) != 1 || batch.Objects[0].Error == nil || !strings.Contains(batch.Objects[0].Error.Message, "quota of group org exceeded") {
+		t.Errorf("Expected object over the quota to be refused, got %+v", batch.Objects)
+	}
+	if status := lfsRequest(t, http.MethodPut, url+"/info/lfs/objects/"+large, bytes.NewReader(bytes.Repeat([]byte("l"), 200)), nil); status != http.StatusInsufficientStorage {
+		t.Errorf("Expected upload over the quota to be refused with 507, got %d", status)
+	}
+
+	if _, batch := lfsBatch(t, url, lfs.OperationUpload, small, smallSize); batch.Objects[0].Actions["upload"] == nil {
+		t.Fatalf("Expected object within the quota to be accepted, got %+v", batch.Objects[0].Error)
+	}
+	if status := lfsRequest(t, http.MethodPut, url+"/info/lfs/objects/"+small, bytes.NewReader(bytes.Repeat([]byte("s"), 60)), nil); status != http.StatusOK {
+		t.Fatalf("Expected upload within the quota to succeed, got %d", status)
+	}
+
+	after, err := manager.GetUsage("org/team/app")
+	if err != nil {
+		t.Fatalf("Failed to get usage: %v", err)
+	}
+	if after.Size != repoUsage.Size+smallSize {
+		t.Errorf("Expected usage to grow by the uploaded object, got %d then %d", repoUsage.Size, after.Size)
+	}
+
+	// The same object is not counted twice
+	if _, batch := lfsBatch(t, url, lfs.OperationUpload, small, smallSize); batch.Objects[0].Error != nil {
+		t.Errorf("Expected uploaded object to be accepted again, got %+v", batch.Objects[0])
+	}
+}
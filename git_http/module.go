@@ -3,11 +3,17 @@ package git_http
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"sync"
 
-	"github.com/sosedoff/gitkit"
 	"github.com/spf13/viper"
 	"github.com/weedbox/common-modules/http_server"
+	"github.com/weedbox/git-modules/audit"
+	"github.com/weedbox/git-modules/auth"
+	"github.com/weedbox/git-modules/hooks"
+	"github.com/weedbox/git-modules/lfs"
 	"github.com/weedbox/git-modules/repository_manager"
+	"github.com/weedbox/git-modules/tokens"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
@@ -15,14 +21,33 @@ import (
 const (
 	ModuleName       = "GitHTTP"
 	DefaultURLPrefix = "/"
+	DefaultAuthRealm = "Git"
+	DefaultBackend   = BackendGit
+)
+
+// Backends serving the git smart HTTP protocol
+const (
+	// BackendGit runs git receive-pack through gitkit and git upload-pack with protocol v2 support
+	BackendGit = "git"
+
+	// BackendGoGit serves the protocol in-process with go-git, without the git binary.
+	// It speaks protocol v0 only and refuses shallow clones. Partial clone filters are not
+	// advertised, so clients receive complete packfiles and filter policies have no effect.
+	BackendGoGit = "go-git"
 )
 
 type GitHTTP struct {
-	params     Params
-	logger     *zap.Logger
-	scope      string
-	gitService *gitkit.Server
-	urlPrefix  string
+	params       Params
+	logger       *zap.Logger
+	scope        string
+	gitService   http.Handler
+	urlPrefix    string
+	realm        string
+	enforceRoles bool
+	protocolV2   bool
+
+	// postReceive tracks the post-receive hooks still running after their push was answered
+	postReceive sync.WaitGroup
 }
 
 type Params struct {
@@ -32,6 +57,21 @@ type Params struct {
 	Logger            *zap.Logger
 	RepositoryManager *repository_manager.RepositoryManager
 	HTTPServer        *http_server.HTTPServer
+
+	// Hooks are invoked around every push, see the hooks package
+	Hooks []hooks.Hook `group:"git_hooks"`
+
+	// AuditLog records pushes and fetches when provided
+	AuditLog *audit.AuditLog `optional:"true"`
+
+	// Authenticator decides on read and write access, repositories are public without one
+	Authenticator auth.Authenticator `optional:"true"`
+
+	// Tokens authenticates requests carrying personal access tokens or deploy tokens when provided
+	Tokens *tokens.TokenStore `optional:"true"`
+
+	// LFS serves the Git LFS API of every repository under {repo}.git/info/lfs when provided
+	LFS *lfs.Store `optional:"true"`
 }
 
 func Module(scope string) fx.Option {
@@ -70,20 +110,39 @@ func (m *GitHTTP) onStart(ctx context.Context) error {
 
 	// Get and save URL prefix
 	m.urlPrefix = viper.GetString(m.getConfigPath("url_prefix"))
+	m.realm = viper.GetString(m.getConfigPath("auth_realm"))
+	m.enforceRoles = viper.GetBool(m.getConfigPath("enforce_roles"))
+
+	backend := viper.GetString(m.getConfigPath("backend"))
+
+	// Only the git backend speaks protocol v2, go-git answers v2 clients with v0
+	m.protocolV2 = viper.GetBool(m.getConfigPath("protocol_v2")) && backend == BackendGit
 
 	reposPath := m.params.RepositoryManager.GetReposPath()
 	m.logger.Info("Initializing Git HTTP service",
 		zap.String("reposPath", reposPath),
 		zap.String("urlPrefix", m.urlPrefix),
+		zap.String("backend", backend),
+		zap.Bool("protocolV2", m.protocolV2),
+		zap.Bool("authentication", m.params.Authenticator != nil),
+		zap.Bool("tokens", m.params.Tokens != nil),
+		zap.Bool("enforceRoles", m.enforceRoles),
+		zap.Bool("lfs", m.params.LFS != nil),
 	)
 
+	if backend == BackendGoGit {
+		m.logger.Warn("The go-git backend does not support shallow clones, partial clone filters or protocol v2, filter policies have no effect")
+	}
+
 	// Initializing Git service for HTTP protocol
-	m.gitService = gitkit.New(gitkit.Config{
-		Dir:        reposPath,
-		AutoCreate: false,
-		AutoHooks:  false,
-		Auth:       false, // Disable authentication for now
-	})
+	switch backend {
+	case BackendGit:
+		m.gitService = newGitServer(m.logger, m.params.RepositoryManager, m.protocolV2)
+	case BackendGoGit:
+		m.gitService = newGoGitServer(m.logger, reposPath)
+	default:
+		return fmt.Errorf("unknown git HTTP backend %q: must be %s or %s", backend, BackendGit, BackendGoGit)
+	}
 
 	// Register routes on main router with urlPrefix
 	router := m.params.HTTPServer.GetRouter()
@@ -95,7 +154,20 @@ func (m *GitHTTP) onStart(ctx context.Context) error {
 	return nil
 }
 
+// onStop waits for running post-receive hooks until ctx expires
 func (m *GitHTTP) onStop(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		m.postReceive.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		m.logger.Warn("Stopped before post-receive hooks finished")
+	}
+
 	m.logger.Info("Stopped " + ModuleName)
 	return nil
 }
@@ -106,6 +178,10 @@ func (m *GitHTTP) getConfigPath(key string) string {
 
 func (m *GitHTTP) initDefaultConfigs() {
 	viper.SetDefault(m.getConfigPath("url_prefix"), DefaultURLPrefix)
+	viper.SetDefault(m.getConfigPath("auth_realm"), DefaultAuthRealm)
+	viper.SetDefault(m.getConfigPath("enforce_roles"), false)
+	viper.SetDefault(m.getConfigPath("backend"), DefaultBackend)
+	viper.SetDefault(m.getConfigPath("protocol_v2"), true)
 }
 
 func (m *GitHTTP) GetRepoPrefix() string {
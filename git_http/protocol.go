@@ -0,0 +1,109 @@
+package git_http
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp"
+)
+
+// headerGitProtocol carries the protocol version and parameters requested by git clients, e.g. version=2
+const headerGitProtocol = "Git-Protocol"
+
+// protocolVersion returns the version requested in a Git-Protocol value, 0 if none is requested.
+// Values are colon separated parameters like version=2:object-format=sha1.
+func protocolVersion(protocol string) int {
+	version := 0
+	for _, param := range strings.Split(protocol, ":") {
+		if value, ok := strings.CutPrefix(param, "version="); ok {
+			if v, err := strconv.Atoi(value); err == nil && v > version {
+				version = v
+			}
+		}
+	}
+
+	return version
+}
+
+// splitGitPath splits a path like /org/app.git/info/refs into the repository name and the git path
+func splitGitPath(path string) (string, string, bool) {
+	path = strings.TrimPrefix(path, "/")
+	idx := strings.Index(path, ".git/")
+	if idx < 0 {
+		return "", "", false
+	}
+
+	return path[:idx], path[idx+4:], true
+}
+
+// protocolV2Command returns the command of a protocol v2 upload-pack request, e.g. ls-refs or fetch.
+// The first packet of the body is peeked, then the body is restored for the backend;
+// gzip encoded bodies are decompressed first. It returns an empty string for other requests.
+func (m *GitHTTP) protocolV2Command(r *http.Request) string {
+	if !m.protocolV2 || protocolVersion(r.Header.Get(headerGitProtocol)) != 2 {
+		return ""
+	}
+
+	body, err := requestBody(r)
+	if err != nil {
+		return ""
+	}
+	r.Header.Del("Content-Encoding")
+
+	br := bufio.NewReader(body)
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{br, body}
+
+	size, err := br.Peek(4)
+	if err != nil {
+		return ""
+	}
+	n, err := strconv.ParseUint(string(size), 16, 16)
+	if err != nil || n <= 4 {
+		return ""
+	}
+	line, err := br.Peek(int(n))
+	if err != nil {
+		return ""
+	}
+
+	command, _ := strings.CutPrefix(strings.TrimSuffix(string(line[4:]), "\n"), "command=")
+	return command
+}
+
+// DecodeReferenceUpdateRequest decodes the commands of a git-receive-pack request, leaving the
+// packfile to be read from req.Packfile. Pushes from shallow clones start with one "shallow"
+// line per shallow commit of the client, which are skipped as the server has the full history.
+func DecodeReferenceUpdateRequest(r io.Reader) (*packp.ReferenceUpdateRequest, error) {
+	br := bufio.NewReader(r)
+	for {
+		size, err := br.Peek(4)
+		if err != nil {
+			break
+		}
+		n, err := strconv.ParseUint(string(size), 16, 16)
+		if err != nil || n <= 4 {
+			break
+		}
+		line, err := br.Peek(int(n))
+		if err != nil || !bytes.HasPrefix(line[4:], []byte("shallow ")) {
+			break
+		}
+		if _, err := br.Discard(int(n)); err != nil {
+			return nil, err
+		}
+	}
+
+	req := packp.NewReferenceUpdateRequest()
+	if err := req.Decode(br); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
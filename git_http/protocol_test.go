@@ -0,0 +1,157 @@
+package git_http
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/weedbox/common-modules/http_server"
+	"github.com/weedbox/git-modules/lfs"
+	"github.com/weedbox/git-modules/repository_manager"
+	"github.com/weedbox/git-modules/tokens"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const testTagCount = 50
+
+// setupTestServer starts git_http with a multi-level repository holding a commit and many tags.
+// It returns the clone URL of the repository.
+func setupTestServer(t *testing.T, protocolV2 bool) (string, *repository_manager.RepositoryManager) {
+	return setupTestServerWith(t, protocolV2)
+}
+
+// setupTestServerWith is setupTestServer with additional options of the app, such as an Authenticator
+// or the population of the token store
+func setupTestServerWith(t *testing.T, protocolV2 bool, opts ...fx.Option) (string, *repository_manager.RepositoryManager) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	viper.Set("http_server.host", "127.0.0.1")
+	viper.Set("http_server.port", port)
+	viper.Set("git_http.url_prefix", "/git")
+	viper.Set("git_http.protocol_v2", protocolV2)
+	viper.Set("repository_manager.repos_path", filepath.Join(t.TempDir(), "repos"))
+	viper.Set("tokens.path", filepath.Join(t.TempDir(), "tokens.json"))
+
+	var manager *repository_manager.RepositoryManager
+	app := fx.New(
+		fx.NopLogger,
+		fx.Provide(zap.NewNop),
+		http_server.Module("http_server"),
+		repository_manager.Module("repository_manager"),
+		lfs.Module("lfs"),
+		tokens.Module("tokens"),
+		Module("git_http"),
+		fx.Populate(&manager),
+		fx.Options(opts...),
+	)
+	if err := app.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start app: %v", err)
+	}
+	t.Cleanup(func() { app.Stop(context.Background()) })
+
+	repoName := "org/team/app"
+	if _, err := manager.CreateRepository(repoName, ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	commit, err := manager.CommitFiles(repoName, repository_manager.CommitRequest{
+		Message: "Initial commit",
+		Actions: []repository_manager.FileAction{{Action: repository_manager.FileActionCreate, Path: "README.md", Content: []byte("hello\n")}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	for i := 0; i < testTagCount; i++ {
+		if _, err := manager.CreateTag(repoName, fmt.Sprintf("v1.0.%d", i), commit.CommitHash, "release", "Test"); err != nil {
+			t.Fatalf("Failed to create tag: %v", err)
+		}
+	}
+
+	return fmt.Sprintf("http://127.0.0.1:%d/git/%s.git", port, repoName), manager
+}
+
+// runGit runs a git command with packet tracing and returns its output and the trace
+func runGit(t *testing.T, dir string, args ...string) (string, string) {
+	trace := filepath.Join(t.TempDir(), "trace")
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TRACE_PACKET="+trace)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, out)
+	}
+
+	data, _ := os.ReadFile(trace)
+	return string(out), string(data)
+}
+
+// Test that protocol v2 clients list only the references they ask for and clone all tags
+func TestProtocolV2(t *testing.T) {
+	url, _ := setupTestServer(t, true)
+	dir := t.TempDir()
+
+	out, trace := runGit(t, dir, "-c", "protocol.version=2", "ls-remote", "--heads", url)
+	if !strings.Contains(trace, "version 2") || !strings.Contains(trace, "ref-prefix refs/heads/") {
+		t.Errorf("Expected ls-refs over protocol v2, got trace:\n%s", trace)
+	}
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 1 || !strings.Contains(lines[0], "refs/heads/") {
+		t.Errorf("Expected only the branch, got:\n%s", out)
+	}
+
+	_, trace = runGit(t, dir, "-c", "protocol.version=2", "clone", "--bare", url, "app.git")
+	if !strings.Contains(trace, "command=fetch") {
+		t.Errorf("Expected clone over protocol v2, got trace:\n%s", trace)
+	}
+
+	out, _ = runGit(t, filepath.Join(dir, "app.git"), "tag")
+	if tags := strings.Fields(out); len(tags) != testTagCount {
+		t.Errorf("Expected %d tags, got %d", testTagCount, len(tags))
+	}
+}
+
+// Test that clients fall back to protocol v0 when protocol v2 is disabled
+func TestProtocolV2_Disabled(t *testing.T) {
+	url, _ := setupTestServer(t, false)
+
+	out, trace := runGit(t, t.TempDir(), "-c", "protocol.version=2", "ls-remote", "--tags", url)
+	if strings.Contains(trace, "version 2") {
+		t.Error("Expected protocol v0, got protocol v2")
+	}
+	if tags := strings.Split(strings.TrimSpace(out), "\n"); len(tags) != testTagCount*2 {
+		t.Errorf("Expected %d tags with peeled entries, got %d", testTagCount, len(tags))
+	}
+}
+
+func TestProtocolVersion(t *testing.T) {
+	tests := []struct {
+		protocol string
+		version  int
+	}{
+		{"", 0},
+		{"version=2", 2},
+		{"version=1", 1},
+		{"object-format=sha1:version=2", 2},
+		{"version=x", 0},
+	}
+	for _, tt := range tests {
+		if got := protocolVersion(tt.protocol); got != tt.version {
+			t.Errorf("protocolVersion(%q) = %d, expected %d", tt.protocol, got, tt.version)
+		}
+	}
+}
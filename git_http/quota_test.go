@@ -0,0 +1,48 @@
+package git_http
+
+import (
+	"crypto/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/weedbox/git-modules/repository_manager"
+)
+
+// Test pushes exceeding the quota of a group
+func TestQuotaPush(t *testing.T) {
+	url, manager := setupTestServer(t, true)
+	dir := t.TempDir()
+	repoDir := filepath.Join(dir, "app")
+	runGit(t, dir, "clone", "-q", url, repoDir)
+
+	usage, err := manager.GetUsage("org")
+	if err != nil {
+		t.Fatalf("Failed to get usage: %v", err)
+	}
+	if _, err := manager.SetQuota("org", repository_manager.Quota{MaxSize: usage.Size + 64*1024}); err != nil {
+		t.Fatalf("Failed to set quota: %v", err)
+	}
+
+	// Random content does not compress, so the packfile exceeds the quota
+	data := make([]byte, 128*1024)
+	rand.Read(data)
+	if err := os.WriteFile(filepath.Join(repoDir, "large.bin"), data, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	runGit(t, repoDir, "add", "large.bin")
+	runGit(t, repoDir, "-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "Add large file")
+
+	cmd := exec.Command("git", "push", "origin", "HEAD")
+	cmd.Dir = repoDir
+	if out, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(out), "quota of group org exceeded") {
+		t.Errorf("Expected push over quota to be rejected, got %v: %s", err, out)
+	}
+
+	// Small pushes still fit
+	runGit(t, repoDir, "reset", "-q", "--hard", "HEAD~1")
+	runGit(t, repoDir, "-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "Small change")
+	runGit(t, repoDir, "push", "-q", "origin", "HEAD")
+}
@@ -0,0 +1,320 @@
+package git_http
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/pktline"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp/capability"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp/sideband"
+	"github.com/weedbox/git-modules/audit"
+	"github.com/weedbox/git-modules/auth"
+	"github.com/weedbox/git-modules/hooks"
+	"github.com/weedbox/git-modules/repository_manager"
+	"go.uber.org/zap"
+)
+
+// capabilityReportStatusV2 is requested by git clients instead of report-status since git 2.29.
+// Rejected commands are reported with the same "ng <ref> <reason>" lines in both versions.
+const capabilityReportStatusV2 capability.Capability = "report-status-v2"
+
+// ReceivePack applies a git-receive-pack request received by another transport, such as SSH.
+// The request is served as if it had been sent over HTTP by the identity stored in ctx,
+// so the same policies, hooks, webhooks and audit records apply. The report-status response,
+// or a plain text error, is written to w.
+//
+// The identity in ctx is trusted as is: the Authenticator is not consulted, only roles and token
+// scopes are checked. Callers must store an identity they authenticated themselves with auth.WithIdentity.
+// Requests received over HTTP never carry an identity in their context, so they are always authenticated.
+func (m *GitHTTP) ReceivePack(ctx context.Context, w http.ResponseWriter, repoName, remoteAddr string, body io.Reader) {
+	target := strings.TrimSuffix(m.urlPrefix, "/") + "/" + repoName + ".git/git-receive-pack"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, body)
+	if err != nil {
+		m.logger.Error("Failed to create receive-pack request", zap.String("repo", repoName), zap.Error(err))
+		http.Error(w, "failed to receive push", http.StatusInternalServerError)
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-git-receive-pack-request")
+	req.RemoteAddr = remoteAddr
+
+	m.params.HTTPServer.GetRouter().ServeHTTP(w, req)
+}
+
+// handleReceivePack checks the reference updates of a git-receive-pack request against
+// the repository policies and the pre-receive hooks before passing the request on to next.
+// The request body is spooled to a temporary file so it can be inspected and replayed.
+// Rejected pushes are answered with a report-status response the git client displays per reference.
+// Webhooks and post-receive hooks are notified of the applied updates once the repository is unlocked,
+// post-receive hooks in the background after the client has been answered.
+func (m *GitHTTP) handleReceivePack(c *gin.Context, repoName string, next http.Handler) {
+	body, err := spoolRequestBody(c.Request)
+	if err != nil {
+		m.logger.Warn("Failed to read receive-pack request", zap.String("repo", repoName), zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+		m.recordAudit(c, audit.ActionGitPush, repoName, "", err)
+		return
+	}
+	defer func() {
+		body.Close()
+		os.Remove(body.Name())
+	}()
+
+	req, err := DecodeReferenceUpdateRequest(body)
+	if err != nil {
+		m.logger.Warn("Failed to decode receive-pack request", zap.String("repo", repoName), zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid receive-pack request"})
+		m.recordAudit(c, audit.ActionGitPush, repoName, "", err)
+		return
+	}
+
+	updates := make([]repository_manager.ReferenceUpdate, 0, len(req.Commands))
+	for _, cmd := range req.Commands {
+		updates = append(updates, repository_manager.ReferenceUpdate{
+			Name:    cmd.Name,
+			OldHash: cmd.Old,
+			NewHash: cmd.New,
+		})
+	}
+
+	push := &hooks.Push{
+		Repository: repoName,
+		Pusher:     auth.IdentityFromContext(c.Request.Context()),
+		RemoteAddr: c.ClientIP(),
+		Updates:    updates,
+	}
+
+	applied, err := m.receivePack(c, req, body, push, next)
+	m.recordAudit(c, audit.ActionGitPush, repoName, referenceNames(updates), err)
+	if len(applied) == 0 {
+		return
+	}
+
+	m.params.RepositoryManager.NotifyReferenceUpdates(repoName, auth.NameFromContext(c.Request.Context()), applied)
+
+	push.Updates = applied
+	push.Objects = nil
+	m.runPostReceiveHooks(c.Request.Context(), push)
+}
+
+// receivePack runs the checks of a push and lets next apply it while the repository is locked,
+// which serializes pushes with reference updates done through RepositoryManager.
+// It returns the reference updates that were applied, and an error if the push was refused or failed.
+// The client has been answered in either case.
+func (m *GitHTTP) receivePack(c *gin.Context, req *packp.ReferenceUpdateRequest, body *os.File, push *hooks.Push, next http.Handler) ([]repository_manager.ReferenceUpdate, error) {
+	repoName := push.Repository
+
+	unlock := m.params.RepositoryManager.LockRepository(repoName)
+	defer unlock()
+
+	objects, err := m.params.RepositoryManager.NewPushObjectStorage(repoName, req.Packfile)
+	if err != nil {
+		m.logger.Warn("Failed to read pushed objects", zap.String("repo", repoName), zap.Error(err))
+		m.writeReceivePackReport(c, req, err.Error(), nil)
+		return nil, err
+	}
+	defer objects.Close()
+	push.Objects = objects
+
+	pusher := auth.NameFromContext(c.Request.Context())
+	err = m.params.RepositoryManager.CheckReferenceUpdates(repoName, pusher, push.Updates, objects)
+	if err == nil {
+		err = m.runPreReceiveHooks(c.Request.Context(), push)
+	}
+	if err != nil {
+		var rejected *repository_manager.PushRejectedError
+		if !errors.As(err, &rejected) {
+			m.logger.Error("Failed to check reference updates", zap.String("repo", repoName), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check push"})
+			return nil, err
+		}
+
+		m.logger.Info("Push rejected",
+			zap.String("repo", repoName),
+			zap.String("pusher", pusher),
+			zap.Error(err),
+		)
+		m.writeReceivePackReport(c, req, "ok", rejected.Rejections)
+		return nil, rejected
+	}
+
+	// Replay the spooled body, which is no longer compressed
+	size, err := body.Seek(0, io.SeekEnd)
+	if err == nil {
+		_, err = body.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		m.logger.Error("Failed to rewind receive-pack request", zap.String("repo", repoName), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process push"})
+		return nil, err
+	}
+
+	c.Request.Body = io.NopCloser(body)
+	c.Request.ContentLength = size
+	c.Request.Header.Del("Content-Encoding")
+
+	next.ServeHTTP(c.Writer, c.Request)
+
+	applied, err := m.params.RepositoryManager.AppliedReferenceUpdates(repoName, push.Updates)
+	if err != nil {
+		m.logger.Error("Failed to read references after push", zap.String("repo", repoName), zap.Error(err))
+		return nil, err
+	}
+
+	if len(applied) < len(push.Updates) {
+		return applied, fmt.Errorf("%d of %d references were not updated", len(push.Updates)-len(applied), len(push.Updates))
+	}
+
+	return applied, nil
+}
+
+// runPostReceiveHooks calls the post-receive hooks in order in the background, so that slow hooks
+// do not delay the response to the client. The hooks keep the values of ctx, such as the identity
+// of the pusher, but are not canceled with the request.
+func (m *GitHTTP) runPostReceiveHooks(ctx context.Context, push *hooks.Push) {
+	if len(m.params.Hooks) == 0 {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+
+	m.postReceive.Add(1)
+	go func() {
+		defer m.postReceive.Done()
+		for _, hook := range m.params.Hooks {
+			hook.PostReceive(ctx, push)
+		}
+	}()
+}
+
+// runPreReceiveHooks calls the pre-receive hooks in order and stops at the first veto.
+// Errors other than *repository_manager.PushRejectedError reject every reference of the push.
+func (m *GitHTTP) runPreReceiveHooks(ctx context.Context, push *hooks.Push) error {
+	for _, hook := range m.params.Hooks {
+		err := hook.PreReceive(ctx, push)
+		if err == nil {
+			continue
+		}
+
+		var rejected *repository_manager.PushRejectedError
+		if errors.As(err, &rejected) {
+			return rejected
+		}
+
+		rejected = &repository_manager.PushRejectedError{}
+		for _, update := range push.Updates {
+			rejected.Rejections = append(rejected.Rejections, repository_manager.ReferenceRejection{
+				Reference: update.Name.String(),
+				Reason:    err.Error(),
+			})
+		}
+		return rejected
+	}
+
+	return nil
+}
+
+// writeReceivePackReport answers a push without applying it.
+// Every command is reported as failed: rejected references with their reason,
+// the remaining ones because the push is refused as a whole.
+func (m *GitHTTP) writeReceivePackReport(c *gin.Context, req *packp.ReferenceUpdateRequest, unpackStatus string, rejections []repository_manager.ReferenceRejection) {
+	// A reference may be rejected for several reasons, all shown as progress messages.
+	// Its status line carries the first one.
+	reasons := make(map[plumbing.ReferenceName]string, len(rejections))
+	for _, r := range rejections {
+		if _, ok := reasons[plumbing.ReferenceName(r.Reference)]; !ok {
+			reasons[plumbing.ReferenceName(r.Reference)] = r.Reason
+		}
+	}
+
+	report := packp.NewReportStatus()
+	report.UnpackStatus = unpackStatus
+	for _, cmd := range req.Commands {
+		status, ok := reasons[cmd.Name]
+		if !ok {
+			status = "push rejected because other references were refused"
+			if unpackStatus != "ok" {
+				status = "unpacker error"
+			}
+		}
+		report.CommandStatuses = append(report.CommandStatuses, &packp.CommandStatus{
+			ReferenceName: cmd.Name,
+			Status:        status,
+		})
+	}
+
+	c.Header("Content-Type", "application/x-git-receive-pack-result")
+	c.Header("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+
+	var w io.Writer = c.Writer
+	var muxer *sideband.Muxer
+	switch {
+	case req.Capabilities.Supports(capability.Sideband64k):
+		muxer = sideband.NewMuxer(sideband.Sideband64k, c.Writer)
+	case req.Capabilities.Supports(capability.Sideband):
+		muxer = sideband.NewMuxer(sideband.Sideband, c.Writer)
+	}
+
+	if muxer != nil {
+		// Human readable reasons are shown by the client as "remote: ..." lines
+		for _, r := range rejections {
+			muxer.WriteChannel(sideband.ProgressMessage, []byte(fmt.Sprintf("error: %s: %s\n", r.Reference, r.Reason)))
+		}
+		w = muxer
+	}
+
+	if req.Capabilities.Supports(capability.ReportStatus) || req.Capabilities.Supports(capabilityReportStatusV2) {
+		if err := report.Encode(w); err != nil {
+			m.logger.Warn("Failed to write receive-pack report", zap.Error(err))
+			return
+		}
+	}
+
+	if muxer != nil {
+		if err := pktline.NewEncoder(c.Writer).Flush(); err != nil {
+			m.logger.Warn("Failed to write receive-pack report", zap.Error(err))
+		}
+	}
+}
+
+// spoolRequestBody copies the (possibly gzip compressed) request body to a temporary file
+// and returns it positioned at the beginning of the uncompressed data
+func spoolRequestBody(r *http.Request) (*os.File, error) {
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	f, err := os.CreateTemp("", "git-receive-pack-*")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := io.Copy(f, body); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	return f, nil
+}
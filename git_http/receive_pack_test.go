@@ -0,0 +1,173 @@
+package git_http
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/weedbox/git-modules/hooks"
+	"github.com/weedbox/git-modules/repository_manager"
+	"go.uber.org/fx"
+)
+
+// Test that pushes are applied while holding the repository lock,
+// so they are serialized with reference updates done through RepositoryManager
+func TestReceivePackLock(t *testing.T) {
+	url, manager := setupTestServer(t, false)
+	dir := t.TempDir()
+	repoDir := filepath.Join(dir, "app")
+	runGit(t, dir, "clone", "-q", url, repoDir)
+	runGit(t, repoDir, "-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "Locked push")
+	head, _ := runGit(t, repoDir, "rev-parse", "HEAD")
+
+	unlock := manager.LockRepository("org/team/app")
+
+	done := make(chan error, 1)
+	go func() {
+		cmd := exec.Command("git", "push", "-q", "origin", "HEAD")
+		cmd.Dir = repoDir
+		_, err := cmd.CombinedOutput()
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		unlock()
+		t.Fatalf("Expected push to wait for the repository lock, it finished with %v", err)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Push failed after unlocking: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Push did not finish after unlocking")
+	}
+
+	if out, _ := runGit(t, repoDir, "ls-remote", "origin", "HEAD"); !strings.HasPrefix(out, strings.TrimSpace(head)) {
+		t.Errorf("Expected remote HEAD at %s, got %s", head, out)
+	}
+}
+
+// Test that pushes violating branch protection are rejected per reference and leave the branch untouched
+func TestProtectedBranchPush(t *testing.T) {
+	url, manager := setupTestServer(t, false)
+	if _, err := manager.CreateBranchProtectionRule("org", repository_manager.BranchProtectionRule{Pattern: "master", DenyForcePush: true, DenyDeletion: true}); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	dir := t.TempDir()
+	repoDir := filepath.Join(dir, "app")
+	runGit(t, dir, "clone", "-q", url, repoDir)
+	before, _ := runGit(t, repoDir, "ls-remote", "origin", "refs/heads/master")
+
+	runGit(t, repoDir, "-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-q", "--amend", "-m", "Rewritten")
+
+	for _, args := range [][]string{
+		{"push", "--force", "origin", "HEAD:master"},
+		{"push", "origin", ":master"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoDir
+		out, err := cmd.CombinedOutput()
+		if err == nil || !strings.Contains(string(out), "protected branch") {
+			t.Errorf("git %s: expected rejection, got %v: %s", strings.Join(args, " "), err, out)
+		}
+	}
+
+	if after, _ := runGit(t, repoDir, "ls-remote", "origin", "refs/heads/master"); after != before {
+		t.Errorf("Expected rejected pushes to leave master at %s, got %s", before, after)
+	}
+
+	// Pushes to unprotected branches are accepted in the same repository
+	runGit(t, repoDir, "push", "-q", "origin", "HEAD:refs/heads/topic")
+}
+
+// Test that pre-receive hooks can veto pushes per reference or as a whole and that
+// post-receive hooks run in the background with the applied updates only
+func TestHooks(t *testing.T) {
+	preReceive := make(chan *hooks.Push, 10)
+	postReceive := make(chan *hooks.Push, 10)
+	release := make(chan struct{})
+
+	hook := hooks.HookFuncs{
+		PreReceiveFunc: func(ctx context.Context, push *hooks.Push) error {
+			preReceive <- push
+			for _, update := range push.Updates {
+				switch update.Name.Short() {
+				case "vetoed":
+					return &repository_manager.PushRejectedError{Rejections: []repository_manager.ReferenceRejection{
+						{Reference: update.Name.String(), Reason: "vetoed by hook"},
+					}}
+				case "broken":
+					return errors.New("hook failed")
+				}
+			}
+			if !push.Objects.IsReceived(push.Updates[0].NewHash) {
+				return errors.New("pushed commit not visible to hook")
+			}
+			return nil
+		},
+		PostReceiveFunc: func(ctx context.Context, push *hooks.Push) {
+			<-release
+			postReceive <- push
+		},
+	}
+	url, _ := setupTestServerWith(t, false, fx.Provide(hooks.AsHook(func() hooks.HookFuncs { return hook })))
+	t.Cleanup(func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	})
+
+	dir := t.TempDir()
+	repoDir := filepath.Join(dir, "app")
+	runGit(t, dir, "clone", "-q", url, repoDir)
+	runGit(t, repoDir, "-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "Hooked push")
+	head, _ := runGit(t, repoDir, "rev-parse", "HEAD")
+
+	for branch, reason := range map[string]string{"vetoed": "vetoed by hook", "broken": "hook failed"} {
+		cmd := exec.Command("git", "push", "origin", "HEAD:refs/heads/"+branch)
+		cmd.Dir = repoDir
+		out, err := cmd.CombinedOutput()
+		if err == nil || !strings.Contains(string(out), reason) {
+			t.Errorf("Expected push to %s to be rejected with %q, got %v: %s", branch, reason, err, out)
+		}
+		<-preReceive
+	}
+
+	// The push is answered while the post-receive hook is still blocked
+	runGit(t, repoDir, "push", "-q", "origin", "HEAD:refs/heads/topic")
+	push := <-preReceive
+	if push.Pusher != nil || push.RemoteAddr == "" {
+		t.Errorf("Expected anonymous pre-receive push with remote address, got %+v", push)
+	}
+
+	select {
+	case push := <-postReceive:
+		t.Fatalf("Expected post-receive hook to wait, got %+v", push)
+	default:
+	}
+	close(release)
+
+	select {
+	case push := <-postReceive:
+		if len(push.Updates) != 1 || push.Updates[0].Name != "refs/heads/topic" || push.Updates[0].NewHash.String() != strings.TrimSpace(head) {
+			t.Errorf("Expected post-receive hook to see the applied update of topic, got %+v", push.Updates)
+		}
+		if push.Objects != nil {
+			t.Error("Expected no objects for post-receive hooks")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Post-receive hook was not called")
+	}
+}
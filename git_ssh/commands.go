@@ -0,0 +1,315 @@
+package git_ssh
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/weedbox/git-modules/audit"
+	"github.com/weedbox/git-modules/auth"
+	"github.com/weedbox/git-modules/git_http"
+	"github.com/weedbox/git-modules/repository_manager"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	serviceUploadPack  = "git-upload-pack"
+	serviceReceivePack = "git-receive-pack"
+)
+
+// session is a git command requested over SSH
+type session struct {
+	conn     *ssh.ServerConn
+	identity *auth.Identity
+	ch       ssh.Channel
+	env      []string
+}
+
+// runCommand runs a git command sent by a client and returns its exit status.
+// Commands look like: git-upload-pack '/org/team/project.git'
+func (m *GitSSH) runCommand(s *session, command string) uint32 {
+	service, repoName, err := parseCommand(command)
+	if err != nil {
+		fmt.Fprintf(s.ch.Stderr(), "%s\n", err)
+		return 1
+	}
+
+	op := auth.OperationRead
+	if service == serviceReceivePack {
+		op = auth.OperationWrite
+	}
+
+	// Denied and unknown repositories get the same answer, so that names cannot be probed
+	if err := m.authorize(s.identity, repoName, op); err != nil {
+		m.logger.Info("SSH git command denied",
+			zap.String("repo", repoName),
+			zap.String("user", s.identity.Name),
+			zap.Error(err),
+		)
+		fmt.Fprintf(s.ch.Stderr(), "repository not found: %s\n", repoName)
+		m.recordAudit(s, auditAction(op), repoName, err)
+		return 1
+	}
+
+	repo, err := m.params.RepositoryManager.GetRepository(repoName)
+	if err != nil {
+		fmt.Fprintf(s.ch.Stderr(), "repository not found: %s\n", repoName)
+		return 1
+	}
+
+	m.logger.Info("Serving git command over SSH",
+		zap.String("service", service),
+		zap.String("repo", repoName),
+		zap.String("user", s.identity.Name),
+		zap.String("remote", s.conn.RemoteAddr().String()),
+	)
+
+	if service == serviceReceivePack {
+		return m.receivePack(s, repoName, repo.Path)
+	}
+
+	return m.uploadPack(s, repoName, repo.Path)
+}
+
+// authorize checks that an identity may run an operation on a repository.
+// The scope of deploy keys and tokens always applies, roles only if enforce_roles is set.
+func (m *GitSSH) authorize(identity *auth.Identity, repoName string, op auth.Operation) error {
+	if identity.Token != nil && (!identity.Token.Covers(repoName) || !identity.Token.Allows(op)) {
+		return auth.ErrForbidden
+	}
+
+	if !m.enforceRoles {
+		return nil
+	}
+
+	required := repository_manager.RoleRead
+	if op == auth.OperationWrite {
+		required = repository_manager.RoleWrite
+	}
+
+	return m.params.RepositoryManager.Authorize(repoName, identity, required)
+}
+
+// uploadPack serves a clone or fetch by running git upload-pack on the SSH channel.
+// Partial clones are served with the filters allowed by the filter policy of the repository.
+func (m *GitSSH) uploadPack(s *session, repoName, repoPath string) uint32 {
+	config, err := m.params.RepositoryManager.UploadPackConfig(repoName)
+	if err != nil {
+		m.logger.Error("Failed to read upload-pack configuration", zap.String("repo", repoName), zap.Error(err))
+		return 1
+	}
+
+	var args []string
+	for _, entry := range config {
+		args = append(args, "-c", entry)
+	}
+
+	cmd := exec.Command("git", append(args, "upload-pack", repoPath)...)
+	cmd.Env = append(os.Environ(), s.env...)
+	cmd.Stdin = s.ch
+	cmd.Stdout = s.ch
+	cmd.Stderr = s.ch.Stderr()
+
+	err = cmd.Run()
+	m.recordAudit(s, audit.ActionGitFetch, repoName, err)
+
+	return exitStatus(err)
+}
+
+// receivePack serves a push. The references are advertised by git receive-pack, then the
+// commands and the packfile sent by the client are read and passed to git_http, which checks
+// and applies them and writes the report-status response back to the client.
+func (m *GitSSH) receivePack(s *session, repoName, repoPath string) uint32 {
+	cmd := exec.Command("git", "receive-pack", "--stateless-rpc", "--advertise-refs", repoPath)
+	cmd.Stdout = s.ch
+	cmd.Stderr = s.ch.Stderr()
+	if err := cmd.Run(); err != nil {
+		m.logger.Error("Failed to advertise references", zap.String("repo", repoName), zap.Error(err))
+		return exitStatus(err)
+	}
+
+	body, err := os.CreateTemp("", "git-receive-pack-*")
+	if err != nil {
+		m.logger.Error("Failed to spool push", zap.String("repo", repoName), zap.Error(err))
+		return 1
+	}
+	defer func() {
+		body.Close()
+		os.Remove(body.Name())
+	}()
+
+	ok, err := readReceivePackRequest(io.TeeReader(s.ch, body))
+	if err != nil {
+		m.logger.Warn("Failed to read push", zap.String("repo", repoName), zap.Error(err))
+		fmt.Fprintf(s.ch.Stderr(), "failed to read push: %s\n", err)
+		return 1
+	}
+	if !ok {
+		// Nothing to update
+		return 0
+	}
+
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		m.logger.Error("Failed to rewind push", zap.String("repo", repoName), zap.Error(err))
+		return 1
+	}
+
+	ctx := auth.WithIdentity(context.Background(), s.identity)
+	w := &channelResponseWriter{ch: s.ch, header: make(http.Header)}
+	m.params.GitHTTP.ReceivePack(ctx, w, repoName, s.conn.RemoteAddr().String(), body)
+
+	if w.status != http.StatusOK {
+		return 1
+	}
+	return 0
+}
+
+// readReceivePackRequest reads the commands and the packfile of a push up to the end of the packfile,
+// as the client keeps the connection open to receive the report. It returns false if the client
+// sent no commands, which it does when all references are up to date.
+func readReceivePackRequest(r io.Reader) (bool, error) {
+	flush := make([]byte, 4)
+	if _, err := io.ReadFull(r, flush); err != nil {
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		return false, err
+	}
+	if string(flush) == "0000" {
+		return false, nil
+	}
+
+	req, err := git_http.DecodeReferenceUpdateRequest(io.MultiReader(bytes.NewReader(flush), r))
+	if err != nil {
+		return false, err
+	}
+
+	// Pushes deleting references only carry no packfile
+	deletesOnly := true
+	for _, cmd := range req.Commands {
+		if !cmd.New.IsZero() {
+			deletesOnly = false
+		}
+	}
+	if deletesOnly {
+		return true, nil
+	}
+
+	scanner := packfile.NewScanner(req.Packfile)
+	_, objects, err := scanner.Header()
+	if err != nil {
+		return false, err
+	}
+
+	for i := uint32(0); i < objects; i++ {
+		if _, err := scanner.NextObjectHeader(); err != nil {
+			return false, err
+		}
+	}
+
+	if _, err := scanner.Checksum(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// recordAudit writes an audit entry for a git command of an SSH session
+func (m *GitSSH) recordAudit(s *session, action, repoName string, err error) {
+	entry := audit.Entry{
+		Actor:      s.identity.Name,
+		Action:     action,
+		Repository: repoName,
+		RemoteAddr: s.conn.RemoteAddr().String(),
+		Outcome:    audit.OutcomeSuccess,
+	}
+	if err != nil {
+		entry.Outcome = audit.OutcomeFailure
+		entry.Error = err.Error()
+	}
+
+	m.params.AuditLog.Record(entry)
+}
+
+// parseCommand splits a git command sent over SSH into the service and the repository name.
+// Both "git-upload-pack" and "git upload-pack" forms are accepted, and the path may be quoted,
+// absolute and with or without the .git suffix.
+func parseCommand(command string) (string, string, error) {
+	command = strings.TrimSpace(command)
+	if rest, ok := strings.CutPrefix(command, "git "); ok {
+		command = "git-" + strings.TrimSpace(rest)
+	}
+
+	service, path, _ := strings.Cut(command, " ")
+	if service != serviceUploadPack && service != serviceReceivePack {
+		return "", "", fmt.Errorf("unsupported command: only git-upload-pack and git-receive-pack are allowed")
+	}
+
+	path = strings.Trim(strings.TrimSpace(path), `'"`)
+	path = strings.Trim(path, "/")
+	path = strings.TrimSuffix(path, ".git")
+	if path == "" {
+		return "", "", fmt.Errorf("missing repository path")
+	}
+
+	return service, path, nil
+}
+
+// exitStatus returns the exit status of a finished command
+func exitStatus(err error) uint32 {
+	if err == nil {
+		return 0
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		return uint32(exitErr.ExitCode())
+	}
+
+	return 1
+}
+
+// auditAction returns the audit action of a git operation
+func auditAction(op auth.Operation) string {
+	if op == auth.OperationWrite {
+		return audit.ActionGitPush
+	}
+	return audit.ActionGitFetch
+}
+
+// channelResponseWriter writes the response of git_http to an SSH channel.
+// Successful responses carry protocol data for the client, errors are written to stderr.
+type channelResponseWriter struct {
+	ch     ssh.Channel
+	header http.Header
+	status int
+}
+
+func (w *channelResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *channelResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *channelResponseWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if w.status != http.StatusOK {
+		return w.ch.Stderr().Write(p)
+	}
+	return w.ch.Write(p)
+}
+
+// Flush implements http.Flusher, SSH channels are not buffered
+func (w *channelResponseWriter) Flush() {}
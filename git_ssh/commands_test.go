@@ -0,0 +1,32 @@
+package git_ssh
+
+import "testing"
+
+// Test that git commands sent by clients are parsed into service and repository name
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		service string
+		repo    string
+		wantErr bool
+	}{
+		{"git-upload-pack '/org/team/project.git'", serviceUploadPack, "org/team/project", false},
+		{"git-receive-pack 'org/app.git'", serviceReceivePack, "org/app", false},
+		{"git upload-pack '/hello'", serviceUploadPack, "hello", false},
+		{"git-upload-archive '/org/app.git'", "", "", true},
+		{"rm -rf /", "", "", true},
+		{"git-upload-pack ''", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			service, repo, err := parseCommand(tt.command)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if service != tt.service || repo != tt.repo {
+				t.Errorf("Expected %s %s, got %s %s", tt.service, tt.repo, service, repo)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+// Package git_ssh serves git-upload-pack and git-receive-pack over SSH.
+//
+// Clients authenticate with a public key, which is resolved to an identity by the keys
+// registered with ssh_keys, when the module is part of the application, or by a custom KeyStore.
+// Repository paths are resolved through RepositoryManager and support multi-level names:
+//
+//	git clone ssh://git@host:2222/org/team/project.git
+//
+// Pushes are passed to git_http, so the same policies, hooks, webhooks and audit records
+// apply as for pushes over HTTP.
+package git_ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/spf13/viper"
+	"github.com/weedbox/git-modules/audit"
+	"github.com/weedbox/git-modules/auth"
+	"github.com/weedbox/git-modules/git_http"
+	"github.com/weedbox/git-modules/repository_manager"
+	"github.com/weedbox/git-modules/ssh_keys"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	ModuleName         = "GitSSH"
+	DefaultHost        = "0.0.0.0"
+	DefaultPort        = 2222
+	DefaultHostKeyPath = "./git/ssh_host_ed25519_key"
+)
+
+// KeyStore resolves the public keys presented by SSH clients to identities
+type KeyStore interface {
+	// LookupPublicKey returns the identity a key belongs to.
+	// Unknown keys yield an error wrapping auth.ErrUnauthenticated.
+	LookupPublicKey(ctx context.Context, key ssh.PublicKey) (*auth.Identity, error)
+}
+
+type GitSSH struct {
+	params       Params
+	logger       *zap.Logger
+	scope        string
+	config       *ssh.ServerConfig
+	listener     net.Listener
+	enforceRoles bool
+	mu           sync.Mutex
+	conns        map[*ssh.ServerConn]struct{}
+	wg           sync.WaitGroup
+}
+
+type Params struct {
+	fx.In
+
+	Lifecycle         fx.Lifecycle
+	Logger            *zap.Logger
+	RepositoryManager *repository_manager.RepositoryManager
+	GitHTTP           *git_http.GitHTTP
+
+	// Keys resolves the registered user keys and deploy keys when provided
+	Keys *ssh_keys.KeyStore `optional:"true"`
+
+	// KeyStore resolves keys not registered with Keys when provided
+	KeyStore KeyStore `optional:"true"`
+
+	// AuditLog records fetches when provided, pushes are recorded by git_http
+	AuditLog *audit.AuditLog `optional:"true"`
+}
+
+func Module(scope string) fx.Option {
+
+	var m *GitSSH
+
+	return fx.Module(
+		scope,
+		fx.Provide(func(p Params) *GitSSH {
+			s := &GitSSH{
+				params: p,
+				logger: p.Logger.Named(scope),
+				scope:  scope,
+				conns:  make(map[*ssh.ServerConn]struct{}),
+			}
+
+			s.initDefaultConfigs()
+
+			return s
+		}),
+		fx.Populate(&m),
+		fx.Invoke(func(p Params) {
+
+			p.Lifecycle.Append(
+				fx.Hook{
+					OnStart: m.onStart,
+					OnStop:  m.onStop,
+				},
+			)
+		}),
+	)
+
+}
+
+func (m *GitSSH) onStart(ctx context.Context) error {
+	m.logger.Info("Starting " + ModuleName)
+
+	m.enforceRoles = viper.GetBool(m.getConfigPath("enforce_roles"))
+
+	if m.params.Keys == nil && m.params.KeyStore == nil {
+		return errors.New("git_ssh requires the ssh_keys module or a KeyStore to authenticate clients")
+	}
+
+	hostKey, err := loadOrCreateHostKey(viper.GetString(m.getConfigPath("host_key_path")))
+	if err != nil {
+		return err
+	}
+
+	m.config = &ssh.ServerConfig{
+		PublicKeyCallback: m.authenticate,
+	}
+	m.config.AddHostKey(hostKey)
+
+	addr := fmt.Sprintf("%s:%d", viper.GetString(m.getConfigPath("host")), viper.GetInt(m.getConfigPath("port")))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	m.listener = listener
+
+	m.logger.Info("Git SSH service listening",
+		zap.String("addr", listener.Addr().String()),
+		zap.String("hostKey", ssh.FingerprintSHA256(hostKey.PublicKey())),
+		zap.Bool("enforceRoles", m.enforceRoles),
+	)
+
+	m.wg.Add(1)
+	go m.serve()
+
+	return nil
+}
+
+// onStop stops accepting connections and waits for running git commands to finish.
+// Connections still open when ctx expires are closed.
+func (m *GitSSH) onStop(ctx context.Context) error {
+	if m.listener != nil {
+		m.listener.Close()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		m.logger.Warn("Closing remaining SSH connections")
+		m.mu.Lock()
+		for conn := range m.conns {
+			conn.Close()
+		}
+		m.mu.Unlock()
+		<-done
+	}
+
+	m.logger.Info("Stopped " + ModuleName)
+	return nil
+}
+
+// Addr returns the address the SSH server listens on, or nil before it is started
+func (m *GitSSH) Addr() net.Addr {
+	if m.listener == nil {
+		return nil
+	}
+	return m.listener.Addr()
+}
+
+func (m *GitSSH) getConfigPath(key string) string {
+	return fmt.Sprintf("%s.%s", m.scope, key)
+}
+
+func (m *GitSSH) initDefaultConfigs() {
+	viper.SetDefault(m.getConfigPath("host"), DefaultHost)
+	viper.SetDefault(m.getConfigPath("port"), DefaultPort)
+	viper.SetDefault(m.getConfigPath("host_key_path"), DefaultHostKeyPath)
+	viper.SetDefault(m.getConfigPath("enforce_roles"), false)
+}
@@ -0,0 +1,233 @@
+package git_ssh
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/weedbox/git-modules/auth"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// identityExtension is the permission extension carrying the JSON encoded identity of a connection
+	identityExtension = "identity"
+
+	// fingerprintExtension is the permission extension carrying the fingerprint of the client key
+	fingerprintExtension = "fingerprint"
+)
+
+// serve accepts connections until the listener is closed
+func (m *GitSSH) serve() {
+	defer m.wg.Done()
+
+	for {
+		conn, err := m.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				m.logger.Error("Failed to accept SSH connection", zap.Error(err))
+			}
+			return
+		}
+
+		m.wg.Add(1)
+		go m.handleConn(conn)
+	}
+}
+
+// authenticate resolves the public key of a client to an identity,
+// through the registered keys first and then through the KeyStore
+func (m *GitSSH) authenticate(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+	identity, err := m.lookupPublicKey(key)
+	if err == nil && identity == nil {
+		err = auth.ErrUnauthenticated
+	}
+	if err != nil {
+		m.logger.Debug("SSH public key rejected",
+			zap.String("remote", meta.RemoteAddr().String()),
+			zap.String("fingerprint", ssh.FingerprintSHA256(key)),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	data, err := json.Marshal(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ssh.Permissions{
+		Extensions: map[string]string{
+			identityExtension:    string(data),
+			fingerprintExtension: ssh.FingerprintSHA256(key),
+		},
+	}, nil
+}
+
+// lookupPublicKey returns the identity of a public key
+func (m *GitSSH) lookupPublicKey(key ssh.PublicKey) (*auth.Identity, error) {
+	ctx := context.Background()
+
+	if m.params.Keys != nil {
+		identity, err := m.params.Keys.LookupPublicKey(ctx, key)
+		if m.params.KeyStore == nil || !errors.Is(err, auth.ErrUnauthenticated) {
+			return identity, err
+		}
+	}
+
+	return m.params.KeyStore.LookupPublicKey(ctx, key)
+}
+
+// handleConn runs the SSH handshake and serves the sessions of a connection
+func (m *GitSSH) handleConn(netConn net.Conn) {
+	defer m.wg.Done()
+
+	conn, chans, reqs, err := ssh.NewServerConn(netConn, m.config)
+	if err != nil {
+		m.logger.Debug("SSH handshake failed", zap.String("remote", netConn.RemoteAddr().String()), zap.Error(err))
+		netConn.Close()
+		return
+	}
+
+	m.mu.Lock()
+	m.conns[conn] = struct{}{}
+	m.mu.Unlock()
+
+	// Keys are looked up before the client signs, so their use is only recorded after the handshake
+	if m.params.Keys != nil {
+		m.params.Keys.RecordUse(conn.Permissions.Extensions[fingerprintExtension])
+	}
+
+	defer func() {
+		conn.Close()
+		m.mu.Lock()
+		delete(m.conns, conn)
+		m.mu.Unlock()
+	}()
+
+	identity := &auth.Identity{}
+	if err := json.Unmarshal([]byte(conn.Permissions.Extensions[identityExtension]), identity); err != nil {
+		m.logger.Error("Failed to decode SSH identity", zap.Error(err))
+		return
+	}
+
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only session channels are supported")
+			continue
+		}
+
+		ch, requests, err := newChannel.Accept()
+		if err != nil {
+			m.logger.Warn("Failed to accept SSH channel", zap.Error(err))
+			continue
+		}
+
+		m.wg.Add(1)
+		go m.handleSession(conn, identity, ch, requests)
+	}
+}
+
+// handleSession serves the git command requested on a session channel.
+// Interactive shells are refused with a greeting, as there is nothing to run besides git.
+func (m *GitSSH) handleSession(conn *ssh.ServerConn, identity *auth.Identity, ch ssh.Channel, requests <-chan *ssh.Request) {
+	defer m.wg.Done()
+	defer ch.Close()
+
+	var env []string
+	for req := range requests {
+		switch req.Type {
+		case "env":
+			var kv struct{ Name, Value string }
+			if err := ssh.Unmarshal(req.Payload, &kv); err == nil && kv.Name == "GIT_PROTOCOL" {
+				env = append(env, kv.Name+"="+kv.Value)
+			}
+			req.Reply(true, nil)
+
+		case "exec":
+			var payload struct{ Command string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+				req.Reply(false, nil)
+				return
+			}
+			req.Reply(true, nil)
+
+			go ssh.DiscardRequests(requests)
+			status := m.runCommand(&session{
+				conn:     conn,
+				identity: identity,
+				ch:       ch,
+				env:      env,
+			}, payload.Command)
+			sendExitStatus(ch, status)
+			return
+
+		case "shell":
+			req.Reply(true, nil)
+			fmt.Fprintf(ch.Stderr(), "Hi %s! You've successfully authenticated, but shell access is not provided.\r\n", identity.Name)
+			sendExitStatus(ch, 1)
+			return
+
+		default:
+			if req.WantReply {
+				req.Reply(req.Type == "pty-req", nil)
+			}
+		}
+	}
+}
+
+// sendExitStatus reports the exit status of a command to the client
+func sendExitStatus(ch ssh.Channel, status uint32) {
+	ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+}
+
+// loadOrCreateHostKey reads the host key, generating a new ed25519 key if the file does not exist
+func loadOrCreateHostKey(path string) (ssh.Signer, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return createHostKey(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read host key: %w", err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse host key: %w", err)
+	}
+
+	return signer, nil
+}
+
+// createHostKey generates an ed25519 host key and stores it in OpenSSH format
+func createHostKey(path string) (ssh.Signer, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate host key: %w", err)
+	}
+
+	block, err := ssh.MarshalPrivateKey(key, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode host key: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create host key directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write host key: %w", err)
+	}
+
+	return ssh.NewSignerFromKey(key)
+}
@@ -0,0 +1,339 @@
+package git_ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/weedbox/common-modules/http_server"
+	"github.com/weedbox/git-modules/auth"
+	"github.com/weedbox/git-modules/git_http"
+	"github.com/weedbox/git-modules/repository_manager"
+	"github.com/weedbox/git-modules/ssh_keys"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+)
+
+// testServer is a running application serving git over SSH
+type testServer struct {
+	app     *fx.App
+	ssh     *GitSSH
+	keys    *ssh_keys.KeyStore
+	manager *repository_manager.RepositoryManager
+}
+
+// setupTestServer starts git_ssh on a free local port with the repository org/app.
+// opts are added to the app, such as a TeamResolver.
+func setupTestServer(t *testing.T, opts ...fx.Option) *testServer {
+	for _, bin := range []string{"git", "ssh", "ssh-keygen"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skip(bin + " is not installed")
+		}
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	httpPort := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	dir := t.TempDir()
+	viper.Set("http_server.host", "127.0.0.1")
+	viper.Set("http_server.port", httpPort)
+	viper.Set("repository_manager.repos_path", filepath.Join(dir, "repos"))
+	viper.Set("ssh_keys.path", filepath.Join(dir, "ssh_keys.json"))
+	viper.Set("git_ssh.host", "127.0.0.1")
+	viper.Set("git_ssh.port", 0)
+	viper.Set("git_ssh.host_key_path", filepath.Join(dir, "ssh_host_ed25519_key"))
+
+	s := &testServer{}
+	s.app = fx.New(
+		fx.NopLogger,
+		fx.Provide(zap.NewNop),
+		http_server.Module("http_server"),
+		repository_manager.Module("repository_manager"),
+		ssh_keys.Module("ssh_keys"),
+		git_http.Module("git_http"),
+		Module("git_ssh"),
+		fx.Populate(&s.ssh, &s.keys, &s.manager),
+		fx.Options(opts...),
+	)
+	if err := s.app.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start app: %v", err)
+	}
+	t.Cleanup(func() { s.app.Stop(context.Background()) })
+
+	if _, err := s.manager.CreateRepository("org/app", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	if _, err := s.manager.CommitFiles("org/app", repository_manager.CommitRequest{
+		Message: "Initial commit",
+		Actions: []repository_manager.FileAction{{Action: repository_manager.FileActionCreate, Path: "README.md", Content: []byte("hello\n")}},
+	}); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	return s
+}
+
+// url returns the SSH URL of a repository
+func (s *testServer) url(repoName string) string {
+	return fmt.Sprintf("ssh://git@%s/%s.git", s.ssh.Addr().String(), repoName)
+}
+
+// generateKey creates a client key pair and returns the path of the private key
+func generateKey(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-C", "test", "-f", path).CombinedOutput(); err != nil {
+		t.Fatalf("Failed to generate key: %v\n%s", err, out)
+	}
+	return path
+}
+
+// registerKey registers the public key of a private key file for a user
+func (s *testServer) registerKey(t *testing.T, user, keyPath string) {
+	pub, err := os.ReadFile(keyPath + ".pub")
+	if err != nil {
+		t.Fatalf("Failed to read public key: %v", err)
+	}
+	if _, err := s.keys.AddUserKey(user, ssh_keys.Options{Title: "test", Key: string(pub)}); err != nil {
+		t.Fatalf("Failed to register key: %v", err)
+	}
+}
+
+// gitSSH runs a git command connecting with the given private key
+func gitSSH(dir, keyPath string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_SSH_COMMAND=ssh -i "+keyPath+" -o IdentitiesOnly=yes -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null -o BatchMode=yes -o LogLevel=ERROR",
+	)
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// Test cloning and pushing over SSH with a registered user key
+func TestCloneAndPush(t *testing.T) {
+	s := setupTestServer(t)
+	key := generateKey(t)
+	s.registerKey(t, "alice", key)
+
+	dir := t.TempDir()
+	repoDir := filepath.Join(dir, "app")
+	if out, err := gitSSH(dir, key, "clone", "-q", s.url("org/app"), repoDir); err != nil {
+		t.Fatalf("Clone failed: %v\n%s", err, out)
+	}
+	if data, err := os.ReadFile(filepath.Join(repoDir, "README.md")); err != nil || string(data) != "hello\n" {
+		t.Fatalf("Unexpected README.md after clone: %q, %v", data, err)
+	}
+
+	if out, err := gitSSH(repoDir, key, "-c", "user.name=Alice", "-c", "user.email=alice@example.com", "commit", "-q", "--allow-empty", "-m", "Pushed over SSH"); err != nil {
+		t.Fatalf("Commit failed: %v\n%s", err, out)
+	}
+	if out, err := gitSSH(repoDir, key, "push", "-q", "origin", "HEAD:refs/heads/topic"); err != nil {
+		t.Fatalf("Push failed: %v\n%s", err, out)
+	}
+
+	head, _ := gitSSH(repoDir, key, "rev-parse", "HEAD")
+	remote, err := gitSSH(repoDir, key, "ls-remote", "origin", "refs/heads/topic")
+	if err != nil || !strings.HasPrefix(remote, strings.TrimSpace(head)) {
+		t.Errorf("Expected topic at %s, got %s (%v)", strings.TrimSpace(head), remote, err)
+	}
+
+	// Unknown repositories are reported without leaking paths
+	if out, err := gitSSH(dir, key, "clone", "-q", s.url("org/missing"), filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("Expected clone of unknown repository to fail: %s", out)
+	}
+}
+
+// Test that keys not registered with ssh_keys are refused during the handshake
+func TestRejectedKey(t *testing.T) {
+	s := setupTestServer(t)
+	s.registerKey(t, "alice", generateKey(t))
+
+	dir := t.TempDir()
+	out, err := gitSSH(dir, generateKey(t), "clone", "-q", s.url("org/app"), filepath.Join(dir, "app"))
+	if err == nil || !strings.Contains(out, "Permission denied") {
+		t.Errorf("Expected unregistered key to be refused, got %v: %s", err, out)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app", "README.md")); !os.IsNotExist(err) {
+		t.Errorf("Expected no checkout with a refused key, got %v", err)
+	}
+}
+
+// Test that stopping the module closes the listener and idle connections within the stop deadline
+func TestShutdown(t *testing.T) {
+	s := setupTestServer(t)
+	key := generateKey(t)
+	s.registerKey(t, "alice", key)
+
+	data, err := os.ReadFile(key)
+	if err != nil {
+		t.Fatalf("Failed to read private key: %v", err)
+	}
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+
+	addr := s.ssh.Addr().String()
+	config := &ssh.ClientConfig{
+		User:            "git",
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         5 * time.Second,
+	}
+	client, err := ssh.Dial("tcp", addr, config)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	closed := make(chan struct{})
+	go func() {
+		client.Wait()
+		close(closed)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.app.Stop(ctx) }()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop did not return after its deadline with an idle connection open")
+	}
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Error("Expected the idle connection to be closed on stop")
+	}
+
+	if conn, err := net.DialTimeout("tcp", addr, time.Second); err == nil {
+		conn.Close()
+		t.Error("Expected the listener to be closed on stop")
+	}
+}
+
+// Test that keys are only marked as used once the client proved it holds the private key
+func TestKeyUsage(t *testing.T) {
+	s := setupTestServer(t)
+	key := generateKey(t)
+	s.registerKey(t, "alice", key)
+
+	data, err := os.ReadFile(key)
+	if err != nil {
+		t.Fatalf("Failed to read private key: %v", err)
+	}
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %v", err)
+	}
+
+	dial := func(signer ssh.Signer) error {
+		client, err := ssh.Dial("tcp", s.ssh.Addr().String(), &ssh.ClientConfig{
+			User:            "git",
+			Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+			Timeout:         5 * time.Second,
+		})
+		if err == nil {
+			client.Close()
+		}
+		return err
+	}
+
+	// The client offers the public key, which the server accepts, but cannot sign
+	if err := dial(failingSigner{signer}); err == nil {
+		t.Fatal("Expected authentication without signature to fail")
+	}
+	if keys := s.keys.ListUserKeys("alice"); len(keys) != 1 || keys[0].LastUsedAt != nil {
+		t.Errorf("Expected key offered without signature to stay unused, got %+v", keys)
+	}
+
+	if err := dial(signer); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	if keys := s.keys.ListUserKeys("alice"); len(keys) != 1 || keys[0].LastUsedAt == nil {
+		t.Errorf("Expected key to be marked as used after authentication, got %+v", keys)
+	}
+}
+
+// failingSigner offers a public key but fails to sign with it
+type failingSigner struct {
+	ssh.Signer
+}
+
+func (s failingSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	return nil, errors.New("signing not possible")
+}
+
+// Test that roles granted to teams apply to users authenticated by their SSH key
+func TestTeamRoles(t *testing.T) {
+	viper.Set("git_ssh.enforce_roles", true)
+	t.Cleanup(func() { viper.Set("git_ssh.enforce_roles", false) })
+
+	teams := auth.TeamResolverFunc(func(ctx context.Context, user string) ([]string, error) {
+		if user == "carol" {
+			return []string{"readers"}, nil
+		}
+		return nil, nil
+	})
+	s := setupTestServer(t, fx.Supply(fx.Annotate(teams, fx.As(new(auth.TeamResolver)))))
+	if _, err := s.manager.SetPermission("org", repository_manager.Permission{Team: "readers", Role: repository_manager.RoleRead}); err != nil {
+		t.Fatalf("Failed to grant role: %v", err)
+	}
+
+	carol, dave := generateKey(t), generateKey(t)
+	s.registerKey(t, "carol", carol)
+	s.registerKey(t, "dave", dave)
+
+	dir := t.TempDir()
+	if out, err := gitSSH(dir, carol, "clone", "-q", s.url("org/app"), filepath.Join(dir, "carol")); err != nil {
+		t.Errorf("Expected team member to clone, got %v: %s", err, out)
+	}
+	if out, err := gitSSH(dir, dave, "clone", "-q", s.url("org/app"), filepath.Join(dir, "dave")); err == nil {
+		t.Errorf("Expected user outside the team to be refused: %s", out)
+	}
+}
+
+// Test that a denied repository cannot be told apart from an unknown one
+func TestDeniedLikeUnknown(t *testing.T) {
+	viper.Set("git_ssh.enforce_roles", true)
+	t.Cleanup(func() { viper.Set("git_ssh.enforce_roles", false) })
+
+	s := setupTestServer(t)
+	key := generateKey(t)
+	s.registerKey(t, "dave", key)
+
+	dir := t.TempDir()
+	denied, err := gitSSH(dir, key, "ls-remote", s.url("org/app"))
+	if err == nil {
+		t.Fatalf("Expected ls-remote without a role to fail: %s", denied)
+	}
+	unknown, err := gitSSH(dir, key, "ls-remote", s.url("org/missing"))
+	if err == nil {
+		t.Fatalf("Expected ls-remote of unknown repository to fail: %s", unknown)
+	}
+
+	if !strings.Contains(denied, "repository not found: org/app") {
+		t.Errorf("Expected denied repository to be reported as not found, got %s", denied)
+	}
+	if !strings.Contains(unknown, "repository not found: org/missing") {
+		t.Errorf("Expected unknown repository to be reported as not found, got %s", unknown)
+	}
+}
@@ -0,0 +1,22 @@
+package gpg_keys
+
+import "time"
+
+// Key describes a registered OpenPGP public key
+// @Description OpenPGP public key a user signs commits with
+type Key struct {
+	ID          string    `json:"id" example:"9f86d081884c7d65"`
+	User        string    `json:"user" example:"john"`
+	KeyID       string    `json:"key_id" example:"3AA5C34371567BD2"`
+	SubkeyIDs   []string  `json:"subkey_ids,omitempty" example:"4BB6D45482678BE3"`
+	Fingerprint string    `json:"fingerprint" example:"ABAF11C65A2970B130ABE3C479BE3E4300411886"`
+	Emails      []string  `json:"emails,omitempty" example:"john@example.com"`
+	PublicKey   string    `json:"public_key" example:"-----BEGIN PGP PUBLIC KEY BLOCK-----..."`
+	CreatedAt   time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+} // @name GPGKey
+
+// Options describes a key to register
+// @Description GPG key registration request with an ASCII armored public key
+type Options struct {
+	Key string `json:"key" binding:"required" example:"-----BEGIN PGP PUBLIC KEY BLOCK-----..."`
+} // @name GPGKeyOptions
@@ -0,0 +1,101 @@
+// Package gpg_keys stores the OpenPGP public keys users sign commits with.
+//
+// A key can only be registered once, so every signature resolves to exactly one user.
+// The KeyStore verifies GPG signed commits for the commit signature policies of
+// repository_manager. Keys are managed through repository_manager_apis when the module
+// is part of the application.
+package gpg_keys
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	ModuleName  = "GPGKeyStore"
+	DefaultPath = "./git/gpg_keys.json"
+)
+
+type KeyStore struct {
+	params   Params
+	logger   *zap.Logger
+	scope    string
+	path     string
+	mu       sync.Mutex
+	keys     map[string]*Key
+	entities map[string]*openpgp.Entity
+}
+
+type Params struct {
+	fx.In
+
+	Lifecycle fx.Lifecycle
+	Logger    *zap.Logger
+}
+
+func Module(scope string) fx.Option {
+
+	var m *KeyStore
+
+	return fx.Module(
+		scope,
+		fx.Provide(func(p Params) *KeyStore {
+			s := &KeyStore{
+				params: p,
+				logger: p.Logger.Named(scope),
+				scope:  scope,
+			}
+
+			s.initDefaultConfigs()
+
+			return s
+		}),
+		fx.Populate(&m),
+		fx.Invoke(func(p Params) {
+
+			p.Lifecycle.Append(
+				fx.Hook{
+					OnStart: m.onStart,
+					OnStop:  m.onStop,
+				},
+			)
+		}),
+	)
+
+}
+
+func (m *KeyStore) onStart(ctx context.Context) error {
+	m.logger.Info("Starting " + ModuleName)
+
+	m.path = viper.GetString(m.getConfigPath("path"))
+
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return fmt.Errorf("failed to create GPG key store directory: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.load()
+}
+
+func (m *KeyStore) onStop(ctx context.Context) error {
+	m.logger.Info("Stopped " + ModuleName)
+	return nil
+}
+
+func (m *KeyStore) getConfigPath(key string) string {
+	return fmt.Sprintf("%s.%s", m.scope, key)
+}
+
+func (m *KeyStore) initDefaultConfigs() {
+	viper.SetDefault(m.getConfigPath("path"), DefaultPath)
+}
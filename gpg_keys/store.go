@@ -0,0 +1,248 @@
+package gpg_keys
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/weedbox/git-modules/auth"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrKeyInvalid indicates a key that is not a single ASCII armored OpenPGP public key
+	ErrKeyInvalid = errors.New("invalid GPG key: expected a single ASCII armored OpenPGP public key")
+
+	// ErrKeyDuplicate indicates a key already registered by a user
+	ErrKeyDuplicate = errors.New("GPG key is already in use")
+
+	// ErrKeyNotFound indicates a key that does not exist or belongs to another user
+	ErrKeyNotFound = errors.New("GPG key not found")
+
+	// ErrSignatureUnverified indicates a signature that was not made by a registered key
+	ErrSignatureUnverified = errors.New("signature was not made by a registered GPG key")
+)
+
+// AddKey registers a public key a user signs commits with
+func (m *KeyStore) AddKey(user string, opts Options) (*Key, error) {
+	if user == "" {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(opts.Key))
+	if err != nil || len(entities) != 1 || entities[0].PrivateKey != nil {
+		return nil, ErrKeyInvalid
+	}
+	entity := entities[0]
+
+	publicKey, err := armorPublicKey(entity)
+	if err != nil {
+		return nil, ErrKeyInvalid
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	key := Key{
+		ID:          id,
+		User:        user,
+		KeyID:       entity.PrimaryKey.KeyIdString(),
+		Fingerprint: fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint),
+		PublicKey:   publicKey,
+		CreatedAt:   time.Now().UTC(),
+	}
+	for _, subkey := range entity.Subkeys {
+		key.SubkeyIDs = append(key.SubkeyIDs, subkey.PublicKey.KeyIdString())
+	}
+	for _, identity := range entity.Identities {
+		if identity.UserId != nil && identity.UserId.Email != "" {
+			key.Emails = append(key.Emails, identity.UserId.Email)
+		}
+	}
+	sort.Strings(key.Emails)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.keys {
+		if existing.Fingerprint == key.Fingerprint {
+			return nil, ErrKeyDuplicate
+		}
+	}
+
+	stored := &key
+	m.keys[stored.ID] = stored
+	m.entities[stored.ID] = entity
+	if err := m.save(); err != nil {
+		delete(m.keys, stored.ID)
+		delete(m.entities, stored.ID)
+		return nil, err
+	}
+
+	m.logger.Info("GPG key added",
+		zap.String("id", key.ID),
+		zap.String("user", key.User),
+		zap.String("fingerprint", key.Fingerprint),
+	)
+
+	created := key
+	return &created, nil
+}
+
+// ListKeys returns the keys of a user, oldest first
+func (m *KeyStore) ListKeys(user string) []Key {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]Key, 0)
+	for _, k := range m.keys {
+		if k.User == user {
+			keys = append(keys, *k)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys
+}
+
+// DeleteKey removes a key of a user
+func (m *KeyStore) DeleteKey(user, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.keys[id]
+	if !ok || stored.User != user {
+		return ErrKeyNotFound
+	}
+
+	entity := m.entities[id]
+	delete(m.keys, id)
+	delete(m.entities, id)
+	if err := m.save(); err != nil {
+		m.keys[id] = stored
+		m.entities[id] = entity
+		return err
+	}
+
+	m.logger.Info("GPG key removed", zap.String("id", id), zap.String("user", user))
+	return nil
+}
+
+// VerifyCommitSignature checks an ASCII armored OpenPGP signature of a commit payload against
+// the registered keys and returns the user who registered the signing key with the emails of the key.
+// Signatures by unknown, revoked or expired keys yield ErrSignatureUnverified.
+func (m *KeyStore) VerifyCommitSignature(signature string, payload []byte) (string, []string, error) {
+	m.mu.Lock()
+	keyring := make(openpgp.EntityList, 0, len(m.entities))
+	owners := make(map[string]*Key, len(m.keys))
+	for id, entity := range m.entities {
+		keyring = append(keyring, entity)
+		owners[m.keys[id].Fingerprint] = m.keys[id]
+	}
+	m.mu.Unlock()
+
+	signer, err := openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(payload), strings.NewReader(signature), nil)
+	if err != nil || signer == nil {
+		return "", nil, ErrSignatureUnverified
+	}
+
+	key, ok := owners[fmt.Sprintf("%X", signer.PrimaryKey.Fingerprint)]
+	if !ok {
+		return "", nil, ErrSignatureUnverified
+	}
+
+	return key.User, append([]string(nil), key.Emails...), nil
+}
+
+// load reads the key file, a missing file yields an empty store
+func (m *KeyStore) load() error {
+	m.keys = make(map[string]*Key)
+	m.entities = make(map[string]*openpgp.Entity)
+
+	data, err := os.ReadFile(m.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read GPG keys: %w", err)
+	}
+
+	var stored []*Key
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("failed to read GPG keys: %w", err)
+	}
+
+	for _, k := range stored {
+		entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(k.PublicKey))
+		if err != nil || len(entities) != 1 {
+			return fmt.Errorf("failed to read GPG key %s: %w", k.ID, ErrKeyInvalid)
+		}
+		m.keys[k.ID] = k
+		m.entities[k.ID] = entities[0]
+	}
+
+	return nil
+}
+
+// save atomically replaces the key file
+func (m *KeyStore) save() error {
+	stored := make([]*Key, 0, len(m.keys))
+	for _, k := range m.keys {
+		stored = append(stored, k)
+	}
+	sort.Slice(stored, func(i, j int) bool { return stored[i].CreatedAt.Before(stored[j].CreatedAt) })
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to write GPG keys: %w", err)
+	}
+
+	tmpPath := m.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write GPG keys: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, m.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write GPG keys: %w", err)
+	}
+
+	return nil
+}
+
+// armorPublicKey returns the public parts of a key, ASCII armored
+func armorPublicKey(entity *openpgp.Entity) (string, error) {
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return "", err
+	}
+	if err := entity.Serialize(w); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// newID returns a random key ID
+func newID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate key ID: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,133 @@
+package gpg_keys
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"go.uber.org/zap"
+)
+
+func setupTestKeyStore(t *testing.T) *KeyStore {
+	m := &KeyStore{
+		logger: zap.NewNop(),
+		path:   filepath.Join(t.TempDir(), "gpg_keys.json"),
+	}
+	if err := m.load(); err != nil {
+		t.Fatalf("Failed to load keys: %v", err)
+	}
+
+	return m
+}
+
+func generateTestEntity(t *testing.T, name, email string) *openpgp.Entity {
+	entity, err := openpgp.NewEntity(name, "", email, nil)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	return entity
+}
+
+func armorTestPrivateKey(t *testing.T, entity *openpgp.Entity) string {
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatalf("Failed to armor key: %v", err)
+	}
+	if err := entity.SerializePrivate(w, nil); err != nil {
+		t.Fatalf("Failed to serialize key: %v", err)
+	}
+	w.Close()
+
+	return buf.String()
+}
+
+// Test key registration, ownership and persistence
+func TestKeys(t *testing.T) {
+	m := setupTestKeyStore(t)
+	entity := generateTestEntity(t, "John", "john@example.com")
+
+	if _, err := m.AddKey("john", Options{Key: armorTestPrivateKey(t, entity)}); !errors.Is(err, ErrKeyInvalid) {
+		t.Errorf("Expected ErrKeyInvalid for private key, got %v", err)
+	}
+	if _, err := m.AddKey("john", Options{Key: "not a key"}); !errors.Is(err, ErrKeyInvalid) {
+		t.Errorf("Expected ErrKeyInvalid, got %v", err)
+	}
+
+	publicKey, err := armorPublicKey(entity)
+	if err != nil {
+		t.Fatalf("Failed to armor key: %v", err)
+	}
+
+	key, err := m.AddKey("john", Options{Key: publicKey})
+	if err != nil {
+		t.Fatalf("Failed to add key: %v", err)
+	}
+	if key.KeyID != entity.PrimaryKey.KeyIdString() || len(key.Emails) != 1 || key.Emails[0] != "john@example.com" {
+		t.Errorf("Unexpected key: %+v", key)
+	}
+	if len(key.SubkeyIDs) != 1 {
+		t.Errorf("Expected the signing subkey to be listed, got %v", key.SubkeyIDs)
+	}
+
+	if _, err := m.AddKey("jane", Options{Key: publicKey}); !errors.Is(err, ErrKeyDuplicate) {
+		t.Errorf("Expected ErrKeyDuplicate, got %v", err)
+	}
+
+	// Keys survive a reload
+	if err := m.load(); err != nil {
+		t.Fatalf("Failed to reload keys: %v", err)
+	}
+	if keys := m.ListKeys("john"); len(keys) != 1 || keys[0].Fingerprint != key.Fingerprint {
+		t.Errorf("Expected one key, got %v", keys)
+	}
+
+	if err := m.DeleteKey("jane", key.ID); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound for another user, got %v", err)
+	}
+	if err := m.DeleteKey("john", key.ID); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+	if keys := m.ListKeys("john"); len(keys) != 0 {
+		t.Errorf("Expected no keys, got %v", keys)
+	}
+}
+
+// Test that commit signatures resolve to the user who registered the signing key
+func TestVerifyCommitSignature(t *testing.T) {
+	m := setupTestKeyStore(t)
+	entity := generateTestEntity(t, "John", "john@example.com")
+
+	payload := []byte("tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n\nInitial commit\n")
+	var signature bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&signature, entity, bytes.NewReader(payload), nil); err != nil {
+		t.Fatalf("Failed to sign payload: %v", err)
+	}
+
+	// Unregistered keys are not trusted
+	if _, _, err := m.VerifyCommitSignature(signature.String(), payload); !errors.Is(err, ErrSignatureUnverified) {
+		t.Errorf("Expected ErrSignatureUnverified for unregistered key, got %v", err)
+	}
+
+	publicKey, _ := armorPublicKey(entity)
+	if _, err := m.AddKey("john", Options{Key: publicKey}); err != nil {
+		t.Fatalf("Failed to add key: %v", err)
+	}
+
+	user, emails, err := m.VerifyCommitSignature(signature.String(), payload)
+	if err != nil {
+		t.Fatalf("Failed to verify signature: %v", err)
+	}
+	if user != "john" || len(emails) != 1 || emails[0] != "john@example.com" {
+		t.Errorf("Expected signature by john <john@example.com>, got %q %v", user, emails)
+	}
+
+	if _, _, err := m.VerifyCommitSignature(signature.String(), []byte(strings.ToUpper(string(payload)))); !errors.Is(err, ErrSignatureUnverified) {
+		t.Errorf("Expected ErrSignatureUnverified for modified payload, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+// Package hooks defines the in-process extension points invoked around pushes.
+//
+// Hooks are registered as fx group values and are called by the git transports
+// for every git-receive-pack request:
+//
+//	fx.Provide(hooks.AsHook(NewReleasePolicy))
+//
+// PreReceive runs before any reference is updated and can veto the push.
+// PostReceive runs in the background after the references have been updated and the
+// client has been answered, so slow hooks do not delay pushes.
+package hooks
+
+import (
+	"context"
+
+	"github.com/weedbox/git-modules/auth"
+	"github.com/weedbox/git-modules/repository_manager"
+	"go.uber.org/fx"
+)
+
+// GroupName is the fx value group hooks are collected from
+const GroupName = "git_hooks"
+
+// Push describes a push received by a git transport
+type Push struct {
+	// Repository is the name of the repository, e.g. "org/team/project"
+	Repository string
+
+	// Pusher is the authenticated identity of the client, nil for anonymous pushes
+	Pusher *auth.Identity
+
+	// RemoteAddr is the network address of the client
+	RemoteAddr string
+
+	// Updates are the reference updates requested by the client.
+	// For PostReceive only the updates that were applied are listed.
+	Updates []repository_manager.ReferenceUpdate
+
+	// Objects gives access to the pushed objects together with the repository objects.
+	// It is only set for PreReceive.
+	Objects *repository_manager.PushObjectStorage
+}
+
+// Hook is implemented by modules that enforce policies on pushes or react to them
+type Hook interface {
+	// PreReceive is called before the references are updated.
+	// Returning an error rejects the whole push. A *repository_manager.PushRejectedError
+	// reports individual reasons per reference, any other error is reported for all references.
+	PreReceive(ctx context.Context, push *Push) error
+
+	// PostReceive is called after the references of a push have been updated.
+	// It runs in the background once the push has been answered, after the hooks registered before it.
+	// ctx keeps the values of the request but is not canceled when the request ends.
+	PostReceive(ctx context.Context, push *Push)
+}
+
+// HookFuncs adapts plain functions to the Hook interface.
+// Either function may be nil.
+type HookFuncs struct {
+	PreReceiveFunc  func(ctx context.Context, push *Push) error
+	PostReceiveFunc func(ctx context.Context, push *Push)
+}
+
+// PreReceive calls PreReceiveFunc if set
+func (h HookFuncs) PreReceive(ctx context.Context, push *Push) error {
+	if h.PreReceiveFunc == nil {
+		return nil
+	}
+	return h.PreReceiveFunc(ctx, push)
+}
+
+// PostReceive calls PostReceiveFunc if set
+func (h HookFuncs) PostReceive(ctx context.Context, push *Push) {
+	if h.PostReceiveFunc != nil {
+		h.PostReceiveFunc(ctx, push)
+	}
+}
+
+// AsHook annotates a constructor returning a Hook implementation so that its result
+// is added to the hook group consumed by the git transports
+func AsHook(constructor any) any {
+	return fx.Annotate(
+		constructor,
+		fx.As(new(Hook)),
+		fx.ResultTags(`group:"`+GroupName+`"`),
+	)
+}
@@ -0,0 +1,86 @@
+package lfs
+
+import "time"
+
+// Operations of a batch request
+const (
+	OperationDownload = "download"
+	OperationUpload   = "upload"
+)
+
+// Transfer adapters and hash algorithms, only the basic transfer of SHA-256 objects is supported
+const (
+	TransferBasic  = "basic"
+	HashAlgoSHA256 = "sha256"
+)
+
+// MediaType is the content type of LFS API requests and responses
+const MediaType = "application/vnd.git-lfs+json"
+
+// Object describes an LFS object stored for a repository
+// @Description Git LFS object uploaded to a repository
+type Object struct {
+	OID       string    `json:"oid" example:"4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393"`
+	Size      int64     `json:"size" example:"12345"`
+	CreatedAt time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+} // @name LFSObject
+
+// Usage summarizes the LFS objects of a repository
+// @Description Number and total size of the Git LFS objects of a repository
+type Usage struct {
+	Objects int   `json:"objects" example:"42"`
+	Size    int64 `json:"size" example:"104857600"`
+} // @name LFSUsage
+
+// Pointer identifies an object in batch and verify requests
+type Pointer struct {
+	OID  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+// BatchRequest is the body of a batch API request
+type BatchRequest struct {
+	Operation string    `json:"operation"`
+	Transfers []string  `json:"transfers,omitempty"`
+	Ref       *Ref      `json:"ref,omitempty"`
+	Objects   []Pointer `json:"objects"`
+	HashAlgo  string    `json:"hash_algo,omitempty"`
+}
+
+// Ref is the reference a batch request is made for
+type Ref struct {
+	Name string `json:"name"`
+}
+
+// BatchResponse is the body of a batch API response
+type BatchResponse struct {
+	Transfer string           `json:"transfer"`
+	Objects  []ObjectResponse `json:"objects"`
+	HashAlgo string           `json:"hash_algo"`
+}
+
+// ObjectResponse tells a client how to transfer an object, or why it cannot
+type ObjectResponse struct {
+	OID           string             `json:"oid"`
+	Size          int64              `json:"size"`
+	Authenticated bool               `json:"authenticated,omitempty"`
+	Actions       map[string]*Action `json:"actions,omitempty"`
+	Error         *ObjectError       `json:"error,omitempty"`
+}
+
+// Action is a request a client makes to transfer an object
+type Action struct {
+	Href   string            `json:"href"`
+	Header map[string]string `json:"header,omitempty"`
+}
+
+// ObjectError is the error of a single object in a batch response
+type ObjectError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// ErrorResponse is the body of LFS API error responses
+type ErrorResponse struct {
+	Message string `json:"message"`
+}
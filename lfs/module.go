@@ -0,0 +1,132 @@
+// Package lfs stores the Git LFS objects of the repositories of a RepositoryManager.
+//
+// Objects are stored once on local disk, content-addressed by their SHA-256 OID, next to
+// the repositories by default. Every repository keeps an index of the objects uploaded to it,
+// so objects are only served to repositories they were uploaded to and the LFS usage of each
+// repository is known. git_http serves the LFS batch API and the basic transfer under
+// {repo}.git/info/lfs when the module is part of the application.
+package lfs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/spf13/viper"
+	"github.com/weedbox/git-modules/events"
+	"github.com/weedbox/git-modules/repository_manager"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	ModuleName = "LFSStore"
+
+	// DefaultMaxObjectSize of 0 accepts objects of any size
+	DefaultMaxObjectSize = 0
+)
+
+type Store struct {
+	params        Params
+	logger        *zap.Logger
+	scope         string
+	path          string
+	maxObjectSize int64
+	mu            sync.Mutex
+	unsubscribe   func()
+}
+
+type Params struct {
+	fx.In
+
+	Lifecycle         fx.Lifecycle
+	Logger            *zap.Logger
+	RepositoryManager *repository_manager.RepositoryManager
+
+	// Bus removes the object index of deleted repositories when provided
+	Bus *events.Bus `optional:"true"`
+}
+
+func Module(scope string) fx.Option {
+
+	var m *Store
+
+	return fx.Module(
+		scope,
+		fx.Provide(func(p Params) *Store {
+			s := &Store{
+				params: p,
+				logger: p.Logger.Named(scope),
+				scope:  scope,
+			}
+
+			s.initDefaultConfigs()
+
+			return s
+		}),
+		fx.Populate(&m),
+		fx.Invoke(func(p Params) {
+
+			p.Lifecycle.Append(
+				fx.Hook{
+					OnStart: m.onStart,
+					OnStop:  m.onStop,
+				},
+			)
+		}),
+	)
+
+}
+
+func (m *Store) onStart(ctx context.Context) error {
+	m.logger.Info("Starting " + ModuleName)
+
+	// Objects are stored next to the repositories unless configured otherwise
+	m.path = viper.GetString(m.getConfigPath("path"))
+	if m.path == "" {
+		m.path = filepath.Join(filepath.Dir(filepath.Clean(m.params.RepositoryManager.GetReposPath())), "lfs")
+	}
+	m.maxObjectSize = viper.GetInt64(m.getConfigPath("max_object_size"))
+
+	for _, dir := range []string{m.objectsPath(), m.tmpPath(), m.indexesPath()} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create LFS store directory: %w", err)
+		}
+	}
+
+	m.logger.Info("Initializing LFS store",
+		zap.String("path", m.path),
+		zap.Int64("maxObjectSize", m.maxObjectSize),
+	)
+
+	// LFS objects are stored outside of the repositories but count towards their quotas
+	m.params.RepositoryManager.AddExternalStorage(m)
+
+	m.unsubscribe = m.params.Bus.Subscribe(func(event events.Event) {
+		if e, ok := event.(events.RepositoryDeleted); ok {
+			m.removeIndex(e.Repository)
+		}
+	})
+
+	return nil
+}
+
+func (m *Store) onStop(ctx context.Context) error {
+	if m.unsubscribe != nil {
+		m.unsubscribe()
+	}
+
+	m.logger.Info("Stopped " + ModuleName)
+	return nil
+}
+
+func (m *Store) getConfigPath(key string) string {
+	return fmt.Sprintf("%s.%s", m.scope, key)
+}
+
+func (m *Store) initDefaultConfigs() {
+	viper.SetDefault(m.getConfigPath("path"), "")
+	viper.SetDefault(m.getConfigPath("max_object_size"), DefaultMaxObjectSize)
+}
@@ -0,0 +1,325 @@
+package lfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/weedbox/git-modules/repository_manager"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrObjectInvalid indicates an OID that is not a SHA-256 hash or a negative size
+	ErrObjectInvalid = errors.New("invalid LFS object: oid must be a lowercase SHA-256 hash and size cannot be negative")
+
+	// ErrObjectNotFound indicates an object that was not uploaded to the repository
+	ErrObjectNotFound = errors.New("LFS object not found")
+
+	// ErrObjectTooLarge indicates an object exceeding the maximum object size
+	ErrObjectTooLarge = errors.New("LFS object exceeds the maximum object size")
+
+	// ErrObjectHashMismatch indicates uploaded content that does not hash to its OID
+	ErrObjectHashMismatch = errors.New("LFS object content does not match its oid")
+
+	// ErrObjectSizeMismatch indicates an object whose stored size differs from the expected size
+	ErrObjectSizeMismatch = errors.New("LFS object size does not match")
+)
+
+// CheckSize returns ErrObjectTooLarge if objects of a size are not accepted
+func (m *Store) CheckSize(size int64) error {
+	if size < 0 {
+		return ErrObjectInvalid
+	}
+	if m.maxObjectSize > 0 && size > m.maxObjectSize {
+		return fmt.Errorf("%w of %d bytes", ErrObjectTooLarge, m.maxObjectSize)
+	}
+	return nil
+}
+
+// Stat returns an object of a repository
+func (m *Store) Stat(repoName, oid string) (*Object, error) {
+	if !IsValidOID(oid) {
+		return nil, ErrObjectInvalid
+	}
+
+	m.mu.Lock()
+	index, err := m.loadIndex(repoName)
+	m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	object, ok := index[oid]
+	if !ok {
+		return nil, ErrObjectNotFound
+	}
+
+	// Objects removed from disk are not served even if still indexed
+	if _, err := os.Stat(m.objectPath(oid)); err != nil {
+		return nil, ErrObjectNotFound
+	}
+
+	return &object, nil
+}
+
+// Open returns the content of an object of a repository, the caller closes it
+func (m *Store) Open(repoName, oid string) (*os.File, *Object, error) {
+	object, err := m.Stat(repoName, oid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f, err := os.Open(m.objectPath(oid))
+	if os.IsNotExist(err) {
+		return nil, nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open LFS object: %w", err)
+	}
+
+	return f, object, nil
+}
+
+// Verify checks that an object of the expected size was uploaded to a repository
+func (m *Store) Verify(repoName, oid string, size int64) error {
+	object, err := m.Stat(repoName, oid)
+	if err != nil {
+		return err
+	}
+
+	if object.Size != size {
+		return fmt.Errorf("%w: expected %d bytes, stored %d bytes", ErrObjectSizeMismatch, size, object.Size)
+	}
+
+	return nil
+}
+
+// Put stores the content of an object uploaded to a repository.
+// The content must hash to the OID, content already stored for another repository is not written again.
+// Objects new to the repository must fit in its quotas, a *repository_manager.QuotaExceededError is returned otherwise.
+func (m *Store) Put(repoName, oid string, r io.Reader) (*Object, error) {
+	if !IsValidOID(oid) {
+		return nil, ErrObjectInvalid
+	}
+	if !m.params.RepositoryManager.IsRepository(repoName) {
+		return nil, repository_manager.NewRepositoryNotFoundError(repoName)
+	}
+
+	tmp, err := os.CreateTemp(m.tmpPath(), "upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to store LFS object: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	// Read one byte more than allowed to detect objects exceeding the limit
+	src := r
+	if m.maxObjectSize > 0 {
+		src = io.LimitReader(r, m.maxObjectSize+1)
+	}
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to store LFS object: %w", err)
+	}
+
+	if err := m.CheckSize(size); err != nil {
+		return nil, err
+	}
+	if hex.EncodeToString(h.Sum(nil)) != oid {
+		return nil, ErrObjectHashMismatch
+	}
+
+	// Objects already uploaded to the repository take no more space
+	if _, err := m.Stat(repoName, oid); errors.Is(err, ErrObjectNotFound) {
+		release, err := m.params.RepositoryManager.ReserveQuota(repoName, size)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	objectPath := m.objectPath(oid)
+	if _, err := os.Stat(objectPath); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(objectPath), 0755); err != nil {
+			return nil, fmt.Errorf("failed to store LFS object: %w", err)
+		}
+		if err := os.Rename(tmp.Name(), objectPath); err != nil {
+			return nil, fmt.Errorf("failed to store LFS object: %w", err)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index, err := m.loadIndex(repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	object, ok := index[oid]
+	if !ok {
+		object = Object{OID: oid, Size: size, CreatedAt: time.Now().UTC()}
+		index[oid] = object
+		if err := m.saveIndex(repoName, index); err != nil {
+			return nil, err
+		}
+
+		m.logger.Info("LFS object stored",
+			zap.String("repo", repoName),
+			zap.String("oid", oid),
+			zap.Int64("size", size),
+		)
+	}
+
+	return &object, nil
+}
+
+// ListObjects returns the objects uploaded to a repository, oldest first
+func (m *Store) ListObjects(repoName string) ([]Object, error) {
+	m.mu.Lock()
+	index, err := m.loadIndex(repoName)
+	m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]Object, 0, len(index))
+	for _, object := range index {
+		objects = append(objects, object)
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		if objects[i].CreatedAt.Equal(objects[j].CreatedAt) {
+			return objects[i].OID < objects[j].OID
+		}
+		return objects[i].CreatedAt.Before(objects[j].CreatedAt)
+	})
+
+	return objects, nil
+}
+
+// GetUsage returns the number and total size of the objects uploaded to a repository
+func (m *Store) GetUsage(repoName string) (*Usage, error) {
+	objects, err := m.ListObjects(repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &Usage{Objects: len(objects)}
+	for _, object := range objects {
+		usage.Size += object.Size
+	}
+
+	return usage, nil
+}
+
+// RepositorySize returns the total size of the objects uploaded to a repository, which counts towards its quotas
+func (m *Store) RepositorySize(repoName string) (int64, error) {
+	usage, err := m.GetUsage(repoName)
+	if err != nil {
+		return 0, err
+	}
+
+	return usage.Size, nil
+}
+
+// removeIndex forgets the objects of a deleted repository.
+// Their content stays on disk as other repositories may share it.
+func (m *Store) removeIndex(repoName string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.Remove(m.indexPath(repoName)); err != nil && !os.IsNotExist(err) {
+		m.logger.Warn("Failed to remove LFS object index", zap.String("repo", repoName), zap.Error(err))
+		return
+	}
+
+	m.logger.Info("LFS object index removed", zap.String("repo", repoName))
+}
+
+// loadIndex reads the object index of a repository, a missing index is empty
+func (m *Store) loadIndex(repoName string) (map[string]Object, error) {
+	if !m.params.RepositoryManager.IsRepository(repoName) {
+		return nil, repository_manager.NewRepositoryNotFoundError(repoName)
+	}
+
+	index := make(map[string]Object)
+
+	data, err := os.ReadFile(m.indexPath(repoName))
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read LFS object index: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to read LFS object index: %w", err)
+	}
+
+	return index, nil
+}
+
+// saveIndex atomically replaces the object index of a repository
+func (m *Store) saveIndex(repoName string, index map[string]Object) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to write LFS object index: %w", err)
+	}
+
+	path := m.indexPath(repoName)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to write LFS object index: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write LFS object index: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write LFS object index: %w", err)
+	}
+
+	return nil
+}
+
+func (m *Store) objectsPath() string { return filepath.Join(m.path, "objects") }
+func (m *Store) tmpPath() string     { return filepath.Join(m.path, "tmp") }
+func (m *Store) indexesPath() string { return filepath.Join(m.path, "repos") }
+
+// objectPath returns where the content of an object is stored, fanned out by the first bytes of the OID
+func (m *Store) objectPath(oid string) string {
+	return filepath.Join(m.objectsPath(), oid[0:2], oid[2:4], oid)
+}
+
+// indexPath returns the object index file of a repository
+func (m *Store) indexPath(repoName string) string {
+	return filepath.Join(m.indexesPath(), filepath.FromSlash(repoName)+".json")
+}
+
+// IsValidOID reports whether an OID is a lowercase hex encoded SHA-256 hash
+func IsValidOID(oid string) bool {
+	if len(oid) != sha256.Size*2 {
+		return false
+	}
+
+	for _, c := range oid {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,148 @@
+package lfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/weedbox/git-modules/events"
+	"github.com/weedbox/git-modules/repository_manager"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+func setupTestStore(t *testing.T, maxObjectSize int64) (*Store, *repository_manager.RepositoryManager) {
+	viper.Set("repository_manager.repos_path", filepath.Join(t.TempDir(), "repos"))
+	viper.Set("lfs.max_object_size", maxObjectSize)
+
+	var store *Store
+	var manager *repository_manager.RepositoryManager
+	app := fx.New(
+		fx.NopLogger,
+		fx.Provide(zap.NewNop),
+		events.Module("events"),
+		repository_manager.Module("repository_manager"),
+		Module("lfs"),
+		fx.Populate(&store, &manager),
+	)
+	if err := app.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start app: %v", err)
+	}
+	t.Cleanup(func() { app.Stop(context.Background()) })
+
+	for _, name := range []string{"org/app", "org/other"} {
+		if _, err := manager.CreateRepository(name, ""); err != nil {
+			t.Fatalf("Failed to create repository: %v", err)
+		}
+	}
+
+	return store, manager
+}
+
+func testOID(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// Test that objects are stored next to the repositories and served to the repositories they were uploaded to
+func TestStore(t *testing.T) {
+	store, manager := setupTestStore(t, 0)
+
+	if want := filepath.Join(filepath.Dir(manager.GetReposPath()), "lfs"); store.path != want {
+		t.Errorf("Expected objects to be stored in %s, got %s", want, store.path)
+	}
+
+	content := "binary asset"
+	oid := testOID(content)
+
+	if _, err := store.Put("org/app", oid, strings.NewReader("other content")); !errors.Is(err, ErrObjectHashMismatch) {
+		t.Errorf("Expected ErrObjectHashMismatch, got %v", err)
+	}
+	if _, err := store.Put("org/app", "../../etc/passwd", strings.NewReader(content)); !errors.Is(err, ErrObjectInvalid) {
+		t.Errorf("Expected ErrObjectInvalid, got %v", err)
+	}
+	if _, err := store.Stat("org/app", oid); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("Expected ErrObjectNotFound before upload, got %v", err)
+	}
+
+	object, err := store.Put("org/app", oid, strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Failed to store object: %v", err)
+	}
+	if object.OID != oid || object.Size != int64(len(content)) {
+		t.Errorf("Unexpected object: %+v", object)
+	}
+
+	f, _, err := store.Open("org/app", oid)
+	if err != nil {
+		t.Fatalf("Failed to open object: %v", err)
+	}
+	data, _ := io.ReadAll(f)
+	f.Close()
+	if string(data) != content {
+		t.Errorf("Expected %q, got %q", content, data)
+	}
+
+	if err := store.Verify("org/app", oid, int64(len(content))); err != nil {
+		t.Errorf("Failed to verify object: %v", err)
+	}
+	if err := store.Verify("org/app", oid, 1); !errors.Is(err, ErrObjectSizeMismatch) {
+		t.Errorf("Expected ErrObjectSizeMismatch, got %v", err)
+	}
+
+	// Other repositories only get the object after uploading it themselves
+	if _, err := store.Stat("org/other", oid); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("Expected object not to be shared, got %v", err)
+	}
+	if _, err := store.Put("org/other", oid, strings.NewReader(content)); err != nil {
+		t.Fatalf("Failed to store object again: %v", err)
+	}
+
+	second := "another asset"
+	if _, err := store.Put("org/app", testOID(second), strings.NewReader(second)); err != nil {
+		t.Fatalf("Failed to store object: %v", err)
+	}
+	usage, err := store.GetUsage("org/app")
+	if err != nil {
+		t.Fatalf("Failed to get usage: %v", err)
+	}
+	if usage.Objects != 2 || usage.Size != int64(len(content)+len(second)) {
+		t.Errorf("Unexpected usage: %+v", usage)
+	}
+
+	if _, err := store.ListObjects("org/missing"); err == nil {
+		t.Error("Expected error for missing repository")
+	}
+
+	// Deleted repositories lose their index but not the content shared with others
+	store.removeIndex("org/app")
+	if usage, _ := store.GetUsage("org/app"); usage.Objects != 0 {
+		t.Errorf("Expected no objects after removing the index, got %+v", usage)
+	}
+	if _, err := store.Stat("org/other", oid); err != nil {
+		t.Errorf("Expected shared object to remain, got %v", err)
+	}
+}
+
+// Test the maximum object size
+func TestStoreMaxObjectSize(t *testing.T) {
+	store, _ := setupTestStore(t, 8)
+
+	if err := store.CheckSize(9); !errors.Is(err, ErrObjectTooLarge) {
+		t.Errorf("Expected ErrObjectTooLarge, got %v", err)
+	}
+	if err := store.CheckSize(8); err != nil {
+		t.Errorf("Expected size at the limit to be accepted, got %v", err)
+	}
+
+	content := "larger than the limit"
+	if _, err := store.Put("org/app", testOID(content), strings.NewReader(content)); !errors.Is(err, ErrObjectTooLarge) {
+		t.Errorf("Expected ErrObjectTooLarge, got %v", err)
+	}
+}
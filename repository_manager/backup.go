@@ -0,0 +1,449 @@
+package repository_manager
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/weedbox/git-modules/events"
+	"go.uber.org/zap"
+)
+
+const (
+	// BackupManifestVersion is the version of the backup archive format
+	BackupManifestVersion = 1
+
+	backupManifestName = "manifest.json"
+	backupBundleDir    = "repositories"
+)
+
+// repositorySnapshot holds the references of a repository captured at backup time
+type repositorySnapshot struct {
+	repo *git.Repository
+	refs []*plumbing.Reference
+}
+
+// ExportArchive writes a gzip compressed tar archive of all groups and repositories to w.
+// The archive starts with a manifest followed by one bundle per non-empty repository.
+// References of every repository are snapshotted before any object data is written,
+// so pushes arriving during the export do not leave the archive in an inconsistent state.
+func (m *RepositoryManager) ExportArchive(w io.Writer) (*BackupManifest, error) {
+	groups, err := m.ListGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	repos, err := m.ListRepositories()
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &BackupManifest{
+		Version:      BackupManifestVersion,
+		CreatedAt:    time.Now().UTC(),
+		Groups:       make([]BackupGroup, 0, len(groups)),
+		Repositories: make([]BackupRepository, 0, len(repos)),
+	}
+
+	for _, group := range groups {
+		name := filepath.ToSlash(group.Name)
+
+		settings, err := m.exportSettings(name)
+		if err != nil {
+			return nil, err
+		}
+
+		manifest.Groups = append(manifest.Groups, BackupGroup{
+			Name:        name,
+			Description: group.Description,
+			Settings:    settings,
+		})
+	}
+
+	// Snapshot references of all repositories first
+	snapshots := make([]repositorySnapshot, 0, len(repos))
+	for _, r := range repos {
+		name := filepath.ToSlash(r.Name)
+
+		repo, err := m.openRepository(name)
+		if err != nil {
+			return nil, err
+		}
+
+		refs, err := bundleReferences(repo)
+		if err != nil {
+			return nil, err
+		}
+
+		settings, err := m.exportSettings(name)
+		if err != nil {
+			return nil, err
+		}
+
+		entry := BackupRepository{
+			Name:        name,
+			Description: r.Description,
+			Settings:    settings,
+		}
+
+		if len(refs) > 0 {
+			entry.Bundle = backupBundleDir + "/" + name + ".bundle"
+			entry.References = make(map[string]string, len(refs))
+			for _, ref := range refs {
+				entry.References[ref.Name().String()] = ref.Hash().String()
+			}
+		}
+
+		manifest.Repositories = append(manifest.Repositories, entry)
+		snapshots = append(snapshots, repositorySnapshot{repo: repo, refs: refs})
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	// Write manifest
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, WrapWriteArchiveError(err)
+	}
+
+	if err := writeArchiveEntry(tw, backupManifestName, int64(len(data)), manifest.CreatedAt, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	// Write one bundle per repository from the snapshotted references
+	for i, entry := range manifest.Repositories {
+		if entry.Bundle == "" {
+			continue
+		}
+
+		if err := m.writeArchiveBundle(tw, entry.Bundle, snapshots[i], manifest.CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, WrapWriteArchiveError(err)
+	}
+
+	if err := gw.Close(); err != nil {
+		return nil, WrapWriteArchiveError(err)
+	}
+
+	m.logger.Info("Archive exported", zap.Int("groups", len(manifest.Groups)), zap.Int("repositories", len(manifest.Repositories)))
+	return manifest, nil
+}
+
+// writeArchiveBundle writes a repository bundle as an archive entry.
+// The bundle is spooled to a temporary file because tar entries need their size upfront.
+func (m *RepositoryManager) writeArchiveBundle(tw *tar.Writer, name string, snapshot repositorySnapshot, modTime time.Time) error {
+	tmp, err := os.CreateTemp("", "git-bundle-*")
+	if err != nil {
+		return WrapWriteArchiveError(err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := writeBundle(snapshot.repo, snapshot.refs, tmp); err != nil {
+		return err
+	}
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return WrapWriteArchiveError(err)
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return WrapWriteArchiveError(err)
+	}
+
+	return writeArchiveEntry(tw, name, size, modTime, tmp)
+}
+
+// ImportArchive recreates all groups and repositories of an archive created by ExportArchive.
+// None of the repositories in the archive may exist yet; existing groups are reused.
+// The archive is restored into a staging directory next to the repositories first and moved into
+// place once it has been read completely, so an invalid or truncated archive leaves nothing behind.
+func (m *RepositoryManager) ImportArchive(r io.Reader) (*BackupManifest, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, ErrArchiveInvalid
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+
+	// The manifest must be the first entry
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != backupManifestName {
+		return nil, ErrArchiveInvalid
+	}
+
+	manifest := &BackupManifest{}
+	if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+		return nil, ErrArchiveInvalid
+	}
+
+	if manifest.Version != BackupManifestVersion {
+		return nil, ErrArchiveInvalid
+	}
+
+	// Refuse to overwrite existing repositories before touching anything
+	bundles := make(map[string]BackupRepository)
+	for _, entry := range manifest.Repositories {
+		if !isValidRepoName(entry.Name) {
+			return nil, ErrRepositoryInvalidName
+		}
+
+		if m.IsRepository(entry.Name) {
+			return nil, NewRepositoryAlreadyExistsError(entry.Name)
+		}
+
+		if entry.Bundle != "" {
+			bundles[entry.Bundle] = entry
+		}
+	}
+
+	staging, err := m.newStagingManager()
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging.reposPath)
+
+	// Recreate groups, parents first
+	groups := append([]BackupGroup{}, manifest.Groups...)
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+
+	for _, group := range groups {
+		if err := staging.restoreGroup(group); err != nil {
+			return nil, err
+		}
+	}
+
+	// Restore repositories from bundles
+	restored := make(map[string]bool)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, WrapReadArchiveError(err)
+		}
+
+		entry, ok := bundles[hdr.Name]
+		if !ok {
+			m.logger.Warn("Skipping unknown archive entry", zap.String("entry", hdr.Name))
+			continue
+		}
+
+		if _, err := staging.CreateRepositoryFromBundle(entry.Name, entry.Description, tr); err != nil {
+			return nil, err
+		}
+
+		restored[entry.Name] = true
+	}
+
+	// Create repositories that were empty at backup time
+	for _, entry := range manifest.Repositories {
+		if entry.Bundle == "" {
+			if _, err := staging.CreateRepository(entry.Name, entry.Description); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if !restored[entry.Name] {
+			return nil, NewArchiveEntryNotFoundError(entry.Bundle)
+		}
+	}
+
+	// Restore repository settings once all repositories exist
+	for _, entry := range manifest.Repositories {
+		if entry.Settings != nil {
+			if err := staging.saveSettings(entry.Name, entry.Settings); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := m.commitStagedArchive(staging, groups, manifest.Repositories); err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("Archive imported", zap.Int("groups", len(manifest.Groups)), zap.Int("repositories", len(manifest.Repositories)))
+	return manifest, nil
+}
+
+// newStagingManager returns a manager for a new empty directory next to the repositories,
+// on the same file system so that its contents can be renamed into place.
+// It publishes no events, the caller announces what it moves into place.
+func (m *RepositoryManager) newStagingManager() (*RepositoryManager, error) {
+	parent := filepath.Dir(filepath.Clean(m.reposPath))
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return nil, WrapCreateParentDirsError(err)
+	}
+
+	dir, err := os.MkdirTemp(parent, "."+filepath.Base(m.reposPath)+"-import-*")
+	if err != nil {
+		return nil, WrapCreateParentDirsError(err)
+	}
+
+	return &RepositoryManager{
+		logger:             m.logger,
+		scope:              m.scope,
+		reposPath:          dir,
+		authorName:         m.authorName,
+		authorEmail:        m.authorEmail,
+		signatureVerifiers: m.signatureVerifiers,
+		quotaUsages:        make(map[string]cachedUsage),
+		quotaReservations:  make(map[string]int64),
+	}, nil
+}
+
+// commitStagedArchive moves the groups and repositories restored by staging into place.
+// Directories missing from the repositories are renamed as a whole, existing groups are merged and
+// get the description and settings of the archive. If a move fails, the moved directories are moved back.
+func (m *RepositoryManager) commitStagedArchive(staging *RepositoryManager, groups []BackupGroup, repositories []BackupRepository) error {
+	existing := make(map[string]bool)
+	for _, group := range groups {
+		existing[group.Name] = m.IsGroup(group.Name)
+	}
+
+	moved := make([][2]string, 0)
+	rollback := func() {
+		for i := len(moved) - 1; i >= 0; i-- {
+			if err := os.Rename(moved[i][1], moved[i][0]); err != nil {
+				m.logger.Error("Failed to roll back archive import", zap.String("path", moved[i][1]), zap.Error(err))
+			}
+		}
+	}
+
+	var merge func(dir string) error
+	merge = func(dir string) error {
+		entries, err := os.ReadDir(filepath.Join(staging.reposPath, dir))
+		if err != nil {
+			return WrapReadArchiveError(err)
+		}
+
+		for _, entry := range entries {
+			// Files of existing groups are restored through restoreGroup
+			if !entry.IsDir() {
+				continue
+			}
+
+			name := filepath.Join(dir, entry.Name())
+			source, target := filepath.Join(staging.reposPath, name), filepath.Join(m.reposPath, name)
+
+			if _, err := os.Stat(target); os.IsNotExist(err) {
+				if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+					return WrapCreateParentDirsError(err)
+				}
+				if err := os.Rename(source, target); err != nil {
+					return WrapCreateGroupDirError(err)
+				}
+				moved = append(moved, [2]string{source, target})
+				continue
+			}
+
+			// Repositories created since the check at the start of the import are not overwritten
+			if strings.HasSuffix(entry.Name(), ".git") {
+				return NewRepositoryAlreadyExistsError(filepath.ToSlash(strings.TrimSuffix(name, ".git")))
+			}
+			if err := merge(name); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := merge(""); err != nil {
+		rollback()
+		return err
+	}
+
+	for _, group := range groups {
+		if existing[group.Name] {
+			if err := m.restoreGroup(group); err != nil {
+				rollback()
+				return err
+			}
+			continue
+		}
+		m.publishEvent(events.GroupCreated{Group: group.Name, Time: time.Now()})
+	}
+
+	for _, entry := range repositories {
+		m.publishRepositoryCreated(entry.Name)
+	}
+
+	return nil
+}
+
+// restoreGroup creates a group from a backup entry or updates the description of an existing one
+func (m *RepositoryManager) restoreGroup(group BackupGroup) error {
+	if !m.IsGroup(group.Name) {
+		if _, err := m.CreateGroup(group.Name, group.Description); err != nil {
+			return err
+		}
+	} else if group.Description != "" {
+		// Parent groups are created implicitly by nested groups and repositories
+		infoPath := filepath.Join(m.reposPath, group.Name, ".groupinfo")
+		if err := os.WriteFile(infoPath, []byte(group.Description), 0644); err != nil {
+			return WrapCreateGroupDirError(err)
+		}
+	}
+
+	if group.Settings != nil {
+		return m.saveSettings(group.Name, group.Settings)
+	}
+
+	return nil
+}
+
+// exportSettings returns the settings of a repository or group for a backup manifest, nil if none are configured
+func (m *RepositoryManager) exportSettings(name string) (*Settings, error) {
+	settings, err := m.loadSettings(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if reflect.DeepEqual(settings, &Settings{}) {
+		return nil, nil
+	}
+
+	return settings, nil
+}
+
+// writeArchiveEntry writes a single regular file entry to a tar archive
+func writeArchiveEntry(tw *tar.Writer, name string, size int64, modTime time.Time, r io.Reader) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    size,
+		ModTime: modTime,
+	}
+
+	if err := tw.WriteHeader(hdr); err != nil {
+		return WrapWriteArchiveError(err)
+	}
+
+	if _, err := io.Copy(tw, r); err != nil {
+		return WrapWriteArchiveError(err)
+	}
+
+	return nil
+}
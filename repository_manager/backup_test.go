@@ -0,0 +1,174 @@
+package repository_manager
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Test exporting a whole server and importing it into a fresh one
+func TestArchive_RoundTrip(t *testing.T) {
+	source, sourceDir := setupTestManager(t)
+	defer teardownTestManager(sourceDir)
+
+	if _, err := source.CreateGroup("org", "Organization"); err != nil {
+		t.Fatalf("Failed to create group: %v", err)
+	}
+	if _, err := source.CreateGroup("org/team", "Team"); err != nil {
+		t.Fatalf("Failed to create group: %v", err)
+	}
+	if _, err := source.CreateRepository("org/team/app", "Application"); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	if _, err := source.CreateRepository("empty", "Empty repository"); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	head := commitTestFile(t, source, "org/team/app", "master", "main.go", "package main")
+
+	var buf bytes.Buffer
+	manifest, err := source.ExportArchive(&buf)
+	if err != nil {
+		t.Fatalf("Failed to export archive: %v", err)
+	}
+
+	if len(manifest.Repositories) != 2 {
+		t.Fatalf("Expected 2 repositories in manifest, got %d", len(manifest.Repositories))
+	}
+
+	// Pushes after the snapshot must not affect the exported data
+	commitTestFile(t, source, "org/team/app", "master", "main.go", "package main // changed")
+
+	target, targetDir := setupTestManager(t)
+	defer teardownTestManager(targetDir)
+
+	if _, err := target.ImportArchive(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Failed to import archive: %v", err)
+	}
+
+	group, err := target.GetGroup("org/team")
+	if err != nil {
+		t.Fatalf("Failed to get restored group: %v", err)
+	}
+	if group.Description != "Team" {
+		t.Errorf("Expected group description 'Team', got '%s'", group.Description)
+	}
+
+	parent, err := target.GetGroup("org")
+	if err != nil || parent.Description != "Organization" {
+		t.Errorf("Expected parent group description 'Organization', got %v (%v)", parent, err)
+	}
+
+	repo, err := target.GetRepository("org/team/app")
+	if err != nil {
+		t.Fatalf("Failed to get restored repository: %v", err)
+	}
+	if repo.Description != "Application" {
+		t.Errorf("Expected description 'Application', got '%s'", repo.Description)
+	}
+
+	restored, _ := target.openRepository("org/team/app")
+	ref, err := restored.Reference(plumbing.NewBranchReferenceName("master"), true)
+	if err != nil || ref.Hash() != head {
+		t.Errorf("Expected master at snapshot commit %s, got %v (%v)", head, ref, err)
+	}
+
+	if !target.IsRepository("empty") {
+		t.Error("Empty repository was not restored")
+	}
+
+	// Importing again must not overwrite existing repositories
+	_, err = target.ImportArchive(bytes.NewReader(buf.Bytes()))
+	var existsErr *AlreadyExistsError
+	if !errors.As(err, &existsErr) {
+		t.Errorf("Expected AlreadyExistsError on second import, got %v", err)
+	}
+}
+
+// Test importing data that is not an archive
+func TestImportArchive_Invalid(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	if _, err := manager.ImportArchive(bytes.NewReader([]byte("not an archive"))); !errors.Is(err, ErrArchiveInvalid) {
+		t.Errorf("Expected ErrArchiveInvalid, got %v", err)
+	}
+}
+
+// Test that a failed import leaves neither groups nor repositories behind
+func TestImportArchive_Atomic(t *testing.T) {
+	source, sourceDir := setupTestManager(t)
+	defer teardownTestManager(sourceDir)
+
+	if _, err := source.CreateGroup("org", "Organization"); err != nil {
+		t.Fatalf("Failed to create group: %v", err)
+	}
+	for _, name := range []string{"org/app", "org/lib"} {
+		if _, err := source.CreateRepository(name, ""); err != nil {
+			t.Fatalf("Failed to create repository: %v", err)
+		}
+		commitTestFile(t, source, name, "master", "README.md", name)
+	}
+
+	var buf bytes.Buffer
+	if _, err := source.ExportArchive(&buf); err != nil {
+		t.Fatalf("Failed to export archive: %v", err)
+	}
+
+	// Drop the last bundle, so the import fails after restoring the first repository
+	gr, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Failed to read archive: %v", err)
+	}
+	tr := tar.NewReader(gr)
+	var truncated bytes.Buffer
+	gw := gzip.NewWriter(&truncated)
+	tw := tar.NewWriter(gw)
+	for i := 0; i < 2; i++ {
+		hdr, err := tr.Next()
+		if err != nil {
+			t.Fatalf("Failed to read archive entry: %v", err)
+		}
+		tw.WriteHeader(hdr)
+		io.Copy(tw, tr)
+	}
+	tw.Close()
+	gw.Close()
+
+	parent := t.TempDir()
+	target, _ := setupTestManager(t)
+	target.reposPath = filepath.Join(parent, "repos")
+	if _, err := target.CreateGroup("existing", ""); err != nil {
+		t.Fatalf("Failed to create group: %v", err)
+	}
+
+	var notFound *NotFoundError
+	if _, err := target.ImportArchive(&truncated); !errors.As(err, &notFound) {
+		t.Fatalf("Expected NotFoundError for the missing bundle, got %v", err)
+	}
+
+	if repos, _ := target.ListRepositories(); len(repos) != 0 {
+		t.Errorf("Expected no repositories after failed import, got %v", repos)
+	}
+	if target.IsGroup("org") {
+		t.Error("Expected no group after failed import")
+	}
+	if entries, _ := os.ReadDir(parent); len(entries) != 1 {
+		t.Errorf("Expected the staging directory to be removed, got %v", entries)
+	}
+
+	// The complete archive is imported into existing groups
+	if _, err := target.ImportArchive(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Failed to import archive: %v", err)
+	}
+	if !target.IsRepository("org/app") || !target.IsRepository("org/lib") || !target.IsGroup("existing") {
+		t.Error("Expected repositories to be imported next to the existing group")
+	}
+}
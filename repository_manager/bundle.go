@@ -0,0 +1,290 @@
+package repository_manager
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/plumbing/revlist"
+	"go.uber.org/zap"
+)
+
+const (
+	bundleV2Signature = "# v2 git bundle"
+	bundleV3Signature = "# v3 git bundle"
+)
+
+// bundleHeader holds the parsed header of a git bundle
+type bundleHeader struct {
+	Prerequisites []plumbing.Hash
+	References    []*plumbing.Reference
+}
+
+// CreateBundle writes a git bundle (v2 format) containing all references of a
+// repository and every object reachable from them to w.
+// The bundle has no prerequisites, so it can be used to fully restore the repository.
+func (m *RepositoryManager) CreateBundle(name string, w io.Writer) error {
+	// Validate repository name to prevent path traversal attacks
+	if !isValidRepoName(name) {
+		return ErrRepositoryInvalidName
+	}
+
+	repo, err := m.openRepository(name)
+	if err != nil {
+		return err
+	}
+
+	refs, err := bundleReferences(repo)
+	if err != nil {
+		return err
+	}
+
+	if err := writeBundle(repo, refs, w); err != nil {
+		return err
+	}
+
+	m.logger.Info("Bundle created", zap.String("name", name), zap.Int("refs", len(refs)))
+	return nil
+}
+
+// writeBundle writes a v2 bundle for the given references and all objects reachable from them
+func writeBundle(repo *git.Repository, refs []*plumbing.Reference, w io.Writer) error {
+	if len(refs) == 0 {
+		return ErrBundleEmpty
+	}
+
+	// Collect all objects reachable from the bundled references
+	wants := make([]plumbing.Hash, 0, len(refs))
+	for _, ref := range refs {
+		wants = append(wants, ref.Hash())
+	}
+
+	hashes, err := revlist.Objects(repo.Storer, wants, nil)
+	if err != nil {
+		return WrapCollectObjectsError(err)
+	}
+
+	// Write bundle header
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s\n", bundleV2Signature)
+	for _, ref := range refs {
+		fmt.Fprintf(bw, "%s %s\n", ref.Hash(), ref.Name())
+	}
+	fmt.Fprint(bw, "\n")
+
+	// Write packfile
+	encoder := packfile.NewEncoder(bw, repo.Storer, false)
+	if _, err := encoder.Encode(hashes, 10); err != nil {
+		return WrapEncodePackfileError(err)
+	}
+
+	if err := bw.Flush(); err != nil {
+		return WrapEncodePackfileError(err)
+	}
+
+	return nil
+}
+
+// CreateRepositoryFromBundle creates a new repository and populates it from a git bundle.
+// All prerequisites of the bundle must be satisfied and every reference of the
+// bundle is recreated in the new repository. The repository is removed again if
+// the bundle cannot be applied.
+func (m *RepositoryManager) CreateRepositoryFromBundle(name, description string, r io.Reader) (*Repository, error) {
+	repository, err := m.createRepository(name, description)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.applyBundle(name, r); err != nil {
+		if rmErr := os.RemoveAll(repository.Path); rmErr != nil {
+			m.logger.Error("Failed to clean up repository after bundle error", zap.String("path", repository.Path), zap.Error(rmErr))
+		}
+		return nil, err
+	}
+
+	m.logger.Info("Repository restored from bundle", zap.String("name", name))
+
+	// Announce the repository only once it holds the bundle
+	m.publishRepositoryCreated(name)
+	return repository, nil
+}
+
+// applyBundle writes the objects and references of a bundle into an existing repository
+func (m *RepositoryManager) applyBundle(name string, r io.Reader) error {
+	repo, err := m.openRepository(name)
+	if err != nil {
+		return err
+	}
+
+	br := bufio.NewReader(r)
+
+	header, err := readBundleHeader(br)
+	if err != nil {
+		return err
+	}
+
+	// Verify prerequisites exist in the target repository
+	for _, hash := range header.Prerequisites {
+		if _, err := repo.Storer.EncodedObject(plumbing.AnyObject, hash); err != nil {
+			return NewBundlePrerequisiteMissingError(hash.String())
+		}
+	}
+
+	// Write packfile objects
+	if err := packfile.UpdateObjectStorage(repo.Storer, br); err != nil && err != packfile.ErrEmptyPackfile {
+		return NewBundleCorruptError(WrapWritePackfileError(err))
+	}
+
+	// Recreate references
+	var head *plumbing.Reference
+	for _, ref := range header.References {
+		if ref.Name() == plumbing.HEAD {
+			head = ref
+			continue
+		}
+
+		if _, err := repo.Storer.EncodedObject(plumbing.AnyObject, ref.Hash()); err != nil {
+			return NewBundleCorruptError(fmt.Errorf("object %s of %s is missing", ref.Hash(), ref.Name()))
+		}
+
+		if err := repo.Storer.SetReference(ref); err != nil {
+			return WrapSetReferenceError(err)
+		}
+	}
+
+	// Point HEAD to the branch the bundle HEAD refers to
+	if target := bundleHeadTarget(header.References, head); target != "" {
+		if err := repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, target)); err != nil {
+			return WrapSetReferenceError(err)
+		}
+	}
+
+	return nil
+}
+
+// readBundleHeader parses the header of a v2 or v3 git bundle.
+// The reader is left positioned at the beginning of the packfile.
+func readBundleHeader(br *bufio.Reader) (*bundleHeader, error) {
+	signature, err := br.ReadString('\n')
+	if err != nil {
+		return nil, ErrBundleInvalid
+	}
+
+	signature = strings.TrimSuffix(signature, "\n")
+	if signature != bundleV2Signature && signature != bundleV3Signature {
+		return nil, ErrBundleInvalid
+	}
+
+	header := &bundleHeader{}
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, ErrBundleInvalid
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			break
+		}
+
+		switch {
+		case strings.HasPrefix(line, "@"):
+			// v3 capabilities, only sha1 object format is supported
+			if strings.HasPrefix(line, "@object-format=") && line != "@object-format=sha1" {
+				return nil, ErrBundleInvalid
+			}
+		case strings.HasPrefix(line, "-"):
+			fields := strings.SplitN(strings.TrimPrefix(line, "-"), " ", 2)
+			if !plumbing.IsHash(fields[0]) {
+				return nil, ErrBundleInvalid
+			}
+			header.Prerequisites = append(header.Prerequisites, plumbing.NewHash(fields[0]))
+		default:
+			fields := strings.SplitN(line, " ", 2)
+			if len(fields) != 2 || !plumbing.IsHash(fields[0]) || !isValidBundleReference(plumbing.ReferenceName(fields[1])) {
+				return nil, ErrBundleInvalid
+			}
+			header.References = append(header.References,
+				plumbing.NewHashReference(plumbing.ReferenceName(fields[1]), plumbing.NewHash(fields[0])))
+		}
+	}
+
+	return header, nil
+}
+
+// isValidBundleReference checks that a reference of a bundle is HEAD or a well-formed name under refs/,
+// so that restoring the bundle cannot write files elsewhere in the repository
+func isValidBundleReference(name plumbing.ReferenceName) bool {
+	if name == plumbing.HEAD {
+		return true
+	}
+	return strings.HasPrefix(name.String(), "refs/") && name.Validate() == nil
+}
+
+// bundleReferences returns all references of a repository resolved to hashes,
+// including HEAD when it points to an existing commit
+func bundleReferences(repo *git.Repository) ([]*plumbing.Reference, error) {
+	iter, err := repo.References()
+	if err != nil {
+		return nil, WrapListReferencesError(err)
+	}
+
+	refs := make([]*plumbing.Reference, 0)
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Name() == plumbing.HEAD {
+			return nil
+		}
+
+		if ref.Type() == plumbing.SymbolicReference {
+			resolved, err := repo.Reference(ref.Name(), true)
+			if err != nil {
+				return nil
+			}
+			ref = plumbing.NewHashReference(ref.Name(), resolved.Hash())
+		}
+
+		refs = append(refs, ref)
+		return nil
+	})
+	if err != nil {
+		return nil, WrapListReferencesError(err)
+	}
+
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].Name() < refs[j].Name()
+	})
+
+	if head, err := repo.Head(); err == nil {
+		refs = append([]*plumbing.Reference{plumbing.NewHashReference(plumbing.HEAD, head.Hash())}, refs...)
+	}
+
+	return refs, nil
+}
+
+// bundleHeadTarget picks the branch HEAD should point to after applying a bundle.
+// It prefers the branch matching the bundle's HEAD and falls back to the first branch.
+func bundleHeadTarget(refs []*plumbing.Reference, head *plumbing.Reference) plumbing.ReferenceName {
+	var fallback plumbing.ReferenceName
+
+	for _, ref := range refs {
+		if !ref.Name().IsBranch() {
+			continue
+		}
+
+		if head != nil && ref.Hash() == head.Hash() {
+			return ref.Name()
+		}
+
+		if fallback == "" {
+			fallback = ref.Name()
+		}
+	}
+
+	return fallback
+}
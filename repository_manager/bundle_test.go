@@ -0,0 +1,197 @@
+package repository_manager
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/weedbox/git-modules/events"
+	"go.uber.org/zap"
+)
+
+// Helper function to commit a single file on a branch of a bare repository
+func commitTestFile(t *testing.T, manager *RepositoryManager, repoName, branch, path, content string) plumbing.Hash {
+	repo, err := manager.openRepository(repoName)
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+
+	blob := repo.Storer.NewEncodedObject()
+	blob.SetType(plumbing.BlobObject)
+	w, _ := blob.Writer()
+	w.Write([]byte(content))
+	w.Close()
+	blobHash, err := repo.Storer.SetEncodedObject(blob)
+	if err != nil {
+		t.Fatalf("Failed to store blob: %v", err)
+	}
+
+	tree := &object.Tree{Entries: []object.TreeEntry{{Name: path, Mode: filemode.Regular, Hash: blobHash}}}
+	treeObj := repo.Storer.NewEncodedObject()
+	if err := tree.Encode(treeObj); err != nil {
+		t.Fatalf("Failed to encode tree: %v", err)
+	}
+	treeHash, err := repo.Storer.SetEncodedObject(treeObj)
+	if err != nil {
+		t.Fatalf("Failed to store tree: %v", err)
+	}
+
+	branchRef := plumbing.NewBranchReferenceName(branch)
+	sig := object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()}
+	commit := &object.Commit{Author: sig, Committer: sig, Message: "update " + path, TreeHash: treeHash}
+	if ref, err := repo.Reference(branchRef, true); err == nil {
+		commit.ParentHashes = []plumbing.Hash{ref.Hash()}
+	}
+
+	commitObj := repo.Storer.NewEncodedObject()
+	if err := commit.Encode(commitObj); err != nil {
+		t.Fatalf("Failed to encode commit: %v", err)
+	}
+	commitHash, err := repo.Storer.SetEncodedObject(commitObj)
+	if err != nil {
+		t.Fatalf("Failed to store commit: %v", err)
+	}
+
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(branchRef, commitHash)); err != nil {
+		t.Fatalf("Failed to set branch: %v", err)
+	}
+
+	return commitHash
+}
+
+// Test bundling a repository and restoring it under a new name
+func TestBundle_RoundTrip(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	if _, err := manager.CreateRepository("org/source", "Source"); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	commitTestFile(t, manager, "org/source", "main", "README.md", "hello")
+	head := commitTestFile(t, manager, "org/source", "main", "README.md", "hello world")
+	develop := commitTestFile(t, manager, "org/source", "develop", "dev.txt", "dev")
+
+	repo, _ := manager.openRepository("org/source")
+	repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName("main")))
+
+	if _, err := manager.CreateTag("org/source", "v1.0.0", head.String(), "Release", "Tester"); err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := manager.CreateBundle("org/source", &buf); err != nil {
+		t.Fatalf("Failed to create bundle: %v", err)
+	}
+
+	if !strings.HasPrefix(buf.String(), bundleV2Signature+"\n") {
+		t.Fatalf("Bundle does not start with v2 signature")
+	}
+
+	restored, err := manager.CreateRepositoryFromBundle("backup/restored", "Restored", bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Failed to create repository from bundle: %v", err)
+	}
+
+	if restored.Description != "Restored" {
+		t.Errorf("Expected description 'Restored', got '%s'", restored.Description)
+	}
+
+	target, _ := manager.openRepository("backup/restored")
+
+	ref, err := target.Reference(plumbing.NewBranchReferenceName("develop"), true)
+	if err != nil || ref.Hash() != develop {
+		t.Errorf("Expected develop at %s, got %v (%v)", develop, ref, err)
+	}
+
+	headRef, err := target.Head()
+	if err != nil || headRef.Name() != plumbing.NewBranchReferenceName("main") || headRef.Hash() != head {
+		t.Errorf("Expected HEAD to point to main at %s, got %v (%v)", head, headRef, err)
+	}
+
+	tag, err := manager.GetTag("backup/restored", "v1.0.0")
+	if err != nil {
+		t.Fatalf("Failed to get restored tag: %v", err)
+	}
+	if tag.Type != "annotated" || tag.CommitHash != head.String() {
+		t.Errorf("Unexpected restored tag: %+v", tag)
+	}
+
+	// Bundles must be readable by the git binary as well
+	if gitPath, err := exec.LookPath("git"); err == nil {
+		bundlePath := filepath.Join(tmpDir, "source.bundle")
+		os.WriteFile(bundlePath, buf.Bytes(), 0644)
+		cmd := exec.Command(gitPath, "bundle", "list-heads", bundlePath)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("git bundle list-heads failed: %v: %s", err, out)
+		}
+	}
+}
+
+// Test bundling an empty repository
+func TestCreateBundle_Empty(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	if _, err := manager.CreateRepository("empty", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	if err := manager.CreateBundle("empty", &bytes.Buffer{}); !errors.Is(err, ErrBundleEmpty) {
+		t.Errorf("Expected ErrBundleEmpty, got %v", err)
+	}
+}
+
+// Test restoring from invalid bundles
+func TestCreateRepositoryFromBundle_Invalid(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	bus := events.NewBus(zap.NewNop())
+	manager.params.Bus = bus
+
+	var received []events.Event
+	bus.Subscribe(func(event events.Event) {
+		received = append(received, event)
+	})
+
+	testCases := []struct {
+		name   string
+		bundle string
+	}{
+		{"not a bundle", "hello world\n"},
+		{"truncated header", bundleV2Signature + "\n"},
+		{"missing prerequisite", bundleV2Signature + "\n-0123456789012345678901234567890123456789 base\n0123456789012345678901234567890123456789 refs/heads/main\n\n"},
+		{"reference outside refs", bundleV2Signature + "\n0123456789012345678901234567890123456789 config\n\n"},
+		{"reference with dot segments", bundleV2Signature + "\n0123456789012345678901234567890123456789 refs/../config\n\n"},
+		{"malformed reference", bundleV2Signature + "\n0123456789012345678901234567890123456789 refs/heads/a..b\n\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := manager.CreateRepositoryFromBundle("restored", "", strings.NewReader(tc.bundle))
+			if err == nil {
+				t.Fatal("Expected error for invalid bundle, got nil")
+			}
+
+			// Failed restores must not leave a repository behind
+			if manager.IsRepository("restored") {
+				t.Error("Repository still exists after failed restore")
+			}
+		})
+	}
+
+	// Repositories are only announced once the bundle is applied
+	bus.Close()
+	if len(received) != 0 {
+		t.Errorf("Expected no events for failed restores, got %+v", received)
+	}
+}
@@ -11,6 +11,7 @@ type Repository struct {
 	Description string    `json:"description" example:"My awesome repository"`
 	Path        string    `json:"path" example:"/path/to/repos/myorg/myrepo.git"`
 	CreatedAt   time.Time `json:"created_at" example:"2025-01-01T00:00:00Z"`
+	Usage       *Usage    `json:"usage,omitempty"`
 } // @name Repository
 
 // Tag represents a Git tag
@@ -31,4 +32,261 @@ type Group struct {
 	Description string    `json:"description" example:"My Organization"`
 	Path        string    `json:"path" example:"/path/to/repos/myorg"`
 	CreatedAt   time.Time `json:"created_at" example:"2025-01-01T00:00:00Z"`
+	Usage       *Usage    `json:"usage,omitempty"`
 } // @name Group
+
+// BackupManifest describes the content of a server backup archive
+// @Description Manifest of a whole-server backup archive
+type BackupManifest struct {
+	Version      int                `json:"version" example:"1"`
+	CreatedAt    time.Time          `json:"created_at" example:"2025-01-01T00:00:00Z"`
+	Groups       []BackupGroup      `json:"groups"`
+	Repositories []BackupRepository `json:"repositories"`
+} // @name BackupManifest
+
+// BackupGroup describes a group stored in a backup archive
+// @Description Group entry of a backup manifest
+type BackupGroup struct {
+	Name        string    `json:"name" example:"myorg"`
+	Description string    `json:"description" example:"My Organization"`
+	Settings    *Settings `json:"settings,omitempty"`
+} // @name BackupGroup
+
+// BackupRepository describes a repository stored in a backup archive
+// @Description Repository entry of a backup manifest with the snapshotted references
+type BackupRepository struct {
+	Name        string            `json:"name" example:"myorg/myrepo"`
+	Description string            `json:"description" example:"My awesome repository"`
+	Bundle      string            `json:"bundle,omitempty" example:"repositories/myorg/myrepo.bundle"`
+	References  map[string]string `json:"references,omitempty"`
+	Settings    *Settings         `json:"settings,omitempty"`
+} // @name BackupRepository
+
+// RepositoryInitOptions controls the initial content of a new repository
+// @Description Options for creating a repository with an initial commit
+type RepositoryInitOptions struct {
+	Readme      bool   `json:"readme" example:"true"`
+	Gitignore   string `json:"gitignore,omitempty" example:"Go"`
+	License     string `json:"license,omitempty" example:"MIT"`
+	Template    string `json:"template,omitempty" example:"templates/go-service"`
+	TemplateRef string `json:"template_ref,omitempty" example:"main"`
+	Branch      string `json:"branch,omitempty" example:"main"`
+	AuthorName  string `json:"author_name,omitempty" example:"John Doe"`
+	AuthorEmail string `json:"author_email,omitempty" example:"john@example.com"`
+	Message     string `json:"message,omitempty" example:"Initial commit"`
+} // @name RepositoryInitOptions
+
+// FileContent represents a file stored in a repository
+// @Description File content at a specific revision
+type FileContent struct {
+	Path    string `json:"path" example:"config/app.yaml"`
+	SHA     string `json:"sha" example:"abc123def456789"`
+	Size    int64  `json:"size" example:"42"`
+	Content []byte `json:"content" swaggertype:"string" format:"base64"`
+} // @name FileContent
+
+// FileCommitOptions describes the commit created when changing files
+// @Description Commit options for file changes
+type FileCommitOptions struct {
+	Branch      string `json:"branch,omitempty" example:"main"`
+	Message     string `json:"message,omitempty" example:"Update config"`
+	AuthorName  string `json:"author_name,omitempty" example:"John Doe"`
+	AuthorEmail string `json:"author_email,omitempty" example:"john@example.com"`
+	SHA         string `json:"sha,omitempty" example:"abc123def456789"`
+
+	// Pusher is the authenticated user making the change, checked against branch protection rules.
+	// It is set by the caller and never read from requests.
+	Pusher string `json:"-" swaggerignore:"true"`
+} // @name FileCommitOptions
+
+// FileCommit represents the result of committing a file change
+// @Description Result of a file change commit
+type FileCommit struct {
+	Path       string `json:"path" example:"config/app.yaml"`
+	SHA        string `json:"sha,omitempty" example:"abc123def456789"`
+	Branch     string `json:"branch" example:"main"`
+	CommitHash string `json:"commit_hash" example:"def456abc123789"`
+} // @name FileCommit
+
+// FileAction describes a single file change of a multi-file commit
+// @Description File change of a multi-file commit
+type FileAction struct {
+	Action       string `json:"action" binding:"required" example:"update" enums:"create,update,delete,move"`
+	Path         string `json:"path" binding:"required" example:"config/app.yaml"`
+	PreviousPath string `json:"previous_path,omitempty" example:"config/old.yaml"`
+	Content      []byte `json:"content,omitempty" swaggertype:"string" format:"base64" example:"aGVsbG8gd29ybGQK"`
+	SHA          string `json:"sha,omitempty" example:"abc123def456789"`
+} // @name FileAction
+
+// CommitRequest describes a commit with multiple file changes
+// @Description Multi-file commit request. The commit is only created if the branch tip equals parent_sha
+type CommitRequest struct {
+	Branch      string       `json:"branch,omitempty" example:"main"`
+	ParentSHA   string       `json:"parent_sha,omitempty" example:"def456abc123789"`
+	Message     string       `json:"message" binding:"required" example:"Update configuration"`
+	AuthorName  string       `json:"author_name,omitempty" example:"John Doe"`
+	AuthorEmail string       `json:"author_email,omitempty" example:"john@example.com"`
+	Actions     []FileAction `json:"actions" binding:"required,min=1"`
+
+	// Pusher is the authenticated user making the change, checked against branch protection rules.
+	// It is set by the caller and never read from requests.
+	Pusher string `json:"-" swaggerignore:"true"`
+} // @name CommitRequest
+
+// CommitResult represents a commit created through the API
+// @Description Result of a multi-file commit
+type CommitResult struct {
+	Branch     string `json:"branch" example:"main"`
+	CommitHash string `json:"commit_hash" example:"abc123def456789"`
+	ParentHash string `json:"parent_hash,omitempty" example:"def456abc123789"`
+} // @name CommitResult
+
+// MergeRequest describes a server-side merge of two branches
+// @Description Request to merge head into the base branch
+type MergeRequest struct {
+	Base        string `json:"base" binding:"required" example:"main"`
+	Head        string `json:"head" binding:"required" example:"develop"`
+	Strategy    string `json:"strategy" example:"merge" enums:"fast-forward,merge,squash"`
+	Message     string `json:"message,omitempty" example:"Promote develop to main"`
+	AuthorName  string `json:"author_name,omitempty" example:"Release Bot"`
+	AuthorEmail string `json:"author_email,omitempty" example:"bot@example.com"`
+
+	// Pusher is the authenticated user making the change, checked against branch protection rules.
+	// It is set by the caller and never read from requests.
+	Pusher string `json:"-" swaggerignore:"true"`
+} // @name MergeRequest
+
+// MergeResult represents the outcome of a server-side merge
+// @Description Result of a merge
+type MergeResult struct {
+	Base        string `json:"base" example:"main"`
+	Head        string `json:"head" example:"develop"`
+	Strategy    string `json:"strategy" example:"merge"`
+	CommitHash  string `json:"commit_hash" example:"abc123def456789"`
+	FastForward bool   `json:"fast_forward" example:"false"`
+	UpToDate    bool   `json:"up_to_date" example:"false"`
+} // @name MergeResult
+
+// Settings holds the policies configured on a repository or group
+// @Description Repository or group settings
+type Settings struct {
+	BranchProtection []BranchProtectionRule `json:"branch_protection,omitempty"`
+	TagProtection    []TagProtectionRule    `json:"tag_protection,omitempty"`
+	Webhooks         []Webhook              `json:"webhooks,omitempty"`
+	Permissions      []Permission           `json:"permissions,omitempty"`
+	Filters          *FilterPolicy          `json:"filters,omitempty"`
+	Quota            *Quota                 `json:"quota,omitempty"`
+	ContentPolicy    *ContentPolicy         `json:"content_policy,omitempty"`
+} // @name Settings
+
+// BranchProtectionRule restricts updates of the branches matching a pattern.
+// Commit rules apply to the commits a push adds to the branches.
+// @Description Branch protection rule applied to pushes
+type BranchProtectionRule struct {
+	Pattern              string   `json:"pattern" binding:"required" example:"release/*"`
+	DenyForcePush        bool     `json:"deny_force_push" example:"true"`
+	DenyDeletion         bool     `json:"deny_deletion" example:"true"`
+	RequireLinearHistory bool     `json:"require_linear_history" example:"false"`
+	CommitMessagePattern string   `json:"commit_message_pattern,omitempty" example:"^(feat|fix|docs|refactor|test|chore)(\\(.+\\))?!?: "`
+	RequireSignoff       bool     `json:"require_signoff" example:"false"`
+	AllowedEmailDomains  []string `json:"allowed_email_domains,omitempty" example:"example.com"`
+	RequireSignedCommits bool     `json:"require_signed_commits" example:"false"`
+	AllowedPushers       []string `json:"allowed_pushers,omitempty" example:"release-bot"`
+	Source               string   `json:"source,omitempty" example:"myorg"`
+} // @name BranchProtectionRule
+
+// FilterPolicy restricts the object filters clients may request for partial clones of a repository.
+// A filter kind is allowed unless denied and, when Allow is not empty, only if listed in Allow.
+// Filters are only served by the git backend of git_http, the go-git backend sends complete packfiles.
+// @Description Partial clone filter policy of a repository, enforced by the git backend only
+type FilterPolicy struct {
+	Allow []string `json:"allow,omitempty" example:"blob:none,blob:limit"`
+	Deny  []string `json:"deny,omitempty" example:"sparse:oid"`
+} // @name FilterPolicy
+
+// Quota limits the disk space used by a repository, or by every repository of a group and its subgroups
+// @Description Storage quota in bytes, 0 for unlimited
+type Quota struct {
+	MaxSize int64 `json:"max_size" example:"1073741824"`
+} // @name Quota
+
+// Usage reports the disk space used by a repository or group together with its quota
+// @Description Storage usage in bytes and the quota, 0 if unlimited
+type Usage struct {
+	Size    int64 `json:"size" example:"52428800"`
+	MaxSize int64 `json:"max_size,omitempty" example:"1073741824"`
+} // @name Usage
+
+// ContentPolicy restricts the files pushed to a repository, or to every repository of a group.
+// The policies of a repository and of its groups all apply, to pushes only.
+// @Description Push content policy: file size limit, forbidden paths and secret detection
+type ContentPolicy struct {
+	MaxFileSize    int64        `json:"max_file_size,omitempty" example:"10485760"`
+	ForbiddenPaths []string     `json:"forbidden_paths,omitempty" example:"*.pem,node_modules/"`
+	SecretScanning bool         `json:"secret_scanning" example:"true"`
+	SecretRules    []SecretRule `json:"secret_rules,omitempty"`
+} // @name ContentPolicy
+
+// SecretRule detects a kind of secret in added lines, in addition to the built-in rules
+// @Description Secret detection rule: a named regular expression
+type SecretRule struct {
+	Name    string `json:"name" binding:"required" example:"Internal API key"`
+	Pattern string `json:"pattern" binding:"required" example:"ikey_[0-9a-f]{32}"`
+} // @name SecretRule
+
+// TagProtectionRule makes the tags matching a pattern immutable
+// @Description Tag protection rule: matching tags cannot be moved or deleted
+type TagProtectionRule struct {
+	Pattern string `json:"pattern" binding:"required" example:"v*"`
+	Source  string `json:"source,omitempty" example:"myorg"`
+} // @name TagProtectionRule
+
+// Permission grants a role on a repository, or on every repository and subgroup of a group,
+// to a user or to the members of a team. The user "*" stands for everyone, including anonymous users.
+// @Description Role granted to a user or team
+type Permission struct {
+	User   string `json:"user,omitempty" example:"john"`
+	Team   string `json:"team,omitempty" example:"developers"`
+	Role   Role   `json:"role" binding:"required" example:"write" enums:"read,write,maintain,admin"`
+	Source string `json:"source,omitempty" example:"myorg"`
+} // @name Permission
+
+// Webhook is an HTTP endpoint notified of the events of a repository, or of every repository in a group.
+// The secret is write-only: it is never returned by the API, HasSecret reports whether one is set.
+// @Description Webhook registration
+type Webhook struct {
+	ID        string    `json:"id" example:"9f86d081884c7d65"`
+	URL       string    `json:"url" binding:"required" example:"https://ci.example.com/hooks/git"`
+	Secret    string    `json:"secret,omitempty" example:"s3cr3t"`
+	HasSecret bool      `json:"has_secret,omitempty" example:"true"`
+	Events    []string  `json:"events,omitempty" example:"push,tag_create"`
+	Source    string    `json:"source,omitempty" example:"myorg"`
+	CreatedAt time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+} // @name Webhook
+
+// WebhookPayload is the JSON body posted to webhooks
+// @Description Webhook event payload
+type WebhookPayload struct {
+	Event      string    `json:"event" example:"push"`
+	Repository string    `json:"repository" example:"myorg/myrepo"`
+	Ref        string    `json:"ref,omitempty" example:"refs/heads/main"`
+	Before     string    `json:"before,omitempty" example:"0000000000000000000000000000000000000000"`
+	After      string    `json:"after,omitempty" example:"abc123def456"`
+	Pusher     string    `json:"pusher,omitempty" example:"john"`
+	Timestamp  time.Time `json:"timestamp" example:"2024-01-01T00:00:00Z"`
+} // @name WebhookPayload
+
+// WebhookDelivery records the outcome of delivering an event to a webhook
+// @Description Webhook delivery record
+type WebhookDelivery struct {
+	ID          string    `json:"id" example:"1b4f0e9851971998"`
+	WebhookID   string    `json:"webhook_id" example:"9f86d081884c7d65"`
+	Event       string    `json:"event" example:"push"`
+	Repository  string    `json:"repository" example:"myorg/myrepo"`
+	URL         string    `json:"url" example:"https://ci.example.com/hooks/git"`
+	Attempts    int       `json:"attempts" example:"1"`
+	StatusCode  int       `json:"status_code,omitempty" example:"200"`
+	Success     bool      `json:"success" example:"true"`
+	Error       string    `json:"error,omitempty" example:""`
+	DeliveredAt time.Time `json:"delivered_at" example:"2024-01-01T00:00:00Z"`
+} // @name WebhookDelivery
@@ -0,0 +1,207 @@
+package repository_manager
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"go.uber.org/zap"
+)
+
+// Armor headers of the commit signature formats made by git
+const (
+	signatureFormatGPG = "-----BEGIN PGP SIGNATURE-----"
+	signatureFormatSSH = "-----BEGIN SSH SIGNATURE-----"
+)
+
+// SignatureVerifier verifies commit signatures of one format against the keys registered by users,
+// see the gpg_keys and ssh_keys packages
+type SignatureVerifier interface {
+	// VerifyCommitSignature returns the user who registered the key a commit payload was signed with,
+	// and the emails of the key if it has any
+	VerifyCommitSignature(signature string, payload []byte) (string, []string, error)
+}
+
+// checkCommitRules returns the violations of the commit rules of the matching branch protection
+// rules by the commits a push adds to a branch, one per commit and broken rule
+func (m *RepositoryManager) checkCommitRules(rules []BranchProtectionRule, update ReferenceUpdate, objects *PushObjectStorage) ([]string, error) {
+	rules = slices.DeleteFunc(slices.Clone(rules), func(rule BranchProtectionRule) bool {
+		return rule.CommitMessagePattern == "" && !rule.RequireSignoff && len(rule.AllowedEmailDomains) == 0 && !rule.RequireSignedCommits
+	})
+	if len(rules) == 0 || update.IsDelete() {
+		return nil, nil
+	}
+
+	commits, err := objects.ReceivedCommits(update.NewHash)
+	if err != nil {
+		return nil, err
+	}
+
+	violations := make([]string, 0)
+	for _, commit := range commits {
+		found := make([]string, 0)
+		for _, rule := range rules {
+			reasons, err := m.checkCommit(rule, commit)
+			if err != nil {
+				return nil, err
+			}
+			for _, reason := range reasons {
+				if !slices.Contains(found, reason) {
+					found = append(found, reason)
+				}
+			}
+		}
+
+		for _, reason := range found {
+			violations = append(violations, fmt.Sprintf("commit %s: %s", commit.Hash.String()[:7], reason))
+		}
+	}
+
+	return violations, nil
+}
+
+// checkCommit returns the reasons why a commit breaks the commit rules of a branch protection rule
+func (m *RepositoryManager) checkCommit(rule BranchProtectionRule, commit *object.Commit) ([]string, error) {
+	reasons := make([]string, 0)
+
+	if rule.CommitMessagePattern != "" {
+		pattern, err := regexp.Compile(rule.CommitMessagePattern)
+		if err != nil {
+			// Rules are validated when saved, skip patterns edited by hand
+			m.logger.Warn("Invalid commit message pattern", zap.String("pattern", rule.CommitMessagePattern), zap.Error(err))
+		} else if !pattern.MatchString(commit.Message) {
+			reasons = append(reasons, "commit message does not match "+rule.CommitMessagePattern)
+		}
+	}
+
+	if rule.RequireSignoff && !hasSignoff(commit.Message, commit.Author.Email) {
+		reasons = append(reasons, "missing Signed-off-by of the author "+commit.Author.Email)
+	}
+
+	if len(rule.AllowedEmailDomains) > 0 {
+		emails := []string{commit.Author.Email}
+		if !strings.EqualFold(commit.Committer.Email, commit.Author.Email) {
+			emails = append(emails, commit.Committer.Email)
+		}
+		for _, email := range emails {
+			if !isAllowedEmailDomain(email, rule.AllowedEmailDomains) {
+				reasons = append(reasons, "email "+email+" is not in an allowed domain")
+			}
+		}
+	}
+
+	if rule.RequireSignedCommits {
+		if reason, err := m.verifyCommitSignature(commit); err != nil {
+			return nil, err
+		} else if reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+
+	return reasons, nil
+}
+
+// verifyCommitSignature returns why the signature of a commit is not accepted, or an empty string
+// if it was made with a key registered by a user who is the author or committer of the commit
+func (m *RepositoryManager) verifyCommitSignature(commit *object.Commit) (string, error) {
+	if commit.PGPSignature == "" {
+		return "commit is not signed", nil
+	}
+
+	var verifier SignatureVerifier
+	for format, v := range m.signatureVerifiers {
+		if strings.HasPrefix(commit.PGPSignature, format) {
+			verifier = v
+		}
+	}
+	if verifier == nil {
+		return "signature format is not supported", nil
+	}
+
+	encoded := &plumbing.MemoryObject{}
+	if err := commit.EncodeWithoutSignature(encoded); err != nil {
+		return "", WrapReadObjectError(err)
+	}
+	r, err := encoded.Reader()
+	if err != nil {
+		return "", WrapReadObjectError(err)
+	}
+	defer r.Close()
+
+	payload, err := io.ReadAll(r)
+	if err != nil {
+		return "", WrapReadObjectError(err)
+	}
+
+	user, emails, err := verifier.VerifyCommitSignature(commit.PGPSignature, payload)
+	if err != nil {
+		return "signature is not verified: " + err.Error(), nil
+	}
+
+	if !isCommitSigner(commit, user, emails) {
+		return "signing key of " + user + " does not belong to the author or committer", nil
+	}
+
+	return "", nil
+}
+
+// isCommitSigner reports whether the owner of a signing key, or one of the emails of the key,
+// is the author or committer of a commit
+func isCommitSigner(commit *object.Commit, user string, emails []string) bool {
+	for _, sig := range []object.Signature{commit.Author, commit.Committer} {
+		if strings.EqualFold(sig.Name, user) {
+			return true
+		}
+		for _, email := range emails {
+			if strings.EqualFold(sig.Email, email) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasSignoff reports whether a commit message has a Signed-off-by trailer with an email
+func hasSignoff(message, email string) bool {
+	for _, line := range strings.Split(message, "\n") {
+		signoff, ok := strings.CutPrefix(strings.TrimSpace(line), "Signed-off-by:")
+		if ok && strings.Contains(strings.ToLower(signoff), "<"+strings.ToLower(email)+">") {
+			return true
+		}
+	}
+	return false
+}
+
+// isAllowedEmailDomain reports whether the domain of an email is one of the allowed domains
+func isAllowedEmailDomain(email string, domains []string) bool {
+	_, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return false
+	}
+
+	for _, allowed := range domains {
+		if strings.EqualFold(domain, strings.TrimPrefix(allowed, "@")) {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidCommitRules checks the commit message pattern and the email domains of a rule
+func isValidCommitRules(rule BranchProtectionRule) bool {
+	if _, err := regexp.Compile(rule.CommitMessagePattern); err != nil {
+		return false
+	}
+
+	for _, domain := range rule.AllowedEmailDomains {
+		if strings.TrimPrefix(domain, "@") == "" {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,148 @@
+package repository_manager
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// testSignatureVerifier accepts signatures containing "valid" over a commit payload as made with
+// a key of alice, and signatures containing "bob" as made with a key of bob with the email bob@example.com
+type testSignatureVerifier struct{}
+
+func (testSignatureVerifier) VerifyCommitSignature(signature string, payload []byte) (string, []string, error) {
+	switch {
+	case !bytes.HasPrefix(payload, []byte("tree ")):
+		return "", nil, errors.New("bad signature")
+	case strings.Contains(signature, "\nvalid\n"):
+		return "alice", nil, nil
+	case strings.Contains(signature, "\nbob\n"):
+		return "bob", []string{"bob@example.com"}, nil
+	}
+	return "", nil, errors.New("bad signature")
+}
+
+// Test enforcement of commit message, sign-off, email domain and signature rules
+func TestCheckCommitRules(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+	manager.signatureVerifiers = map[string]SignatureVerifier{signatureFormatSSH: testSignatureVerifier{}}
+
+	if _, err := manager.CreateRepository("app", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	if _, err := manager.CreateRepository("source", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	base := commitTestFile(t, manager, "app", "master", "a.txt", "a")
+	sourceRepo, _ := manager.openRepository("source")
+	if err := packfile.UpdateObjectStorage(sourceRepo.Storer, encodeTestPack(t, manager, "app", base, nil)); err != nil {
+		t.Fatalf("Failed to copy objects: %v", err)
+	}
+	baseCommit, _ := sourceRepo.CommitObject(base)
+
+	if _, err := manager.CreateBranchProtectionRule("app", BranchProtectionRule{Pattern: "main", CommitMessagePattern: "("}); !errors.Is(err, ErrCommitRuleInvalid) {
+		t.Errorf("Expected ErrCommitRuleInvalid, got %v", err)
+	}
+	if _, err := manager.CreateBranchProtectionRule("app", BranchProtectionRule{
+		Pattern:              "master",
+		CommitMessagePattern: "^(feat|fix): ",
+		RequireSignoff:       true,
+		AllowedEmailDomains:  []string{"Example.com"},
+		RequireSignedCommits: true,
+	}); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	push := func(message, email, signature string) error {
+		signature = strings.ReplaceAll(signature, "HEADER", signatureFormatSSH)
+		sig := object.Signature{Name: "Alice", Email: email, When: time.Unix(1700000000, 0)}
+		hash, err := storeCommit(sourceRepo.Storer, &object.Commit{
+			Author:       sig,
+			Committer:    sig,
+			Message:      message,
+			PGPSignature: signature,
+			TreeHash:     baseCommit.TreeHash,
+			ParentHashes: []plumbing.Hash{base},
+		})
+		if err != nil {
+			t.Fatalf("Failed to store commit: %v", err)
+		}
+
+		objects, err := manager.NewPushObjectStorage("app", encodeTestPack(t, manager, "source", hash, []plumbing.Hash{base}))
+		if err != nil {
+			t.Fatalf("Failed to read pushed objects: %v", err)
+		}
+		defer objects.Close()
+		return manager.CheckBranchProtection("app", "alice", []ReferenceUpdate{{Name: plumbing.Master, OldHash: base, NewHash: hash}}, objects)
+	}
+
+	valid := "HEADER\nvalid\n-----END SSH SIGNATURE-----\n"
+	if err := push("feat: add a\n\nSigned-off-by: Alice <alice@example.com>\n", "alice@example.com", valid); err != nil {
+		t.Errorf("Expected compliant commit to be accepted, got %v", err)
+	}
+
+	var rejected *PushRejectedError
+	if err := push("wip\n", "bob@other.org", ""); !errors.As(err, &rejected) {
+		t.Fatalf("Expected commit to be rejected, got %v", err)
+	}
+	if len(rejected.Rejections) != 4 {
+		t.Fatalf("Expected one rejection per broken rule, got %+v", rejected.Rejections)
+	}
+	for i, want := range []string{"does not match", "missing Signed-off-by", "bob@other.org is not in an allowed domain", "not signed"} {
+		if !strings.Contains(rejected.Rejections[i].Reason, want) || !strings.HasPrefix(rejected.Rejections[i].Reason, "protected branch: commit ") {
+			t.Errorf("Expected rejection %d to mention %q, got %q", i, want, rejected.Rejections[i].Reason)
+		}
+	}
+
+	signedOff := "fix: b\n\nSigned-off-by: Alice <alice@example.com>\n"
+	tests := []struct {
+		signature string
+		want      string
+	}{
+		{"HEADER\nforged\n-----END SSH SIGNATURE-----\n", "signature is not verified"},
+		{"-----BEGIN PGP SIGNATURE-----\nvalid\n-----END PGP SIGNATURE-----\n", "signature format is not supported"},
+		{"HEADER\nbob\n-----END SSH SIGNATURE-----\n", "signing key of bob does not belong to the author or committer"},
+	}
+	for _, tt := range tests {
+		if err := push(signedOff, "alice@example.com", tt.signature); !errors.As(err, &rejected) {
+			t.Errorf("Expected %q to be rejected, got %v", tt.want, err)
+		} else if len(rejected.Rejections) != 1 || !strings.Contains(rejected.Rejections[0].Reason, tt.want) {
+			t.Errorf("Expected %q, got %+v", tt.want, rejected.Rejections)
+		}
+	}
+
+	// Keys also sign the commits of their emails
+	if err := push("fix: c\n\nSigned-off-by: Bob <bob@example.com>\n", "bob@example.com", "HEADER\nbob\n-----END SSH SIGNATURE-----\n"); err != nil {
+		t.Errorf("Expected commit signed with a key of the author email to be accepted, got %v", err)
+	}
+}
+
+// Test sign-off and email domain matching
+func TestCommitRuleHelpers(t *testing.T) {
+	if !hasSignoff("msg\n\nSigned-off-by: Alice <Alice@Example.com>", "alice@example.com") {
+		t.Error("Expected sign-off to match case-insensitively")
+	}
+	if hasSignoff("msg\n\nSigned-off-by: Bob <bob@example.com>", "alice@example.com") {
+		t.Error("Expected sign-off of another person not to match")
+	}
+
+	domains := []string{"example.com", "@corp.example"}
+	for email, want := range map[string]bool{
+		"a@example.com":     true,
+		"a@corp.example":    true,
+		"a@sub.example.com": false,
+		"example.com":       false,
+	} {
+		if got := isAllowedEmailDomain(email, domains); got != want {
+			t.Errorf("isAllowedEmailDomain(%q) = %v, want %v", email, got, want)
+		}
+	}
+}
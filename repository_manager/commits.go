@@ -0,0 +1,221 @@
+package repository_manager
+
+import (
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"go.uber.org/zap"
+)
+
+// File actions supported by CommitFiles
+const (
+	FileActionCreate = "create"
+	FileActionUpdate = "update"
+	FileActionDelete = "delete"
+	FileActionMove   = "move"
+)
+
+// CommitFiles applies a list of file actions as a single commit on a branch.
+// If req.ParentSHA is set, the commit is only created when the branch tip equals it,
+// otherwise a ReferenceConflictError is returned. The branch is moved with a
+// compare-and-swap update while holding the repository lock, so concurrent
+// pushes and API commits never overwrite each other.
+// Branch protection rules refuse the commit with a ForbiddenError, as they would refuse a push by req.Pusher.
+func (m *RepositoryManager) CommitFiles(repoName string, req CommitRequest) (*CommitResult, error) {
+	// Validate repository name to prevent path traversal attacks
+	if !isValidRepoName(repoName) {
+		return nil, ErrRepositoryInvalidName
+	}
+
+	if len(req.Actions) == 0 {
+		return nil, ErrCommitEmpty
+	}
+
+	if req.ParentSHA != "" && !plumbing.IsHash(req.ParentSHA) {
+		return nil, NewRevisionNotFoundError(req.ParentSHA)
+	}
+
+	unlock := m.LockRepository(repoName)
+	defer unlock()
+
+	repo, err := m.openRepository(repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	branch, parent, err := resolveBranchTip(repo, req.Branch)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compare the branch tip with the expected parent
+	current := plumbing.ZeroHash
+	if parent != nil {
+		current = parent.Hash
+	}
+	if req.ParentSHA != "" && plumbing.NewHash(req.ParentSHA) != current {
+		return nil, NewReferenceConflictError(branch.String(), req.ParentSHA, current.String())
+	}
+
+	var baseTree *object.Tree
+	if parent != nil {
+		baseTree, err = parent.Tree()
+		if err != nil {
+			return nil, WrapCommitNotFoundError(err)
+		}
+	}
+
+	changes, err := buildFileActionChanges(repo.Storer, baseTree, req.Actions)
+	if err != nil {
+		return nil, err
+	}
+
+	treeHash, _, err := applyTreeChanges(repo.Storer, baseTree, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	commitHash, err := m.commitTree(repoName, req.Pusher, repo, branch, parent, treeHash, req.Message, req.AuthorName, req.AuthorEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CommitResult{
+		Branch:     branch.Short(),
+		CommitHash: commitHash.String(),
+	}
+	if parent != nil {
+		result.ParentHash = parent.Hash.String()
+	}
+
+	m.logger.Info("Files committed",
+		zap.String("repo", repoName),
+		zap.String("branch", branch.Short()),
+		zap.Int("actions", len(req.Actions)),
+		zap.String("commit", commitHash.String()),
+	)
+
+	return result, nil
+}
+
+// buildFileActionChanges validates file actions against the base tree and converts them into tree changes.
+// The SHA of an action is the blob expected at its path, or at its previous path for moves.
+// Each path may only be changed by one action, and no changed path may lie below another one.
+func buildFileActionChanges(s storer.EncodedObjectStorer, baseTree *object.Tree, actions []FileAction) ([]treeChange, error) {
+	changes := make([]treeChange, 0, len(actions))
+	touched := make(map[string]bool)
+
+	// lookup returns the entry of an existing file in the base tree
+	lookup := func(path string) (*object.TreeEntry, bool) {
+		if baseTree == nil {
+			return nil, false
+		}
+		entry, err := baseTree.FindEntry(path)
+		if err != nil || entry.Mode == filemode.Dir {
+			return nil, false
+		}
+		return entry, true
+	}
+
+	for _, action := range actions {
+		paths := []string{action.Path}
+		if action.Action == FileActionMove {
+			paths = append(paths, action.PreviousPath)
+		}
+
+		for _, path := range paths {
+			if !isValidFilePath(path) {
+				return nil, NewInvalidFileActionError(action.Action, path, "invalid file path")
+			}
+			if touched[path] {
+				return nil, NewInvalidFileActionError(action.Action, path, "path is changed by more than one action")
+			}
+			if other, ok := overlappingPath(touched, path); ok {
+				return nil, NewInvalidFileActionError(action.Action, path, "path overlaps with "+other+" changed by another action")
+			}
+			touched[path] = true
+		}
+
+		checked := action.Path
+		if action.Action == FileActionMove {
+			checked = action.PreviousPath
+		}
+		if action.SHA != "" {
+			current := ""
+			if entry, ok := lookup(checked); ok {
+				current = entry.Hash.String()
+			}
+			if current != action.SHA {
+				return nil, NewFileSHAMismatchError(checked, action.SHA, current)
+			}
+		}
+
+		entry, exists := lookup(action.Path)
+
+		switch action.Action {
+		case FileActionCreate, FileActionUpdate:
+			if action.Action == FileActionCreate && exists {
+				return nil, NewFileSHAMismatchError(action.Path, "", entry.Hash.String())
+			}
+			if action.Action == FileActionUpdate && !exists {
+				return nil, NewFileNotFoundError(action.Path)
+			}
+
+			blob, err := storeBlob(s, action.Content)
+			if err != nil {
+				return nil, err
+			}
+
+			change := treeChange{Path: action.Path, Blob: blob}
+			if exists {
+				change.Mode = entry.Mode
+			}
+			changes = append(changes, change)
+
+		case FileActionDelete:
+			if !exists {
+				return nil, NewFileNotFoundError(action.Path)
+			}
+			changes = append(changes, treeChange{Path: action.Path, Delete: true})
+
+		case FileActionMove:
+			if exists {
+				return nil, NewFileSHAMismatchError(action.Path, "", entry.Hash.String())
+			}
+
+			source, ok := lookup(action.PreviousPath)
+			if !ok {
+				return nil, NewFileNotFoundError(action.PreviousPath)
+			}
+
+			// Keep the original content unless new content is provided
+			change := treeChange{Path: action.Path, Blob: source.Hash, Mode: source.Mode}
+			if action.Content != nil {
+				blob, err := storeBlob(s, action.Content)
+				if err != nil {
+					return nil, err
+				}
+				change.Blob = blob
+			}
+			changes = append(changes, treeChange{Path: action.PreviousPath, Delete: true}, change)
+
+		default:
+			return nil, NewInvalidFileActionError(action.Action, action.Path, "unknown action")
+		}
+	}
+
+	return changes, nil
+}
+
+// overlappingPath returns a path of touched that is a parent directory of path or lies below it
+func overlappingPath(touched map[string]bool, path string) (string, bool) {
+	for other := range touched {
+		if strings.HasPrefix(path, other+"/") || strings.HasPrefix(other, path+"/") {
+			return other, true
+		}
+	}
+	return "", false
+}
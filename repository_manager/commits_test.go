@@ -0,0 +1,182 @@
+package repository_manager
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// Test multi-file commits with all action types
+func TestCommitFiles(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	if _, err := manager.CreateRepository("app", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	first, err := manager.CommitFiles("app", CommitRequest{
+		Message: "Initial files",
+		Actions: []FileAction{
+			{Action: FileActionCreate, Path: "a.txt", Content: []byte("a")},
+			{Action: FileActionCreate, Path: "dir/b.txt", Content: []byte("b")},
+			{Action: FileActionCreate, Path: "dir/c.txt", Content: []byte("c")},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+	if first.ParentHash != "" {
+		t.Errorf("Expected root commit, got parent %s", first.ParentHash)
+	}
+
+	second, err := manager.CommitFiles("app", CommitRequest{
+		ParentSHA: first.CommitHash,
+		Message:   "Rework files",
+		Actions: []FileAction{
+			{Action: FileActionUpdate, Path: "a.txt", Content: []byte("a2")},
+			{Action: FileActionDelete, Path: "dir/c.txt"},
+			{Action: FileActionMove, PreviousPath: "dir/b.txt", Path: "moved/b.txt"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create second commit: %v", err)
+	}
+	if second.ParentHash != first.CommitHash {
+		t.Errorf("Expected parent %s, got %s", first.CommitHash, second.ParentHash)
+	}
+
+	expected := map[string]string{"a.txt": "a2", "moved/b.txt": "b"}
+	for path, content := range expected {
+		file, err := manager.GetFile("app", "", path)
+		if err != nil {
+			t.Errorf("Failed to get %s: %v", path, err)
+			continue
+		}
+		if string(file.Content) != content {
+			t.Errorf("Expected %s to contain %q, got %q", path, content, file.Content)
+		}
+	}
+
+	for _, path := range []string{"dir/b.txt", "dir/c.txt"} {
+		if _, err := manager.GetFile("app", "", path); err == nil {
+			t.Errorf("Expected %s to be removed", path)
+		}
+	}
+
+	// A stale parent must be rejected with a typed conflict error
+	_, err = manager.CommitFiles("app", CommitRequest{
+		ParentSHA: first.CommitHash,
+		Message:   "Stale",
+		Actions:   []FileAction{{Action: FileActionCreate, Path: "stale.txt"}},
+	})
+	var refConflict *ReferenceConflictError
+	if !errors.As(err, &refConflict) {
+		t.Fatalf("Expected ReferenceConflictError, got %v", err)
+	}
+	if refConflict.Actual != second.CommitHash {
+		t.Errorf("Expected actual tip %s, got %s", second.CommitHash, refConflict.Actual)
+	}
+
+	// The SHA of a move is checked against the file being moved
+	source, err := manager.GetFile("app", "", "moved/b.txt")
+	if err != nil {
+		t.Fatalf("Failed to get moved/b.txt: %v", err)
+	}
+	var conflict *ConflictError
+	_, err = manager.CommitFiles("app", CommitRequest{
+		Message: "Stale move",
+		Actions: []FileAction{{Action: FileActionMove, PreviousPath: "moved/b.txt", Path: "b.txt", SHA: "0123456789012345678901234567890123456789"}},
+	})
+	if !errors.As(err, &conflict) {
+		t.Errorf("Expected ConflictError for a stale move, got %v", err)
+	}
+	if _, err := manager.CommitFiles("app", CommitRequest{
+		Message: "Move back",
+		Actions: []FileAction{{Action: FileActionMove, PreviousPath: "moved/b.txt", Path: "b.txt", SHA: source.SHA}},
+	}); err != nil {
+		t.Errorf("Expected move with the SHA of its source to succeed, got %v", err)
+	}
+}
+
+// Test invalid file actions
+func TestCommitFiles_InvalidActions(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	if _, err := manager.CreateRepository("app", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	commitTestFile(t, manager, "app", "master", "exists.txt", "x")
+
+	testCases := []struct {
+		name    string
+		actions []FileAction
+	}{
+		{"create existing", []FileAction{{Action: FileActionCreate, Path: "exists.txt"}}},
+		{"update missing", []FileAction{{Action: FileActionUpdate, Path: "missing.txt"}}},
+		{"delete missing", []FileAction{{Action: FileActionDelete, Path: "missing.txt"}}},
+		{"move missing", []FileAction{{Action: FileActionMove, PreviousPath: "missing.txt", Path: "new.txt"}}},
+		{"unknown action", []FileAction{{Action: "copy", Path: "new.txt"}}},
+		{"invalid path", []FileAction{{Action: FileActionCreate, Path: "../new.txt"}}},
+		{"duplicate path", []FileAction{
+			{Action: FileActionCreate, Path: "new.txt"},
+			{Action: FileActionDelete, Path: "new.txt"},
+		}},
+		{"file below changed file", []FileAction{
+			{Action: FileActionDelete, Path: "exists.txt"},
+			{Action: FileActionCreate, Path: "exists.txt/new.txt"},
+		}},
+		{"file above changed file", []FileAction{
+			{Action: FileActionCreate, Path: "dir/new.txt"},
+			{Action: FileActionCreate, Path: "dir"},
+		}},
+		{"file below existing file", []FileAction{{Action: FileActionCreate, Path: "exists.txt/new.txt"}}},
+		{"move into moved file", []FileAction{
+			{Action: FileActionMove, PreviousPath: "exists.txt", Path: "exists.txt/moved.txt"},
+		}},
+		{"no actions", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := manager.CommitFiles("app", CommitRequest{Message: tc.name, Actions: tc.actions})
+			if err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+// Test concurrent commits on the same branch never lose updates
+func TestCommitFiles_Concurrent(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	if _, err := manager.CreateRepository("app", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	const writers = 8
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := manager.CommitFiles("app", CommitRequest{
+				Message: "concurrent",
+				Actions: []FileAction{{Action: FileActionCreate, Path: "file" + string(rune('a'+i)), Content: []byte("x")}},
+			})
+			if err != nil {
+				t.Errorf("Concurrent commit failed: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < writers; i++ {
+		if _, err := manager.GetFile("app", "", "file"+string(rune('a'+i))); err != nil {
+			t.Errorf("File from concurrent commit %d is missing: %v", i, err)
+		}
+	}
+}
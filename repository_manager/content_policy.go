@@ -0,0 +1,404 @@
+package repository_manager
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxReportedViolations limits the policy violations reported per reference
+	maxReportedViolations = 20
+
+	// maxSecretScanSize is the size above which files are not scanned for secrets
+	maxSecretScanSize = 1 << 20
+
+	// binaryDetectionSize is the length of the file prefix searched for NUL bytes, as done by git
+	binaryDetectionSize = 8000
+)
+
+// DefaultSecretRules are the secret detection rules applied whenever secret scanning is enabled
+var DefaultSecretRules = []SecretRule{
+	{Name: "private key", Pattern: `-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?-----`},
+	{Name: "AWS access key ID", Pattern: `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`},
+	{Name: "AWS secret access key", Pattern: `(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}\b`},
+	{Name: "Google API key", Pattern: `\bAIza[0-9A-Za-z_\-]{35}\b`},
+	{Name: "GitHub token", Pattern: `\bgh[pousr]_[A-Za-z0-9]{36,}\b`},
+	{Name: "Slack token", Pattern: `\bxox[abposr]-[0-9A-Za-z\-]{10,}`},
+}
+
+// GetContentPolicy returns the content policy configured directly on a repository or group
+func (m *RepositoryManager) GetContentPolicy(name string) (*ContentPolicy, error) {
+	settings, err := m.loadSettings(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.ContentPolicy == nil {
+		return &ContentPolicy{}, nil
+	}
+
+	return settings.ContentPolicy, nil
+}
+
+// SetContentPolicy replaces the content policy of a repository or group
+func (m *RepositoryManager) SetContentPolicy(name string, policy ContentPolicy) (*ContentPolicy, error) {
+	if !isValidContentPolicy(policy) {
+		return nil, ErrContentPolicyInvalid
+	}
+
+	err := m.updateSettings(name, func(settings *Settings) error {
+		settings.ContentPolicy = &policy
+		if policy.MaxFileSize == 0 && len(policy.ForbiddenPaths) == 0 && !policy.SecretScanning && len(policy.SecretRules) == 0 {
+			settings.ContentPolicy = nil
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("Content policy updated",
+		zap.String("name", name),
+		zap.Int64("maxFileSize", policy.MaxFileSize),
+		zap.Strings("forbiddenPaths", policy.ForbiddenPaths),
+		zap.Bool("secretScanning", policy.SecretScanning),
+	)
+
+	return &policy, nil
+}
+
+// CheckContentPolicy verifies the files of the commits received with a push against the
+// content policies of a repository and its groups: file size limit, forbidden paths and,
+// in added lines, secrets. Only files changed by a received commit are checked, and sizes
+// and secrets only for file contents received with the push.
+// A *PushRejectedError with one rejection per violation is returned if the push must be refused.
+// Content policies only apply to pushes: commits and merges made through RepositoryManager
+// are management operations authorized by the role of the caller and are exempt.
+func (m *RepositoryManager) CheckContentPolicy(repoName string, updates []ReferenceUpdate, objects *PushObjectStorage) error {
+	checker, err := m.contentChecker(repoName)
+	if err != nil || checker == nil {
+		return err
+	}
+
+	violations := make(map[plumbing.Hash][]string)
+	rejected := &PushRejectedError{}
+	for _, update := range updates {
+		if update.IsDelete() {
+			continue
+		}
+
+		hash, ok := peelToCommit(objects, update.NewHash)
+		if !ok {
+			continue
+		}
+
+		commits, err := objects.ReceivedCommits(hash)
+		if err != nil {
+			return err
+		}
+
+		reasons := make([]string, 0)
+		for _, commit := range commits {
+			found, ok := violations[commit.Hash]
+			if !ok {
+				found, err = checker.checkCommit(objects, commit)
+				if err != nil {
+					return err
+				}
+				violations[commit.Hash] = found
+			}
+			reasons = append(reasons, found...)
+		}
+
+		for _, reason := range limitViolations(reasons) {
+			rejected.Rejections = append(rejected.Rejections, ReferenceRejection{
+				Reference: update.Name.String(),
+				Reason:    reason,
+			})
+		}
+	}
+
+	if len(rejected.Rejections) > 0 {
+		return rejected
+	}
+
+	return nil
+}
+
+// limitViolations truncates a list of violations to the number reported to the client
+func limitViolations(violations []string) []string {
+	if len(violations) <= maxReportedViolations {
+		return violations
+	}
+
+	return append(violations[:maxReportedViolations], fmt.Sprintf("and %d more violations", len(violations)-maxReportedViolations))
+}
+
+// contentChecker combines the content policies of a repository and its groups:
+// the smallest file size limit, every forbidden path and, if any policy enables
+// secret scanning, the built-in secret rules with the rules of every policy
+type contentChecker struct {
+	maxFileSize    int64
+	forbiddenPaths []string
+	secretRules    []secretMatcher
+}
+
+type secretMatcher struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// contentChecker returns the checker of the content policies applying to a repository, nil if there are none
+func (m *RepositoryManager) contentChecker(repoName string) (*contentChecker, error) {
+	chain, err := m.settingsChain(repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	checker := &contentChecker{}
+	scanning := false
+	rules := make([]SecretRule, 0)
+	for _, source := range chain {
+		policy := source.Settings.ContentPolicy
+		if policy == nil {
+			continue
+		}
+
+		if policy.MaxFileSize > 0 && (checker.maxFileSize == 0 || policy.MaxFileSize < checker.maxFileSize) {
+			checker.maxFileSize = policy.MaxFileSize
+		}
+		checker.forbiddenPaths = append(checker.forbiddenPaths, policy.ForbiddenPaths...)
+		scanning = scanning || policy.SecretScanning
+		rules = append(rules, policy.SecretRules...)
+	}
+
+	if scanning {
+		for _, rule := range slices.Concat(DefaultSecretRules, rules) {
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				// Rules are validated when saved, skip rules edited by hand
+				m.logger.Warn("Invalid secret rule", zap.String("repo", repoName), zap.String("rule", rule.Name), zap.Error(err))
+				continue
+			}
+			checker.secretRules = append(checker.secretRules, secretMatcher{name: rule.Name, pattern: pattern})
+		}
+	}
+
+	if checker.maxFileSize == 0 && len(checker.forbiddenPaths) == 0 && len(checker.secretRules) == 0 {
+		return nil, nil
+	}
+
+	return checker, nil
+}
+
+// checkCommit returns the violations of the files changed by a commit compared to its first parent
+func (c *contentChecker) checkCommit(objects *PushObjectStorage, commit *object.Commit) ([]string, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, WrapReadObjectError(err)
+	}
+
+	var parentTree *object.Tree
+	if commit.NumParents() > 0 {
+		parent, err := commit.Parent(0)
+		if err != nil {
+			return nil, WrapReadObjectError(err)
+		}
+		if parentTree, err = parent.Tree(); err != nil {
+			return nil, WrapReadObjectError(err)
+		}
+	}
+
+	changes, err := object.DiffTree(parentTree, tree)
+	if err != nil {
+		return nil, WrapReadObjectError(err)
+	}
+
+	violations := make([]string, 0)
+	for _, change := range changes {
+		to := change.To
+		if to.Name == "" || !to.TreeEntry.Mode.IsFile() {
+			continue
+		}
+
+		prefix := fmt.Sprintf("commit %s: %s", commit.Hash.String()[:7], to.Name)
+		for _, pattern := range c.forbiddenPaths {
+			if matchForbiddenPath(pattern, to.Name) {
+				violations = append(violations, fmt.Sprintf("%s: path matches forbidden pattern %s", prefix, pattern))
+				break
+			}
+		}
+
+		if !objects.IsReceived(to.TreeEntry.Hash) {
+			continue
+		}
+
+		size, err := objects.EncodedObjectSize(to.TreeEntry.Hash)
+		if err != nil {
+			return nil, WrapReadObjectError(err)
+		}
+
+		if c.maxFileSize > 0 && size > c.maxFileSize {
+			violations = append(violations, fmt.Sprintf("%s: file size %s exceeds the limit of %s", prefix, formatSize(size), formatSize(c.maxFileSize)))
+		}
+
+		if len(c.secretRules) > 0 && size <= maxSecretScanSize {
+			found, err := c.scanSecrets(objects, prefix, change.From.TreeEntry.Hash, to.TreeEntry.Hash)
+			if err != nil {
+				return nil, err
+			}
+			violations = append(violations, found...)
+		}
+	}
+
+	return violations, nil
+}
+
+// scanSecrets searches the lines of a file that are not in its previous version for secrets.
+// Binary files are skipped. Secrets are not included in the violations.
+func (c *contentChecker) scanSecrets(objects *PushObjectStorage, prefix string, from, to plumbing.Hash) ([]string, error) {
+	content, err := readBlobContent(objects, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.IndexByte(content[:min(len(content), binaryDetectionSize)], 0) >= 0 {
+		return nil, nil
+	}
+
+	previous := make(map[string]bool)
+	if !from.IsZero() {
+		old, err := readBlobContent(objects, from)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range bytes.Split(old, []byte("\n")) {
+			previous[string(line)] = true
+		}
+	}
+
+	violations := make([]string, 0)
+	for i, line := range bytes.Split(content, []byte("\n")) {
+		if previous[string(line)] {
+			continue
+		}
+
+		for _, rule := range c.secretRules {
+			if rule.pattern.Match(line) {
+				violations = append(violations, fmt.Sprintf("%s:%d: %s detected", prefix, i+1, rule.name))
+				break
+			}
+		}
+	}
+
+	return violations, nil
+}
+
+func readBlobContent(objects *PushObjectStorage, hash plumbing.Hash) ([]byte, error) {
+	blob, err := object.GetBlob(objects, hash)
+	if err != nil {
+		return nil, WrapReadObjectError(err)
+	}
+
+	r, err := blob.Reader()
+	if err != nil {
+		return nil, WrapReadObjectError(err)
+	}
+	defer r.Close()
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, WrapReadObjectError(err)
+	}
+
+	return content, nil
+}
+
+// peelToCommit resolves annotated tags to the commit they point to.
+// It reports false for objects that are neither commits nor tags of commits.
+func peelToCommit(objects *PushObjectStorage, hash plumbing.Hash) (plumbing.Hash, bool) {
+	for {
+		obj, err := objects.EncodedObject(plumbing.AnyObject, hash)
+		if err != nil {
+			return plumbing.ZeroHash, false
+		}
+
+		switch obj.Type() {
+		case plumbing.CommitObject:
+			return hash, true
+		case plumbing.TagObject:
+			tag, err := object.DecodeTag(objects, obj)
+			if err != nil {
+				return plumbing.ZeroHash, false
+			}
+			hash = tag.Target
+		default:
+			return plumbing.ZeroHash, false
+		}
+	}
+}
+
+// matchForbiddenPath reports whether a file path matches a forbidden path pattern, similar to gitignore:
+// patterns without a slash match a file or directory name at any depth, e.g. *.pem,
+// patterns with a slash match from the repository root, e.g. config/secrets,
+// and patterns ending with a slash only match directories, e.g. node_modules/.
+func matchForbiddenPath(pattern, filePath string) bool {
+	pattern, dirOnly := strings.CutSuffix(pattern, "/")
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	segments := strings.Split(filePath, "/")
+	n := len(segments)
+	if dirOnly {
+		n--
+	}
+
+	for i := 1; i <= n; i++ {
+		name := segments[i-1]
+		if anchored {
+			name = strings.Join(segments[:i], "/")
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isValidContentPolicy checks the size limit, the forbidden path globs and the secret rules of a policy
+func isValidContentPolicy(policy ContentPolicy) bool {
+	if policy.MaxFileSize < 0 {
+		return false
+	}
+
+	for _, pattern := range policy.ForbiddenPaths {
+		pattern = strings.Trim(pattern, "/")
+		if pattern == "" {
+			return false
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return false
+		}
+	}
+
+	for _, rule := range policy.SecretRules {
+		if rule.Name == "" || rule.Pattern == "" {
+			return false
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,98 @@
+package repository_manager
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMatchForbiddenPath(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{"*.pem", "server.pem", true},
+		{"*.pem", "certs/prod/server.pem", true},
+		{"*.pem", "server.pem.txt", false},
+		{"node_modules/", "node_modules/left-pad/index.js", true},
+		{"node_modules/", "web/node_modules/react/index.js", true},
+		{"node_modules/", "node_modules", false},
+		{"node_modules", "node_modules", true},
+		{"config/secrets", "config/secrets/prod.yaml", true},
+		{"config/secrets", "app/config/secrets/prod.yaml", false},
+		{"/build/*.bin", "build/app.bin", true},
+		{"/build/*.bin", "build/out/app.bin", false},
+		{".env", "services/api/.env", true},
+	}
+
+	for _, tt := range tests {
+		if got := matchForbiddenPath(tt.pattern, tt.path); got != tt.expected {
+			t.Errorf("matchForbiddenPath(%q, %q) = %v, expected %v", tt.pattern, tt.path, got, tt.expected)
+		}
+	}
+}
+
+// Test content policy validation and the combination of group and repository policies
+func TestContentPolicy(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	if _, err := manager.CreateRepository("org/app", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	invalid := []ContentPolicy{
+		{MaxFileSize: -1},
+		{ForbiddenPaths: []string{"/"}},
+		{ForbiddenPaths: []string{"[a-"}},
+		{SecretRules: []SecretRule{{Name: "broken", Pattern: "("}}},
+		{SecretRules: []SecretRule{{Pattern: "key"}}},
+	}
+	for _, policy := range invalid {
+		if _, err := manager.SetContentPolicy("org", policy); !errors.Is(err, ErrContentPolicyInvalid) {
+			t.Errorf("Expected ErrContentPolicyInvalid for %+v, got %v", policy, err)
+		}
+	}
+
+	if checker, err := manager.contentChecker("org/app"); err != nil || checker != nil {
+		t.Errorf("Expected no checker without policies, got %+v, %v", checker, err)
+	}
+
+	if _, err := manager.SetContentPolicy("org", ContentPolicy{MaxFileSize: 1024, ForbiddenPaths: []string{"*.pem"}, SecretScanning: true}); err != nil {
+		t.Fatalf("Failed to set group policy: %v", err)
+	}
+	if _, err := manager.SetContentPolicy("org/app", ContentPolicy{MaxFileSize: 4096, ForbiddenPaths: []string{"node_modules/"}, SecretRules: []SecretRule{{Name: "internal key", Pattern: "ikey_[0-9a-f]{32}"}}}); err != nil {
+		t.Fatalf("Failed to set repository policy: %v", err)
+	}
+
+	checker, err := manager.contentChecker("org/app")
+	if err != nil {
+		t.Fatalf("Failed to combine policies: %v", err)
+	}
+	if checker.maxFileSize != 1024 {
+		t.Errorf("Expected the smallest size limit, got %d", checker.maxFileSize)
+	}
+	if len(checker.forbiddenPaths) != 2 {
+		t.Errorf("Expected forbidden paths of both policies, got %v", checker.forbiddenPaths)
+	}
+	if len(checker.secretRules) != len(DefaultSecretRules)+1 {
+		t.Errorf("Expected built-in and custom secret rules, got %d", len(checker.secretRules))
+	}
+
+	// Commits made through RepositoryManager are exempt
+	if _, err := manager.CommitFiles("org/app", CommitRequest{
+		Message: "Add key",
+		Actions: []FileAction{{Action: FileActionCreate, Path: "node_modules/key.pem", Content: []byte("key")}},
+	}); err != nil {
+		t.Errorf("Expected content policies not to restrict CommitFiles, got %v", err)
+	}
+
+	// An empty policy removes the policy
+	if _, err := manager.SetContentPolicy("org", ContentPolicy{}); err != nil {
+		t.Fatalf("Failed to clear group policy: %v", err)
+	}
+	settings, _ := manager.loadSettings("org")
+	if settings.ContentPolicy != nil {
+		t.Errorf("Expected group policy to be removed, got %+v", settings.ContentPolicy)
+	}
+}
@@ -0,0 +1,266 @@
+package repository_manager
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"go.uber.org/zap"
+)
+
+// GetFile returns the content of a file at the given ref (branch, tag or commit).
+// An empty ref reads from HEAD.
+func (m *RepositoryManager) GetFile(repoName, ref, filePath string) (*FileContent, error) {
+	// Validate repository name to prevent path traversal attacks
+	if !isValidRepoName(repoName) {
+		return nil, ErrRepositoryInvalidName
+	}
+
+	if !isValidFilePath(filePath) {
+		return nil, ErrFilePathInvalid
+	}
+
+	repo, err := m.openRepository(repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return nil, NewRevisionNotFoundError(ref)
+	}
+
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, WrapCommitNotFoundError(err)
+	}
+
+	file, err := commit.File(filePath)
+	if err != nil {
+		return nil, NewFileNotFoundError(filePath)
+	}
+
+	reader, err := file.Reader()
+	if err != nil {
+		return nil, WrapReadObjectError(err)
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, WrapReadObjectError(err)
+	}
+
+	return &FileContent{
+		Path:    filePath,
+		SHA:     file.Hash.String(),
+		Size:    file.Size,
+		Content: content,
+	}, nil
+}
+
+// PutFile creates or updates a file on a branch with a new commit.
+// Updating an existing file requires opts.SHA to match the current blob SHA,
+// creating a new file requires opts.SHA to be empty.
+func (m *RepositoryManager) PutFile(repoName, filePath string, content []byte, opts FileCommitOptions) (*FileCommit, error) {
+	return m.commitFileChange(repoName, filePath, content, false, opts)
+}
+
+// DeleteFile removes a file from a branch with a new commit.
+// opts.SHA must match the current blob SHA of the file.
+func (m *RepositoryManager) DeleteFile(repoName, filePath string, opts FileCommitOptions) (*FileCommit, error) {
+	return m.commitFileChange(repoName, filePath, nil, true, opts)
+}
+
+// commitFileChange writes a commit changing a single file on top of the branch tip
+func (m *RepositoryManager) commitFileChange(repoName, filePath string, content []byte, remove bool, opts FileCommitOptions) (*FileCommit, error) {
+	// Validate repository name to prevent path traversal attacks
+	if !isValidRepoName(repoName) {
+		return nil, ErrRepositoryInvalidName
+	}
+
+	if !isValidFilePath(filePath) {
+		return nil, ErrFilePathInvalid
+	}
+
+	unlock := m.LockRepository(repoName)
+	defer unlock()
+
+	repo, err := m.openRepository(repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	branch, parent, err := resolveBranchTip(repo, opts.Branch)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the expected blob SHA against the current file
+	current := ""
+	mode := filemode.Empty
+	var baseTree *object.Tree
+	if parent != nil {
+		baseTree, err = parent.Tree()
+		if err != nil {
+			return nil, WrapCommitNotFoundError(err)
+		}
+
+		entry, err := baseTree.FindEntry(filePath)
+		if err == nil {
+			if entry.Mode == filemode.Dir {
+				return nil, &InvalidTypeError{Expected: "file", Actual: "directory", Name: filePath}
+			}
+			current = entry.Hash.String()
+			mode = entry.Mode
+		}
+	}
+
+	if remove && current == "" {
+		return nil, NewFileNotFoundError(filePath)
+	}
+
+	if opts.SHA != current {
+		return nil, NewFileSHAMismatchError(filePath, opts.SHA, current)
+	}
+
+	// Build the new tree, keeping the mode of executables and symlinks
+	change := treeChange{Path: filePath, Delete: remove, Mode: mode}
+	if !remove {
+		change.Blob, err = storeBlob(repo.Storer, content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	treeHash, _, err := applyTreeChanges(repo.Storer, baseTree, []treeChange{change})
+	if err != nil {
+		return nil, err
+	}
+
+	message := opts.Message
+	if message == "" {
+		if remove {
+			message = fmt.Sprintf("Delete %s", filePath)
+		} else if current == "" {
+			message = fmt.Sprintf("Create %s", filePath)
+		} else {
+			message = fmt.Sprintf("Update %s", filePath)
+		}
+	}
+
+	commitHash, err := m.commitTree(repoName, opts.Pusher, repo, branch, parent, treeHash, message, opts.AuthorName, opts.AuthorEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &FileCommit{
+		Path:       filePath,
+		Branch:     branch.Short(),
+		CommitHash: commitHash.String(),
+	}
+	if !remove {
+		result.SHA = change.Blob.String()
+	}
+
+	m.logger.Info("File committed",
+		zap.String("repo", repoName),
+		zap.String("path", filePath),
+		zap.String("branch", branch.Short()),
+		zap.String("commit", commitHash.String()),
+	)
+
+	return result, nil
+}
+
+// commitTree writes a commit for treeHash on top of parent and moves the branch to it on behalf of pusher.
+// The branch update fails if the branch tip is no longer parent or its protection rules refuse it.
+func (m *RepositoryManager) commitTree(repoName, pusher string, repo *git.Repository, branch plumbing.ReferenceName, parent *object.Commit, treeHash plumbing.Hash, message, authorName, authorEmail string) (plumbing.Hash, error) {
+	sig := m.signature(authorName, authorEmail)
+	commit := &object.Commit{
+		Author:    sig,
+		Committer: sig,
+		Message:   strings.TrimRight(message, "\n") + "\n",
+		TreeHash:  treeHash,
+	}
+
+	oldHash := plumbing.ZeroHash
+	if parent != nil {
+		commit.ParentHashes = []plumbing.Hash{parent.Hash}
+		oldHash = parent.Hash
+	}
+
+	commitHash, err := storeCommit(repo.Storer, commit)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if err := m.updateReference(repoName, pusher, repo, branch, commitHash, oldHash); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return commitHash, nil
+}
+
+// resolveBranchTip returns the full branch name and its tip commit.
+// An empty branch selects the branch HEAD points to. The tip is nil for a
+// branch without commits, which is only allowed for the HEAD branch of an empty repository.
+func resolveBranchTip(repo *git.Repository, branch string) (plumbing.ReferenceName, *object.Commit, error) {
+	head, err := repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return "", nil, WrapGetHEADError(err)
+	}
+
+	refName := head.Target()
+	if branch != "" {
+		refName = plumbing.NewBranchReferenceName(branch)
+		if refName.Validate() != nil {
+			return "", nil, ErrBranchInvalidName
+		}
+	}
+
+	ref, err := repo.Storer.Reference(refName)
+	if err == plumbing.ErrReferenceNotFound {
+		if refName != head.Target() {
+			return "", nil, NewBranchNotFoundError(refName.Short())
+		}
+		return refName, nil, nil
+	}
+	if err != nil {
+		return "", nil, WrapGetBranchError(err)
+	}
+
+	commit, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return "", nil, WrapCommitNotFoundError(err)
+	}
+
+	return refName, commit, nil
+}
+
+// isValidFilePath checks if a path is a valid slash separated path inside a repository tree
+func isValidFilePath(filePath string) bool {
+	if filePath == "" || strings.HasPrefix(filePath, "/") || strings.HasSuffix(filePath, "/") {
+		return false
+	}
+
+	for _, segment := range strings.Split(filePath, "/") {
+		if segment == "" || segment == "." || segment == ".." || segment == ".git" {
+			return false
+		}
+
+		if strings.ContainsAny(segment, "\x00\\") {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,179 @@
+package repository_manager
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+)
+
+// Test creating, updating and deleting files through commits
+func TestFileCommits(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	if _, err := manager.CreateRepository("org/config", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	// First commit in an empty repository
+	created, err := manager.PutFile("org/config", "envs/prod/app.yaml", []byte("replicas: 1\n"), FileCommitOptions{
+		Message:    "Add prod config",
+		AuthorName: "Bot",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if created.Branch != "master" {
+		t.Errorf("Expected commit on HEAD branch 'master', got '%s'", created.Branch)
+	}
+
+	if _, err := manager.PutFile("org/config", "envs/dev/app.yaml", []byte("replicas: 0\n"), FileCommitOptions{}); err != nil {
+		t.Fatalf("Failed to create second file: %v", err)
+	}
+
+	// Updating without the current SHA must fail
+	_, err = manager.PutFile("org/config", "envs/prod/app.yaml", []byte("replicas: 2\n"), FileCommitOptions{})
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected ConflictError when updating without sha, got %v", err)
+	}
+
+	updated, err := manager.PutFile("org/config", "envs/prod/app.yaml", []byte("replicas: 2\n"), FileCommitOptions{SHA: created.SHA})
+	if err != nil {
+		t.Fatalf("Failed to update file: %v", err)
+	}
+
+	// A stale SHA must be rejected
+	if _, err := manager.PutFile("org/config", "envs/prod/app.yaml", []byte("replicas: 3\n"), FileCommitOptions{SHA: created.SHA}); !errors.As(err, &conflict) {
+		t.Errorf("Expected ConflictError for stale sha, got %v", err)
+	}
+
+	file, err := manager.GetFile("org/config", "master", "envs/prod/app.yaml")
+	if err != nil {
+		t.Fatalf("Failed to get file: %v", err)
+	}
+	if string(file.Content) != "replicas: 2\n" || file.SHA != updated.SHA {
+		t.Errorf("Unexpected file content: %+v", file)
+	}
+
+	// Deleting the last file of a directory removes the directory
+	if _, err := manager.DeleteFile("org/config", "envs/dev/app.yaml", FileCommitOptions{}); !errors.As(err, &conflict) {
+		t.Errorf("Expected ConflictError when deleting without sha, got %v", err)
+	}
+
+	dev, _ := manager.GetFile("org/config", "", "envs/dev/app.yaml")
+	if _, err := manager.DeleteFile("org/config", "envs/dev/app.yaml", FileCommitOptions{SHA: dev.SHA}); err != nil {
+		t.Fatalf("Failed to delete file: %v", err)
+	}
+
+	var notFound *NotFoundError
+	if _, err := manager.GetFile("org/config", "", "envs/dev/app.yaml"); !errors.As(err, &notFound) {
+		t.Errorf("Expected NotFoundError after delete, got %v", err)
+	}
+
+	// Files are not replaced by directories or the other way round
+	var invalidType *InvalidTypeError
+	if _, err := manager.PutFile("org/config", "envs/prod/app.yaml/nested.yaml", []byte("x"), FileCommitOptions{}); !errors.As(err, &invalidType) {
+		t.Errorf("Expected InvalidTypeError for a path below a file, got %v", err)
+	} else if invalidType.Name != "envs/prod/app.yaml" || invalidType.Expected != "directory" {
+		t.Errorf("Unexpected InvalidTypeError: %+v", invalidType)
+	}
+	if _, err := manager.PutFile("org/config", "envs/prod", []byte("x"), FileCommitOptions{}); !errors.As(err, &invalidType) {
+		t.Errorf("Expected InvalidTypeError for a file over a directory, got %v", err)
+	}
+	if file, err := manager.GetFile("org/config", "", "envs/prod/app.yaml"); err != nil || string(file.Content) != "replicas: 2\n" {
+		t.Errorf("Expected envs/prod/app.yaml to be unchanged, got %+v, %v", file, err)
+	}
+
+	// Commits on a missing branch other than HEAD are rejected
+	if _, err := manager.PutFile("org/config", "a.txt", []byte("a"), FileCommitOptions{Branch: "missing"}); !errors.As(err, &notFound) {
+		t.Errorf("Expected NotFoundError for missing branch, got %v", err)
+	}
+
+	// The resulting repository must be valid for the git binary
+	if gitPath, err := exec.LookPath("git"); err == nil {
+		cmd := exec.Command(gitPath, "--git-dir", filepath.Join(tmpDir, "org/config.git"), "fsck", "--strict")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("git fsck failed: %v: %s", err, out)
+		}
+	}
+}
+
+// Test that updating a file keeps the mode of executables and symlinks
+func TestFileCommitsKeepMode(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	if _, err := manager.CreateRepository("app", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	repo, err := manager.openRepository("app")
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+
+	script, _ := storeBlob(repo.Storer, []byte("#!/bin/sh\n"))
+	link, _ := storeBlob(repo.Storer, []byte("run.sh"))
+	treeHash, _, err := applyTreeChanges(repo.Storer, nil, []treeChange{
+		{Path: "bin/run.sh", Blob: script, Mode: filemode.Executable},
+		{Path: "bin/start", Blob: link, Mode: filemode.Symlink},
+	})
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	if _, err := manager.commitTree("app", "", repo, plumbing.Master, nil, treeHash, "Add scripts", "", ""); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	if _, err := manager.PutFile("app", "bin/run.sh", []byte("#!/bin/sh\nexit 0\n"), FileCommitOptions{SHA: script.String()}); err != nil {
+		t.Fatalf("Failed to update script: %v", err)
+	}
+	if _, err := manager.PutFile("app", "bin/start", []byte("other.sh"), FileCommitOptions{SHA: link.String()}); err != nil {
+		t.Fatalf("Failed to update symlink: %v", err)
+	}
+
+	_, head, err := resolveBranchTip(repo, "")
+	if err != nil {
+		t.Fatalf("Failed to resolve HEAD: %v", err)
+	}
+	tree, err := head.Tree()
+	if err != nil {
+		t.Fatalf("Failed to read tree: %v", err)
+	}
+	for path, mode := range map[string]filemode.FileMode{"bin/run.sh": filemode.Executable, "bin/start": filemode.Symlink} {
+		if entry, err := tree.FindEntry(path); err != nil || entry.Mode != mode {
+			t.Errorf("Expected %s to keep mode %s, got %+v, %v", path, mode, entry, err)
+		}
+	}
+}
+
+// Test file path validation
+func TestIsValidFilePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"README.md", true},
+		{"dir/sub/file.txt", true},
+		{".github/workflows/ci.yml", true},
+		{"", false},
+		{"/absolute", false},
+		{"trailing/", false},
+		{"a//b", false},
+		{"../escape", false},
+		{"a/./b", false},
+		{".git/config", false},
+		{"back\\slash", false},
+	}
+
+	for _, tt := range tests {
+		if got := isValidFilePath(tt.path); got != tt.want {
+			t.Errorf("isValidFilePath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package repository_manager
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 // Common errors
@@ -29,6 +30,99 @@ var (
 	ErrTagNameEmpty = errors.New("tag name cannot be empty")
 )
 
+// Branch errors
+var (
+	// ErrBranchInvalidName indicates branch name is not a valid reference name
+	ErrBranchInvalidName = errors.New("invalid branch name")
+)
+
+// File errors
+var (
+	// ErrFilePathInvalid indicates the file path is not a valid path inside a repository
+	ErrFilePathInvalid = errors.New("invalid file path: must be a relative slash separated path without . or .. segments")
+)
+
+// Commit errors
+var (
+	// ErrCommitEmpty indicates a commit request without file actions
+	ErrCommitEmpty = errors.New("commit must contain at least one file action")
+)
+
+// Merge errors
+var (
+	// ErrMergeStrategyInvalid indicates an unsupported merge strategy
+	ErrMergeStrategyInvalid = errors.New("invalid merge strategy: must be one of fast-forward, merge, squash")
+)
+
+// Protection errors
+var (
+	// ErrProtectionPatternInvalid indicates a protection rule pattern that is not a valid glob
+	ErrProtectionPatternInvalid = errors.New("invalid protection pattern: must be a non-empty glob such as main or release/*")
+
+	// ErrCommitRuleInvalid indicates a commit message pattern that is not a regular expression or an empty email domain
+	ErrCommitRuleInvalid = errors.New("invalid commit rule: commit_message_pattern must be a regular expression and allowed email domains cannot be empty")
+)
+
+// Webhook errors
+var (
+	// ErrWebhookURLInvalid indicates a webhook URL that is not an absolute http or https URL
+	ErrWebhookURLInvalid = errors.New("invalid webhook url: must be an absolute http or https URL")
+
+	// ErrWebhookURLForbidden indicates a webhook URL pointing to a loopback, link-local or unspecified address
+	ErrWebhookURLForbidden = errors.New("webhook url not allowed: loopback and link-local addresses must be listed in webhook_allowed_hosts")
+
+	// ErrWebhookEventInvalid indicates an unknown event in the event filter of a webhook
+	ErrWebhookEventInvalid = errors.New("invalid webhook event: must be one of push, tag_create, tag_delete, branch_create, branch_delete, repository_create, repository_delete")
+)
+
+// Permission errors
+var (
+	// ErrRoleInvalid indicates an unknown role
+	ErrRoleInvalid = errors.New("invalid role: must be one of read, write, maintain, admin")
+
+	// ErrPermissionSubjectInvalid indicates a permission granted to neither or both of a user and a team
+	ErrPermissionSubjectInvalid = errors.New("invalid permission: exactly one of user or team must be set")
+)
+
+// Filter errors
+var (
+	// ErrFilterKindInvalid indicates an unknown object filter kind in a filter policy
+	ErrFilterKindInvalid = errors.New("invalid filter kind: must be one of blob:none, blob:limit, tree, sparse:oid, object:type, combine")
+)
+
+// Quota errors
+var (
+	// ErrQuotaInvalid indicates a negative quota
+	ErrQuotaInvalid = errors.New("invalid quota: max_size must not be negative")
+)
+
+// Content policy errors
+var (
+	// ErrContentPolicyInvalid indicates a content policy with a negative size, an invalid path glob or an invalid secret rule
+	ErrContentPolicyInvalid = errors.New("invalid content policy: max_file_size must not be negative, forbidden paths must be globs and secret rules named regular expressions")
+)
+
+// Bundle errors
+var (
+	// ErrBundleInvalid indicates the bundle data is malformed or uses an unsupported format
+	ErrBundleInvalid = errors.New("invalid bundle: expected a v2 or v3 git bundle")
+
+	// ErrBundleCorrupt indicates a bundle whose packfile cannot be read or lacks referenced objects
+	ErrBundleCorrupt = errors.New("corrupt bundle")
+
+	// ErrBundlePrerequisiteMissing indicates a bundle depending on commits the repository does not have
+	ErrBundlePrerequisiteMissing = errors.New("bundle prerequisite missing: bundles restoring a new repository must be complete")
+
+	// ErrBundleEmpty indicates the repository has no references to bundle
+	ErrBundleEmpty = errors.New("repository has no references to bundle")
+)
+
+// Archive errors
+var (
+	// ErrArchiveInvalid indicates the backup archive is malformed or has an unsupported manifest
+	ErrArchiveInvalid = errors.New("invalid archive: expected a gzip compressed tar archive starting with a supported manifest")
+)
+
 // Group errors
 var (
 	// ErrGroupInvalidName indicates group name is invalid
@@ -37,6 +131,39 @@ var (
 
 // Error types for dynamic errors with context
 
+// ReferenceRejection describes why the update of a single reference was refused
+type ReferenceRejection struct {
+	Reference string
+	Reason    string
+}
+
+// PushRejectedError represents a push whose reference updates violate the repository policies
+type PushRejectedError struct {
+	Rejections []ReferenceRejection
+}
+
+func (e *PushRejectedError) Error() string {
+	reasons := make([]string, 0, len(e.Rejections))
+	for _, r := range e.Rejections {
+		reasons = append(reasons, fmt.Sprintf("%s: %s", r.Reference, r.Reason))
+	}
+	return "push rejected: " + strings.Join(reasons, "; ")
+}
+
+// QuotaExceededError represents data that does not fit in the quota of a repository or group
+type QuotaExceededError struct {
+	ResourceType string // "repository" or "group"
+	Name         string
+	Used         int64
+	MaxSize      int64
+	Size         int64
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("quota of %s %s exceeded: %s used of %s, adding %s",
+		e.ResourceType, e.Name, formatSize(e.Used), formatSize(e.MaxSize), formatSize(e.Size))
+}
+
 // AlreadyExistsError represents a resource that already exists
 type AlreadyExistsError struct {
 	ResourceType string // "repository", "group", etc.
@@ -87,6 +214,46 @@ func (e *ConflictError) Error() string {
 	return e.Message
 }
 
+// ForbiddenError represents an operation refused by a repository policy
+type ForbiddenError struct {
+	Message string
+}
+
+func (e *ForbiddenError) Error() string {
+	return e.Message
+}
+
+// InvalidFileActionError represents a file action of a commit request that cannot be applied
+type InvalidFileActionError struct {
+	Action string
+	Path   string
+	Reason string
+}
+
+func (e *InvalidFileActionError) Error() string {
+	return fmt.Sprintf("invalid %s action for %s: %s", e.Action, e.Path, e.Reason)
+}
+
+// MergeConflictError represents a merge that cannot be completed because of conflicting changes
+type MergeConflictError struct {
+	Paths []string
+}
+
+func (e *MergeConflictError) Error() string {
+	return fmt.Sprintf("merge conflict in %d path(s): %s", len(e.Paths), strings.Join(e.Paths, ", "))
+}
+
+// ReferenceConflictError represents a reference that does not have the expected value
+type ReferenceConflictError struct {
+	Reference string
+	Expected  string
+	Actual    string
+}
+
+func (e *ReferenceConflictError) Error() string {
+	return fmt.Sprintf("reference %s has changed: expected %s, found %s", e.Reference, e.Expected, e.Actual)
+}
+
 // Operation errors - wrapping underlying errors
 
 // OperationError represents an error during an operation
@@ -153,6 +320,204 @@ func NewTagNotFoundError(name string) error {
 	}
 }
 
+// NewBundlePrerequisiteMissingError creates an error when a bundle prerequisite commit is missing
+func NewBundlePrerequisiteMissingError(hash string) error {
+	return fmt.Errorf("%w: %s", ErrBundlePrerequisiteMissing, hash)
+}
+
+// NewBundleCorruptError creates an error when the content of a bundle cannot be applied
+func NewBundleCorruptError(err error) error {
+	return fmt.Errorf("%w: %v", ErrBundleCorrupt, err)
+}
+
+// NewArchiveEntryNotFoundError creates an error when an archive entry referenced by the manifest is missing
+func NewArchiveEntryNotFoundError(name string) error {
+	return &NotFoundError{
+		ResourceType: "archive entry",
+		Name:         name,
+	}
+}
+
+// NewRevisionNotFoundError creates a revision not found error
+func NewRevisionNotFoundError(revision string) error {
+	return &NotFoundError{
+		ResourceType: "revision",
+		Name:         revision,
+	}
+}
+
+// NewTemplateNotFoundError creates a template not found error
+func NewTemplateNotFoundError(templateType, name string) error {
+	return &NotFoundError{
+		ResourceType: templateType,
+		Name:         name,
+	}
+}
+
+// NewReferenceConflictError creates an error when a reference does not have the expected value
+func NewReferenceConflictError(ref, expected, actual string) error {
+	return &ReferenceConflictError{
+		Reference: ref,
+		Expected:  expected,
+		Actual:    actual,
+	}
+}
+
+// NewReferenceLockedError creates an error when a reference is locked by another update
+func NewReferenceLockedError(ref string) error {
+	return &ConflictError{
+		Message: fmt.Sprintf("reference is locked by another update: %s", ref),
+	}
+}
+
+// NewBranchNotFoundError creates a branch not found error
+func NewBranchNotFoundError(name string) error {
+	return &NotFoundError{
+		ResourceType: "branch",
+		Name:         name,
+	}
+}
+
+// NewFileNotFoundError creates a file not found error
+func NewFileNotFoundError(path string) error {
+	return &NotFoundError{
+		ResourceType: "file",
+		Name:         path,
+	}
+}
+
+// NewFileSHAMismatchError creates an error when the expected blob SHA of a file does not match
+func NewFileSHAMismatchError(path, expected, actual string) error {
+	if actual == "" {
+		return &ConflictError{
+			Message: fmt.Sprintf("file does not exist: %s (expected sha %s)", path, expected),
+		}
+	}
+	if expected == "" {
+		return &ConflictError{
+			Message: fmt.Sprintf("file already exists: %s (current sha %s)", path, actual),
+		}
+	}
+	return &ConflictError{
+		Message: fmt.Sprintf("file has been modified: %s (expected sha %s, current sha %s)", path, expected, actual),
+	}
+}
+
+// NewInvalidFileActionError creates an invalid file action error
+func NewInvalidFileActionError(action, path, reason string) error {
+	return &InvalidFileActionError{
+		Action: action,
+		Path:   path,
+		Reason: reason,
+	}
+}
+
+// NewMergeConflictError creates a merge conflict error for the given paths
+func NewMergeConflictError(paths []string) error {
+	return &MergeConflictError{
+		Paths: paths,
+	}
+}
+
+// NewNotFastForwardError creates an error when a fast-forward merge is not possible
+func NewNotFastForwardError(base, head string) error {
+	return &ConflictError{
+		Message: fmt.Sprintf("cannot fast-forward %s to %s: branches have diverged", base, head),
+	}
+}
+
+// NewUnrelatedHistoriesError creates an error when two revisions have no common ancestor
+func NewUnrelatedHistoriesError(base, head string) error {
+	return &ConflictError{
+		Message: fmt.Sprintf("refusing to merge unrelated histories: %s and %s", base, head),
+	}
+}
+
+// NewBranchProtectionRuleNotFoundError creates a branch protection rule not found error
+func NewBranchProtectionRuleNotFoundError(pattern string) error {
+	return &NotFoundError{
+		ResourceType: "branch protection rule",
+		Name:         pattern,
+	}
+}
+
+// NewBranchProtectionRuleAlreadyExistsError creates a branch protection rule already exists error
+func NewBranchProtectionRuleAlreadyExistsError(pattern string) error {
+	return &AlreadyExistsError{
+		ResourceType: "branch protection rule",
+		Name:         pattern,
+	}
+}
+
+// NewTagProtectionRuleNotFoundError creates a tag protection rule not found error
+func NewTagProtectionRuleNotFoundError(pattern string) error {
+	return &NotFoundError{
+		ResourceType: "tag protection rule",
+		Name:         pattern,
+	}
+}
+
+// NewTagProtectionRuleAlreadyExistsError creates a tag protection rule already exists error
+func NewTagProtectionRuleAlreadyExistsError(pattern string) error {
+	return &AlreadyExistsError{
+		ResourceType: "tag protection rule",
+		Name:         pattern,
+	}
+}
+
+// NewWebhookNotFoundError creates a webhook not found error
+func NewWebhookNotFoundError(id string) error {
+	return &NotFoundError{
+		ResourceType: "webhook",
+		Name:         id,
+	}
+}
+
+// NewPermissionNotFoundError creates a permission not found error
+func NewPermissionNotFoundError(subject string) error {
+	return &NotFoundError{
+		ResourceType: "permission",
+		Name:         subject,
+	}
+}
+
+// NewRoleRequiredError creates an error when a user lacks the role an operation requires
+func NewRoleRequiredError(name string, role Role) error {
+	return &ForbiddenError{
+		Message: fmt.Sprintf("%s role required on %s", role, name),
+	}
+}
+
+// NewProtectedTagError creates an error when a protected tag would be moved or deleted
+func NewProtectedTagError(tag, pattern string) error {
+	return &ForbiddenError{
+		Message: fmt.Sprintf("tag %s is protected by rule %s and cannot be overwritten or deleted", tag, pattern),
+	}
+}
+
+// NewProtectedBranchError creates an error when a branch protection rule refuses a change to a branch
+func NewProtectedBranchError(branch, reason string) error {
+	return &ForbiddenError{
+		Message: fmt.Sprintf("branch %s is protected: %s", branch, reason),
+	}
+}
+
+// NewQuotaExceededError creates an error when size more bytes exceed the quota of a repository or group
+func NewQuotaExceededError(name string, isRepository bool, usage *Usage, size int64) error {
+	resourceType := "group"
+	if isRepository {
+		resourceType = "repository"
+	}
+
+	return &QuotaExceededError{
+		ResourceType: resourceType,
+		Name:         name,
+		Used:         usage.Size,
+		MaxSize:      usage.MaxSize,
+		Size:         size,
+	}
+}
+
 // NewNotAGroupError creates a not a group error
 func NewNotAGroupError(name string) error {
 	return &InvalidTypeError{
@@ -283,3 +648,93 @@ func WrapReadGroupDirError(err error) error {
 func WrapDeleteGroupDirError(err error) error {
 	return &OperationError{Op: "delete group directory", Err: err}
 }
+
+// WrapListReferencesError wraps an error when listing references
+func WrapListReferencesError(err error) error {
+	return &OperationError{Op: "list references", Err: err}
+}
+
+// WrapSetReferenceError wraps an error when setting a reference
+func WrapSetReferenceError(err error) error {
+	return &OperationError{Op: "set reference", Err: err}
+}
+
+// WrapCollectObjectsError wraps an error when collecting reachable objects
+func WrapCollectObjectsError(err error) error {
+	return &OperationError{Op: "collect reachable objects", Err: err}
+}
+
+// WrapEncodePackfileError wraps an error when encoding a packfile
+func WrapEncodePackfileError(err error) error {
+	return &OperationError{Op: "encode packfile", Err: err}
+}
+
+// WrapWritePackfileError wraps an error when writing a packfile to object storage
+func WrapWritePackfileError(err error) error {
+	return &OperationError{Op: "write packfile", Err: err}
+}
+
+// WrapWriteArchiveError wraps an error when writing a backup archive
+func WrapWriteArchiveError(err error) error {
+	return &OperationError{Op: "write archive", Err: err}
+}
+
+// WrapReadArchiveError wraps an error when reading a backup archive
+func WrapReadArchiveError(err error) error {
+	return &OperationError{Op: "read archive", Err: err}
+}
+
+// WrapStoreObjectError wraps an error when storing a git object
+func WrapStoreObjectError(err error) error {
+	return &OperationError{Op: "store object", Err: err}
+}
+
+// WrapReadObjectError wraps an error when reading a git object
+func WrapReadObjectError(err error) error {
+	return &OperationError{Op: "read object", Err: err}
+}
+
+// WrapGetBranchError wraps an error when getting a branch
+func WrapGetBranchError(err error) error {
+	return &OperationError{Op: "get branch", Err: err}
+}
+
+// WrapMergeBaseError wraps an error when computing the merge base
+func WrapMergeBaseError(err error) error {
+	return &OperationError{Op: "compute merge base", Err: err}
+}
+
+// WrapReadSettingsError wraps an error when reading repository or group settings
+func WrapReadSettingsError(err error) error {
+	return &OperationError{Op: "read settings", Err: err}
+}
+
+// WrapWriteSettingsError wraps an error when writing repository or group settings
+func WrapWriteSettingsError(err error) error {
+	return &OperationError{Op: "write settings", Err: err}
+}
+
+// WrapReadPackfileError wraps an error when reading a received packfile
+func WrapReadPackfileError(err error) error {
+	return &OperationError{Op: "read packfile", Err: err}
+}
+
+// WrapReadWebhookDeliveriesError wraps an error when reading the webhook delivery history
+func WrapReadWebhookDeliveriesError(err error) error {
+	return &OperationError{Op: "read webhook deliveries", Err: err}
+}
+
+// WrapWriteWebhookDeliveriesError wraps an error when writing the webhook delivery history
+func WrapWriteWebhookDeliveriesError(err error) error {
+	return &OperationError{Op: "write webhook deliveries", Err: err}
+}
+
+// WrapUpdateServerInfoError wraps an error when regenerating the server info files of a repository
+func WrapUpdateServerInfoError(err error) error {
+	return &OperationError{Op: "update server info", Err: err}
+}
+
+// WrapComputeUsageError wraps an error when computing the disk usage of a repository or group
+func WrapComputeUsageError(err error) error {
+	return &OperationError{Op: "compute usage", Err: err}
+}
@@ -0,0 +1,62 @@
+package repository_manager
+
+import (
+	"time"
+
+	"github.com/weedbox/git-modules/events"
+)
+
+// publishEvent publishes an event on the bus, if one is provided
+func (m *RepositoryManager) publishEvent(event events.Event) {
+	m.params.Bus.Publish(event)
+}
+
+// publishReferenceUpdates publishes a RefsPushed event for updated references,
+// together with TagCreated and TagDeleted events for the updated tags
+func (m *RepositoryManager) publishReferenceUpdates(repoName, pusher string, updates []ReferenceUpdate) {
+	if len(updates) == 0 {
+		return
+	}
+
+	now := time.Now()
+	pushed := events.RefsPushed{
+		Repository: repoName,
+		Pusher:     pusher,
+		Updates:    make([]events.RefUpdate, 0, len(updates)),
+		Time:       now,
+	}
+
+	for _, update := range updates {
+		pushed.Updates = append(pushed.Updates, events.RefUpdate{
+			Name:    update.Name,
+			OldHash: update.OldHash,
+			NewHash: update.NewHash,
+		})
+	}
+	m.publishEvent(pushed)
+
+	for _, update := range updates {
+		if !update.Name.IsTag() {
+			continue
+		}
+
+		if update.IsDelete() {
+			m.publishEvent(events.TagDeleted{
+				Repository: repoName,
+				Tag:        update.Name.Short(),
+				Hash:       update.OldHash,
+				Actor:      pusher,
+				Time:       now,
+			})
+			continue
+		}
+
+		m.publishEvent(events.TagCreated{
+			Repository: repoName,
+			Tag:        update.Name.Short(),
+			Hash:       update.NewHash,
+			Actor:      pusher,
+			Time:       now,
+		})
+	}
+}
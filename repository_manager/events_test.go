@@ -0,0 +1,72 @@
+package repository_manager
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/weedbox/git-modules/events"
+	"go.uber.org/zap"
+)
+
+// Test that repository lifecycle and reference updates are published on the event bus
+func TestEvents(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	bus := events.NewBus(zap.NewNop())
+	manager.params.Bus = bus
+
+	var received []events.Event
+	bus.Subscribe(func(event events.Event) {
+		received = append(received, event)
+	})
+
+	if _, err := manager.CreateGroup("org", ""); err != nil {
+		t.Fatalf("Failed to create group: %v", err)
+	}
+	if _, err := manager.CreateRepository("org/app", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	commit := commitTestFile(t, manager, "org/app", "master", "a.txt", "a")
+	if _, err := manager.CreateTag("org/app", "v1.0.0", commit.String(), "", "john"); err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+	if err := manager.DeleteTag("org/app", "v1.0.0"); err != nil {
+		t.Fatalf("Failed to delete tag: %v", err)
+	}
+	if err := manager.DeleteRepository("org/app"); err != nil {
+		t.Fatalf("Failed to delete repository: %v", err)
+	}
+
+	// Close waits until the subscriber has handled all events
+	bus.Close()
+
+	names := make([]string, 0, len(received))
+	for _, event := range received {
+		names = append(names, event.EventName())
+	}
+
+	expected := []string{
+		events.NameGroupCreated,
+		events.NameRepositoryCreated,
+		events.NameRefsPushed,
+		events.NameTagCreated,
+		events.NameRefsPushed,
+		events.NameTagDeleted,
+		events.NameRepositoryDeleted,
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("Expected events %v, got %v", expected, names)
+	}
+
+	// The tagger is recorded in the tag, not reported as the actor
+	created := received[3].(events.TagCreated)
+	if created.Repository != "org/app" || created.Tag != "v1.0.0" || created.Hash != commit || created.Actor != "" {
+		t.Errorf("Unexpected TagCreated event %+v", created)
+	}
+
+	pushed := received[4].(events.RefsPushed)
+	if len(pushed.Updates) != 1 || !pushed.Updates[0].NewHash.IsZero() || pushed.Updates[0].OldHash != commit {
+		t.Errorf("Unexpected RefsPushed event %+v", pushed)
+	}
+}
@@ -0,0 +1,108 @@
+package repository_manager
+
+import (
+	"slices"
+
+	"go.uber.org/zap"
+)
+
+// Object filter kinds clients may request for partial clones, named as by git
+const (
+	FilterBlobNone   = "blob:none"
+	FilterBlobLimit  = "blob:limit"
+	FilterTree       = "tree"
+	FilterSparseOID  = "sparse:oid"
+	FilterObjectType = "object:type"
+	FilterCombine    = "combine"
+)
+
+// filterKinds are the valid filter kinds of a filter policy
+var filterKinds = []string{FilterBlobNone, FilterBlobLimit, FilterTree, FilterSparseOID, FilterObjectType, FilterCombine}
+
+// GetFilterPolicy returns the partial clone filter policy of a repository.
+// Repositories without a policy allow every filter kind.
+func (m *RepositoryManager) GetFilterPolicy(repoName string) (*FilterPolicy, error) {
+	if !m.IsRepository(repoName) {
+		return nil, NewRepositoryNotFoundError(repoName)
+	}
+
+	settings, err := m.loadSettings(repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.Filters == nil {
+		return &FilterPolicy{}, nil
+	}
+
+	return settings.Filters, nil
+}
+
+// SetFilterPolicy replaces the partial clone filter policy of a repository
+func (m *RepositoryManager) SetFilterPolicy(repoName string, policy FilterPolicy) (*FilterPolicy, error) {
+	if !m.IsRepository(repoName) {
+		return nil, NewRepositoryNotFoundError(repoName)
+	}
+
+	for _, kind := range append(slices.Clone(policy.Allow), policy.Deny...) {
+		if !slices.Contains(filterKinds, kind) {
+			return nil, ErrFilterKindInvalid
+		}
+	}
+
+	err := m.updateSettings(repoName, func(settings *Settings) error {
+		settings.Filters = &policy
+		if len(policy.Allow) == 0 && len(policy.Deny) == 0 {
+			settings.Filters = nil
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("Filter policy updated",
+		zap.String("repo", repoName),
+		zap.Strings("allow", policy.Allow),
+		zap.Strings("deny", policy.Deny),
+	)
+
+	return &policy, nil
+}
+
+// Allows reports whether the policy allows a filter kind
+func (p *FilterPolicy) Allows(kind string) bool {
+	if slices.Contains(p.Deny, kind) {
+		return false
+	}
+
+	return len(p.Allow) == 0 || slices.Contains(p.Allow, kind)
+}
+
+// UploadPackConfig returns the git configuration, as key=value entries, for git upload-pack to serve
+// partial clones of a repository under its filter policy. Clients fetch the objects left out by a
+// filter later on, so objects reachable from references may be requested without being advertised.
+func (m *RepositoryManager) UploadPackConfig(repoName string) ([]string, error) {
+	policy, err := m.GetFilterPolicy(repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	config := []string{
+		"uploadpack.allowFilter=true",
+		"uploadpack.allowReachableSHA1InWant=true",
+	}
+
+	if len(policy.Allow) == 0 && len(policy.Deny) == 0 {
+		return config, nil
+	}
+
+	config = append(config, "uploadpackfilter.allow=false")
+	for _, kind := range filterKinds {
+		if policy.Allows(kind) {
+			config = append(config, "uploadpackfilter."+kind+".allow=true")
+		}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,51 @@
+package repository_manager
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// Test filter policies and the upload-pack configuration derived from them
+func TestFilterPolicy(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	if _, err := manager.CreateRepository("org/app", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	config, err := manager.UploadPackConfig("org/app")
+	if err != nil {
+		t.Fatalf("Failed to get upload-pack config: %v", err)
+	}
+	if !slices.Contains(config, "uploadpack.allowFilter=true") || slices.Contains(config, "uploadpackfilter.allow=false") {
+		t.Errorf("Expected every filter to be allowed without a policy, got %v", config)
+	}
+
+	if _, err := manager.SetFilterPolicy("org/app", FilterPolicy{Allow: []string{"blob:everything"}}); !errors.Is(err, ErrFilterKindInvalid) {
+		t.Errorf("Expected ErrFilterKindInvalid, got %v", err)
+	}
+	if _, err := manager.SetFilterPolicy("org", FilterPolicy{}); err == nil {
+		t.Error("Expected filter policies to be refused on groups")
+	}
+
+	policy := FilterPolicy{Allow: []string{FilterBlobNone, FilterBlobLimit}, Deny: []string{FilterBlobLimit}}
+	if _, err := manager.SetFilterPolicy("org/app", policy); err != nil {
+		t.Fatalf("Failed to set filter policy: %v", err)
+	}
+
+	config, err = manager.UploadPackConfig("org/app")
+	if err != nil {
+		t.Fatalf("Failed to get upload-pack config: %v", err)
+	}
+	expected := []string{
+		"uploadpack.allowFilter=true",
+		"uploadpack.allowReachableSHA1InWant=true",
+		"uploadpackfilter.allow=false",
+		"uploadpackfilter.blob:none.allow=true",
+	}
+	if !slices.Equal(config, expected) {
+		t.Errorf("Expected config %v, got %v", expected, config)
+	}
+}
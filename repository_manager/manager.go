@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/go-git/go-billy/v5/osfs"
 	"github.com/go-git/go-git/v5"
@@ -11,11 +12,24 @@ import (
 	"github.com/go-git/go-git/v5/plumbing/cache"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/weedbox/git-modules/events"
 	"go.uber.org/zap"
 )
 
 // CreateRepository creates a new Git repository
 func (m *RepositoryManager) CreateRepository(name, description string) (*Repository, error) {
+	repository, err := m.createRepository(name, description)
+	if err != nil {
+		return nil, err
+	}
+
+	m.publishRepositoryCreated(name)
+	return repository, nil
+}
+
+// createRepository creates a new bare repository without announcing it,
+// so callers filling it can publish the creation once the repository is complete
+func (m *RepositoryManager) createRepository(name, description string) (*Repository, error) {
 	// Validate repository name
 	if name == "" {
 		return nil, ErrRepositoryNameEmpty
@@ -92,6 +106,12 @@ func (m *RepositoryManager) CreateRepository(name, description string) (*Reposit
 	return repository, nil
 }
 
+// publishRepositoryCreated announces a new repository to event subscribers and webhooks
+func (m *RepositoryManager) publishRepositoryCreated(name string) {
+	m.publishEvent(events.RepositoryCreated{Repository: name, Time: time.Now()})
+	m.dispatchWebhookEvent(WebhookPayload{Event: WebhookEventRepositoryCreate, Repository: name})
+}
+
 // GetReposPath returns the base path where repositories are stored
 func (m *RepositoryManager) GetReposPath() string {
 	return m.reposPath
@@ -111,6 +131,12 @@ func (m *RepositoryManager) DeleteRepository(name string) error {
 		return NewRepositoryNotFoundError(name)
 	}
 
+	// Webhooks are stored in the repository settings, collect them before they are removed
+	webhooks, err := m.effectiveWebhooks(name)
+	if err != nil {
+		m.logger.Warn("Failed to load webhooks", zap.String("repo", name), zap.Error(err))
+	}
+
 	// Delete filesystem directory
 	if err := os.RemoveAll(repoPath); err != nil {
 		m.logger.Error("Failed to delete repository directory", zap.String("path", repoPath), zap.Error(err))
@@ -118,6 +144,9 @@ func (m *RepositoryManager) DeleteRepository(name string) error {
 	}
 
 	m.logger.Info("Repository deleted", zap.String("name", name), zap.String("path", repoPath))
+
+	m.publishEvent(events.RepositoryDeleted{Repository: name, Time: time.Now()})
+	m.deliverWebhookEvent(webhooks, WebhookPayload{Event: WebhookEventRepositoryDelete, Repository: name})
 	return nil
 }
 
@@ -234,7 +263,9 @@ func (m *RepositoryManager) ListRepositories() ([]Repository, error) {
 	return repos, nil
 }
 
-// CreateTag creates a Git tag
+// CreateTag creates a Git tag.
+// The tagger is the free-form identity recorded in annotated tags, not an authenticated pusher,
+// so reference update events and webhooks report no pusher like other API reference changes.
 func (m *RepositoryManager) CreateTag(repoName, tagName, commitHash, message, tagger string) (*Tag, error) {
 	// Validate repository name to prevent path traversal attacks
 	if !isValidRepoName(repoName) {
@@ -274,11 +305,26 @@ func (m *RepositoryManager) CreateTag(repoName, tagName, commitHash, message, ta
 		return nil, WrapCommitNotFoundError(err)
 	}
 
+	// Protected tags are immutable once created
+	unlock := m.LockRepository(repoName)
+	defer unlock()
+
+	oldHash := plumbing.ZeroHash
+	if ref, err := repo.Storer.Reference(plumbing.NewTagReferenceName(tagName)); err == nil {
+		if err := m.checkTagWritable(repoName, tagName); err != nil {
+			return nil, err
+		}
+		oldHash = ref.Hash()
+	}
+
 	tag := &Tag{
 		Name:       tagName,
 		CommitHash: hash.String(),
 	}
 
+	// The tag reference points to the tag object for annotated tags
+	refHash := hash
+
 	// Create tag
 	if message != "" {
 		// Annotated tag
@@ -318,6 +364,7 @@ func (m *RepositoryManager) CreateTag(repoName, tagName, commitHash, message, ta
 		if err := repo.Storer.SetReference(tagRef); err != nil {
 			return nil, WrapSetTagRefError(err)
 		}
+		refHash = tagHash
 
 		tag.Type = "annotated"
 		tag.Message = message
@@ -337,6 +384,13 @@ func (m *RepositoryManager) CreateTag(repoName, tagName, commitHash, message, ta
 	}
 
 	m.logger.Info("Tag created", zap.String("repo", repoName), zap.String("tag", tagName), zap.String("commit", hash.String()))
+
+	m.NotifyReferenceUpdates(repoName, "", []ReferenceUpdate{{
+		Name:    plumbing.NewTagReferenceName(tagName),
+		OldHash: oldHash,
+		NewHash: refHash,
+	}})
+
 	return tag, nil
 }
 
@@ -361,13 +415,35 @@ func (m *RepositoryManager) DeleteTag(repoName, tagName string) error {
 		return WrapOpenRepoError(err)
 	}
 
+	// Protected tags cannot be deleted
+	unlock := m.LockRepository(repoName)
+	defer unlock()
+
+	if err := m.checkTagWritable(repoName, tagName); err != nil {
+		return err
+	}
+
 	// Delete tag reference
 	tagRef := plumbing.NewTagReferenceName(tagName)
+	oldHash := plumbing.ZeroHash
+	if ref, err := repo.Storer.Reference(tagRef); err == nil {
+		oldHash = ref.Hash()
+	}
+
 	if err := repo.Storer.RemoveReference(tagRef); err != nil {
 		return WrapDeleteTagError(err)
 	}
 
 	m.logger.Info("Tag deleted", zap.String("repo", repoName), zap.String("tag", tagName))
+
+	if !oldHash.IsZero() {
+		m.NotifyReferenceUpdates(repoName, "", []ReferenceUpdate{{
+			Name:    tagRef,
+			OldHash: oldHash,
+			NewHash: plumbing.ZeroHash,
+		}})
+	}
+
 	return nil
 }
 
@@ -476,6 +552,24 @@ func (m *RepositoryManager) ListTags(repoName string) ([]Tag, error) {
 	return tags, nil
 }
 
+// openRepository opens an existing bare repository by name
+func (m *RepositoryManager) openRepository(name string) (*git.Repository, error) {
+	repoPath := filepath.Join(m.reposPath, name+".git")
+
+	if _, err := os.Stat(repoPath); os.IsNotExist(err) {
+		return nil, NewRepositoryNotFoundError(name)
+	}
+
+	fs := osfs.New(repoPath)
+	storer := filesystem.NewStorage(fs, cache.NewObjectLRUDefault())
+	repo, err := git.Open(storer, fs)
+	if err != nil {
+		return nil, WrapOpenRepoError(err)
+	}
+
+	return repo, nil
+}
+
 // isValidRepoName checks if the repository name is valid
 // Supports multi-level paths like "username/repo" or "group/project/repo"
 func isValidRepoName(name string) bool {
@@ -569,6 +663,8 @@ func (m *RepositoryManager) CreateGroup(name, description string) (*Group, error
 	}
 
 	m.logger.Info("Group created", zap.String("name", name), zap.String("path", groupPath))
+
+	m.publishEvent(events.GroupCreated{Group: name, Time: time.Now()})
 	return group, nil
 }
 
@@ -699,14 +795,14 @@ func (m *RepositoryManager) DeleteGroup(name string) error {
 		return NewNotAGroupError(name)
 	}
 
-	// Check if group is empty (contains only .groupinfo file or is completely empty)
+	// Check if group is empty (contains only .groupinfo/.groupsettings/.webhookdeliveries files or is completely empty)
 	entries, err := os.ReadDir(groupPath)
 	if err != nil {
 		return WrapReadGroupDirError(err)
 	}
 
 	for _, entry := range entries {
-		if entry.Name() != ".groupinfo" {
+		if entry.Name() != ".groupinfo" && entry.Name() != groupSettingsFile && entry.Name() != groupDeliveriesFile {
 			return NewGroupNotEmptyError(name)
 		}
 	}
@@ -19,8 +19,13 @@ func setupTestManager(t *testing.T) (*RepositoryManager, string) {
 	logger, _ := zap.NewDevelopment()
 
 	manager := &RepositoryManager{
-		logger:    logger,
-		reposPath: tmpDir,
+		logger:            logger,
+		reposPath:         tmpDir,
+		quotaUsages:       make(map[string]cachedUsage),
+		quotaReservations: make(map[string]int64),
+
+		// Test receivers listen on the loopback interface
+		webhookAllowedHosts: []string{"127.0.0.1"},
 	}
 
 	return manager, tmpDir
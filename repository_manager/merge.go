@@ -0,0 +1,475 @@
+package repository_manager
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"go.uber.org/zap"
+)
+
+// Merge strategies supported by Merge
+const (
+	MergeStrategyFastForward = "fast-forward"
+	MergeStrategyMerge       = "merge"
+	MergeStrategySquash      = "squash"
+)
+
+// Merge merges the head revision into the base branch using the given strategy.
+//   - fast-forward: moves base to head, fails if base has diverged
+//   - merge: creates a merge commit with base and head as parents
+//   - squash: creates a single commit on base containing the changes of head
+//
+// Conflicting changes are detected with a three-way merge against the merge base;
+// in that case a MergeConflictError listing the conflicting paths is returned and
+// the base branch is left untouched.
+// Branch protection rules refuse the merge with a ForbiddenError, as they would refuse a push by req.Pusher.
+func (m *RepositoryManager) Merge(repoName string, req MergeRequest) (*MergeResult, error) {
+	// Validate repository name to prevent path traversal attacks
+	if !isValidRepoName(repoName) {
+		return nil, ErrRepositoryInvalidName
+	}
+
+	if req.Strategy == "" {
+		req.Strategy = MergeStrategyMerge
+	}
+
+	switch req.Strategy {
+	case MergeStrategyFastForward, MergeStrategyMerge, MergeStrategySquash:
+	default:
+		return nil, ErrMergeStrategyInvalid
+	}
+
+	if req.Base == "" || req.Head == "" {
+		return nil, ErrBranchInvalidName
+	}
+
+	unlock := m.LockRepository(repoName)
+	defer unlock()
+
+	repo, err := m.openRepository(repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	baseBranch, baseCommit, err := resolveBranchTip(repo, req.Base)
+	if err != nil {
+		return nil, err
+	}
+	if baseCommit == nil {
+		return nil, NewBranchNotFoundError(req.Base)
+	}
+
+	headHash, err := repo.ResolveRevision(plumbing.Revision(req.Head))
+	if err != nil {
+		return nil, NewRevisionNotFoundError(req.Head)
+	}
+
+	headCommit, err := repo.CommitObject(*headHash)
+	if err != nil {
+		return nil, WrapCommitNotFoundError(err)
+	}
+
+	result := &MergeResult{
+		Base:     req.Base,
+		Head:     req.Head,
+		Strategy: req.Strategy,
+	}
+
+	// Nothing to do if head is already contained in base
+	if merged, err := headCommit.IsAncestor(baseCommit); err != nil {
+		return nil, WrapMergeBaseError(err)
+	} else if merged {
+		result.UpToDate = true
+		result.CommitHash = baseCommit.Hash.String()
+		return result, nil
+	}
+
+	canFastForward, err := baseCommit.IsAncestor(headCommit)
+	if err != nil {
+		return nil, WrapMergeBaseError(err)
+	}
+
+	if req.Strategy == MergeStrategyFastForward {
+		if !canFastForward {
+			return nil, NewNotFastForwardError(req.Base, req.Head)
+		}
+
+		if err := m.updateReference(repoName, req.Pusher, repo, baseBranch, headCommit.Hash, baseCommit.Hash); err != nil {
+			return nil, err
+		}
+
+		result.FastForward = true
+		result.CommitHash = headCommit.Hash.String()
+		m.logger.Info("Branch fast-forwarded", zap.String("repo", repoName), zap.String("base", req.Base), zap.String("head", req.Head))
+		return result, nil
+	}
+
+	// Three-way merge of the trees
+	bases, err := baseCommit.MergeBase(headCommit)
+	if err != nil {
+		return nil, WrapMergeBaseError(err)
+	}
+	if len(bases) == 0 {
+		return nil, NewUnrelatedHistoriesError(req.Base, req.Head)
+	}
+
+	treeHash, conflicts, err := mergeTrees(repo.Storer, bases[0], baseCommit, headCommit)
+	if err != nil {
+		return nil, err
+	}
+	if len(conflicts) > 0 {
+		return nil, NewMergeConflictError(conflicts)
+	}
+
+	message := req.Message
+	if message == "" {
+		if req.Strategy == MergeStrategySquash {
+			message = fmt.Sprintf("Squash merge '%s' into %s", req.Head, baseBranch.Short())
+		} else {
+			message = fmt.Sprintf("Merge '%s' into %s", req.Head, baseBranch.Short())
+		}
+	}
+
+	sig := m.signature(req.AuthorName, req.AuthorEmail)
+	commit := &object.Commit{
+		Author:       sig,
+		Committer:    sig,
+		Message:      strings.TrimRight(message, "\n") + "\n",
+		TreeHash:     treeHash,
+		ParentHashes: []plumbing.Hash{baseCommit.Hash},
+	}
+	if req.Strategy == MergeStrategyMerge {
+		commit.ParentHashes = append(commit.ParentHashes, headCommit.Hash)
+	}
+
+	commitHash, err := storeCommit(repo.Storer, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.updateReference(repoName, req.Pusher, repo, baseBranch, commitHash, baseCommit.Hash); err != nil {
+		return nil, err
+	}
+
+	result.CommitHash = commitHash.String()
+	m.logger.Info("Branches merged",
+		zap.String("repo", repoName),
+		zap.String("base", req.Base),
+		zap.String("head", req.Head),
+		zap.String("strategy", req.Strategy),
+		zap.String("commit", commitHash.String()),
+	)
+
+	return result, nil
+}
+
+// mergeTrees performs a three-way merge of the trees of ours and theirs against ancestor.
+// It returns the merged tree, or the sorted list of conflicting paths.
+func mergeTrees(s storer.EncodedObjectStorer, ancestor, ours, theirs *object.Commit) (plumbing.Hash, []string, error) {
+	ancestorFiles, err := flattenCommitTree(ancestor)
+	if err != nil {
+		return plumbing.ZeroHash, nil, err
+	}
+
+	ourFiles, err := flattenCommitTree(ours)
+	if err != nil {
+		return plumbing.ZeroHash, nil, err
+	}
+
+	theirFiles, err := flattenCommitTree(theirs)
+	if err != nil {
+		return plumbing.ZeroHash, nil, err
+	}
+
+	paths := make(map[string]bool)
+	for _, files := range []map[string]object.TreeEntry{ancestorFiles, ourFiles, theirFiles} {
+		for path := range files {
+			paths[path] = true
+		}
+	}
+
+	merged := make(map[string]object.TreeEntry)
+	conflicts := make([]string, 0)
+
+	for path := range paths {
+		o, inO := ancestorFiles[path]
+		a, inA := ourFiles[path]
+		b, inB := theirFiles[path]
+
+		switch {
+		case inA == inB && (!inA || a == b):
+			// Both sides agree
+			if inA {
+				merged[path] = a
+			}
+		case inO && inA && a == o:
+			// Only theirs changed
+			if inB {
+				merged[path] = b
+			}
+		case inO && inB && b == o:
+			// Only ours changed
+			if inA {
+				merged[path] = a
+			}
+		case !inO && inA != inB:
+			// Added on one side only
+			if inA {
+				merged[path] = a
+			} else {
+				merged[path] = b
+			}
+		case inO && inA && inB && a.Mode == b.Mode:
+			// Both sides modified the content, try a line based merge
+			entry, ok, err := mergeFileContents(s, o, a, b)
+			if err != nil {
+				return plumbing.ZeroHash, nil, err
+			}
+			if !ok {
+				conflicts = append(conflicts, path)
+				continue
+			}
+			merged[path] = entry
+		default:
+			// Modify/delete, add/add or mode conflicts
+			conflicts = append(conflicts, path)
+		}
+	}
+
+	// A path cannot be a file on one side and a directory on the other
+	for path := range merged {
+		for dir := parentDir(path); dir != ""; dir = parentDir(dir) {
+			if _, ok := merged[dir]; ok {
+				conflicts = append(conflicts, dir)
+			}
+		}
+	}
+
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return plumbing.ZeroHash, dedupeSorted(conflicts), nil
+	}
+
+	changes := make([]treeChange, 0, len(merged))
+	for path, entry := range merged {
+		changes = append(changes, treeChange{Path: path, Blob: entry.Hash, Mode: entry.Mode})
+	}
+
+	treeHash, _, err := applyTreeChanges(s, nil, changes)
+	if err != nil {
+		return plumbing.ZeroHash, nil, err
+	}
+
+	return treeHash, nil, nil
+}
+
+// mergeFileContents merges the text content of a file changed on both sides.
+// It returns false if the changes overlap or the file is binary.
+func mergeFileContents(s storer.EncodedObjectStorer, ancestor, ours, theirs object.TreeEntry) (object.TreeEntry, bool, error) {
+	if ours.Mode == filemode.Submodule {
+		return object.TreeEntry{}, false, nil
+	}
+
+	contents := make([]string, 0, 3)
+	for _, entry := range []object.TreeEntry{ancestor, ours, theirs} {
+		blob, err := object.GetBlob(s, entry.Hash)
+		if err != nil {
+			return object.TreeEntry{}, false, WrapReadObjectError(err)
+		}
+
+		reader, err := blob.Reader()
+		if err != nil {
+			return object.TreeEntry{}, false, WrapReadObjectError(err)
+		}
+
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return object.TreeEntry{}, false, WrapReadObjectError(err)
+		}
+
+		// Binary files are never merged
+		if bytes.IndexByte(data, 0) >= 0 {
+			return object.TreeEntry{}, false, nil
+		}
+
+		contents = append(contents, string(data))
+	}
+
+	mergedContent, ok := mergeText(contents[0], contents[1], contents[2])
+	if !ok {
+		return object.TreeEntry{}, false, nil
+	}
+
+	hash, err := storeBlob(s, []byte(mergedContent))
+	if err != nil {
+		return object.TreeEntry{}, false, err
+	}
+
+	return object.TreeEntry{Name: ours.Name, Mode: ours.Mode, Hash: hash}, true, nil
+}
+
+// textHunk is a replacement of the ancestor lines [start, end) with new lines
+type textHunk struct {
+	start int
+	end   int
+	lines []string
+}
+
+// mergeText performs a line based three-way merge.
+// Changes of both sides are combined if they do not touch the same or adjacent ancestor lines.
+func mergeText(ancestor, ours, theirs string) (string, bool) {
+	ourHunks := textHunks(ancestor, ours)
+	theirHunks := textHunks(ancestor, theirs)
+
+	hunks := make([]textHunk, 0, len(ourHunks)+len(theirHunks))
+	hunks = append(hunks, ourHunks...)
+
+	for _, theirs := range theirHunks {
+		duplicate := false
+		for _, ours := range ourHunks {
+			if theirs.start <= ours.end && ours.start <= theirs.end {
+				// Identical changes on both sides are fine, anything else overlapping conflicts
+				if theirs.start == ours.start && theirs.end == ours.end && equalLines(theirs.lines, ours.lines) {
+					duplicate = true
+					break
+				}
+				return "", false
+			}
+		}
+		if !duplicate {
+			hunks = append(hunks, theirs)
+		}
+	}
+
+	sort.Slice(hunks, func(i, j int) bool {
+		return hunks[i].start < hunks[j].start
+	})
+
+	ancestorLines := splitLines(ancestor)
+
+	var out strings.Builder
+	pos := 0
+	for _, hunk := range hunks {
+		out.WriteString(strings.Join(ancestorLines[pos:hunk.start], ""))
+		out.WriteString(strings.Join(hunk.lines, ""))
+		pos = hunk.end
+	}
+	out.WriteString(strings.Join(ancestorLines[pos:], ""))
+
+	return out.String(), true
+}
+
+// textHunks returns the changes turning src into dst as hunks on the lines of src
+func textHunks(src, dst string) []textHunk {
+	hunks := make([]textHunk, 0)
+
+	var current *textHunk
+	pos := 0
+	for _, d := range diff.Do(src, dst) {
+		lines := splitLines(d.Text)
+
+		if d.Type == diffmatchpatch.DiffEqual {
+			if current != nil {
+				hunks = append(hunks, *current)
+				current = nil
+			}
+			pos += len(lines)
+			continue
+		}
+
+		if current == nil {
+			current = &textHunk{start: pos, end: pos}
+		}
+
+		if d.Type == diffmatchpatch.DiffDelete {
+			pos += len(lines)
+			current.end = pos
+		} else {
+			current.lines = append(current.lines, lines...)
+		}
+	}
+
+	if current != nil {
+		hunks = append(hunks, *current)
+	}
+
+	return hunks
+}
+
+// flattenCommitTree returns all non-directory entries of a commit tree keyed by path
+func flattenCommitTree(commit *object.Commit) (map[string]object.TreeEntry, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, WrapCommitNotFoundError(err)
+	}
+
+	files := make(map[string]object.TreeEntry)
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+
+	for {
+		path, entry, err := walker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, WrapReadObjectError(err)
+		}
+
+		if entry.Mode == filemode.Dir {
+			continue
+		}
+		files[path] = entry
+	}
+
+	return files, nil
+}
+
+// splitLines splits text into lines, keeping the line terminators
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func parentDir(path string) string {
+	idx := strings.LastIndex(path, "/")
+	if idx < 0 {
+		return ""
+	}
+	return path[:idx]
+}
+
+func dedupeSorted(values []string) []string {
+	result := values[:0]
+	for i, v := range values {
+		if i == 0 || v != values[i-1] {
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,187 @@
+package repository_manager
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Helper function to create a repository with a develop branch forked from master
+func setupMergeRepository(t *testing.T, manager *RepositoryManager, files map[string]string) {
+	if _, err := manager.CreateRepository("app", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	actions := make([]FileAction, 0, len(files))
+	for path, content := range files {
+		actions = append(actions, FileAction{Action: FileActionCreate, Path: path, Content: []byte(content)})
+	}
+
+	result, err := manager.CommitFiles("app", CommitRequest{Message: "Initial files", Actions: actions})
+	if err != nil {
+		t.Fatalf("Failed to create initial commit: %v", err)
+	}
+
+	repo, err := manager.openRepository("app")
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+
+	ref := plumbing.NewHashReference(plumbing.NewBranchReferenceName("develop"), plumbing.NewHash(result.CommitHash))
+	if err := repo.Storer.SetReference(ref); err != nil {
+		t.Fatalf("Failed to create develop branch: %v", err)
+	}
+}
+
+// Helper function to commit file updates on a branch
+func commitTestUpdates(t *testing.T, manager *RepositoryManager, branch string, files map[string]string) {
+	actions := make([]FileAction, 0, len(files))
+	for path, content := range files {
+		actions = append(actions, FileAction{Action: FileActionUpdate, Path: path, Content: []byte(content)})
+	}
+
+	if _, err := manager.CommitFiles("app", CommitRequest{Branch: branch, Message: "Update on " + branch, Actions: actions}); err != nil {
+		t.Fatalf("Failed to commit on %s: %v", branch, err)
+	}
+}
+
+// Test fast-forward merges and the up-to-date case
+func TestMerge_FastForward(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	setupMergeRepository(t, manager, map[string]string{"a.txt": "a\n"})
+	commitTestUpdates(t, manager, "develop", map[string]string{"a.txt": "a2\n"})
+
+	result, err := manager.Merge("app", MergeRequest{Base: "master", Head: "develop", Strategy: MergeStrategyFastForward})
+	if err != nil {
+		t.Fatalf("Failed to fast-forward: %v", err)
+	}
+	if !result.FastForward {
+		t.Error("Expected a fast-forward merge")
+	}
+
+	file, err := manager.GetFile("app", "master", "a.txt")
+	if err != nil {
+		t.Fatalf("Failed to get file: %v", err)
+	}
+	if string(file.Content) != "a2\n" {
+		t.Errorf("Expected merged content, got %q", file.Content)
+	}
+
+	result, err = manager.Merge("app", MergeRequest{Base: "master", Head: "develop"})
+	if err != nil {
+		t.Fatalf("Failed to merge up-to-date branches: %v", err)
+	}
+	if !result.UpToDate {
+		t.Error("Expected branches to be up to date")
+	}
+
+	// Diverged branches cannot be fast-forwarded
+	commitTestUpdates(t, manager, "master", map[string]string{"a.txt": "a3\n"})
+	commitTestUpdates(t, manager, "develop", map[string]string{"a.txt": "a4\n"})
+
+	_, err = manager.Merge("app", MergeRequest{Base: "master", Head: "develop", Strategy: MergeStrategyFastForward})
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Errorf("Expected ConflictError for diverged branches, got %v", err)
+	}
+}
+
+// Test merge commits and squash merges of non-overlapping changes
+func TestMerge_Strategies(t *testing.T) {
+	for _, strategy := range []string{MergeStrategyMerge, MergeStrategySquash} {
+		t.Run(strategy, func(t *testing.T) {
+			manager, tmpDir := setupTestManager(t)
+			defer teardownTestManager(tmpDir)
+
+			setupMergeRepository(t, manager, map[string]string{
+				"a.txt": "one\ntwo\nthree\nfour\nfive\n",
+				"b.txt": "b\n",
+			})
+			commitTestUpdates(t, manager, "master", map[string]string{"a.txt": "ONE\ntwo\nthree\nfour\nfive\n"})
+			commitTestUpdates(t, manager, "develop", map[string]string{
+				"a.txt": "one\ntwo\nthree\nfour\nFIVE\n",
+				"b.txt": "b2\n",
+			})
+
+			result, err := manager.Merge("app", MergeRequest{Base: "master", Head: "develop", Strategy: strategy})
+			if err != nil {
+				t.Fatalf("Failed to merge: %v", err)
+			}
+
+			repo, _ := manager.openRepository("app")
+			commit, err := repo.CommitObject(plumbing.NewHash(result.CommitHash))
+			if err != nil {
+				t.Fatalf("Failed to read merge commit: %v", err)
+			}
+
+			expectedParents := 2
+			if strategy == MergeStrategySquash {
+				expectedParents = 1
+			}
+			if commit.NumParents() != expectedParents {
+				t.Errorf("Expected %d parents, got %d", expectedParents, commit.NumParents())
+			}
+
+			expected := map[string]string{"a.txt": "ONE\ntwo\nthree\nfour\nFIVE\n", "b.txt": "b2\n"}
+			for path, content := range expected {
+				file, err := manager.GetFile("app", "master", path)
+				if err != nil {
+					t.Fatalf("Failed to get %s: %v", path, err)
+				}
+				if string(file.Content) != content {
+					t.Errorf("Expected %s to contain %q, got %q", path, content, file.Content)
+				}
+			}
+		})
+	}
+}
+
+// Test that conflicting changes are reported and leave the base branch untouched
+func TestMerge_Conflict(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	setupMergeRepository(t, manager, map[string]string{"a.txt": "a\n", "b.txt": "b\n", "c.txt": "c\n"})
+	commitTestUpdates(t, manager, "master", map[string]string{"a.txt": "master\n", "c.txt": "c2\n"})
+	commitTestUpdates(t, manager, "develop", map[string]string{"a.txt": "develop\n", "b.txt": "b2\n"})
+
+	repo, _ := manager.openRepository("app")
+	before, _ := repo.Reference(plumbing.NewBranchReferenceName("master"), true)
+
+	_, err := manager.Merge("app", MergeRequest{Base: "master", Head: "develop"})
+	var conflict *MergeConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected MergeConflictError, got %v", err)
+	}
+	if len(conflict.Paths) != 1 || conflict.Paths[0] != "a.txt" {
+		t.Errorf("Expected conflict on a.txt, got %v", conflict.Paths)
+	}
+
+	after, _ := repo.Reference(plumbing.NewBranchReferenceName("master"), true)
+	if before.Hash() != after.Hash() {
+		t.Error("Expected base branch to be unchanged after a conflict")
+	}
+}
+
+// Test merge request validation
+func TestMerge_Invalid(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	setupMergeRepository(t, manager, map[string]string{"a.txt": "a\n"})
+
+	if _, err := manager.Merge("app", MergeRequest{Base: "master", Head: "develop", Strategy: "rebase"}); err != ErrMergeStrategyInvalid {
+		t.Errorf("Expected ErrMergeStrategyInvalid, got %v", err)
+	}
+
+	if _, err := manager.Merge("app", MergeRequest{Base: "master", Head: "missing"}); err == nil {
+		t.Error("Expected error for unknown head revision")
+	}
+
+	if _, err := manager.Merge("app", MergeRequest{Base: "missing", Head: "develop"}); err == nil {
+		t.Error("Expected error for unknown base branch")
+	}
+}
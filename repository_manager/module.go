@@ -3,22 +3,60 @@ package repository_manager
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"sync"
+	"time"
 
 	"github.com/spf13/viper"
+	"github.com/weedbox/git-modules/events"
+	"github.com/weedbox/git-modules/gpg_keys"
+	"github.com/weedbox/git-modules/ssh_keys"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
 
 const (
-	ModuleName       = "RepositoryManager"
-	DefaultReposPath = "./git/repos"
+	ModuleName         = "RepositoryManager"
+	DefaultReposPath   = "./git/repos"
+	DefaultAuthorName  = "Git Server"
+	DefaultAuthorEmail = "git@localhost"
+
+	DefaultWebhookTimeout      = 10 * time.Second
+	DefaultWebhookMaxAttempts  = 5
+	DefaultWebhookRetryBackoff = time.Second
 )
 
 type RepositoryManager struct {
-	params    Params
-	logger    *zap.Logger
-	scope     string
-	reposPath string
+	params      Params
+	logger      *zap.Logger
+	scope       string
+	reposPath   string
+	authorName  string
+	authorEmail string
+	repoLocks   sync.Map // map[string]*sync.Mutex
+	settingsMu  sync.Mutex
+
+	// Storages of repository data kept outside of reposPath, counted towards quotas
+	externalStorage   []ExternalStorage
+	externalStorageMu sync.Mutex
+
+	// Quota checks, see ReserveQuota
+	quotaMu           sync.Mutex
+	quotaUsages       map[string]cachedUsage
+	quotaReservations map[string]int64
+
+	// Commit signature verifiers by armor header
+	signatureVerifiers map[string]SignatureVerifier
+
+	// Webhook delivery
+	webhookClient       *http.Client
+	webhookAllowedHosts []string
+	webhookMaxAttempts  int
+	webhookRetryBackoff time.Duration
+	webhookCtx          context.Context
+	webhookCancel       context.CancelFunc
+	webhookWG           sync.WaitGroup
+	deliveriesMu        sync.Mutex
 }
 
 type Params struct {
@@ -26,6 +64,13 @@ type Params struct {
 
 	Lifecycle fx.Lifecycle
 	Logger    *zap.Logger
+
+	// Bus receives repository lifecycle and push events, see the events package
+	Bus *events.Bus `optional:"true"`
+
+	// GPGKeys and SSHKeys verify signed commits on protected branches requiring signatures
+	GPGKeys *gpg_keys.KeyStore `optional:"true"`
+	SSHKeys *ssh_keys.KeyStore `optional:"true"`
 }
 
 func Module(scope string) fx.Option {
@@ -39,6 +84,16 @@ func Module(scope string) fx.Option {
 				params: p,
 				logger: p.Logger.Named(scope),
 				scope:  scope,
+
+				signatureVerifiers: make(map[string]SignatureVerifier),
+				quotaUsages:        make(map[string]cachedUsage),
+				quotaReservations:  make(map[string]int64),
+			}
+			if p.GPGKeys != nil {
+				rm.signatureVerifiers[signatureFormatGPG] = p.GPGKeys
+			}
+			if p.SSHKeys != nil {
+				rm.signatureVerifiers[signatureFormatSSH] = p.SSHKeys
 			}
 
 			rm.initDefaultConfigs()
@@ -62,10 +117,24 @@ func Module(scope string) fx.Option {
 func (m *RepositoryManager) onStart(ctx context.Context) error {
 	m.logger.Info("Starting " + ModuleName)
 	m.reposPath = viper.GetString(m.getConfigPath("repos_path"))
+	m.authorName = viper.GetString(m.getConfigPath("author_name"))
+	m.authorEmail = viper.GetString(m.getConfigPath("author_email"))
+
+	m.webhookAllowedHosts = viper.GetStringSlice(m.getConfigPath("webhook_allowed_hosts"))
+	m.webhookClient = newWebhookClient(viper.GetDuration(m.getConfigPath("webhook_timeout")), m.webhookAllowedHosts)
+	m.webhookMaxAttempts = viper.GetInt(m.getConfigPath("webhook_max_attempts"))
+	m.webhookRetryBackoff = viper.GetDuration(m.getConfigPath("webhook_retry_backoff"))
+	m.webhookCtx, m.webhookCancel = context.WithCancel(context.Background())
 	return nil
 }
 
 func (m *RepositoryManager) onStop(ctx context.Context) error {
+	// Abort pending webhook retries and wait for running deliveries
+	if m.webhookCancel != nil {
+		m.webhookCancel()
+	}
+	m.webhookWG.Wait()
+
 	m.logger.Info("Stopped " + ModuleName)
 	return nil
 }
@@ -76,4 +145,10 @@ func (m *RepositoryManager) getConfigPath(key string) string {
 
 func (m *RepositoryManager) initDefaultConfigs() {
 	viper.SetDefault(m.getConfigPath("repos_path"), DefaultReposPath)
+	viper.SetDefault(m.getConfigPath("author_name"), DefaultAuthorName)
+	viper.SetDefault(m.getConfigPath("author_email"), DefaultAuthorEmail)
+	viper.SetDefault(m.getConfigPath("webhook_timeout"), DefaultWebhookTimeout)
+	viper.SetDefault(m.getConfigPath("webhook_max_attempts"), DefaultWebhookMaxAttempts)
+	viper.SetDefault(m.getConfigPath("webhook_retry_backoff"), DefaultWebhookRetryBackoff)
+	viper.SetDefault(m.getConfigPath("webhook_allowed_hosts"), []string{})
 }
@@ -0,0 +1,163 @@
+package repository_manager
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// treeChange describes a modification of a single path in a tree
+type treeChange struct {
+	Path   string            // Slash separated path relative to the tree root
+	Blob   plumbing.Hash     // Blob to store at the path (ignored when Delete is set)
+	Mode   filemode.FileMode // File mode of the blob, defaults to a regular file
+	Delete bool              // Remove the path from the tree
+}
+
+// storeBlob writes content as a blob object
+func storeBlob(s storer.EncodedObjectStorer, content []byte) (plumbing.Hash, error) {
+	obj := s.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	obj.SetSize(int64(len(content)))
+
+	w, err := obj.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, WrapStoreObjectError(err)
+	}
+
+	if _, err := w.Write(content); err != nil {
+		w.Close()
+		return plumbing.ZeroHash, WrapStoreObjectError(err)
+	}
+
+	if err := w.Close(); err != nil {
+		return plumbing.ZeroHash, WrapStoreObjectError(err)
+	}
+
+	hash, err := s.SetEncodedObject(obj)
+	if err != nil {
+		return plumbing.ZeroHash, WrapStoreObjectError(err)
+	}
+
+	return hash, nil
+}
+
+// storeCommit writes a commit object
+func storeCommit(s storer.EncodedObjectStorer, commit *object.Commit) (plumbing.Hash, error) {
+	obj := s.NewEncodedObject()
+	if err := commit.Encode(obj); err != nil {
+		return plumbing.ZeroHash, WrapStoreObjectError(err)
+	}
+
+	hash, err := s.SetEncodedObject(obj)
+	if err != nil {
+		return plumbing.ZeroHash, WrapStoreObjectError(err)
+	}
+
+	return hash, nil
+}
+
+// applyTreeChanges writes a new tree derived from base with the given changes applied.
+// base may be nil to start from an empty tree. Directories left without entries are removed.
+// Files and directories are not replaced by each other: a change below an existing file, or
+// writing a file over an existing directory, yields an *InvalidTypeError.
+// It returns the hash of the new tree and whether the tree is empty.
+func applyTreeChanges(s storer.EncodedObjectStorer, base *object.Tree, changes []treeChange) (plumbing.Hash, bool, error) {
+	return applySubtreeChanges(s, base, changes, "")
+}
+
+// applySubtreeChanges is applyTreeChanges for the tree at dir, which prefixes the paths in errors
+func applySubtreeChanges(s storer.EncodedObjectStorer, base *object.Tree, changes []treeChange, dir string) (plumbing.Hash, bool, error) {
+	entries := make(map[string]object.TreeEntry)
+	if base != nil {
+		for _, entry := range base.Entries {
+			entries[entry.Name] = entry
+		}
+	}
+
+	// Apply changes to direct children and group nested changes by directory
+	nested := make(map[string][]treeChange)
+	for _, change := range changes {
+		name, rest, isNested := strings.Cut(change.Path, "/")
+		if isNested {
+			change.Path = rest
+			nested[name] = append(nested[name], change)
+			continue
+		}
+
+		if change.Delete {
+			delete(entries, name)
+			continue
+		}
+
+		if entry, ok := entries[name]; ok && entry.Mode == filemode.Dir {
+			return plumbing.ZeroHash, false, &InvalidTypeError{Expected: "file", Actual: "directory", Name: path.Join(dir, name)}
+		}
+
+		mode := change.Mode
+		if mode == filemode.Empty {
+			mode = filemode.Regular
+		}
+		entries[name] = object.TreeEntry{Name: name, Mode: mode, Hash: change.Blob}
+	}
+
+	for name, subChanges := range nested {
+		var subTree *object.Tree
+		if entry, ok := entries[name]; ok {
+			if entry.Mode != filemode.Dir {
+				return plumbing.ZeroHash, false, &InvalidTypeError{Expected: "directory", Actual: "file", Name: path.Join(dir, name)}
+			}
+
+			tree, err := object.GetTree(s, entry.Hash)
+			if err != nil {
+				return plumbing.ZeroHash, false, WrapStoreObjectError(err)
+			}
+			subTree = tree
+		}
+
+		hash, empty, err := applySubtreeChanges(s, subTree, subChanges, path.Join(dir, name))
+		if err != nil {
+			return plumbing.ZeroHash, false, err
+		}
+
+		if empty {
+			delete(entries, name)
+			continue
+		}
+		entries[name] = object.TreeEntry{Name: name, Mode: filemode.Dir, Hash: hash}
+	}
+
+	tree := &object.Tree{Entries: make([]object.TreeEntry, 0, len(entries))}
+	for _, entry := range entries {
+		tree.Entries = append(tree.Entries, entry)
+	}
+
+	// Git orders tree entries as if directory names had a trailing slash
+	sort.Slice(tree.Entries, func(i, j int) bool {
+		return treeEntrySortName(tree.Entries[i]) < treeEntrySortName(tree.Entries[j])
+	})
+
+	obj := s.NewEncodedObject()
+	if err := tree.Encode(obj); err != nil {
+		return plumbing.ZeroHash, false, WrapStoreObjectError(err)
+	}
+
+	hash, err := s.SetEncodedObject(obj)
+	if err != nil {
+		return plumbing.ZeroHash, false, WrapStoreObjectError(err)
+	}
+
+	return hash, len(tree.Entries) == 0, nil
+}
+
+func treeEntrySortName(entry object.TreeEntry) string {
+	if entry.Mode == filemode.Dir {
+		return entry.Name + "/"
+	}
+	return entry.Name
+}
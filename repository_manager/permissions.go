@@ -0,0 +1,220 @@
+package repository_manager
+
+import (
+	"github.com/weedbox/git-modules/auth"
+	"go.uber.org/zap"
+)
+
+// Role is the level of access granted on a repository or group.
+// Every role includes the access of the roles below it.
+type Role string
+
+const (
+	// RoleNone grants no access
+	RoleNone Role = ""
+
+	// RoleRead allows cloning, fetching and reading repositories
+	RoleRead Role = "read"
+
+	// RoleWrite additionally allows pushing, committing and creating tags
+	RoleWrite Role = "write"
+
+	// RoleMaintain additionally allows deleting tags and managing protection rules and webhooks
+	RoleMaintain Role = "maintain"
+
+	// RoleAdmin additionally allows deleting repositories and groups and managing permissions
+	RoleAdmin Role = "admin"
+)
+
+// EveryoneUser is the user of permissions applying to every user, including anonymous users
+const EveryoneUser = "*"
+
+// roleLevels orders the roles from least to most privileged
+var roleLevels = map[Role]int{
+	RoleNone:     0,
+	RoleRead:     1,
+	RoleWrite:    2,
+	RoleMaintain: 3,
+	RoleAdmin:    4,
+}
+
+// IsValid reports whether r is one of the roles that can be granted
+func (r Role) IsValid() bool {
+	level, ok := roleLevels[r]
+	return ok && level > 0
+}
+
+// Includes reports whether r grants at least the access of other
+func (r Role) Includes(other Role) bool {
+	return roleLevels[r] >= roleLevels[other]
+}
+
+// ListPermissions returns the permissions granted directly on a repository or group
+func (m *RepositoryManager) ListPermissions(name string) ([]Permission, error) {
+	settings, err := m.loadSettings(name)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make([]Permission, 0, len(settings.Permissions))
+	return append(permissions, settings.Permissions...), nil
+}
+
+// ListEffectivePermissions returns the permissions applying to a repository or group,
+// including the permissions inherited from its parent groups. Source names the group or repository granting each one.
+func (m *RepositoryManager) ListEffectivePermissions(name string) ([]Permission, error) {
+	chain, err := m.settingsChain(name)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make([]Permission, 0)
+	for _, source := range chain {
+		for _, permission := range source.Settings.Permissions {
+			permission.Source = source.Name
+			permissions = append(permissions, permission)
+		}
+	}
+
+	return permissions, nil
+}
+
+// SetPermission grants a role to a user or team on a repository or group,
+// replacing the role previously granted to the same user or team there
+func (m *RepositoryManager) SetPermission(name string, permission Permission) (*Permission, error) {
+	if (permission.User == "") == (permission.Team == "") {
+		return nil, ErrPermissionSubjectInvalid
+	}
+	if !permission.Role.IsValid() {
+		return nil, ErrRoleInvalid
+	}
+	permission.Source = ""
+
+	err := m.updateSettings(name, func(settings *Settings) error {
+		for i, existing := range settings.Permissions {
+			if sameSubject(existing, permission) {
+				settings.Permissions[i] = permission
+				return nil
+			}
+		}
+
+		settings.Permissions = append(settings.Permissions, permission)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("Permission granted",
+		zap.String("name", name),
+		zap.String("subject", permissionSubject(permission)),
+		zap.String("role", string(permission.Role)),
+	)
+	return &permission, nil
+}
+
+// DeletePermission revokes the role granted on a repository or group to the user or team of subject.
+// The role of subject is ignored. Roles inherited from groups are not affected.
+func (m *RepositoryManager) DeletePermission(name string, subject Permission) error {
+	err := m.updateSettings(name, func(settings *Settings) error {
+		for i, existing := range settings.Permissions {
+			if sameSubject(existing, subject) {
+				settings.Permissions = append(settings.Permissions[:i], settings.Permissions[i+1:]...)
+				return nil
+			}
+		}
+
+		return NewPermissionNotFoundError(permissionSubject(subject))
+	})
+	if err != nil {
+		return err
+	}
+
+	m.logger.Info("Permission revoked", zap.String("name", name), zap.String("subject", permissionSubject(subject)))
+	return nil
+}
+
+// EffectiveRole returns the highest role an identity holds on a repository or group,
+// granted on it or on any of its parent groups. Anonymous users (nil identity) only
+// hold the roles granted to EveryoneUser.
+// Identities authenticated with an access token are limited to the scope of the token:
+// read-only tokens hold at most the read role and deploy tokens hold the role of their scope.
+func (m *RepositoryManager) EffectiveRole(name string, identity *auth.Identity) (Role, error) {
+	permissions, err := m.ListEffectivePermissions(name)
+	if err != nil {
+		return RoleNone, err
+	}
+
+	var token *auth.TokenScope
+	if identity != nil {
+		token = identity.Token
+	}
+
+	if token != nil && !token.Covers(name) {
+		return RoleNone, nil
+	}
+
+	if token != nil && token.Deploy {
+		if token.Write {
+			return RoleWrite, nil
+		}
+		return RoleRead, nil
+	}
+
+	role := RoleNone
+	for _, permission := range permissions {
+		if permissionApplies(permission, identity) && !role.Includes(permission.Role) {
+			role = permission.Role
+		}
+	}
+
+	if token != nil && !token.Write && role.Includes(RoleRead) {
+		role = RoleRead
+	}
+
+	return role, nil
+}
+
+// Authorize returns a ForbiddenError unless an identity holds at least the required role on a repository or group
+func (m *RepositoryManager) Authorize(name string, identity *auth.Identity, required Role) error {
+	role, err := m.EffectiveRole(name, identity)
+	if err != nil {
+		return err
+	}
+
+	if !role.Includes(required) {
+		return NewRoleRequiredError(name, required)
+	}
+
+	return nil
+}
+
+// permissionApplies reports whether a permission is granted to an identity
+func permissionApplies(permission Permission, identity *auth.Identity) bool {
+	if permission.User == EveryoneUser {
+		return true
+	}
+
+	if identity == nil {
+		return false
+	}
+
+	if permission.User != "" {
+		return permission.User == identity.Name
+	}
+
+	return containsString(identity.Teams, permission.Team)
+}
+
+// sameSubject reports whether two permissions are granted to the same user or team
+func sameSubject(a, b Permission) bool {
+	return a.User == b.User && a.Team == b.Team
+}
+
+// permissionSubject describes the user or team of a permission, e.g. "user:john" or "team:developers"
+func permissionSubject(permission Permission) string {
+	if permission.Team != "" {
+		return "team:" + permission.Team
+	}
+	return "user:" + permission.User
+}
@@ -0,0 +1,100 @@
+package repository_manager
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/weedbox/git-modules/auth"
+)
+
+// Test that roles granted on groups are inherited by nested groups and repositories
+func TestPermissions(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	if _, err := manager.CreateGroup("org/team", ""); err != nil {
+		t.Fatalf("Failed to create group: %v", err)
+	}
+	if _, err := manager.CreateRepository("org/team/app", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	grants := []struct {
+		name       string
+		permission Permission
+	}{
+		{"org", Permission{User: EveryoneUser, Role: RoleRead}},
+		{"org", Permission{Team: "developers", Role: RoleWrite}},
+		{"org/team", Permission{User: "alice", Role: RoleMaintain}},
+		{"org/team/app", Permission{User: "bob", Role: RoleAdmin}},
+	}
+	for _, g := range grants {
+		if _, err := manager.SetPermission(g.name, g.permission); err != nil {
+			t.Fatalf("Failed to grant %v on %s: %v", g.permission, g.name, err)
+		}
+	}
+
+	tests := []struct {
+		identity *auth.Identity
+		name     string
+		expected Role
+	}{
+		{nil, "org/team/app", RoleRead},
+		{&auth.Identity{Name: "carol", Teams: []string{"developers"}}, "org/team/app", RoleWrite},
+		{&auth.Identity{Name: "alice"}, "org/team/app", RoleMaintain},
+		{&auth.Identity{Name: "alice"}, "org", RoleRead},
+		{&auth.Identity{Name: "bob", Teams: []string{"developers"}}, "org/team/app", RoleAdmin},
+		{&auth.Identity{Name: "bob"}, "org/team", RoleRead},
+	}
+	for _, tt := range tests {
+		role, err := manager.EffectiveRole(tt.name, tt.identity)
+		if err != nil {
+			t.Fatalf("Failed to resolve role on %s: %v", tt.name, err)
+		}
+		if role != tt.expected {
+			t.Errorf("Expected role %q for %v on %s, got %q", tt.expected, tt.identity, tt.name, role)
+		}
+	}
+
+	var forbidden *ForbiddenError
+	if err := manager.Authorize("org/team/app", &auth.Identity{Name: "alice"}, RoleAdmin); !errors.As(err, &forbidden) {
+		t.Errorf("Expected ForbiddenError for insufficient role, got %v", err)
+	}
+	if err := manager.Authorize("org/team/app", &auth.Identity{Name: "alice"}, RoleWrite); err != nil {
+		t.Errorf("Expected maintain role to include write, got %v", err)
+	}
+
+	// Granting again replaces the role of the same subject
+	if _, err := manager.SetPermission("org/team", Permission{User: "alice", Role: RoleRead}); err != nil {
+		t.Fatalf("Failed to update permission: %v", err)
+	}
+	permissions, err := manager.ListPermissions("org/team")
+	if err != nil {
+		t.Fatalf("Failed to list permissions: %v", err)
+	}
+	if len(permissions) != 1 || permissions[0].Role != RoleRead {
+		t.Errorf("Expected a single read permission, got %v", permissions)
+	}
+
+	effective, err := manager.ListEffectivePermissions("org/team/app")
+	if err != nil {
+		t.Fatalf("Failed to list effective permissions: %v", err)
+	}
+	if len(effective) != 4 || effective[0].Source != "org" || effective[3].Source != "org/team/app" {
+		t.Errorf("Unexpected effective permissions: %v", effective)
+	}
+
+	if err := manager.DeletePermission("org/team/app", Permission{User: "bob"}); err != nil {
+		t.Fatalf("Failed to delete permission: %v", err)
+	}
+	if err := manager.DeletePermission("org/team/app", Permission{User: "bob"}); !isNotFound(err) {
+		t.Errorf("Expected NotFoundError when deleting a missing permission, got %v", err)
+	}
+
+	if _, err := manager.SetPermission("org", Permission{User: "x", Team: "y", Role: RoleRead}); !errors.Is(err, ErrPermissionSubjectInvalid) {
+		t.Errorf("Expected ErrPermissionSubjectInvalid, got %v", err)
+	}
+	if _, err := manager.SetPermission("org", Permission{User: "x", Role: "owner"}); !errors.Is(err, ErrRoleInvalid) {
+		t.Errorf("Expected ErrRoleInvalid, got %v", err)
+	}
+}
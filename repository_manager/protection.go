@@ -0,0 +1,369 @@
+package repository_manager
+
+import (
+	"path"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"go.uber.org/zap"
+)
+
+// ListBranchProtectionRules returns the branch protection rules configured directly on a repository or group
+func (m *RepositoryManager) ListBranchProtectionRules(name string) ([]BranchProtectionRule, error) {
+	settings, err := m.loadSettings(name)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]BranchProtectionRule, 0, len(settings.BranchProtection))
+	return append(rules, settings.BranchProtection...), nil
+}
+
+// ListEffectiveBranchProtectionRules returns the rules applying to a repository,
+// including the rules inherited from its groups. Source names the group or repository defining each rule.
+func (m *RepositoryManager) ListEffectiveBranchProtectionRules(repoName string) ([]BranchProtectionRule, error) {
+	if !m.IsRepository(repoName) {
+		return nil, NewRepositoryNotFoundError(repoName)
+	}
+
+	chain, err := m.settingsChain(repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]BranchProtectionRule, 0)
+	for _, source := range chain {
+		for _, rule := range source.Settings.BranchProtection {
+			rule.Source = source.Name
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules, nil
+}
+
+// GetBranchProtectionRule returns the rule of a repository or group with the given pattern
+func (m *RepositoryManager) GetBranchProtectionRule(name, pattern string) (*BranchProtectionRule, error) {
+	settings, err := m.loadSettings(name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rule := range settings.BranchProtection {
+		if rule.Pattern == pattern {
+			return &rule, nil
+		}
+	}
+
+	return nil, NewBranchProtectionRuleNotFoundError(pattern)
+}
+
+// CreateBranchProtectionRule adds a branch protection rule to a repository or group
+func (m *RepositoryManager) CreateBranchProtectionRule(name string, rule BranchProtectionRule) (*BranchProtectionRule, error) {
+	if !isValidProtectionPattern(rule.Pattern) {
+		return nil, ErrProtectionPatternInvalid
+	}
+	if !isValidCommitRules(rule) {
+		return nil, ErrCommitRuleInvalid
+	}
+	rule.Source = ""
+
+	err := m.updateSettings(name, func(settings *Settings) error {
+		for _, existing := range settings.BranchProtection {
+			if existing.Pattern == rule.Pattern {
+				return NewBranchProtectionRuleAlreadyExistsError(rule.Pattern)
+			}
+		}
+
+		settings.BranchProtection = append(settings.BranchProtection, rule)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("Branch protection rule created", zap.String("name", name), zap.String("pattern", rule.Pattern))
+	return &rule, nil
+}
+
+// UpdateBranchProtectionRule replaces the branch protection rule with the given pattern
+func (m *RepositoryManager) UpdateBranchProtectionRule(name, pattern string, rule BranchProtectionRule) (*BranchProtectionRule, error) {
+	if !isValidCommitRules(rule) {
+		return nil, ErrCommitRuleInvalid
+	}
+	rule.Pattern = pattern
+	rule.Source = ""
+
+	err := m.updateSettings(name, func(settings *Settings) error {
+		for i, existing := range settings.BranchProtection {
+			if existing.Pattern == pattern {
+				settings.BranchProtection[i] = rule
+				return nil
+			}
+		}
+
+		return NewBranchProtectionRuleNotFoundError(pattern)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("Branch protection rule updated", zap.String("name", name), zap.String("pattern", pattern))
+	return &rule, nil
+}
+
+// DeleteBranchProtectionRule removes the branch protection rule with the given pattern
+func (m *RepositoryManager) DeleteBranchProtectionRule(name, pattern string) error {
+	err := m.updateSettings(name, func(settings *Settings) error {
+		for i, existing := range settings.BranchProtection {
+			if existing.Pattern == pattern {
+				settings.BranchProtection = append(settings.BranchProtection[:i], settings.BranchProtection[i+1:]...)
+				return nil
+			}
+		}
+
+		return NewBranchProtectionRuleNotFoundError(pattern)
+	})
+	if err != nil {
+		return err
+	}
+
+	m.logger.Info("Branch protection rule deleted", zap.String("name", name), zap.String("pattern", pattern))
+	return nil
+}
+
+// CheckBranchProtection verifies the reference updates of a push against the branch
+// protection rules of a repository and its groups. objects must contain the objects
+// received with the push. pusher is the name of the authenticated user, empty if anonymous.
+// A *PushRejectedError listing every violating update is returned if the push must be refused.
+// Branches moved by RepositoryManager itself, such as by CommitFiles and Merge, are checked by checkBranchWritable.
+func (m *RepositoryManager) CheckBranchProtection(repoName, pusher string, updates []ReferenceUpdate, objects *PushObjectStorage) error {
+	rules, err := m.ListEffectiveBranchProtectionRules(repoName)
+	if err != nil {
+		return err
+	}
+
+	if len(rules) == 0 {
+		return nil
+	}
+
+	rejected := &PushRejectedError{}
+	for _, update := range updates {
+		if !update.Name.IsBranch() {
+			continue
+		}
+
+		matching := matchingBranchRules(rules, update.Name)
+		reason, err := checkBranchUpdate(matching, pusher, update, objects)
+		if err != nil {
+			return err
+		}
+
+		if reason != "" {
+			rejected.Rejections = append(rejected.Rejections, ReferenceRejection{
+				Reference: update.Name.String(),
+				Reason:    "protected branch: " + reason,
+			})
+			continue
+		}
+
+		violations, err := m.checkCommitRules(matching, update, objects)
+		if err != nil {
+			return err
+		}
+
+		for _, violation := range limitViolations(violations) {
+			rejected.Rejections = append(rejected.Rejections, ReferenceRejection{
+				Reference: update.Name.String(),
+				Reason:    "protected branch: " + violation,
+			})
+		}
+	}
+
+	if len(rejected.Rejections) > 0 {
+		return rejected
+	}
+
+	return nil
+}
+
+// checkBranchUpdate returns the reason why the rules refuse an update, or an empty string
+func checkBranchUpdate(rules []BranchProtectionRule, pusher string, update ReferenceUpdate, objects *PushObjectStorage) (string, error) {
+	if reason := checkBranchPusher(rules, pusher, update); reason != "" {
+		return reason, nil
+	}
+
+	if update.IsDelete() {
+		return "", nil
+	}
+
+	for _, rule := range rules {
+		if rule.DenyForcePush && !update.IsCreate() {
+			fastForward, err := isFastForward(objects, update.OldHash, update.NewHash)
+			if err != nil {
+				return "", err
+			}
+			if !fastForward {
+				return "force-push is not allowed", nil
+			}
+		}
+
+		if rule.RequireLinearHistory {
+			commits, err := objects.ReceivedCommits(update.NewHash)
+			if err != nil {
+				return "", err
+			}
+			for _, commit := range commits {
+				if commit.NumParents() > 1 {
+					return "merge commits are not allowed, history must be linear (" + commit.Hash.String()[:7] + ")", nil
+				}
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// checkBranchPusher returns the reason why the rules refuse an update by pusher, or an empty string
+func checkBranchPusher(rules []BranchProtectionRule, pusher string, update ReferenceUpdate) string {
+	for _, rule := range rules {
+		if len(rule.AllowedPushers) > 0 && !containsString(rule.AllowedPushers, pusher) {
+			return "you are not allowed to push to this branch"
+		}
+
+		if rule.DenyDeletion && update.IsDelete() {
+			return "deletion is not allowed"
+		}
+	}
+
+	return ""
+}
+
+// checkBranchWritable returns a ForbiddenError if the branch protection rules refuse an update made by
+// RepositoryManager, such as a commit or merge through the API. The commits of the update are already
+// stored in s. pusher is the authenticated user requesting the change, empty if unknown, so rules
+// restricting the pushers of a branch also refuse changes without a user.
+func (m *RepositoryManager) checkBranchWritable(repoName, pusher string, s storer.EncodedObjectStorer, update ReferenceUpdate) error {
+	if !update.Name.IsBranch() {
+		return nil
+	}
+
+	rules, err := m.ListEffectiveBranchProtectionRules(repoName)
+	if err != nil {
+		return err
+	}
+
+	matching := matchingBranchRules(rules, update.Name)
+	if len(matching) == 0 {
+		return nil
+	}
+
+	if reason := checkBranchPusher(matching, pusher, update); reason != "" {
+		return NewProtectedBranchError(update.Name.Short(), reason)
+	}
+
+	if update.IsDelete() || update.IsCreate() {
+		return nil
+	}
+
+	for _, rule := range matching {
+		if rule.DenyForcePush {
+			fastForward, err := isFastForward(s, update.OldHash, update.NewHash)
+			if err != nil {
+				return err
+			}
+			if !fastForward {
+				return NewProtectedBranchError(update.Name.Short(), "force-push is not allowed")
+			}
+		}
+
+		if rule.RequireLinearHistory {
+			merge, err := firstMergeCommit(s, update.NewHash, update.OldHash)
+			if err != nil {
+				return err
+			}
+			if merge != nil {
+				return NewProtectedBranchError(update.Name.Short(), "merge commits are not allowed, history must be linear ("+merge.Hash.String()[:7]+")")
+			}
+		}
+	}
+
+	return nil
+}
+
+// firstMergeCommit follows the history of hash until it reaches base and returns the first merge commit
+// on the way, or nil. Updates made by RepositoryManager are fast-forwards, so every commit before base
+// has a single path to it and a merge commit found first is always new to the branch.
+func firstMergeCommit(s storer.EncodedObjectStorer, hash, base plumbing.Hash) (*object.Commit, error) {
+	for hash != base {
+		commit, err := object.GetCommit(s, hash)
+		if err != nil {
+			return nil, WrapCommitNotFoundError(err)
+		}
+
+		switch commit.NumParents() {
+		case 0:
+			return nil, nil
+		case 1:
+			hash = commit.ParentHashes[0]
+		default:
+			return commit, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// isFastForward reports whether newHash contains oldHash in its history
+func isFastForward(objects storer.EncodedObjectStorer, oldHash, newHash plumbing.Hash) (bool, error) {
+	newCommit, err := object.GetCommit(objects, newHash)
+	if err != nil {
+		return false, nil
+	}
+
+	oldCommit, err := object.GetCommit(objects, oldHash)
+	if err != nil {
+		return false, nil
+	}
+
+	fastForward, err := oldCommit.IsAncestor(newCommit)
+	if err != nil {
+		return false, WrapMergeBaseError(err)
+	}
+
+	return fastForward, nil
+}
+
+// matchingBranchRules returns the rules whose pattern matches a branch
+func matchingBranchRules(rules []BranchProtectionRule, refName plumbing.ReferenceName) []BranchProtectionRule {
+	branch := refName.Short()
+	matched := make([]BranchProtectionRule, 0)
+
+	for _, rule := range rules {
+		if ok, _ := path.Match(rule.Pattern, branch); ok {
+			matched = append(matched, rule)
+		}
+	}
+
+	return matched
+}
+
+// isValidProtectionPattern checks that a pattern is a usable glob for references
+func isValidProtectionPattern(pattern string) bool {
+	if pattern == "" {
+		return false
+	}
+
+	_, err := path.Match(pattern, "")
+	return err == nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,265 @@
+package repository_manager
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/revlist"
+)
+
+// Helper function to encode the objects reachable from want but not from have as a packfile
+func encodeTestPack(t *testing.T, manager *RepositoryManager, repoName string, want plumbing.Hash, have []plumbing.Hash) *bytes.Buffer {
+	repo, err := manager.openRepository(repoName)
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+
+	hashes, err := revlist.Objects(repo.Storer, []plumbing.Hash{want}, have)
+	if err != nil {
+		t.Fatalf("Failed to collect objects: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if _, err := packfile.NewEncoder(buf, repo.Storer, false).Encode(hashes, 10); err != nil {
+		t.Fatalf("Failed to encode packfile: %v", err)
+	}
+
+	return buf
+}
+
+// Test branch protection rule CRUD on repositories and groups with inheritance
+func TestBranchProtectionRules(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	if _, err := manager.CreateGroup("org", ""); err != nil {
+		t.Fatalf("Failed to create group: %v", err)
+	}
+	if _, err := manager.CreateRepository("org/app", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	if _, err := manager.CreateBranchProtectionRule("org", BranchProtectionRule{Pattern: "main", DenyDeletion: true}); err != nil {
+		t.Fatalf("Failed to create group rule: %v", err)
+	}
+	if _, err := manager.CreateBranchProtectionRule("org/app", BranchProtectionRule{Pattern: "release/*", DenyForcePush: true}); err != nil {
+		t.Fatalf("Failed to create repository rule: %v", err)
+	}
+
+	var exists *AlreadyExistsError
+	if _, err := manager.CreateBranchProtectionRule("org", BranchProtectionRule{Pattern: "main"}); !errors.As(err, &exists) {
+		t.Errorf("Expected AlreadyExistsError for duplicate pattern, got %v", err)
+	}
+	if _, err := manager.CreateBranchProtectionRule("org", BranchProtectionRule{Pattern: "[main"}); err != ErrProtectionPatternInvalid {
+		t.Errorf("Expected ErrProtectionPatternInvalid, got %v", err)
+	}
+
+	rules, err := manager.ListEffectiveBranchProtectionRules("org/app")
+	if err != nil {
+		t.Fatalf("Failed to list effective rules: %v", err)
+	}
+	if len(rules) != 2 || rules[0].Source != "org" || rules[1].Source != "org/app" {
+		t.Errorf("Expected inherited group rule followed by repository rule, got %+v", rules)
+	}
+
+	updated, err := manager.UpdateBranchProtectionRule("org", "main", BranchProtectionRule{DenyForcePush: true})
+	if err != nil {
+		t.Fatalf("Failed to update rule: %v", err)
+	}
+	if updated.Pattern != "main" || updated.DenyDeletion || !updated.DenyForcePush {
+		t.Errorf("Unexpected updated rule: %+v", updated)
+	}
+
+	if err := manager.DeleteBranchProtectionRule("org", "main"); err != nil {
+		t.Fatalf("Failed to delete rule: %v", err)
+	}
+	var notFound *NotFoundError
+	if _, err := manager.GetBranchProtectionRule("org", "main"); !errors.As(err, &notFound) {
+		t.Errorf("Expected NotFoundError after delete, got %v", err)
+	}
+
+	// Settings files must not prevent deleting an otherwise empty group
+	if _, err := manager.CreateGroup("empty", ""); err != nil {
+		t.Fatalf("Failed to create group: %v", err)
+	}
+	if _, err := manager.CreateBranchProtectionRule("empty", BranchProtectionRule{Pattern: "*"}); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	if err := manager.DeleteGroup("empty"); err != nil {
+		t.Errorf("Failed to delete group with settings: %v", err)
+	}
+}
+
+// Test enforcement of branch protection rules on pushed reference updates
+func TestCheckBranchProtection(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	if _, err := manager.CreateRepository("app", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	if _, err := manager.CreateRepository("source", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	base := commitTestFile(t, manager, "app", "master", "a.txt", "a")
+	next := commitTestFile(t, manager, "app", "master", "a.txt", "b")
+	if _, err := manager.CreateBranchProtectionRule("app", BranchProtectionRule{
+		Pattern:              "master",
+		DenyForcePush:        true,
+		DenyDeletion:         true,
+		RequireLinearHistory: true,
+	}); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	noObjects, err := manager.NewPushObjectStorage("app", bytes.NewReader(nil))
+	if err != nil {
+		t.Fatalf("Failed to create object storage: %v", err)
+	}
+	defer noObjects.Close()
+
+	check := func(objects *PushObjectStorage, updates ...ReferenceUpdate) error {
+		return manager.CheckBranchProtection("app", "alice", updates, objects)
+	}
+
+	// Fast-forward and unprotected branches are accepted
+	if err := check(noObjects, ReferenceUpdate{Name: plumbing.Master, OldHash: base, NewHash: next}); err != nil {
+		t.Errorf("Expected fast-forward to be accepted, got %v", err)
+	}
+	if err := check(noObjects, ReferenceUpdate{Name: "refs/heads/topic", OldHash: next, NewHash: base}); err != nil {
+		t.Errorf("Expected unprotected branch update to be accepted, got %v", err)
+	}
+
+	var rejected *PushRejectedError
+	if err := check(noObjects, ReferenceUpdate{Name: plumbing.Master, OldHash: next, NewHash: base}); !errors.As(err, &rejected) {
+		t.Errorf("Expected force-push to be rejected, got %v", err)
+	}
+	if err := check(noObjects, ReferenceUpdate{Name: plumbing.Master, OldHash: next}); !errors.As(err, &rejected) {
+		t.Errorf("Expected deletion to be rejected, got %v", err)
+	} else if len(rejected.Rejections) != 1 || rejected.Rejections[0].Reference != "refs/heads/master" {
+		t.Errorf("Unexpected rejections: %+v", rejected.Rejections)
+	}
+
+	// A merge commit only present in the pushed packfile violates linear history
+	repo, _ := manager.openRepository("app")
+	nextCommit, _ := repo.CommitObject(next)
+	merge := &object.Commit{
+		Author:       nextCommit.Author,
+		Committer:    nextCommit.Committer,
+		Message:      "Merge",
+		TreeHash:     nextCommit.TreeHash,
+		ParentHashes: []plumbing.Hash{next, base},
+	}
+	sourceRepo, _ := manager.openRepository("source")
+	src := encodeTestPack(t, manager, "app", next, nil)
+	if err := packfile.UpdateObjectStorage(sourceRepo.Storer, src); err != nil {
+		t.Fatalf("Failed to copy objects: %v", err)
+	}
+	mergeHash, err := storeCommit(sourceRepo.Storer, merge)
+	if err != nil {
+		t.Fatalf("Failed to store merge commit: %v", err)
+	}
+
+	pack := encodeTestPack(t, manager, "source", mergeHash, []plumbing.Hash{next})
+	objects, err := manager.NewPushObjectStorage("app", pack)
+	if err != nil {
+		t.Fatalf("Failed to read pushed objects: %v", err)
+	}
+	defer objects.Close()
+	if !objects.IsReceived(mergeHash) || objects.IsReceived(next) {
+		t.Error("Expected only the merge commit to be received")
+	}
+
+	if err := check(objects, ReferenceUpdate{Name: plumbing.Master, OldHash: next, NewHash: mergeHash}); !errors.As(err, &rejected) {
+		t.Errorf("Expected merge commit to be rejected, got %v", err)
+	}
+
+	// Received objects are kept on disk until the storage is closed
+	if err := objects.Close(); err != nil {
+		t.Errorf("Failed to close object storage: %v", err)
+	}
+	if _, err := os.Stat(objects.dir); !os.IsNotExist(err) {
+		t.Errorf("Expected received objects to be removed, got %v", err)
+	}
+
+	// Restricted pushers
+	if _, err := manager.CreateBranchProtectionRule("app", BranchProtectionRule{Pattern: "release/*", AllowedPushers: []string{"release-bot"}}); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	update := ReferenceUpdate{Name: "refs/heads/release/1.0", NewHash: next}
+	if err := check(noObjects, update); !errors.As(err, &rejected) {
+		t.Errorf("Expected push by unlisted user to be rejected, got %v", err)
+	}
+	if err := manager.CheckBranchProtection("app", "release-bot", []ReferenceUpdate{update}, noObjects); err != nil {
+		t.Errorf("Expected push by allowed user to be accepted, got %v", err)
+	}
+}
+
+// Test that commits and merges made through RepositoryManager respect branch protection rules
+func TestBranchProtectionManagerChanges(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	if _, err := manager.CreateRepository("app", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	base := commitTestFile(t, manager, "app", "master", "a.txt", "a")
+	repo, err := manager.openRepository("app")
+	if err != nil {
+		t.Fatalf("Failed to open repository: %v", err)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("topic"), base)); err != nil {
+		t.Fatalf("Failed to create branch: %v", err)
+	}
+	commitTestFile(t, manager, "app", "topic", "b.txt", "b")
+
+	if _, err := manager.CreateBranchProtectionRule("app", BranchProtectionRule{
+		Pattern:              "master",
+		AllowedPushers:       []string{"alice"},
+		RequireLinearHistory: true,
+	}); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	commit := func(pusher string) error {
+		_, err := manager.CommitFiles("app", CommitRequest{
+			Branch:  "master",
+			Message: "Update c.txt",
+			Actions: []FileAction{{Action: FileActionCreate, Path: "c.txt", Content: []byte(pusher)}},
+			Pusher:  pusher,
+		})
+		return err
+	}
+
+	var forbidden *ForbiddenError
+	for _, pusher := range []string{"", "bob"} {
+		if err := commit(pusher); !errors.As(err, &forbidden) {
+			t.Errorf("Expected ForbiddenError for commit by %q, got %v", pusher, err)
+		}
+	}
+	if _, err := manager.PutFile("app", "d.txt", []byte("d"), FileCommitOptions{Branch: "master", Pusher: "bob"}); !errors.As(err, &forbidden) {
+		t.Errorf("Expected ForbiddenError for file change by bob, got %v", err)
+	}
+	if err := commit("alice"); err != nil {
+		t.Fatalf("Expected commit by allowed pusher, got %v", err)
+	}
+
+	// Unprotected branches stay writable
+	if _, err := manager.PutFile("app", "d.txt", []byte("d"), FileCommitOptions{Branch: "topic", Pusher: "bob"}); err != nil {
+		t.Errorf("Expected change to unprotected branch, got %v", err)
+	}
+
+	// Merge commits break the linear history, squashed changes keep it
+	if _, err := manager.Merge("app", MergeRequest{Base: "master", Head: "topic", Strategy: MergeStrategyMerge, Pusher: "alice"}); !errors.As(err, &forbidden) {
+		t.Errorf("Expected ForbiddenError for merge commit, got %v", err)
+	}
+	if _, err := manager.Merge("app", MergeRequest{Base: "master", Head: "topic", Strategy: MergeStrategySquash, Pusher: "alice"}); err != nil {
+		t.Errorf("Expected squash merge, got %v", err)
+	}
+}
@@ -0,0 +1,253 @@
+package repository_manager
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/go-git/go-git/v5/storage/filesystem/dotgit"
+)
+
+// ReferenceUpdate describes the update of a single reference requested by a push
+type ReferenceUpdate struct {
+	Name    plumbing.ReferenceName
+	OldHash plumbing.Hash
+	NewHash plumbing.Hash
+}
+
+// IsCreate reports whether the update creates the reference
+func (u ReferenceUpdate) IsCreate() bool {
+	return u.OldHash.IsZero() && !u.NewHash.IsZero()
+}
+
+// IsDelete reports whether the update deletes the reference
+func (u ReferenceUpdate) IsDelete() bool {
+	return u.NewHash.IsZero()
+}
+
+// CheckReferenceUpdates verifies the reference updates of a push against all policies of a
+// repository: branch protection, tag protection, quotas and content policies. Rejections of all policies are combined
+// into a single *PushRejectedError so the client learns every reason at once.
+func (m *RepositoryManager) CheckReferenceUpdates(repoName, pusher string, updates []ReferenceUpdate, objects *PushObjectStorage) error {
+	checks := []func() error{
+		func() error { return m.CheckBranchProtection(repoName, pusher, updates, objects) },
+		func() error { return m.CheckTagProtection(repoName, updates) },
+		func() error { return m.CheckQuota(repoName, updates, objects) },
+		func() error { return m.CheckContentPolicy(repoName, updates, objects) },
+	}
+
+	rejected := &PushRejectedError{}
+	for _, check := range checks {
+		err := check()
+		if err == nil {
+			continue
+		}
+
+		var e *PushRejectedError
+		if !errors.As(err, &e) {
+			return err
+		}
+		rejected.Rejections = append(rejected.Rejections, e.Rejections...)
+	}
+
+	if len(rejected.Rejections) > 0 {
+		return rejected
+	}
+
+	return nil
+}
+
+// PushObjectStorage gives read access to the objects of a repository together with
+// the objects received in a push that have not been written to the repository yet.
+// It lets policies inspect a push before the transport accepts it.
+// Received objects are kept in a temporary directory rather than in memory, so large
+// pushes do not exhaust the server. Close removes them.
+type PushObjectStorage struct {
+	*filesystem.ObjectStorage
+	repo     storer.EncodedObjectStorer
+	dir      string
+	packSize int64
+
+	// releaseQuota frees the quota reserved for the packfile by CheckQuota
+	releaseQuota func()
+}
+
+// NewPushObjectStorage parses the packfile of a push against an existing repository.
+// Thin packs are resolved with the objects already stored in the repository.
+// An empty reader is accepted, as pushes deleting references carry no packfile.
+// The caller closes the returned storage.
+func (m *RepositoryManager) NewPushObjectStorage(name string, pack io.Reader) (*PushObjectStorage, error) {
+	// Validate repository name to prevent path traversal attacks
+	if !isValidRepoName(name) {
+		return nil, ErrRepositoryInvalidName
+	}
+
+	repo, err := m.openRepository(name)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "git-push-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to store pushed objects: %w", err)
+	}
+
+	s := &PushObjectStorage{
+		ObjectStorage: filesystem.NewObjectStorage(dotgit.New(osfs.New(dir)), cache.NewObjectLRUDefault()),
+		repo:          repo.Storer,
+		dir:           dir,
+	}
+
+	counter := &countingReader{r: pack}
+	br := bufio.NewReader(counter)
+	if _, err := br.Peek(1); err == io.EOF {
+		return s, nil
+	}
+
+	parser, err := packfile.NewParserWithStorage(packfile.NewScanner(br), s)
+	if err != nil {
+		s.Close()
+		return nil, WrapReadPackfileError(err)
+	}
+
+	if _, err := parser.Parse(); err != nil {
+		s.Close()
+		return nil, WrapReadPackfileError(err)
+	}
+
+	// The packfile ends the request, so every byte read belongs to it
+	s.packSize = counter.n
+	return s, nil
+}
+
+// Close removes the received objects and frees the quota reserved for them.
+// Transports close the storage once the push has been applied or refused.
+func (s *PushObjectStorage) Close() error {
+	if s.releaseQuota != nil {
+		s.releaseQuota()
+		s.releaseQuota = nil
+	}
+	return os.RemoveAll(s.dir)
+}
+
+// AddAlternate is not supported, the repository is the only source of objects besides the push
+func (s *PushObjectStorage) AddAlternate(remote string) error {
+	return errors.New("alternates are not supported for pushed objects")
+}
+
+// PackSize returns the size in bytes of the received packfile, 0 if the push carried none
+func (s *PushObjectStorage) PackSize() int64 {
+	return s.packSize
+}
+
+// countingReader counts the bytes read from r
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// EncodedObject returns a received object, falling back to the repository objects
+func (s *PushObjectStorage) EncodedObject(t plumbing.ObjectType, h plumbing.Hash) (plumbing.EncodedObject, error) {
+	obj, err := s.ObjectStorage.EncodedObject(t, h)
+	if err == plumbing.ErrObjectNotFound {
+		return s.repo.EncodedObject(t, h)
+	}
+	return obj, err
+}
+
+// HasEncodedObject checks the received objects and the repository objects
+func (s *PushObjectStorage) HasEncodedObject(h plumbing.Hash) error {
+	if err := s.ObjectStorage.HasEncodedObject(h); err == nil {
+		return nil
+	}
+	return s.repo.HasEncodedObject(h)
+}
+
+// EncodedObjectSize returns the size of a received object or of a repository object
+func (s *PushObjectStorage) EncodedObjectSize(h plumbing.Hash) (int64, error) {
+	size, err := s.ObjectStorage.EncodedObjectSize(h)
+	if err == plumbing.ErrObjectNotFound {
+		return s.repo.EncodedObjectSize(h)
+	}
+	return size, err
+}
+
+// IsReceived reports whether an object was sent by the push rather than already stored
+func (s *PushObjectStorage) IsReceived(h plumbing.Hash) bool {
+	return s.ObjectStorage.HasEncodedObject(h) == nil
+}
+
+// ReceivedCommits returns the commits reachable from hash that were sent by the push,
+// stopping at commits already stored in the repository
+func (s *PushObjectStorage) ReceivedCommits(hash plumbing.Hash) ([]*object.Commit, error) {
+	commits := make([]*object.Commit, 0)
+	seen := make(map[plumbing.Hash]bool)
+	pending := []plumbing.Hash{hash}
+
+	for len(pending) > 0 {
+		h := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		if seen[h] || !s.IsReceived(h) {
+			continue
+		}
+		seen[h] = true
+
+		commit, err := object.GetCommit(s, h)
+		if err != nil {
+			return nil, WrapReadObjectError(err)
+		}
+
+		commits = append(commits, commit)
+		pending = append(pending, commit.ParentHashes...)
+	}
+
+	return commits, nil
+}
+
+// AppliedReferenceUpdates returns the updates of a push that are reflected in the repository,
+// i.e. the references now pointing to NewHash or removed for deletions.
+// Transports call it after a push to learn which commands were accepted.
+func (m *RepositoryManager) AppliedReferenceUpdates(repoName string, updates []ReferenceUpdate) ([]ReferenceUpdate, error) {
+	// Validate repository name to prevent path traversal attacks
+	if !isValidRepoName(repoName) {
+		return nil, ErrRepositoryInvalidName
+	}
+
+	repo, err := m.openRepository(repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make([]ReferenceUpdate, 0, len(updates))
+	for _, update := range updates {
+		ref, err := repo.Storer.Reference(update.Name)
+		switch {
+		case err == plumbing.ErrReferenceNotFound:
+			if update.IsDelete() {
+				applied = append(applied, update)
+			}
+		case err != nil:
+			return nil, WrapListReferencesError(err)
+		case !update.IsDelete() && ref.Hash() == update.NewHash:
+			applied = append(applied, update)
+		}
+	}
+
+	return applied, nil
+}
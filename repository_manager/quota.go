@@ -0,0 +1,340 @@
+package repository_manager
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// quotaUsageTTL is how long the usage computed for quota checks is reused.
+// Space reserved by pushes and Git LFS uploads is counted at once, other writes within this delay.
+const quotaUsageTTL = 30 * time.Second
+
+// cachedUsage is the usage of a repository or group computed for quota checks
+type cachedUsage struct {
+	size int64
+	at   time.Time
+}
+
+// ExternalStorage reports the disk space repositories use outside of the repositories directory,
+// e.g. for Git LFS objects, so that it counts towards their quotas
+type ExternalStorage interface {
+	RepositorySize(repoName string) (int64, error)
+}
+
+// AddExternalStorage includes the space a storage uses for each repository in usages and quotas
+func (m *RepositoryManager) AddExternalStorage(storage ExternalStorage) {
+	m.externalStorageMu.Lock()
+	defer m.externalStorageMu.Unlock()
+
+	m.externalStorage = append(m.externalStorage, storage)
+}
+
+// GetQuota returns the quota configured directly on a repository or group
+func (m *RepositoryManager) GetQuota(name string) (*Quota, error) {
+	settings, err := m.loadSettings(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.Quota == nil {
+		return &Quota{}, nil
+	}
+
+	return settings.Quota, nil
+}
+
+// SetQuota replaces the quota of a repository or group. A MaxSize of 0 removes the quota.
+// The quota of a group applies to the total size of its repositories and subgroups.
+func (m *RepositoryManager) SetQuota(name string, quota Quota) (*Quota, error) {
+	if quota.MaxSize < 0 {
+		return nil, ErrQuotaInvalid
+	}
+
+	err := m.updateSettings(name, func(settings *Settings) error {
+		settings.Quota = &quota
+		if quota.MaxSize == 0 {
+			settings.Quota = nil
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("Quota updated", zap.String("name", name), zap.Int64("maxSize", quota.MaxSize))
+	return &quota, nil
+}
+
+// GetUsage returns the disk space used by a repository or group, including every
+// repository and subgroup of a group and the space used in external storages, together with its quota
+func (m *RepositoryManager) GetUsage(name string) (*Usage, error) {
+	path, err := m.storagePath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	quota, err := m.GetQuota(name)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := directorySize(path)
+	if err != nil {
+		return nil, err
+	}
+
+	external, err := m.externalSize(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Usage{Size: size + external, MaxSize: quota.MaxSize}, nil
+}
+
+// CheckQuota verifies that the packfile received with a push fits in the quotas of a
+// repository and of its groups. objects must contain the objects received with the push.
+// Pushes without a packfile, e.g. deleting references, are always accepted.
+// The size of an accepted packfile is reserved until objects is closed, so concurrent pushes
+// to the repositories of a group cannot exceed its quota together.
+// A *PushRejectedError refusing every reference created or updated is returned if a quota would be exceeded.
+func (m *RepositoryManager) CheckQuota(repoName string, updates []ReferenceUpdate, objects *PushObjectStorage) error {
+	if objects.releaseQuota != nil {
+		objects.releaseQuota()
+		objects.releaseQuota = nil
+	}
+
+	release, err := m.ReserveQuota(repoName, objects.PackSize())
+	if err == nil {
+		objects.releaseQuota = release
+		return nil
+	}
+
+	var exceeded *QuotaExceededError
+	if !errors.As(err, &exceeded) {
+		return err
+	}
+
+	rejected := &PushRejectedError{}
+	for _, update := range updates {
+		if update.IsDelete() {
+			continue
+		}
+		rejected.Rejections = append(rejected.Rejections, ReferenceRejection{
+			Reference: update.Name.String(),
+			Reason:    exceeded.Error(),
+		})
+	}
+
+	if len(rejected.Rejections) > 0 {
+		return rejected
+	}
+
+	return nil
+}
+
+// CheckQuotaSize verifies that size more bytes fit in the quotas of a repository and of its groups.
+// A *QuotaExceededError is returned if a quota would be exceeded.
+func (m *RepositoryManager) CheckQuotaSize(repoName string, size int64) error {
+	m.quotaMu.Lock()
+	defer m.quotaMu.Unlock()
+
+	return m.checkQuotaSize(repoName, size)
+}
+
+// ReserveQuota checks like CheckQuotaSize that size more bytes fit in the quotas of a repository
+// and of its groups, and counts them as used until release is called. Writers reserve the space
+// of data they are about to store and release it once the data is on disk.
+func (m *RepositoryManager) ReserveQuota(repoName string, size int64) (release func(), err error) {
+	if size == 0 {
+		return func() {}, nil
+	}
+
+	m.quotaMu.Lock()
+	defer m.quotaMu.Unlock()
+
+	if err := m.checkQuotaSize(repoName, size); err != nil {
+		return nil, err
+	}
+	m.quotaReservations[repoName] += size
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.quotaMu.Lock()
+			defer m.quotaMu.Unlock()
+
+			m.quotaReservations[repoName] -= size
+			if m.quotaReservations[repoName] == 0 {
+				delete(m.quotaReservations, repoName)
+			}
+
+			// The reserved data is now on disk
+			for name := range m.quotaUsages {
+				if name == repoName || strings.HasPrefix(repoName, name+"/") {
+					delete(m.quotaUsages, name)
+				}
+			}
+		})
+	}, nil
+}
+
+// checkQuotaSize implements CheckQuotaSize, m.quotaMu must be held
+func (m *RepositoryManager) checkQuotaSize(repoName string, size int64) error {
+	if size == 0 {
+		return nil
+	}
+
+	chain, err := m.settingsChain(repoName)
+	if err != nil {
+		return err
+	}
+
+	for _, source := range chain {
+		if source.Settings.Quota == nil || source.Settings.Quota.MaxSize <= 0 {
+			continue
+		}
+
+		used, err := m.quotaUsage(source.Name)
+		if err != nil {
+			return err
+		}
+
+		usage := &Usage{Size: used, MaxSize: source.Settings.Quota.MaxSize}
+		if usage.Size+size > usage.MaxSize {
+			return NewQuotaExceededError(source.Name, source.Name == repoName, usage, size)
+		}
+	}
+
+	return nil
+}
+
+// quotaUsage returns the space counted towards the quota of a repository or group: its usage,
+// computed at most every quotaUsageTTL as walking large repositories is slow, plus the space
+// reserved by writers for its repositories. m.quotaMu must be held.
+func (m *RepositoryManager) quotaUsage(name string) (int64, error) {
+	cached, ok := m.quotaUsages[name]
+	if !ok || time.Since(cached.at) > quotaUsageTTL {
+		usage, err := m.GetUsage(name)
+		if err != nil {
+			return 0, err
+		}
+
+		cached = cachedUsage{size: usage.Size, at: time.Now()}
+		m.quotaUsages[name] = cached
+	}
+
+	size := cached.size
+	for repoName, reserved := range m.quotaReservations {
+		if repoName == name || strings.HasPrefix(repoName, name+"/") {
+			size += reserved
+		}
+	}
+
+	return size, nil
+}
+
+// externalSize returns the space used in external storages by a repository, or by every repository of a group
+func (m *RepositoryManager) externalSize(name string) (int64, error) {
+	m.externalStorageMu.Lock()
+	storages := slices.Clone(m.externalStorage)
+	m.externalStorageMu.Unlock()
+
+	if len(storages) == 0 {
+		return 0, nil
+	}
+
+	repoNames := []string{name}
+	if !m.IsRepository(name) {
+		repos, err := m.ListRepositories()
+		if err != nil {
+			return 0, WrapComputeUsageError(err)
+		}
+
+		repoNames = repoNames[:0]
+		for _, repo := range repos {
+			if strings.HasPrefix(repo.Name, name+"/") {
+				repoNames = append(repoNames, repo.Name)
+			}
+		}
+	}
+
+	var size int64
+	for _, storage := range storages {
+		for _, repoName := range repoNames {
+			repoSize, err := storage.RepositorySize(repoName)
+			if err != nil {
+				return 0, WrapComputeUsageError(err)
+			}
+			size += repoSize
+		}
+	}
+
+	return size, nil
+}
+
+// storagePath returns the directory of a repository or group
+func (m *RepositoryManager) storagePath(name string) (string, error) {
+	if !isValidRepoName(name) {
+		return "", ErrRepositoryInvalidName
+	}
+
+	if m.IsRepository(name) {
+		return filepath.Join(m.reposPath, name+".git"), nil
+	}
+
+	if m.IsGroup(name) {
+		return filepath.Join(m.reposPath, name), nil
+	}
+
+	return "", NewRepositoryNotFoundError(name)
+}
+
+// directorySize returns the total size of the regular files below a directory
+func directorySize(path string) (int64, error) {
+	var size int64
+
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, WrapComputeUsageError(err)
+	}
+
+	return size, nil
+}
+
+// formatSize formats a size in bytes for humans, e.g. 1.5 MiB
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
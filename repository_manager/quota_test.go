@@ -0,0 +1,199 @@
+package repository_manager
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Test quotas, usage and the quota check of pushes
+func TestQuota(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	if _, err := manager.CreateRepository("org/team/app", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	usage, err := manager.GetUsage("org/team/app")
+	if err != nil {
+		t.Fatalf("Failed to get usage: %v", err)
+	}
+	if usage.Size == 0 || usage.MaxSize != 0 {
+		t.Errorf("Expected a non-empty repository without quota, got %+v", usage)
+	}
+
+	groupUsage, err := manager.GetUsage("org")
+	if err != nil {
+		t.Fatalf("Failed to get group usage: %v", err)
+	}
+	if groupUsage.Size < usage.Size {
+		t.Errorf("Expected group usage %d to include repository usage %d", groupUsage.Size, usage.Size)
+	}
+
+	if _, err := manager.SetQuota("org", Quota{MaxSize: -1}); !errors.Is(err, ErrQuotaInvalid) {
+		t.Errorf("Expected ErrQuotaInvalid, got %v", err)
+	}
+
+	head := plumbing.NewHash("1111111111111111111111111111111111111111")
+	updates := []ReferenceUpdate{
+		{Name: plumbing.Master, NewHash: head},
+		{Name: "refs/heads/old", OldHash: head},
+	}
+	push := &PushObjectStorage{packSize: 1024}
+
+	if err := manager.CheckQuota("org/team/app", updates, push); err != nil {
+		t.Errorf("Expected push without quota to be accepted, got %v", err)
+	}
+
+	if _, err := manager.SetQuota("org", Quota{MaxSize: groupUsage.Size + 512}); err != nil {
+		t.Fatalf("Failed to set quota: %v", err)
+	}
+
+	var rejected *PushRejectedError
+	if err := manager.CheckQuota("org/team/app", updates, push); !errors.As(err, &rejected) {
+		t.Fatalf("Expected push over the group quota to be rejected, got %v", err)
+	}
+	if len(rejected.Rejections) != 1 || rejected.Rejections[0].Reference != "refs/heads/master" ||
+		!strings.Contains(rejected.Rejections[0].Reason, "quota of group org exceeded") {
+		t.Errorf("Unexpected rejections: %+v", rejected.Rejections)
+	}
+
+	// Deletions carry no packfile and are always accepted
+	if err := manager.CheckQuota("org/team/app", updates[1:], &PushObjectStorage{}); err != nil {
+		t.Errorf("Expected deletion to be accepted, got %v", err)
+	}
+
+	// Removing the quota accepts the push again
+	if _, err := manager.SetQuota("org", Quota{}); err != nil {
+		t.Fatalf("Failed to remove quota: %v", err)
+	}
+	if quota, _ := manager.GetQuota("org"); quota.MaxSize != 0 {
+		t.Errorf("Expected quota to be removed, got %+v", quota)
+	}
+	if err := manager.CheckQuota("org/team/app", updates, push); err != nil {
+		t.Errorf("Expected push to be accepted, got %v", err)
+	}
+
+	if _, err := manager.SetQuota("org/team/app", Quota{MaxSize: 1}); err != nil {
+		t.Fatalf("Failed to set quota: %v", err)
+	}
+	if err := manager.CheckQuota("org/team/app", updates, push); !errors.As(err, &rejected) ||
+		!strings.Contains(rejected.Rejections[0].Reason, "quota of repository org/team/app exceeded") {
+		t.Errorf("Expected push over the repository quota to be rejected, got %v", err)
+	}
+}
+
+// Test that reserved space counts towards quotas until it is released
+func TestReserveQuota(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	for _, name := range []string{"org/app", "org/lib"} {
+		if _, err := manager.CreateRepository(name, ""); err != nil {
+			t.Fatalf("Failed to create repository: %v", err)
+		}
+	}
+	if _, err := manager.SetQuota("org", Quota{MaxSize: 1 << 20}); err != nil {
+		t.Fatalf("Failed to set quota: %v", err)
+	}
+	usage, err := manager.GetUsage("org")
+	if err != nil {
+		t.Fatalf("Failed to get usage: %v", err)
+	}
+	if _, err := manager.SetQuota("org", Quota{MaxSize: usage.Size + 1500}); err != nil {
+		t.Fatalf("Failed to set quota: %v", err)
+	}
+
+	// Pushes to two repositories of the group do not fit together
+	release, err := manager.ReserveQuota("org/app", 1000)
+	if err != nil {
+		t.Fatalf("Failed to reserve quota: %v", err)
+	}
+	var exceeded *QuotaExceededError
+	if _, err := manager.ReserveQuota("org/lib", 1000); !errors.As(err, &exceeded) || exceeded.Name != "org" {
+		t.Errorf("Expected the group quota to be exceeded, got %v", err)
+	}
+	if err := manager.CheckQuotaSize("org/lib", 1000); !errors.As(err, &exceeded) {
+		t.Errorf("Expected reserved space to be checked, got %v", err)
+	}
+
+	release()
+	release()
+	lib, err := manager.ReserveQuota("org/lib", 1000)
+	if err != nil {
+		t.Errorf("Expected released space to be available, got %v", err)
+	} else {
+		lib()
+	}
+
+	// Closing the objects of a push releases its reservation
+	push := &PushObjectStorage{packSize: 1000}
+	if err := manager.CheckQuota("org/app", []ReferenceUpdate{{Name: plumbing.Master, NewHash: plumbing.NewHash("1111111111111111111111111111111111111111")}}, push); err != nil {
+		t.Fatalf("Expected push to be accepted, got %v", err)
+	}
+	if err := manager.CheckQuotaSize("org/lib", 1000); !errors.As(err, &exceeded) {
+		t.Errorf("Expected the push to reserve space, got %v", err)
+	}
+	push.Close()
+	if err := manager.CheckQuotaSize("org/lib", 1000); err != nil {
+		t.Errorf("Expected closing the push to release its space, got %v", err)
+	}
+}
+
+// testExternalStorage reports fixed sizes by repository
+type testExternalStorage map[string]int64
+
+func (s testExternalStorage) RepositorySize(repoName string) (int64, error) {
+	return s[repoName], nil
+}
+
+// Test that the space used in external storages counts towards usages and quotas
+func TestQuota_ExternalStorage(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	for _, name := range []string{"org/app", "org/team/lib", "other"} {
+		if _, err := manager.CreateRepository(name, ""); err != nil {
+			t.Fatalf("Failed to create repository: %v", err)
+		}
+	}
+
+	before, _ := manager.GetUsage("org")
+	repoBefore, _ := manager.GetUsage("org/app")
+	manager.AddExternalStorage(testExternalStorage{"org/app": 1000, "org/team/lib": 500, "other": 10000})
+
+	if usage, _ := manager.GetUsage("org/app"); usage.Size != repoBefore.Size+1000 {
+		t.Errorf("Expected repository usage to include 1000 external bytes, got %d then %d", repoBefore.Size, usage.Size)
+	}
+	if usage, _ := manager.GetUsage("org"); usage.Size != before.Size+1500 {
+		t.Errorf("Expected group usage to include 1500 external bytes, got %d then %d", before.Size, usage.Size)
+	}
+
+	if _, err := manager.SetQuota("org/app", Quota{MaxSize: repoBefore.Size + 1000}); err != nil {
+		t.Fatalf("Failed to set quota: %v", err)
+	}
+	var exceeded *QuotaExceededError
+	if err := manager.CheckQuotaSize("org/app", 1); !errors.As(err, &exceeded) || exceeded.Name != "org/app" {
+		t.Errorf("Expected QuotaExceededError, got %v", err)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{
+		0:                "0 B",
+		1023:             "1023 B",
+		1024:             "1.0 KiB",
+		1536:             "1.5 KiB",
+		10 * 1024 * 1024: "10.0 MiB",
+		3 << 30:          "3.0 GiB",
+	}
+
+	for size, expected := range tests {
+		if got := formatSize(size); got != expected {
+			t.Errorf("formatSize(%d) = %q, expected %q", size, got, expected)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package repository_manager
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// LockRepository acquires the in-process write lock of a repository and returns its release function.
+// All reference updates done by RepositoryManager hold this lock, and transports should hold it
+// while accepting pushes so that API commits and pushes are serialized per repository.
+func (m *RepositoryManager) LockRepository(name string) func() {
+	value, _ := m.repoLocks.LoadOrStore(name, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// updateReference moves a reference from oldHash to newHash on behalf of pusher.
+// A zero oldHash requires the reference not to exist yet. Branches are checked against
+// their protection rules like pushes, see checkBranchWritable.
+// The update uses a git compatible "<ref>.lock" file, so it is also safe against
+// concurrent updates by git processes working on the same repository.
+func (m *RepositoryManager) updateReference(name, pusher string, repo *git.Repository, refName plumbing.ReferenceName, newHash, oldHash plumbing.Hash) error {
+	update := ReferenceUpdate{Name: refName, OldHash: oldHash, NewHash: newHash}
+	if err := m.checkBranchWritable(name, pusher, repo.Storer, update); err != nil {
+		return err
+	}
+
+	refPath := filepath.Join(m.reposPath, name+".git", filepath.FromSlash(refName.String()))
+	lockPath := refPath + ".lock"
+
+	if err := os.MkdirAll(filepath.Dir(refPath), 0755); err != nil {
+		return WrapSetReferenceError(err)
+	}
+
+	lock, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return NewReferenceLockedError(refName.String())
+	}
+	if err != nil {
+		return WrapSetReferenceError(err)
+	}
+
+	committed := false
+	defer func() {
+		lock.Close()
+		if !committed {
+			os.Remove(lockPath)
+		}
+	}()
+
+	// Compare the current value while holding the lock
+	current := plumbing.ZeroHash
+	if ref, err := repo.Storer.Reference(refName); err == nil {
+		current = ref.Hash()
+	} else if err != plumbing.ErrReferenceNotFound {
+		return WrapSetReferenceError(err)
+	}
+
+	if current != oldHash {
+		return NewReferenceConflictError(refName.String(), oldHash.String(), current.String())
+	}
+
+	if _, err := lock.WriteString(newHash.String() + "\n"); err != nil {
+		return WrapSetReferenceError(err)
+	}
+
+	if err := lock.Close(); err != nil {
+		return WrapSetReferenceError(err)
+	}
+
+	if err := os.Rename(lockPath, refPath); err != nil {
+		return WrapSetReferenceError(err)
+	}
+
+	committed = true
+
+	m.NotifyReferenceUpdates(name, pusher, []ReferenceUpdate{update})
+	return nil
+}
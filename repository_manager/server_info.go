@@ -0,0 +1,117 @@
+package repository_manager
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// UpdateServerInfo regenerates info/refs and objects/info/packs of a repository like
+// git update-server-info. Clients of the dumb HTTP protocol read them to find the references
+// and packfiles of the repository. It is called after every reference update.
+func (m *RepositoryManager) UpdateServerInfo(name string) error {
+	// Validate repository name to prevent path traversal attacks
+	if !isValidRepoName(name) {
+		return ErrRepositoryInvalidName
+	}
+
+	repo, err := m.openRepository(name)
+	if err != nil {
+		return err
+	}
+
+	iter, err := repo.Storer.IterReferences()
+	if err != nil {
+		return WrapListReferencesError(err)
+	}
+
+	var refs []*plumbing.Reference
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Name() == plumbing.HEAD {
+			return nil
+		}
+		if ref.Type() == plumbing.SymbolicReference {
+			resolved, err := storer.ResolveReference(repo.Storer, ref.Name())
+			if err != nil {
+				// Dangling symbolic references are not listed
+				return nil
+			}
+			ref = plumbing.NewHashReference(ref.Name(), resolved.Hash())
+		}
+		refs = append(refs, ref)
+		return nil
+	})
+	if err != nil {
+		return WrapListReferencesError(err)
+	}
+
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].Name() < refs[j].Name()
+	})
+
+	// Annotated tags are followed by the object they point to
+	var info bytes.Buffer
+	for _, ref := range refs {
+		fmt.Fprintf(&info, "%s\t%s\n", ref.Hash(), ref.Name())
+		if ref.Name().IsTag() {
+			if tag, err := object.GetTag(repo.Storer, ref.Hash()); err == nil {
+				fmt.Fprintf(&info, "%s\t%s^{}\n", tag.Target, ref.Name())
+			}
+		}
+	}
+
+	var packs bytes.Buffer
+	if pos, ok := repo.Storer.(storer.PackedObjectStorer); ok {
+		hashes, err := pos.ObjectPacks()
+		if err != nil {
+			return WrapUpdateServerInfoError(err)
+		}
+		for _, hash := range hashes {
+			fmt.Fprintf(&packs, "P pack-%s.pack\n", hash)
+		}
+	}
+	packs.WriteString("\n")
+
+	repoPath := filepath.Join(m.reposPath, name+".git")
+	if err := writeServerInfoFile(filepath.Join(repoPath, "info", "refs"), info.Bytes()); err != nil {
+		return err
+	}
+
+	return writeServerInfoFile(filepath.Join(repoPath, "objects", "info", "packs"), packs.Bytes())
+}
+
+// writeServerInfoFile atomically replaces a server info file, so dumb clients never read a partial file
+func writeServerInfoFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return WrapUpdateServerInfoError(err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return WrapUpdateServerInfoError(err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return WrapUpdateServerInfoError(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return WrapUpdateServerInfoError(err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return WrapUpdateServerInfoError(err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return WrapUpdateServerInfoError(err)
+	}
+
+	return nil
+}
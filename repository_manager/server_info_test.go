@@ -0,0 +1,52 @@
+package repository_manager
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test that the server info files follow tag creation and deletion
+func TestUpdateServerInfo(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	if _, err := manager.CreateRepository("org/app", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	commit := commitTestFile(t, manager, "org/app", "main", "a.txt", "a")
+
+	if _, err := manager.CreateTag("org/app", "v1.0.0", commit.String(), "release", "john"); err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	infoRefs := filepath.Join(tmpDir, "org/app.git/info/refs")
+	data, err := os.ReadFile(infoRefs)
+	if err != nil {
+		t.Fatalf("Failed to read info/refs: %v", err)
+	}
+	for _, line := range []string{
+		commit.String() + "\trefs/heads/main",
+		commit.String() + "\trefs/tags/v1.0.0^{}",
+	} {
+		if !strings.Contains(string(data), line+"\n") {
+			t.Errorf("Expected info/refs to contain %q, got:\n%s", line, data)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "org/app.git/objects/info/packs")); err != nil {
+		t.Errorf("Expected objects/info/packs to exist: %v", err)
+	}
+
+	if err := manager.DeleteTag("org/app", "v1.0.0"); err != nil {
+		t.Fatalf("Failed to delete tag: %v", err)
+	}
+	data, err = os.ReadFile(infoRefs)
+	if err != nil {
+		t.Fatalf("Failed to read info/refs: %v", err)
+	}
+	if strings.Contains(string(data), "refs/tags/v1.0.0") {
+		t.Errorf("Expected deleted tag to be removed from info/refs, got:\n%s", data)
+	}
+}
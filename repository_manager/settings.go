@@ -0,0 +1,135 @@
+package repository_manager
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// repositorySettingsFile is the settings file stored inside a bare repository directory
+	repositorySettingsFile = "settings.json"
+
+	// groupSettingsFile is the settings file stored inside a group directory, next to .groupinfo
+	groupSettingsFile = ".groupsettings"
+)
+
+// settingsSource holds the settings of a repository or group together with its name
+type settingsSource struct {
+	Name     string
+	Settings *Settings
+}
+
+// settingsPath returns the settings file of a repository or group
+func (m *RepositoryManager) settingsPath(name string) (string, error) {
+	if !isValidRepoName(name) {
+		return "", ErrRepositoryInvalidName
+	}
+
+	if m.IsRepository(name) {
+		return filepath.Join(m.reposPath, name+".git", repositorySettingsFile), nil
+	}
+
+	if m.IsGroup(name) {
+		return filepath.Join(m.reposPath, name, groupSettingsFile), nil
+	}
+
+	return "", NewRepositoryNotFoundError(name)
+}
+
+// loadSettings reads the settings of a repository or group.
+// Missing settings files yield empty settings.
+func (m *RepositoryManager) loadSettings(name string) (*Settings, error) {
+	path, err := m.settingsPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return readSettingsFile(path)
+}
+
+// saveSettings atomically replaces the settings of a repository or group
+func (m *RepositoryManager) saveSettings(name string, settings *Settings) error {
+	path, err := m.settingsPath(name)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return WrapWriteSettingsError(err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return WrapWriteSettingsError(err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return WrapWriteSettingsError(err)
+	}
+
+	return nil
+}
+
+// updateSettings applies fn to the settings of a repository or group and saves the result.
+// Updates are serialized so concurrent changes of different rules are not lost.
+func (m *RepositoryManager) updateSettings(name string, fn func(settings *Settings) error) error {
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+
+	settings, err := m.loadSettings(name)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(settings); err != nil {
+		return err
+	}
+
+	return m.saveSettings(name, settings)
+}
+
+// settingsChain returns the settings of every group containing a repository,
+// outermost group first, followed by the settings of the repository itself.
+func (m *RepositoryManager) settingsChain(repoName string) ([]settingsSource, error) {
+	parts := strings.Split(repoName, "/")
+	chain := make([]settingsSource, 0, len(parts))
+
+	for i := 1; i < len(parts); i++ {
+		groupName := strings.Join(parts[:i], "/")
+		settings, err := readSettingsFile(filepath.Join(m.reposPath, groupName, groupSettingsFile))
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, settingsSource{Name: groupName, Settings: settings})
+	}
+
+	settings, err := m.loadSettings(repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(chain, settingsSource{Name: repoName, Settings: settings}), nil
+}
+
+// readSettingsFile reads a settings file, returning empty settings if it does not exist
+func readSettingsFile(path string) (*Settings, error) {
+	settings := &Settings{}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, WrapReadSettingsError(err)
+	}
+
+	if err := json.Unmarshal(data, settings); err != nil {
+		return nil, WrapReadSettingsError(err)
+	}
+
+	return settings, nil
+}
@@ -0,0 +1,159 @@
+package repository_manager
+
+import (
+	"path"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"go.uber.org/zap"
+)
+
+// ListTagProtectionRules returns the tag protection rules configured directly on a repository or group
+func (m *RepositoryManager) ListTagProtectionRules(name string) ([]TagProtectionRule, error) {
+	settings, err := m.loadSettings(name)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]TagProtectionRule, 0, len(settings.TagProtection))
+	return append(rules, settings.TagProtection...), nil
+}
+
+// ListEffectiveTagProtectionRules returns the tag protection rules applying to a repository,
+// including the rules inherited from its groups. Source names the group or repository defining each rule.
+func (m *RepositoryManager) ListEffectiveTagProtectionRules(repoName string) ([]TagProtectionRule, error) {
+	if !m.IsRepository(repoName) {
+		return nil, NewRepositoryNotFoundError(repoName)
+	}
+
+	chain, err := m.settingsChain(repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]TagProtectionRule, 0)
+	for _, source := range chain {
+		for _, rule := range source.Settings.TagProtection {
+			rule.Source = source.Name
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules, nil
+}
+
+// GetTagProtectionRule returns the tag protection rule of a repository or group with the given pattern
+func (m *RepositoryManager) GetTagProtectionRule(name, pattern string) (*TagProtectionRule, error) {
+	settings, err := m.loadSettings(name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rule := range settings.TagProtection {
+		if rule.Pattern == pattern {
+			return &rule, nil
+		}
+	}
+
+	return nil, NewTagProtectionRuleNotFoundError(pattern)
+}
+
+// CreateTagProtectionRule makes the tags matching a pattern immutable in a repository or in every repository of a group
+func (m *RepositoryManager) CreateTagProtectionRule(name string, rule TagProtectionRule) (*TagProtectionRule, error) {
+	if !isValidProtectionPattern(rule.Pattern) {
+		return nil, ErrProtectionPatternInvalid
+	}
+	rule.Source = ""
+
+	err := m.updateSettings(name, func(settings *Settings) error {
+		for _, existing := range settings.TagProtection {
+			if existing.Pattern == rule.Pattern {
+				return NewTagProtectionRuleAlreadyExistsError(rule.Pattern)
+			}
+		}
+
+		settings.TagProtection = append(settings.TagProtection, rule)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("Tag protection rule created", zap.String("name", name), zap.String("pattern", rule.Pattern))
+	return &rule, nil
+}
+
+// DeleteTagProtectionRule removes the tag protection rule with the given pattern
+func (m *RepositoryManager) DeleteTagProtectionRule(name, pattern string) error {
+	err := m.updateSettings(name, func(settings *Settings) error {
+		for i, existing := range settings.TagProtection {
+			if existing.Pattern == pattern {
+				settings.TagProtection = append(settings.TagProtection[:i], settings.TagProtection[i+1:]...)
+				return nil
+			}
+		}
+
+		return NewTagProtectionRuleNotFoundError(pattern)
+	})
+	if err != nil {
+		return err
+	}
+
+	m.logger.Info("Tag protection rule deleted", zap.String("name", name), zap.String("pattern", pattern))
+	return nil
+}
+
+// CheckTagProtection verifies that the reference updates of a push do not move or delete protected tags.
+// Creating a protected tag is allowed. A *PushRejectedError is returned if the push must be refused.
+func (m *RepositoryManager) CheckTagProtection(repoName string, updates []ReferenceUpdate) error {
+	rules, err := m.ListEffectiveTagProtectionRules(repoName)
+	if err != nil {
+		return err
+	}
+
+	rejected := &PushRejectedError{}
+	for _, update := range updates {
+		if !update.Name.IsTag() || update.OldHash.IsZero() {
+			continue
+		}
+
+		if rule := matchingTagRule(rules, update.Name); rule != nil {
+			rejected.Rejections = append(rejected.Rejections, ReferenceRejection{
+				Reference: update.Name.String(),
+				Reason:    "protected tag: tags matching " + rule.Pattern + " cannot be moved or deleted",
+			})
+		}
+	}
+
+	if len(rejected.Rejections) > 0 {
+		return rejected
+	}
+
+	return nil
+}
+
+// checkTagWritable returns a ForbiddenError if an existing tag is protected
+func (m *RepositoryManager) checkTagWritable(repoName, tagName string) error {
+	rules, err := m.ListEffectiveTagProtectionRules(repoName)
+	if err != nil {
+		return err
+	}
+
+	if rule := matchingTagRule(rules, plumbing.NewTagReferenceName(tagName)); rule != nil {
+		return NewProtectedTagError(tagName, rule.Pattern)
+	}
+
+	return nil
+}
+
+// matchingTagRule returns the first rule whose pattern matches a tag, or nil
+func matchingTagRule(rules []TagProtectionRule, refName plumbing.ReferenceName) *TagProtectionRule {
+	tag := refName.Short()
+
+	for i := range rules {
+		if ok, _ := path.Match(rules[i].Pattern, tag); ok {
+			return &rules[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package repository_manager
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Test that protected tags cannot be overwritten or deleted through RepositoryManager
+func TestTagProtection(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	if _, err := manager.CreateGroup("org", ""); err != nil {
+		t.Fatalf("Failed to create group: %v", err)
+	}
+	if _, err := manager.CreateRepository("org/app", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	first := commitTestFile(t, manager, "org/app", "master", "a.txt", "a")
+	second := commitTestFile(t, manager, "org/app", "master", "a.txt", "b")
+
+	if _, err := manager.CreateTagProtectionRule("org", TagProtectionRule{Pattern: "v*"}); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	// Creating a protected tag is allowed, overwriting it is not
+	if _, err := manager.CreateTag("org/app", "v1.0.0", first.String(), "", ""); err != nil {
+		t.Fatalf("Failed to create protected tag: %v", err)
+	}
+
+	var forbidden *ForbiddenError
+	if _, err := manager.CreateTag("org/app", "v1.0.0", second.String(), "", ""); !errors.As(err, &forbidden) {
+		t.Errorf("Expected ForbiddenError when overwriting a protected tag, got %v", err)
+	}
+	if err := manager.DeleteTag("org/app", "v1.0.0"); !errors.As(err, &forbidden) {
+		t.Errorf("Expected ForbiddenError when deleting a protected tag, got %v", err)
+	}
+
+	tag, err := manager.GetTag("org/app", "v1.0.0")
+	if err != nil {
+		t.Fatalf("Failed to get tag: %v", err)
+	}
+	if tag.CommitHash != first.String() {
+		t.Errorf("Expected protected tag to still point to %s, got %s", first, tag.CommitHash)
+	}
+
+	// Unprotected tags can still be replaced and deleted
+	if _, err := manager.CreateTag("org/app", "nightly", first.String(), "", ""); err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+	if _, err := manager.CreateTag("org/app", "nightly", second.String(), "", ""); err != nil {
+		t.Errorf("Failed to overwrite unprotected tag: %v", err)
+	}
+	if err := manager.DeleteTag("org/app", "nightly"); err != nil {
+		t.Errorf("Failed to delete unprotected tag: %v", err)
+	}
+
+	// Pushes may create protected tags but not move or delete them
+	tagRef := plumbing.NewTagReferenceName("v1.0.0")
+	var rejected *PushRejectedError
+	if err := manager.CheckTagProtection("org/app", []ReferenceUpdate{{Name: tagRef, OldHash: first, NewHash: second}}); !errors.As(err, &rejected) {
+		t.Errorf("Expected moving a protected tag to be rejected, got %v", err)
+	}
+	if err := manager.CheckTagProtection("org/app", []ReferenceUpdate{{Name: tagRef, OldHash: first}}); !errors.As(err, &rejected) {
+		t.Errorf("Expected deleting a protected tag to be rejected, got %v", err)
+	}
+	if err := manager.CheckTagProtection("org/app", []ReferenceUpdate{{Name: plumbing.NewTagReferenceName("v2.0.0"), NewHash: second}}); err != nil {
+		t.Errorf("Expected creating a protected tag to be accepted, got %v", err)
+	}
+
+	// Removing the rule makes the tag mutable again
+	if err := manager.DeleteTagProtectionRule("org", "v*"); err != nil {
+		t.Fatalf("Failed to delete rule: %v", err)
+	}
+	if err := manager.DeleteTag("org/app", "v1.0.0"); err != nil {
+		t.Errorf("Failed to delete tag after removing the rule: %v", err)
+	}
+}
@@ -0,0 +1,255 @@
+package repository_manager
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/revlist"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultInitMessage   = "Initial commit"
+	DefaultTemplateRef   = "HEAD"
+	gitignoreTemplateDir = "templates/gitignore"
+	licenseTemplateDir   = "templates/license"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// ListGitignoreTemplates returns the names of the available .gitignore templates
+func ListGitignoreTemplates() []string {
+	return listTemplates(gitignoreTemplateDir, ".gitignore")
+}
+
+// ListLicenseTemplates returns the names of the available license templates
+func ListLicenseTemplates() []string {
+	return listTemplates(licenseTemplateDir, "")
+}
+
+// CreateRepositoryWithOptions creates a new repository with an initial commit.
+// The commit either contains a README, .gitignore and license generated from the options,
+// or a copy of the tree of a template repository at the given ref.
+// The repository is removed again if the initial content cannot be written.
+func (m *RepositoryManager) CreateRepositoryWithOptions(name, description string, opts RepositoryInitOptions) (*Repository, error) {
+	// Validate template repository before creating anything
+	if opts.Template != "" {
+		if !isValidRepoName(opts.Template) {
+			return nil, ErrRepositoryInvalidName
+		}
+		if !m.IsRepository(opts.Template) {
+			return nil, NewRepositoryNotFoundError(opts.Template)
+		}
+	}
+
+	repository, err := m.createRepository(name, description)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.initializeRepository(name, description, opts); err != nil {
+		if rmErr := os.RemoveAll(repository.Path); rmErr != nil {
+			m.logger.Error("Failed to clean up repository after initialization error", zap.String("path", repository.Path), zap.Error(rmErr))
+		}
+		return nil, err
+	}
+
+	// Announce the repository only once it holds its initial commit
+	m.publishRepositoryCreated(name)
+	return repository, nil
+}
+
+// initializeRepository writes the initial commit of a freshly created repository
+func (m *RepositoryManager) initializeRepository(name, description string, opts RepositoryInitOptions) error {
+	repo, err := m.openRepository(name)
+	if err != nil {
+		return err
+	}
+
+	var treeHash plumbing.Hash
+	if opts.Template != "" {
+		treeHash, err = m.copyTemplateTree(opts.Template, opts.TemplateRef, repo.Storer)
+	} else {
+		treeHash, err = m.buildInitialTree(name, description, opts, repo.Storer)
+	}
+	if err != nil {
+		return err
+	}
+
+	message := opts.Message
+	if message == "" {
+		message = DefaultInitMessage
+	}
+
+	sig := m.signature(opts.AuthorName, opts.AuthorEmail)
+	commitHash, err := storeCommit(repo.Storer, &object.Commit{
+		Author:    sig,
+		Committer: sig,
+		Message:   message + "\n",
+		TreeHash:  treeHash,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Commit to the requested branch, or the branch HEAD points to
+	branch := plumbing.NewBranchReferenceName(opts.Branch)
+	if opts.Branch == "" {
+		head, err := repo.Storer.Reference(plumbing.HEAD)
+		if err != nil {
+			return WrapGetHEADError(err)
+		}
+		branch = head.Target()
+	} else if branch.Validate() != nil {
+		return ErrBranchInvalidName
+	}
+
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(branch, commitHash)); err != nil {
+		return WrapSetReferenceError(err)
+	}
+
+	if err := repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branch)); err != nil {
+		return WrapSetReferenceError(err)
+	}
+
+	m.logger.Info("Repository initialized", zap.String("name", name), zap.String("branch", branch.Short()), zap.String("commit", commitHash.String()))
+	return nil
+}
+
+// buildInitialTree writes a tree with README, .gitignore and LICENSE files as requested
+func (m *RepositoryManager) buildInitialTree(name, description string, opts RepositoryInitOptions, s storer.EncodedObjectStorer) (plumbing.Hash, error) {
+	files := make(map[string][]byte)
+
+	if opts.Readme {
+		readme := "# " + path.Base(name) + "\n"
+		if description != "" {
+			readme += "\n" + description + "\n"
+		}
+		files["README.md"] = []byte(readme)
+	}
+
+	if opts.Gitignore != "" {
+		content, err := readTemplate(gitignoreTemplateDir, opts.Gitignore+".gitignore")
+		if err != nil {
+			return plumbing.ZeroHash, NewTemplateNotFoundError("gitignore template", opts.Gitignore)
+		}
+		files[".gitignore"] = content
+	}
+
+	if opts.License != "" {
+		content, err := readTemplate(licenseTemplateDir, opts.License)
+		if err != nil {
+			return plumbing.ZeroHash, NewTemplateNotFoundError("license template", opts.License)
+		}
+
+		author := opts.AuthorName
+		if author == "" {
+			author = m.signature("", "").Name
+		}
+		replacer := strings.NewReplacer("{{year}}", strconv.Itoa(time.Now().Year()), "{{author}}", author)
+		files["LICENSE"] = []byte(replacer.Replace(string(content)))
+	}
+
+	changes := make([]treeChange, 0, len(files))
+	for filePath, content := range files {
+		blobHash, err := storeBlob(s, content)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		changes = append(changes, treeChange{Path: filePath, Blob: blobHash})
+	}
+
+	treeHash, _, err := applyTreeChanges(s, nil, changes)
+	return treeHash, err
+}
+
+// copyTemplateTree copies the tree of a template repository at ref into the target storage
+func (m *RepositoryManager) copyTemplateTree(template, ref string, s storer.EncodedObjectStorer) (plumbing.Hash, error) {
+	src, err := m.openRepository(template)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if ref == "" {
+		ref = DefaultTemplateRef
+	}
+
+	hash, err := src.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return plumbing.ZeroHash, NewRevisionNotFoundError(ref)
+	}
+
+	commit, err := src.CommitObject(*hash)
+	if err != nil {
+		return plumbing.ZeroHash, WrapCommitNotFoundError(err)
+	}
+
+	hashes, err := revlist.Objects(src.Storer, []plumbing.Hash{commit.TreeHash}, nil)
+	if err != nil {
+		return plumbing.ZeroHash, WrapCollectObjectsError(err)
+	}
+
+	for _, h := range hashes {
+		obj, err := src.Storer.EncodedObject(plumbing.AnyObject, h)
+		if err != nil {
+			return plumbing.ZeroHash, WrapCollectObjectsError(err)
+		}
+
+		if _, err := s.SetEncodedObject(obj); err != nil {
+			return plumbing.ZeroHash, WrapStoreObjectError(err)
+		}
+	}
+
+	return commit.TreeHash, nil
+}
+
+// signature returns a commit signature, falling back to the configured default author
+func (m *RepositoryManager) signature(name, email string) object.Signature {
+	if name == "" {
+		name = m.authorName
+	}
+	if name == "" {
+		name = DefaultAuthorName
+	}
+
+	if email == "" {
+		email = m.authorEmail
+	}
+	if email == "" {
+		email = DefaultAuthorEmail
+	}
+
+	return object.Signature{Name: name, Email: email, When: time.Now()}
+}
+
+func readTemplate(dir, name string) ([]byte, error) {
+	if strings.Contains(name, "/") || strings.Contains(name, "..") {
+		return nil, fmt.Errorf("invalid template name: %s", name)
+	}
+	return templateFS.ReadFile(dir + "/" + name)
+}
+
+func listTemplates(dir, suffix string) []string {
+	entries, err := templateFS.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), suffix))
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,154 @@
+package repository_manager
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/weedbox/git-modules/events"
+	"go.uber.org/zap"
+)
+
+// Test creating a repository with README, .gitignore and license
+func TestCreateRepositoryWithOptions_Files(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	_, err := manager.CreateRepositoryWithOptions("org/service", "My service", RepositoryInitOptions{
+		Readme:      true,
+		Gitignore:   "Go",
+		License:     "MIT",
+		Branch:      "main",
+		AuthorName:  "Jane Doe",
+		AuthorEmail: "jane@example.com",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	repo, _ := manager.openRepository("org/service")
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatalf("Failed to get HEAD: %v", err)
+	}
+	if head.Name() != plumbing.NewBranchReferenceName("main") {
+		t.Errorf("Expected HEAD to point to main, got %s", head.Name())
+	}
+
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatalf("Failed to get commit: %v", err)
+	}
+	if commit.Author.Name != "Jane Doe" || commit.Author.Email != "jane@example.com" {
+		t.Errorf("Unexpected author: %v", commit.Author)
+	}
+
+	expected := map[string]string{
+		"README.md":  "My service",
+		".gitignore": "vendor/",
+		"LICENSE":    "Copyright (c)",
+	}
+	for name, content := range expected {
+		file, err := commit.File(name)
+		if err != nil {
+			t.Errorf("File %s missing from initial commit: %v", name, err)
+			continue
+		}
+		data, _ := file.Contents()
+		if !strings.Contains(data, content) {
+			t.Errorf("File %s does not contain %q", name, content)
+		}
+	}
+
+	license, _ := commit.File("LICENSE")
+	data, _ := license.Contents()
+	if !strings.Contains(data, "Jane Doe") || strings.Contains(data, "{{") {
+		t.Errorf("License placeholders were not replaced: %s", data)
+	}
+}
+
+// Test creating a repository from a template repository
+func TestCreateRepositoryWithOptions_Template(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	if _, err := manager.CreateRepository("templates/base", "Template"); err != nil {
+		t.Fatalf("Failed to create template repository: %v", err)
+	}
+	base := commitTestFile(t, manager, "templates/base", "master", "main.go", "package main")
+	commitTestFile(t, manager, "templates/base", "master", "main.go", "package main // newer")
+
+	if _, err := manager.CreateTag("templates/base", "v1", base.String(), "", ""); err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	_, err := manager.CreateRepositoryWithOptions("app", "", RepositoryInitOptions{
+		Template:    "templates/base",
+		TemplateRef: "v1",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create repository from template: %v", err)
+	}
+
+	repo, _ := manager.openRepository("app")
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatalf("Failed to get HEAD: %v", err)
+	}
+
+	commit, _ := repo.CommitObject(head.Hash())
+	if commit.NumParents() != 0 {
+		t.Errorf("Expected a single root commit, got %d parents", commit.NumParents())
+	}
+
+	file, err := commit.File("main.go")
+	if err != nil {
+		t.Fatalf("Template file missing: %v", err)
+	}
+	if data, _ := file.Contents(); data != "package main" {
+		t.Errorf("Expected template content at tag v1, got %q", data)
+	}
+}
+
+// Test invalid initialization options
+func TestCreateRepositoryWithOptions_Invalid(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	bus := events.NewBus(zap.NewNop())
+	manager.params.Bus = bus
+
+	var received []events.Event
+	bus.Subscribe(func(event events.Event) {
+		received = append(received, event)
+	})
+
+	testCases := []struct {
+		name string
+		opts RepositoryInitOptions
+	}{
+		{"unknown gitignore", RepositoryInitOptions{Gitignore: "Cobol"}},
+		{"unknown license", RepositoryInitOptions{License: "Proprietary"}},
+		{"template path traversal", RepositoryInitOptions{License: "../license/MIT"}},
+		{"missing template", RepositoryInitOptions{Template: "missing"}},
+		{"invalid branch", RepositoryInitOptions{Readme: true, Branch: "bad..branch"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := manager.CreateRepositoryWithOptions("repo", "", tc.opts); err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+
+			if manager.IsRepository("repo") {
+				t.Error("Repository still exists after failed initialization")
+			}
+		})
+	}
+
+	// Repositories are only announced once initialized
+	bus.Close()
+	if len(received) != 0 {
+		t.Errorf("Expected no events for failed initializations, got %+v", received)
+	}
+}
@@ -0,0 +1,627 @@
+package repository_manager
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Webhook events
+const (
+	WebhookEventPush             = "push"
+	WebhookEventTagCreate        = "tag_create"
+	WebhookEventTagDelete        = "tag_delete"
+	WebhookEventBranchCreate     = "branch_create"
+	WebhookEventBranchDelete     = "branch_delete"
+	WebhookEventRepositoryCreate = "repository_create"
+	WebhookEventRepositoryDelete = "repository_delete"
+)
+
+var webhookEvents = []string{
+	WebhookEventPush,
+	WebhookEventTagCreate,
+	WebhookEventTagDelete,
+	WebhookEventBranchCreate,
+	WebhookEventBranchDelete,
+	WebhookEventRepositoryCreate,
+	WebhookEventRepositoryDelete,
+}
+
+const (
+	// repositoryDeliveriesFile is the webhook delivery history stored inside a bare repository directory
+	repositoryDeliveriesFile = "webhook_deliveries.json"
+
+	// groupDeliveriesFile is the webhook delivery history stored inside a group directory
+	groupDeliveriesFile = ".webhookdeliveries"
+
+	// maxWebhookDeliveries is the number of deliveries kept per webhook
+	maxWebhookDeliveries = 50
+)
+
+// Webhook request headers
+const (
+	WebhookHeaderEvent     = "X-Git-Event"
+	WebhookHeaderDelivery  = "X-Git-Delivery"
+	WebhookHeaderSignature = "X-Hub-Signature-256"
+)
+
+// ListWebhooks returns the webhooks registered directly on a repository or group
+func (m *RepositoryManager) ListWebhooks(name string) ([]Webhook, error) {
+	settings, err := m.loadSettings(name)
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks := make([]Webhook, 0, len(settings.Webhooks))
+	for _, hook := range settings.Webhooks {
+		webhooks = append(webhooks, redactWebhook(hook))
+	}
+
+	return webhooks, nil
+}
+
+// ListEffectiveWebhooks returns the webhooks notified of the events of a repository,
+// including the webhooks of its groups. Source names the group or repository defining each webhook.
+func (m *RepositoryManager) ListEffectiveWebhooks(repoName string) ([]Webhook, error) {
+	webhooks, err := m.effectiveWebhooks(repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range webhooks {
+		webhooks[i] = redactWebhook(webhooks[i])
+	}
+
+	return webhooks, nil
+}
+
+// GetWebhook returns the webhook of a repository or group with the given ID
+func (m *RepositoryManager) GetWebhook(name, id string) (*Webhook, error) {
+	settings, err := m.loadSettings(name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hook := range settings.Webhooks {
+		if hook.ID == id {
+			hook = redactWebhook(hook)
+			return &hook, nil
+		}
+	}
+
+	return nil, NewWebhookNotFoundError(id)
+}
+
+// CreateWebhook registers a webhook on a repository or group.
+// An empty event filter subscribes the webhook to all events.
+func (m *RepositoryManager) CreateWebhook(name string, hook Webhook) (*Webhook, error) {
+	if err := m.validateWebhook(hook); err != nil {
+		return nil, err
+	}
+
+	id, err := newWebhookID()
+	if err != nil {
+		return nil, err
+	}
+
+	hook.ID = id
+	hook.HasSecret = false
+	hook.Source = ""
+	hook.CreatedAt = time.Now()
+
+	err = m.updateSettings(name, func(settings *Settings) error {
+		settings.Webhooks = append(settings.Webhooks, hook)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("Webhook created", zap.String("name", name), zap.String("id", hook.ID), zap.String("url", hook.URL))
+
+	hook = redactWebhook(hook)
+	return &hook, nil
+}
+
+// UpdateWebhook replaces the URL and event filter of a webhook.
+// The secret is only replaced if a new one is given.
+func (m *RepositoryManager) UpdateWebhook(name, id string, hook Webhook) (*Webhook, error) {
+	if err := m.validateWebhook(hook); err != nil {
+		return nil, err
+	}
+
+	var updated Webhook
+	err := m.updateSettings(name, func(settings *Settings) error {
+		for i, existing := range settings.Webhooks {
+			if existing.ID != id {
+				continue
+			}
+
+			existing.URL = hook.URL
+			existing.Events = hook.Events
+			if hook.Secret != "" {
+				existing.Secret = hook.Secret
+			}
+
+			settings.Webhooks[i] = existing
+			updated = existing
+			return nil
+		}
+
+		return NewWebhookNotFoundError(id)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("Webhook updated", zap.String("name", name), zap.String("id", id), zap.String("url", updated.URL))
+
+	updated = redactWebhook(updated)
+	return &updated, nil
+}
+
+// DeleteWebhook removes a webhook and its delivery history
+func (m *RepositoryManager) DeleteWebhook(name, id string) error {
+	err := m.updateSettings(name, func(settings *Settings) error {
+		for i, existing := range settings.Webhooks {
+			if existing.ID == id {
+				settings.Webhooks = append(settings.Webhooks[:i], settings.Webhooks[i+1:]...)
+				return nil
+			}
+		}
+
+		return NewWebhookNotFoundError(id)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = m.updateWebhookDeliveries(name, func(deliveries []WebhookDelivery) []WebhookDelivery {
+		kept := deliveries[:0]
+		for _, d := range deliveries {
+			if d.WebhookID != id {
+				kept = append(kept, d)
+			}
+		}
+		return kept
+	})
+	if err != nil {
+		m.logger.Warn("Failed to remove webhook deliveries", zap.String("name", name), zap.String("id", id), zap.Error(err))
+	}
+
+	m.logger.Info("Webhook deleted", zap.String("name", name), zap.String("id", id))
+	return nil
+}
+
+// ListWebhookDeliveries returns the recent deliveries of a webhook, newest first
+func (m *RepositoryManager) ListWebhookDeliveries(name, id string) ([]WebhookDelivery, error) {
+	if _, err := m.GetWebhook(name, id); err != nil {
+		return nil, err
+	}
+
+	path, err := m.deliveriesPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	m.deliveriesMu.Lock()
+	all, err := readDeliveriesFile(path)
+	m.deliveriesMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]WebhookDelivery, 0)
+	for _, d := range all {
+		if d.WebhookID == id {
+			deliveries = append(deliveries, d)
+		}
+	}
+
+	return deliveries, nil
+}
+
+// NotifyReferenceUpdates publishes the events of reference updates applied to a repository
+// and sends the matching webhook events: push, branch create/delete and tag create/delete.
+// The server info files read by dumb HTTP clients are regenerated as well.
+// Transports call it after a push has been applied.
+func (m *RepositoryManager) NotifyReferenceUpdates(repoName, pusher string, updates []ReferenceUpdate) {
+	if err := m.UpdateServerInfo(repoName); err != nil {
+		m.logger.Warn("Failed to update server info", zap.String("repo", repoName), zap.Error(err))
+	}
+
+	m.publishReferenceUpdates(repoName, pusher, updates)
+
+	for _, update := range updates {
+		payload := WebhookPayload{
+			Repository: repoName,
+			Ref:        update.Name.String(),
+			Before:     update.OldHash.String(),
+			After:      update.NewHash.String(),
+			Pusher:     pusher,
+		}
+
+		events := referenceUpdateEvents(update)
+		for _, event := range events {
+			payload.Event = event
+			m.dispatchWebhookEvent(payload)
+		}
+	}
+}
+
+// referenceUpdateEvents returns the webhook events of a single reference update
+func referenceUpdateEvents(update ReferenceUpdate) []string {
+	switch {
+	case update.Name.IsTag() && update.IsDelete():
+		return []string{WebhookEventTagDelete}
+	case update.Name.IsTag():
+		return []string{WebhookEventTagCreate}
+	case !update.Name.IsBranch():
+		return nil
+	case update.IsDelete():
+		return []string{WebhookEventBranchDelete}
+	case update.IsCreate():
+		return []string{WebhookEventBranchCreate, WebhookEventPush}
+	default:
+		return []string{WebhookEventPush}
+	}
+}
+
+// dispatchWebhookEvent delivers an event to the webhooks of its repository in the background
+func (m *RepositoryManager) dispatchWebhookEvent(payload WebhookPayload) {
+	webhooks, err := m.effectiveWebhooks(payload.Repository)
+	if err != nil {
+		m.logger.Warn("Failed to load webhooks", zap.String("repo", payload.Repository), zap.Error(err))
+		return
+	}
+
+	m.deliverWebhookEvent(webhooks, payload)
+}
+
+// deliverWebhookEvent delivers an event to the subscribed webhooks in the background.
+// Each webhook gets its own delivery with retries.
+func (m *RepositoryManager) deliverWebhookEvent(webhooks []Webhook, payload WebhookPayload) {
+	if payload.Timestamp.IsZero() {
+		payload.Timestamp = time.Now()
+	}
+
+	var body []byte
+	for _, hook := range webhooks {
+		if !webhookSubscribes(hook, payload.Event) {
+			continue
+		}
+
+		if body == nil {
+			data, err := json.Marshal(payload)
+			if err != nil {
+				m.logger.Error("Failed to encode webhook payload", zap.Error(err))
+				return
+			}
+			body = data
+		}
+
+		m.webhookWG.Add(1)
+		go func(hook Webhook) {
+			defer m.webhookWG.Done()
+			m.deliverWebhook(hook, payload, body)
+		}(hook)
+	}
+}
+
+// deliverWebhook posts a payload to a webhook, retrying failed attempts with exponential backoff,
+// and records the outcome in the delivery history of the webhook owner
+func (m *RepositoryManager) deliverWebhook(hook Webhook, payload WebhookPayload, body []byte) {
+	ctx := m.webhookCtx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	id, err := newWebhookID()
+	if err != nil {
+		m.logger.Error("Failed to create webhook delivery ID", zap.Error(err))
+		return
+	}
+
+	delivery := WebhookDelivery{
+		ID:         id,
+		WebhookID:  hook.ID,
+		Event:      payload.Event,
+		Repository: payload.Repository,
+		URL:        hook.URL,
+	}
+
+	maxAttempts := max(m.webhookMaxAttempts, 1)
+	backoff := m.webhookRetryBackoff
+
+retry:
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		delivery.Attempts = attempt
+		delivery.StatusCode, err = m.postWebhook(ctx, hook, delivery.ID, payload.Event, body)
+		delivery.DeliveredAt = time.Now()
+
+		if err == nil && delivery.StatusCode >= 200 && delivery.StatusCode < 300 {
+			delivery.Success = true
+			delivery.Error = ""
+			break
+		}
+
+		if err != nil {
+			delivery.Error = err.Error()
+		} else {
+			delivery.Error = fmt.Sprintf("unexpected status code %d", delivery.StatusCode)
+		}
+
+		if attempt == maxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			break retry
+		case <-time.After(backoff):
+			backoff *= 2
+		}
+	}
+
+	if !delivery.Success {
+		m.logger.Warn("Webhook delivery failed",
+			zap.String("repo", payload.Repository),
+			zap.String("webhook", hook.ID),
+			zap.String("event", payload.Event),
+			zap.Int("attempts", delivery.Attempts),
+			zap.String("error", delivery.Error),
+		)
+	}
+
+	// The settings of a deleted repository are gone with it, so its own hooks keep no history
+	if payload.Event == WebhookEventRepositoryDelete && hook.Source == payload.Repository {
+		return
+	}
+
+	err = m.updateWebhookDeliveries(hook.Source, func(deliveries []WebhookDelivery) []WebhookDelivery {
+		deliveries = append([]WebhookDelivery{delivery}, deliveries...)
+
+		kept := deliveries[:0]
+		count := 0
+		for _, d := range deliveries {
+			if d.WebhookID == hook.ID {
+				if count == maxWebhookDeliveries {
+					continue
+				}
+				count++
+			}
+			kept = append(kept, d)
+		}
+		return kept
+	})
+	if err != nil {
+		m.logger.Warn("Failed to record webhook delivery", zap.String("webhook", hook.ID), zap.Error(err))
+	}
+}
+
+// postWebhook sends a single delivery attempt and returns the response status code
+func (m *RepositoryManager) postWebhook(ctx context.Context, hook Webhook, deliveryID, event string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "git-modules-webhook")
+	req.Header.Set(WebhookHeaderEvent, event)
+	req.Header.Set(WebhookHeaderDelivery, deliveryID)
+	if hook.Secret != "" {
+		req.Header.Set(WebhookHeaderSignature, SignWebhookPayload(hook.Secret, body))
+	}
+
+	client := m.webhookClient
+	if client == nil {
+		client = newWebhookClient(0, m.webhookAllowedHosts)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
+
+// newWebhookClient returns the HTTP client delivering webhooks. It refuses to connect to loopback,
+// link-local and unspecified addresses, so webhooks cannot reach the server itself or cloud metadata
+// endpoints, unless the host of the URL is listed in allowedHosts. Addresses are checked after name
+// resolution, which covers redirects and host names resolving to blocked addresses.
+func newWebhookClient(timeout time.Duration, allowedHosts []string) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	guarded := &net.Dialer{
+		Timeout:   dialer.Timeout,
+		KeepAlive: dialer.KeepAlive,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isBlockedWebhookIP(ip) {
+				return fmt.Errorf("%w: %s", ErrWebhookURLForbidden, host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err == nil && containsString(allowedHosts, host) {
+			return dialer.DialContext(ctx, network, address)
+		}
+		return guarded.DialContext(ctx, network, address)
+	}
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// isBlockedWebhookIP reports whether webhooks may not be delivered to an address without allowing its host
+func isBlockedWebhookIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified()
+}
+
+// SignWebhookPayload returns the signature header value of a payload: "sha256=" followed by
+// the hex encoded HMAC-SHA256 of the body keyed with the webhook secret
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// effectiveWebhooks returns the webhooks of a repository and its groups, including their secrets
+func (m *RepositoryManager) effectiveWebhooks(repoName string) ([]Webhook, error) {
+	if !m.IsRepository(repoName) {
+		return nil, NewRepositoryNotFoundError(repoName)
+	}
+
+	chain, err := m.settingsChain(repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks := make([]Webhook, 0)
+	for _, source := range chain {
+		for _, hook := range source.Settings.Webhooks {
+			hook.Source = source.Name
+			webhooks = append(webhooks, hook)
+		}
+	}
+
+	return webhooks, nil
+}
+
+// deliveriesPath returns the webhook delivery history file of a repository or group
+func (m *RepositoryManager) deliveriesPath(name string) (string, error) {
+	if !isValidRepoName(name) {
+		return "", ErrRepositoryInvalidName
+	}
+
+	if m.IsRepository(name) {
+		return filepath.Join(m.reposPath, name+".git", repositoryDeliveriesFile), nil
+	}
+
+	if m.IsGroup(name) {
+		return filepath.Join(m.reposPath, name, groupDeliveriesFile), nil
+	}
+
+	return "", NewRepositoryNotFoundError(name)
+}
+
+// updateWebhookDeliveries atomically rewrites the delivery history of a repository or group
+func (m *RepositoryManager) updateWebhookDeliveries(name string, fn func(deliveries []WebhookDelivery) []WebhookDelivery) error {
+	m.deliveriesMu.Lock()
+	defer m.deliveriesMu.Unlock()
+
+	path, err := m.deliveriesPath(name)
+	if err != nil {
+		return err
+	}
+
+	deliveries, err := readDeliveriesFile(path)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(fn(deliveries), "", "  ")
+	if err != nil {
+		return WrapWriteWebhookDeliveriesError(err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return WrapWriteWebhookDeliveriesError(err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return WrapWriteWebhookDeliveriesError(err)
+	}
+
+	return nil
+}
+
+// readDeliveriesFile reads a delivery history file, a missing file yields no deliveries
+func readDeliveriesFile(path string) ([]WebhookDelivery, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return []WebhookDelivery{}, nil
+	}
+	if err != nil {
+		return nil, WrapReadWebhookDeliveriesError(err)
+	}
+
+	var deliveries []WebhookDelivery
+	if err := json.Unmarshal(data, &deliveries); err != nil {
+		return nil, WrapReadWebhookDeliveriesError(err)
+	}
+
+	return deliveries, nil
+}
+
+// validateWebhook checks the URL and event filter of a webhook.
+// URLs naming a blocked address directly are refused here, host names resolving to one when delivering.
+func (m *RepositoryManager) validateWebhook(hook Webhook) error {
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrWebhookURLInvalid
+	}
+
+	host := u.Hostname()
+	if !containsString(m.webhookAllowedHosts, host) {
+		if ip := net.ParseIP(host); (ip != nil && isBlockedWebhookIP(ip)) || strings.EqualFold(host, "localhost") {
+			return ErrWebhookURLForbidden
+		}
+	}
+
+	for _, event := range hook.Events {
+		if !containsString(webhookEvents, event) {
+			return ErrWebhookEventInvalid
+		}
+	}
+
+	return nil
+}
+
+// webhookSubscribes reports whether a webhook receives an event
+func webhookSubscribes(hook Webhook, event string) bool {
+	return len(hook.Events) == 0 || containsString(hook.Events, event)
+}
+
+// redactWebhook removes the secret of a webhook returned to callers
+func redactWebhook(hook Webhook) Webhook {
+	hook.HasSecret = hook.Secret != ""
+	hook.Secret = ""
+	return hook
+}
+
+// newWebhookID returns a random identifier for webhooks and deliveries
+func newWebhookID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,250 @@
+package repository_manager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// Test webhook registration, signed delivery of events and the delivery history
+func TestWebhooks(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	var mu sync.Mutex
+	var events []WebhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(WebhookHeaderSignature) != SignWebhookPayload("s3cr3t", body) {
+			t.Errorf("Invalid signature %q", r.Header.Get(WebhookHeaderSignature))
+		}
+
+		var payload WebhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("Invalid payload: %v", err)
+		}
+		if r.Header.Get(WebhookHeaderEvent) != payload.Event {
+			t.Errorf("Expected event header %s, got %s", payload.Event, r.Header.Get(WebhookHeaderEvent))
+		}
+
+		mu.Lock()
+		events = append(events, payload)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	if _, err := manager.CreateGroup("org", ""); err != nil {
+		t.Fatalf("Failed to create group: %v", err)
+	}
+
+	if _, err := manager.CreateWebhook("org", Webhook{URL: "ftp://example.com"}); !errors.Is(err, ErrWebhookURLInvalid) {
+		t.Errorf("Expected ErrWebhookURLInvalid, got %v", err)
+	}
+	if _, err := manager.CreateWebhook("org", Webhook{URL: server.URL, Events: []string{"deploy"}}); !errors.Is(err, ErrWebhookEventInvalid) {
+		t.Errorf("Expected ErrWebhookEventInvalid, got %v", err)
+	}
+
+	hook, err := manager.CreateWebhook("org", Webhook{
+		URL:    server.URL,
+		Secret: "s3cr3t",
+		Events: []string{WebhookEventRepositoryCreate, WebhookEventTagCreate},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+	if hook.Secret != "" || !hook.HasSecret {
+		t.Errorf("Expected secret to be redacted, got %+v", hook)
+	}
+
+	if _, err := manager.CreateRepository("org/app", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	commit := commitTestFile(t, manager, "org/app", "master", "a.txt", "a")
+	if _, err := manager.CreateTag("org/app", "v1.0.0", commit.String(), "", "jane"); err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+	manager.webhookWG.Wait()
+
+	// Pushes to master are filtered out
+	mu.Lock()
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %+v", events)
+	}
+	for _, payload := range events {
+		if payload.Repository != "org/app" {
+			t.Errorf("Expected repository org/app, got %s", payload.Repository)
+		}
+		// The tagger is not an authenticated pusher
+		if payload.Event == WebhookEventTagCreate && (payload.Ref != "refs/tags/v1.0.0" || payload.After != commit.String() || payload.Pusher != "") {
+			t.Errorf("Unexpected tag payload %+v", payload)
+		}
+	}
+	mu.Unlock()
+
+	deliveries, err := manager.ListWebhookDeliveries("org", hook.ID)
+	if err != nil {
+		t.Fatalf("Failed to list deliveries: %v", err)
+	}
+	if len(deliveries) != 2 || !deliveries[0].Success || deliveries[0].StatusCode != http.StatusOK {
+		t.Errorf("Unexpected deliveries %+v", deliveries)
+	}
+
+	if err := manager.DeleteWebhook("org", hook.ID); err != nil {
+		t.Fatalf("Failed to delete webhook: %v", err)
+	}
+	if _, err := manager.ListWebhookDeliveries("org", hook.ID); !isNotFound(err) {
+		t.Errorf("Expected NotFoundError after delete, got %v", err)
+	}
+}
+
+// Test that failed deliveries are retried and recorded
+func TestWebhookRetries(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	manager.webhookMaxAttempts = 3
+	manager.webhookRetryBackoff = time.Millisecond
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	if _, err := manager.CreateRepository("app", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	hook, err := manager.CreateWebhook("app", Webhook{URL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+
+	manager.NotifyReferenceUpdates("app", "john", []ReferenceUpdate{{Name: "refs/heads/feature", NewHash: [20]byte{1}}})
+	manager.webhookWG.Wait()
+
+	deliveries, err := manager.ListWebhookDeliveries("app", hook.ID)
+	if err != nil {
+		t.Fatalf("Failed to list deliveries: %v", err)
+	}
+
+	// A created branch yields a branch_create and a push event
+	if len(deliveries) != 2 || attempts.Load() != 6 {
+		t.Fatalf("Expected 2 deliveries with 6 attempts, got %d attempts: %+v", attempts.Load(), deliveries)
+	}
+	for _, d := range deliveries {
+		if d.Success || d.Attempts != 3 || d.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("Unexpected delivery %+v", d)
+		}
+	}
+}
+
+// Test that deleting a repository notifies its own and group hooks, keeping history only for group hooks
+func TestWebhookRepositoryDelete(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	core, logs := observer.New(zap.WarnLevel)
+	manager.logger = zap.New(core)
+
+	var received atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(WebhookHeaderEvent) == WebhookEventRepositoryDelete {
+			received.Add(1)
+		}
+	}))
+	defer server.Close()
+
+	if _, err := manager.CreateRepository("org/app", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	groupHook, err := manager.CreateWebhook("org", Webhook{URL: server.URL, Events: []string{WebhookEventRepositoryDelete}})
+	if err != nil {
+		t.Fatalf("Failed to create group webhook: %v", err)
+	}
+	if _, err := manager.CreateWebhook("org/app", Webhook{URL: server.URL, Events: []string{WebhookEventRepositoryDelete}}); err != nil {
+		t.Fatalf("Failed to create repository webhook: %v", err)
+	}
+
+	if err := manager.DeleteRepository("org/app"); err != nil {
+		t.Fatalf("Failed to delete repository: %v", err)
+	}
+	manager.webhookWG.Wait()
+
+	if received.Load() != 2 {
+		t.Errorf("Expected 2 repository_delete events, got %d", received.Load())
+	}
+	if logs.Len() != 0 {
+		t.Errorf("Unexpected warnings %+v", logs.All())
+	}
+
+	deliveries, err := manager.ListWebhookDeliveries("org", groupHook.ID)
+	if err != nil {
+		t.Fatalf("Failed to list deliveries: %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Event != WebhookEventRepositoryDelete || !deliveries[0].Success {
+		t.Errorf("Unexpected deliveries %+v", deliveries)
+	}
+}
+
+// Test that webhooks cannot target loopback or link-local addresses unless their host is allowed
+func TestWebhookForbiddenHosts(t *testing.T) {
+	manager, tmpDir := setupTestManager(t)
+	defer teardownTestManager(tmpDir)
+
+	manager.webhookAllowedHosts = nil
+
+	if _, err := manager.CreateRepository("app", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	for _, target := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://[::1]/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://0.0.0.0/hook",
+	} {
+		if _, err := manager.CreateWebhook("app", Webhook{URL: target}); !errors.Is(err, ErrWebhookURLForbidden) {
+			t.Errorf("Expected ErrWebhookURLForbidden for %s, got %v", target, err)
+		}
+	}
+	if _, err := manager.CreateWebhook("app", Webhook{URL: "https://hooks.example.com/ci"}); err != nil {
+		t.Errorf("Expected public host to be accepted, got %v", err)
+	}
+
+	manager.webhookAllowedHosts = []string{"localhost"}
+	if _, err := manager.CreateWebhook("app", Webhook{URL: "http://localhost:8080/hook"}); err != nil {
+		t.Errorf("Expected allowed host to be accepted, got %v", err)
+	}
+
+	// Addresses are checked again when connecting, catching host names resolving to blocked addresses
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	port := server.Listener.Addr().(*net.TCPAddr).Port
+
+	if _, err := newWebhookClient(time.Second, nil).Get(fmt.Sprintf("http://localhost:%d", port)); !errors.Is(err, ErrWebhookURLForbidden) {
+		t.Errorf("Expected ErrWebhookURLForbidden when connecting, got %v", err)
+	}
+	resp, err := newWebhookClient(time.Second, []string{"localhost"}).Get(fmt.Sprintf("http://localhost:%d", port))
+	if err != nil {
+		t.Fatalf("Expected allowed host to be reachable, got %v", err)
+	}
+	resp.Body.Close()
+}
+
+func isNotFound(err error) bool {
+	var notFound *NotFoundError
+	return errors.As(err, &notFound)
+}
@@ -0,0 +1,95 @@
+package repository_manager_apis
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weedbox/git-modules/audit"
+	"github.com/weedbox/git-modules/auth"
+	"go.uber.org/zap"
+)
+
+// maxAuditQueryLimit caps the number of audit entries returned by a single query
+const maxAuditQueryLimit = 1000
+
+// recordAudit writes an audit entry for a management operation once its handler has responded.
+// The outcome is derived from the response status.
+func (m *RepositoryManagerAPIs) recordAudit(c *gin.Context, action, repository, target string) {
+	status := c.Writer.Status()
+
+	entry := audit.Entry{
+		Actor:      auth.NameFromContext(c.Request.Context()),
+		Action:     action,
+		Repository: repository,
+		Target:     target,
+		RemoteAddr: c.ClientIP(),
+		Outcome:    audit.OutcomeSuccess,
+		Status:     status,
+	}
+	if status >= http.StatusBadRequest {
+		entry.Outcome = audit.OutcomeFailure
+		entry.Error = http.StatusText(status)
+	}
+
+	m.params.AuditLog.Record(entry)
+}
+
+// handleListAuditEntries handles GET /apis/v1/audit
+// @Summary Query the audit log
+// @Description List audit log entries of management and git operations, newest first
+// @Tags Audit
+// @Produce json
+// @Param actor query string false "Actor name" example:"john"
+// @Param repo query string false "Repository or group, including everything below a group" example:"myorg/myrepo"
+// @Param action query string false "Action" example:"tag.create"
+// @Param since query string false "Earliest entry time (RFC 3339)" example:"2024-01-01T00:00:00Z"
+// @Param until query string false "Latest entry time (RFC 3339)" example:"2024-12-31T23:59:59Z"
+// @Param limit query int false "Maximum number of entries (default 100, max 1000)" example:"100"
+// @Success 200 {array} audit.Entry "Audit log entries"
+// @Failure 400 {object} ErrorResponse "Invalid query parameter"
+// @Failure 401 {object} ErrorResponse "Authentication required"
+// @Failure 403 {object} ErrorResponse "Server admin access required"
+// @Failure 500 {object} ErrorResponse "Failed to read audit log"
+// @Router /apis/v1/audit [get]
+func (m *RepositoryManagerAPIs) handleListAuditEntries(c *gin.Context) {
+	filter := audit.Filter{
+		Actor:      c.Query("actor"),
+		Repository: c.Query("repo"),
+		Action:     c.Query("action"),
+	}
+
+	var err error
+	if since := c.Query("since"); since != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid since: expected RFC 3339 time"})
+			return
+		}
+	}
+
+	if until := c.Query("until"); until != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid until: expected RFC 3339 time"})
+			return
+		}
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil || filter.Limit <= 0 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid limit: expected a positive number"})
+			return
+		}
+		filter.Limit = min(filter.Limit, maxAuditQueryLimit)
+	}
+
+	entries, err := m.params.AuditLog.Query(filter)
+	if err != nil {
+		m.logger.Error("Failed to query audit log", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
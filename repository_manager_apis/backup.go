@@ -0,0 +1,64 @@
+package repository_manager_apis
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const archiveContentType = "application/gzip"
+
+// handleExportArchive handles GET /apis/v1/backup
+// @Summary Export a server backup
+// @Description Stream a gzip compressed tar archive containing a manifest, every group with its description and one git bundle per repository. References are snapshotted before any data is written
+// @Tags Backup
+// @Produce application/gzip
+// @Success 200 {file} binary "Backup archive"
+// @Failure 401 {object} ErrorResponse "Authentication required"
+// @Failure 403 {object} ErrorResponse "Server admin access required"
+// @Failure 500 {object} ErrorResponse "Failed to export backup"
+// @Router /apis/v1/backup [get]
+func (m *RepositoryManagerAPIs) handleExportArchive(c *gin.Context) {
+	filename := fmt.Sprintf("git-backup-%s.tar.gz", time.Now().UTC().Format("20060102-150405"))
+
+	c.Header("Content-Type", archiveContentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if _, err := m.params.RepositoryManager.ExportArchive(c.Writer); err != nil {
+		m.logger.Error("Failed to export backup", zap.Error(err))
+
+		// Headers can only be replaced if nothing has been streamed yet
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		}
+		return
+	}
+}
+
+// handleImportArchive handles POST /apis/v1/backup
+// @Summary Import a server backup
+// @Description Recreate all groups and repositories from a backup archive created by the export endpoint. None of the repositories in the archive may exist on the server. Nothing is imported if the archive is invalid or incomplete
+// @Tags Backup
+// @Accept application/gzip
+// @Produce json
+// @Param body body string true "Backup archive"
+// @Success 200 {object} repository_manager.BackupManifest "Manifest of the imported backup"
+// @Failure 401 {object} ErrorResponse "Authentication required"
+// @Failure 403 {object} ErrorResponse "Server admin access required"
+// @Failure 500 {object} ErrorResponse "Failed to import backup"
+// @Router /apis/v1/backup [post]
+func (m *RepositoryManagerAPIs) handleImportArchive(c *gin.Context) {
+	manifest, err := m.params.RepositoryManager.ImportArchive(c.Request.Body)
+	if err != nil {
+		m.logger.Error("Failed to import backup", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, manifest)
+}
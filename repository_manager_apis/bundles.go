@@ -0,0 +1,83 @@
+package repository_manager_apis
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weedbox/git-modules/audit"
+	"go.uber.org/zap"
+)
+
+const bundleContentType = "application/x-git-bundle"
+
+// handleGetBundle handles GET /apis/v1/repos/*name/bundle
+// @Summary Download a repository bundle
+// @Description Stream a git bundle containing all references and objects of a repository. The bundle can be used to restore the repository on any server. Supports multi-level repository paths like "username/repo/bundle"
+// @Tags Repositories
+// @Produce application/x-git-bundle
+// @Param name path string true "Repository name (supports multi-level paths)" example:"myorg/myrepo"
+// @Success 200 {file} binary "Git bundle"
+// @Failure 400 {object} ErrorResponse "Invalid repository name"
+// @Failure 404 {object} ErrorResponse "Repository not found or without references"
+// @Failure 500 {object} ErrorResponse "Failed to create bundle"
+// @Router /apis/v1/repos/{name}/bundle [get]
+func (m *RepositoryManagerAPIs) handleGetBundle(c *gin.Context) {
+	// Extract repository name from path parameter
+	// c.Param("name") returns path with leading slash, e.g., "/username/repo"
+	repoName := strings.TrimPrefix(c.Param("name"), "/")
+
+	c.Header("Content-Type", bundleContentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(repoName)+".bundle"))
+
+	if err := m.params.RepositoryManager.CreateBundle(repoName, c.Writer); err != nil {
+		m.logger.Error("Failed to create bundle", zap.String("repo", repoName), zap.Error(err))
+
+		// Headers can only be replaced if nothing has been streamed yet
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		}
+		return
+	}
+}
+
+// handleCreateRepositoryFromBundle handles POST /apis/v1/repos with a bundle body
+// @Summary Create a repository from a bundle
+// @Description Create a new repository from an uploaded git bundle. Bundle prerequisites are validated and all references of the bundle are recreated
+// @Tags Repositories
+// @Accept application/x-git-bundle
+// @Produce json
+// @Param name query string true "Repository name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param description query string false "Repository description" example:"My awesome repository"
+// @Param body body string true "Git bundle"
+// @Success 201 {object} repository_manager.Repository "Repository created successfully"
+// @Failure 400 {object} ErrorResponse "Missing or invalid repository name, invalid or incomplete bundle"
+// @Failure 409 {object} ErrorResponse "Repository already exists"
+// @Failure 500 {object} ErrorResponse "Failed to create repository from bundle"
+// @Router /apis/v1/repos [post]
+func (m *RepositoryManagerAPIs) handleCreateRepositoryFromBundle(c *gin.Context) {
+	name := c.Query("name")
+	defer m.recordAudit(c, audit.ActionRepositoryCreate, name, "")
+
+	if name == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "name query parameter is required"})
+		return
+	}
+
+	if !m.authorizeCreate(c, name) {
+		return
+	}
+
+	repo, err := m.params.RepositoryManager.CreateRepositoryFromBundle(name, c.Query("description"), c.Request.Body)
+	if err != nil {
+		m.logger.Error("Failed to create repository from bundle", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, repo)
+}
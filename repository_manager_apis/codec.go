@@ -1,11 +1,19 @@
 package repository_manager_apis
 
+import (
+	"github.com/weedbox/git-modules/lfs"
+	"github.com/weedbox/git-modules/repository_manager"
+)
+
 // CreateRepositoryRequest represents the request body for creating a repository or group
 // @Description Request body for creating a repository or group
 type CreateRepositoryRequest struct {
 	Name        string `json:"name" binding:"required" example:"myorg/myrepo"`
 	Description string `json:"description" example:"My awesome repository"`
 	Type        string `json:"type" example:"repository" enums:"repository,group"`
+
+	// Init creates the repository with an initial commit (repositories only)
+	Init *repository_manager.RepositoryInitOptions `json:"init,omitempty"`
 } // @name CreateRepositoryRequest
 
 // CreateTagRequest represents the request body for creating a tag
@@ -23,8 +31,62 @@ type ErrorResponse struct {
 	Error string `json:"error" example:"repository not found"`
 } // @name ErrorResponse
 
+// MergeConflictResponse represents a merge that failed because of conflicts
+// @Description Merge conflict response with the conflicting paths
+type MergeConflictResponse struct {
+	Error     string   `json:"error" example:"merge conflict in 1 path(s): config/app.yaml"`
+	Conflicts []string `json:"conflicts" example:"config/app.yaml"`
+} // @name MergeConflictResponse
+
 // MessageResponse represents a success message response
 // @Description Success message response
 type MessageResponse struct {
 	Message string `json:"message" example:"Repository deleted successfully"`
 } // @name MessageResponse
+
+// PutFileRequest represents the request body for creating or updating a file
+// @Description Request body for committing a file. The sha field must contain the current blob SHA when updating an existing file
+type PutFileRequest struct {
+	repository_manager.FileCommitOptions
+	Content []byte `json:"content" swaggertype:"string" format:"base64" example:"aGVsbG8gd29ybGQK"`
+} // @name PutFileRequest
+
+// DeleteFileRequest represents the request body for deleting a file
+// @Description Request body for deleting a file. The sha field must contain the current blob SHA of the file
+type DeleteFileRequest struct {
+	repository_manager.FileCommitOptions
+} // @name DeleteFileRequest
+
+// UpdateBranchProtectionRuleRequest represents the request body for updating a branch protection rule
+// @Description Request body for updating a branch protection rule. The pattern is taken from the path
+type UpdateBranchProtectionRuleRequest struct {
+	DenyForcePush        bool     `json:"deny_force_push" example:"true"`
+	DenyDeletion         bool     `json:"deny_deletion" example:"true"`
+	RequireLinearHistory bool     `json:"require_linear_history" example:"false"`
+	CommitMessagePattern string   `json:"commit_message_pattern,omitempty" example:"^(feat|fix|docs|refactor|test|chore)(\\(.+\\))?!?: "`
+	RequireSignoff       bool     `json:"require_signoff" example:"false"`
+	AllowedEmailDomains  []string `json:"allowed_email_domains,omitempty" example:"example.com"`
+	RequireSignedCommits bool     `json:"require_signed_commits" example:"false"`
+	AllowedPushers       []string `json:"allowed_pushers,omitempty" example:"release-bot"`
+} // @name UpdateBranchProtectionRuleRequest
+
+// WebhookRequest represents the request body for creating or updating a webhook
+// @Description Request body for registering a webhook. An empty event list subscribes to all events. On update an empty secret keeps the current secret
+type WebhookRequest struct {
+	URL    string   `json:"url" binding:"required" example:"https://ci.example.com/hooks/git"`
+	Secret string   `json:"secret" example:"s3cr3t"`
+	Events []string `json:"events,omitempty" example:"push,tag_create"`
+} // @name WebhookRequest
+
+// SetPermissionRequest represents the request body for granting a role
+// @Description Request body for granting a role to a user or team. The user or team is taken from the path
+type SetPermissionRequest struct {
+	Role repository_manager.Role `json:"role" binding:"required" example:"write" enums:"read,write,maintain,admin"`
+} // @name SetPermissionRequest
+
+// LFSObjectsResponse represents the Git LFS objects of a repository
+// @Description Git LFS objects uploaded to a repository, oldest first, with their number and total size
+type LFSObjectsResponse struct {
+	Usage   lfs.Usage    `json:"usage"`
+	Objects []lfs.Object `json:"objects"`
+} // @name LFSObjectsResponse
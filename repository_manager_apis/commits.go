@@ -0,0 +1,47 @@
+package repository_manager_apis
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weedbox/git-modules/auth"
+	"github.com/weedbox/git-modules/repository_manager"
+	"go.uber.org/zap"
+)
+
+// handleCreateCommit handles POST /apis/v1/repos/*name/commits
+// @Summary Create a multi-file commit
+// @Description Apply a list of file actions (create, update, delete, move) as a single commit on a branch. If parent_sha is set, the commit fails with 409 when the branch tip has moved. The sha of an action is checked against the file at its path, or at previous_path for moves. Paths of different actions must not overlap
+// @Tags Contents
+// @Accept json
+// @Produce json
+// @Param name path string true "Repository name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param body body repository_manager.CommitRequest true "Commit request"
+// @Success 201 {object} repository_manager.CommitResult "Commit created"
+// @Failure 400 {object} ErrorResponse "Invalid request body or file action"
+// @Failure 403 {object} ErrorResponse "Branch protection refuses the change"
+// @Failure 404 {object} ErrorResponse "Branch or file not found"
+// @Failure 409 {object} ErrorResponse "Branch tip or file SHA does not match"
+// @Failure 500 {object} ErrorResponse "Failed to create commit"
+// @Router /apis/v1/repos/{name}/commits [post]
+func (m *RepositoryManagerAPIs) handleCreateCommit(c *gin.Context) {
+	repoName := strings.TrimPrefix(c.Param("name"), "/")
+
+	var req repository_manager.CommitRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	req.Pusher = auth.NameFromContext(c.Request.Context())
+	result, err := m.params.RepositoryManager.CommitFiles(repoName, req)
+	if err != nil {
+		m.logger.Error("Failed to create commit", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
@@ -0,0 +1,66 @@
+package repository_manager_apis
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weedbox/git-modules/repository_manager"
+	"go.uber.org/zap"
+)
+
+// handleGetContentPolicy handles GET /apis/v1/repos/*name/content_policy
+// @Summary Get the content policy
+// @Description Get the push content policy configured directly on a repository or group. Policies inherited from groups are not included
+// @Tags Content Policies
+// @Produce json
+// @Param name path string true "Repository or group name (supports multi-level paths)" example:"myorg"
+// @Success 200 {object} repository_manager.ContentPolicy "Content policy"
+// @Failure 404 {object} ErrorResponse "Repository or group not found"
+// @Failure 500 {object} ErrorResponse "Failed to get content policy"
+// @Router /apis/v1/repos/{name}/content_policy [get]
+func (m *RepositoryManagerAPIs) handleGetContentPolicy(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+
+	policy, err := m.params.RepositoryManager.GetContentPolicy(name)
+	if err != nil {
+		m.logger.Error("Failed to get content policy", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// handleUpdateContentPolicy handles PUT /apis/v1/repos/*name/content_policy
+// @Summary Update the content policy
+// @Description Set the files accepted by pushes to a repository, or to every repository of a group: a maximum file size in bytes, forbidden path globs (e.g. "*.pem" or "node_modules/") and secret scanning of added lines with the built-in rules plus custom ones. The policies of a repository and of its groups all apply, and violations are reported to the git client per commit and file
+// @Tags Content Policies
+// @Accept json
+// @Produce json
+// @Param name path string true "Repository or group name (supports multi-level paths)" example:"myorg"
+// @Param body body repository_manager.ContentPolicy true "Content policy"
+// @Success 200 {object} repository_manager.ContentPolicy "Content policy updated"
+// @Failure 400 {object} ErrorResponse "Invalid request body or content policy"
+// @Failure 404 {object} ErrorResponse "Repository or group not found"
+// @Failure 500 {object} ErrorResponse "Failed to update content policy"
+// @Router /apis/v1/repos/{name}/content_policy [put]
+func (m *RepositoryManagerAPIs) handleUpdateContentPolicy(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+
+	var req repository_manager.ContentPolicy
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	policy, err := m.params.RepositoryManager.SetContentPolicy(name, req)
+	if err != nil {
+		m.logger.Error("Failed to update content policy", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
@@ -0,0 +1,110 @@
+package repository_manager_apis
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weedbox/git-modules/auth"
+	"go.uber.org/zap"
+)
+
+// handleGetFile handles GET /apis/v1/repos/*name/contents/*path
+// @Summary Get file content
+// @Description Get the content and blob SHA of a file. Supports multi-level repository paths like "username/repo/contents/config/app.yaml"
+// @Tags Contents
+// @Produce json
+// @Param name path string true "Repository name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param path path string true "File path" example:"config/app.yaml"
+// @Param ref query string false "Branch, tag or commit (defaults to HEAD)" example:"main"
+// @Success 200 {object} repository_manager.FileContent "File content"
+// @Failure 404 {object} ErrorResponse "File not found"
+// @Router /apis/v1/repos/{name}/contents/{path} [get]
+func (m *RepositoryManagerAPIs) handleGetFile(c *gin.Context) {
+	repoName := strings.TrimPrefix(c.Param("name"), "/")
+	filePath := strings.TrimPrefix(c.Param("path"), "/")
+
+	file, err := m.params.RepositoryManager.GetFile(repoName, c.Query("ref"), filePath)
+	if err != nil {
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, file)
+}
+
+// handlePutFile handles PUT /apis/v1/repos/*name/contents/*path
+// @Summary Create or update a file
+// @Description Commit a new or changed file to a branch. Updating an existing file requires the current blob SHA for optimistic concurrency
+// @Tags Contents
+// @Accept json
+// @Produce json
+// @Param name path string true "Repository name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param path path string true "File path" example:"config/app.yaml"
+// @Param body body PutFileRequest true "File commit request"
+// @Success 200 {object} repository_manager.FileCommit "File committed"
+// @Failure 400 {object} ErrorResponse "Invalid request body"
+// @Failure 403 {object} ErrorResponse "Branch protection refuses the change"
+// @Failure 404 {object} ErrorResponse "Branch not found"
+// @Failure 409 {object} ErrorResponse "File SHA or branch tip does not match"
+// @Failure 500 {object} ErrorResponse "Failed to commit file"
+// @Router /apis/v1/repos/{name}/contents/{path} [put]
+func (m *RepositoryManagerAPIs) handlePutFile(c *gin.Context) {
+	repoName := strings.TrimPrefix(c.Param("name"), "/")
+	filePath := strings.TrimPrefix(c.Param("path"), "/")
+
+	var req PutFileRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	req.FileCommitOptions.Pusher = auth.NameFromContext(c.Request.Context())
+	result, err := m.params.RepositoryManager.PutFile(repoName, filePath, req.Content, req.FileCommitOptions)
+	if err != nil {
+		m.logger.Error("Failed to commit file", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// handleDeleteFile handles DELETE /apis/v1/repos/*name/contents/*path
+// @Summary Delete a file
+// @Description Commit the removal of a file from a branch. The current blob SHA of the file is required for optimistic concurrency
+// @Tags Contents
+// @Accept json
+// @Produce json
+// @Param name path string true "Repository name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param path path string true "File path" example:"config/app.yaml"
+// @Param body body DeleteFileRequest true "File delete request"
+// @Success 200 {object} repository_manager.FileCommit "File deleted"
+// @Failure 400 {object} ErrorResponse "Invalid request body"
+// @Failure 403 {object} ErrorResponse "Branch protection refuses the change"
+// @Failure 404 {object} ErrorResponse "File or branch not found"
+// @Failure 409 {object} ErrorResponse "File SHA or branch tip does not match"
+// @Failure 500 {object} ErrorResponse "Failed to delete file"
+// @Router /apis/v1/repos/{name}/contents/{path} [delete]
+func (m *RepositoryManagerAPIs) handleDeleteFile(c *gin.Context) {
+	repoName := strings.TrimPrefix(c.Param("name"), "/")
+	filePath := strings.TrimPrefix(c.Param("path"), "/")
+
+	var req DeleteFileRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	req.FileCommitOptions.Pusher = auth.NameFromContext(c.Request.Context())
+	result, err := m.params.RepositoryManager.DeleteFile(repoName, filePath, req.FileCommitOptions)
+	if err != nil {
+		m.logger.Error("Failed to delete file", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,55 @@
+package repository_manager_apis
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/weedbox/git-modules/repository_manager"
+)
+
+// statusCodeForError maps typed repository manager errors to HTTP status codes.
+// Errors without a specific mapping use the fallback status code.
+func statusCodeForError(err error, fallback int) int {
+	var notFound *repository_manager.NotFoundError
+	var forbidden *repository_manager.ForbiddenError
+	var alreadyExists *repository_manager.AlreadyExistsError
+	var conflict *repository_manager.ConflictError
+	var refConflict *repository_manager.ReferenceConflictError
+	var invalidType *repository_manager.InvalidTypeError
+	var invalidAction *repository_manager.InvalidFileActionError
+	var mergeConflict *repository_manager.MergeConflictError
+
+	switch {
+	case errors.As(err, &notFound), errors.Is(err, repository_manager.ErrBundleEmpty):
+		return http.StatusNotFound
+	case errors.As(err, &forbidden):
+		return http.StatusForbidden
+	case errors.As(err, &alreadyExists), errors.As(err, &conflict), errors.As(err, &refConflict), errors.As(err, &mergeConflict):
+		return http.StatusConflict
+	case errors.As(err, &invalidType):
+		return http.StatusUnprocessableEntity
+	case errors.As(err, &invalidAction),
+		errors.Is(err, repository_manager.ErrFilePathInvalid),
+		errors.Is(err, repository_manager.ErrBranchInvalidName),
+		errors.Is(err, repository_manager.ErrCommitEmpty),
+		errors.Is(err, repository_manager.ErrMergeStrategyInvalid),
+		errors.Is(err, repository_manager.ErrProtectionPatternInvalid),
+		errors.Is(err, repository_manager.ErrCommitRuleInvalid),
+		errors.Is(err, repository_manager.ErrWebhookURLInvalid),
+		errors.Is(err, repository_manager.ErrWebhookURLForbidden),
+		errors.Is(err, repository_manager.ErrWebhookEventInvalid),
+		errors.Is(err, repository_manager.ErrRoleInvalid),
+		errors.Is(err, repository_manager.ErrPermissionSubjectInvalid),
+		errors.Is(err, repository_manager.ErrFilterKindInvalid),
+		errors.Is(err, repository_manager.ErrQuotaInvalid),
+		errors.Is(err, repository_manager.ErrContentPolicyInvalid),
+		errors.Is(err, repository_manager.ErrRepositoryNameEmpty),
+		errors.Is(err, repository_manager.ErrRepositoryInvalidName),
+		errors.Is(err, repository_manager.ErrBundleInvalid),
+		errors.Is(err, repository_manager.ErrBundleCorrupt),
+		errors.Is(err, repository_manager.ErrBundlePrerequisiteMissing):
+		return http.StatusBadRequest
+	default:
+		return fallback
+	}
+}
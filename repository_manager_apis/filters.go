@@ -0,0 +1,66 @@
+package repository_manager_apis
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weedbox/git-modules/repository_manager"
+	"go.uber.org/zap"
+)
+
+// handleGetFilterPolicy handles GET /apis/v1/repos/*name/filters
+// @Summary Get the filter policy
+// @Description Get the object filters clients may request for partial clones of a repository. An empty policy allows every filter. Filters are only served by the git backend of git_http, with the go-git backend clients receive complete packfiles and the policy has no effect
+// @Tags Filters
+// @Produce json
+// @Param name path string true "Repository name (supports multi-level paths)" example:"myorg/myrepo"
+// @Success 200 {object} repository_manager.FilterPolicy "Filter policy"
+// @Failure 404 {object} ErrorResponse "Repository not found"
+// @Failure 500 {object} ErrorResponse "Failed to get filter policy"
+// @Router /apis/v1/repos/{name}/filters [get]
+func (m *RepositoryManagerAPIs) handleGetFilterPolicy(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+
+	policy, err := m.params.RepositoryManager.GetFilterPolicy(name)
+	if err != nil {
+		m.logger.Error("Failed to get filter policy", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// handleUpdateFilterPolicy handles PUT /apis/v1/repos/*name/filters
+// @Summary Update the filter policy
+// @Description Allow or deny object filter kinds (blob:none, blob:limit, tree, sparse:oid, object:type, combine) for partial clones of a repository. Denied kinds win over allowed ones, and a non-empty allow list only permits the listed kinds. Only the git backend of git_http serves filters, the policy has no effect with the go-git backend
+// @Tags Filters
+// @Accept json
+// @Produce json
+// @Param name path string true "Repository name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param body body repository_manager.FilterPolicy true "Filter policy"
+// @Success 200 {object} repository_manager.FilterPolicy "Filter policy updated"
+// @Failure 400 {object} ErrorResponse "Invalid request body or filter kind"
+// @Failure 404 {object} ErrorResponse "Repository not found"
+// @Failure 500 {object} ErrorResponse "Failed to update filter policy"
+// @Router /apis/v1/repos/{name}/filters [put]
+func (m *RepositoryManagerAPIs) handleUpdateFilterPolicy(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+
+	var req repository_manager.FilterPolicy
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	policy, err := m.params.RepositoryManager.SetFilterPolicy(name, req)
+	if err != nil {
+		m.logger.Error("Failed to update filter policy", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
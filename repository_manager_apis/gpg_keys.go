@@ -0,0 +1,104 @@
+package repository_manager_apis
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weedbox/git-modules/auth"
+	"github.com/weedbox/git-modules/gpg_keys"
+	"go.uber.org/zap"
+)
+
+// handleListGPGKeys handles GET /apis/v1/user/gpg_keys
+// @Summary List GPG keys
+// @Description List the GPG public keys of the authenticated user
+// @Tags GPG Keys
+// @Produce json
+// @Success 200 {array} gpg_keys.Key "List of keys"
+// @Failure 401 {object} ErrorResponse "Authentication required"
+// @Router /apis/v1/user/gpg_keys [get]
+func (m *RepositoryManagerAPIs) handleListGPGKeys(c *gin.Context) {
+	identity, ok := m.credentialsOwner(c, "GPG keys")
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, m.params.GPGKeys.ListKeys(identity.Name))
+}
+
+// handleCreateGPGKey handles POST /apis/v1/user/gpg_keys
+// @Summary Add a GPG key
+// @Description Register an ASCII armored OpenPGP public key the authenticated user signs commits with. A key can only be registered once across all users
+// @Tags GPG Keys
+// @Accept json
+// @Produce json
+// @Param body body gpg_keys.Options true "Key options"
+// @Success 201 {object} gpg_keys.Key "Key added"
+// @Failure 400 {object} ErrorResponse "Invalid request body or key"
+// @Failure 401 {object} ErrorResponse "Authentication required"
+// @Failure 403 {object} ErrorResponse "Tokens cannot manage GPG keys"
+// @Failure 409 {object} ErrorResponse "Key already in use"
+// @Failure 500 {object} ErrorResponse "Failed to add key"
+// @Router /apis/v1/user/gpg_keys [post]
+func (m *RepositoryManagerAPIs) handleCreateGPGKey(c *gin.Context) {
+	identity, ok := m.credentialsOwner(c, "GPG keys")
+	if !ok {
+		return
+	}
+
+	var req gpg_keys.Options
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	key, err := m.params.GPGKeys.AddKey(identity.Name, req)
+	if err != nil {
+		m.logger.Error("Failed to add GPG key", zap.Error(err))
+		c.JSON(statusCodeForGPGKeyError(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// handleDeleteGPGKey handles DELETE /apis/v1/user/gpg_keys/{id}
+// @Summary Remove a GPG key
+// @Description Remove a GPG public key of the authenticated user. Commits signed with the key are no longer verified
+// @Tags GPG Keys
+// @Produce json
+// @Param id path string true "Key ID" example:"9f86d081884c7d65"
+// @Success 200 {object} MessageResponse "Key removed"
+// @Failure 401 {object} ErrorResponse "Authentication required"
+// @Failure 404 {object} ErrorResponse "Key not found"
+// @Router /apis/v1/user/gpg_keys/{id} [delete]
+func (m *RepositoryManagerAPIs) handleDeleteGPGKey(c *gin.Context) {
+	identity, ok := m.credentialsOwner(c, "GPG keys")
+	if !ok {
+		return
+	}
+
+	if err := m.params.GPGKeys.DeleteKey(identity.Name, c.Param("id")); err != nil {
+		c.JSON(statusCodeForGPGKeyError(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "GPG key removed successfully"})
+}
+
+// statusCodeForGPGKeyError maps GPG key store errors to HTTP status codes
+func statusCodeForGPGKeyError(err error) int {
+	switch {
+	case errors.Is(err, gpg_keys.ErrKeyNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, gpg_keys.ErrKeyDuplicate):
+		return http.StatusConflict
+	case errors.Is(err, gpg_keys.ErrKeyInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, auth.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/weedbox/git-modules/audit"
 	"go.uber.org/zap"
 )
 
@@ -21,12 +22,17 @@ import (
 // @Router /apis/v1/repos [post]
 func (m *RepositoryManagerAPIs) handleCreateGroup(c *gin.Context) {
 	var req CreateRepositoryRequest
+	defer func() { m.recordAudit(c, audit.ActionGroupCreate, req.Name, "") }()
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
 
+	if !m.authorizeCreate(c, req.Name) {
+		return
+	}
+
 	group, err := m.params.RepositoryManager.CreateGroup(req.Name, req.Description)
 	if err != nil {
 		m.logger.Error("Failed to create group", zap.Error(err))
@@ -58,12 +64,13 @@ func (m *RepositoryManagerAPIs) handleListGroups(c *gin.Context) {
 
 // handleGetGroup handles GET /apis/v1/repos/*name (when it's a group)
 // @Summary Get group information
-// @Description Get detailed information about a specific group. Supports multi-level paths like "org/team"
+// @Description Get detailed information about a specific group, including the disk usage of all its repositories and its quota. Supports multi-level paths like "org/team"
 // @Tags Groups
 // @Produce json
 // @Param name path string true "Group name (supports multi-level paths)" example:"myorg"
 // @Success 200 {object} repository_manager.Group "Group information"
 // @Failure 404 {object} ErrorResponse "Group not found"
+// @Failure 500 {object} ErrorResponse "Failed to compute group usage"
 // @Router /apis/v1/repos/{name} [get]
 func (m *RepositoryManagerAPIs) handleGetGroup(c *gin.Context) {
 	// Extract group name from path parameter
@@ -75,6 +82,14 @@ func (m *RepositoryManagerAPIs) handleGetGroup(c *gin.Context) {
 		return
 	}
 
+	usage, err := m.params.RepositoryManager.GetUsage(name)
+	if err != nil {
+		m.logger.Error("Failed to compute group usage", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	group.Usage = usage
+
 	c.JSON(http.StatusOK, group)
 }
 
@@ -90,6 +105,7 @@ func (m *RepositoryManagerAPIs) handleGetGroup(c *gin.Context) {
 func (m *RepositoryManagerAPIs) handleDeleteGroup(c *gin.Context) {
 	// Extract group name from path parameter
 	name := strings.TrimPrefix(c.Param("name"), "/")
+	defer m.recordAudit(c, audit.ActionGroupDelete, name, "")
 
 	if err := m.params.RepositoryManager.DeleteGroup(name); err != nil {
 		m.logger.Error("Failed to delete group", zap.Error(err))
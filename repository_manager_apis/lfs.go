@@ -0,0 +1,44 @@
+package repository_manager_apis
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// handleListLFSObjects handles GET /apis/v1/repos/*name/lfs_objects
+// @Summary List Git LFS objects
+// @Description List the Git LFS objects uploaded to a repository with their number and total size. Objects are stored once and shared by the repositories they were uploaded to
+// @Tags Git LFS
+// @Produce json
+// @Param name path string true "Repository name (supports multi-level paths)" example:"myorg/myrepo"
+// @Success 200 {object} LFSObjectsResponse "LFS objects"
+// @Failure 404 {object} ErrorResponse "Repository not found or Git LFS not enabled"
+// @Failure 500 {object} ErrorResponse "Failed to list LFS objects"
+// @Router /apis/v1/repos/{name}/lfs_objects [get]
+func (m *RepositoryManagerAPIs) handleListLFSObjects(c *gin.Context) {
+	if m.params.LFS == nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Git LFS is not enabled"})
+		return
+	}
+
+	name := strings.TrimPrefix(c.Param("name"), "/")
+
+	objects, err := m.params.LFS.ListObjects(name)
+	if err != nil {
+		m.logger.Error("Failed to list LFS objects", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	usage, err := m.params.LFS.GetUsage(name)
+	if err != nil {
+		m.logger.Error("Failed to get LFS usage", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, LFSObjectsResponse{Usage: *usage, Objects: objects})
+}
@@ -0,0 +1,54 @@
+package repository_manager_apis
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weedbox/git-modules/auth"
+	"github.com/weedbox/git-modules/repository_manager"
+	"go.uber.org/zap"
+)
+
+// handleCreateMerge handles POST /apis/v1/repos/*name/merges
+// @Summary Merge branches
+// @Description Merge a head revision into a base branch using the fast-forward, merge or squash strategy. Conflicting paths are returned with status 409
+// @Tags Merges
+// @Accept json
+// @Produce json
+// @Param name path string true "Repository name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param body body repository_manager.MergeRequest true "Merge request"
+// @Success 200 {object} repository_manager.MergeResult "Merge completed"
+// @Failure 400 {object} ErrorResponse "Invalid request body or strategy"
+// @Failure 403 {object} ErrorResponse "Branch protection refuses the change"
+// @Failure 404 {object} ErrorResponse "Branch or revision not found"
+// @Failure 409 {object} MergeConflictResponse "Merge conflicts or branches diverged"
+// @Failure 500 {object} ErrorResponse "Failed to merge"
+// @Router /apis/v1/repos/{name}/merges [post]
+func (m *RepositoryManagerAPIs) handleCreateMerge(c *gin.Context) {
+	repoName := strings.TrimPrefix(c.Param("name"), "/")
+
+	var req repository_manager.MergeRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	req.Pusher = auth.NameFromContext(c.Request.Context())
+	result, err := m.params.RepositoryManager.Merge(repoName, req)
+	if err != nil {
+		var conflict *repository_manager.MergeConflictError
+		if errors.As(err, &conflict) {
+			c.JSON(http.StatusConflict, MergeConflictResponse{Error: err.Error(), Conflicts: conflict.Paths})
+			return
+		}
+
+		m.logger.Error("Failed to merge", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -8,6 +8,7 @@ type MiddlewareConfig struct {
 	ListRepositories []gin.HandlerFunc
 	GetRepository    []gin.HandlerFunc
 	DeleteRepository []gin.HandlerFunc
+	GetBundle        []gin.HandlerFunc
 
 	// Tag middlewares
 	CreateTag []gin.HandlerFunc
@@ -15,27 +16,150 @@ type MiddlewareConfig struct {
 	GetTag    []gin.HandlerFunc
 	DeleteTag []gin.HandlerFunc
 
+	// Contents middlewares
+	GetFile      []gin.HandlerFunc
+	PutFile      []gin.HandlerFunc
+	DeleteFile   []gin.HandlerFunc
+	CreateCommit []gin.HandlerFunc
+	CreateMerge  []gin.HandlerFunc
+
+	// Protection middlewares
+	ListBranchProtectionRules  []gin.HandlerFunc
+	CreateBranchProtectionRule []gin.HandlerFunc
+	GetBranchProtectionRule    []gin.HandlerFunc
+	UpdateBranchProtectionRule []gin.HandlerFunc
+	DeleteBranchProtectionRule []gin.HandlerFunc
+	ListTagProtectionRules     []gin.HandlerFunc
+	CreateTagProtectionRule    []gin.HandlerFunc
+	GetTagProtectionRule       []gin.HandlerFunc
+	DeleteTagProtectionRule    []gin.HandlerFunc
+
+	// Webhook middlewares
+	ListWebhooks          []gin.HandlerFunc
+	CreateWebhook         []gin.HandlerFunc
+	GetWebhook            []gin.HandlerFunc
+	UpdateWebhook         []gin.HandlerFunc
+	DeleteWebhook         []gin.HandlerFunc
+	ListWebhookDeliveries []gin.HandlerFunc
+
+	// Permission middlewares
+	ListPermissions  []gin.HandlerFunc
+	SetPermission    []gin.HandlerFunc
+	DeletePermission []gin.HandlerFunc
+
+	// Token middlewares
+	ListPersonalTokens  []gin.HandlerFunc
+	CreatePersonalToken []gin.HandlerFunc
+	DeletePersonalToken []gin.HandlerFunc
+	ListDeployTokens    []gin.HandlerFunc
+	CreateDeployToken   []gin.HandlerFunc
+	DeleteDeployToken   []gin.HandlerFunc
+
+	// SSH key middlewares
+	ListUserKeys    []gin.HandlerFunc
+	CreateUserKey   []gin.HandlerFunc
+	DeleteUserKey   []gin.HandlerFunc
+	ListDeployKeys  []gin.HandlerFunc
+	CreateDeployKey []gin.HandlerFunc
+	DeleteDeployKey []gin.HandlerFunc
+
+	// GPG key middlewares
+	ListGPGKeys  []gin.HandlerFunc
+	CreateGPGKey []gin.HandlerFunc
+	DeleteGPGKey []gin.HandlerFunc
+
+	// Filter policy middlewares
+	GetFilterPolicy    []gin.HandlerFunc
+	UpdateFilterPolicy []gin.HandlerFunc
+
+	// Quota middlewares
+	GetQuota    []gin.HandlerFunc
+	UpdateQuota []gin.HandlerFunc
+
+	// Content policy middlewares
+	GetContentPolicy    []gin.HandlerFunc
+	UpdateContentPolicy []gin.HandlerFunc
+
+	// Git LFS middlewares
+	ListLFSObjects []gin.HandlerFunc
+
 	// Group middlewares
 	CreateGroup []gin.HandlerFunc
 	ListGroups  []gin.HandlerFunc
 	GetGroup    []gin.HandlerFunc
 	DeleteGroup []gin.HandlerFunc
+
+	// Backup middlewares
+	ExportArchive []gin.HandlerFunc
+	ImportArchive []gin.HandlerFunc
+
+	// Audit middlewares
+	ListAuditEntries []gin.HandlerFunc
 }
 
 func NewMiddlewareConfig() MiddlewareConfig {
 	return MiddlewareConfig{
-		CreateRepository: []gin.HandlerFunc{},
-		ListRepositories: []gin.HandlerFunc{},
-		GetRepository:    []gin.HandlerFunc{},
-		DeleteRepository: []gin.HandlerFunc{},
-		CreateTag:        []gin.HandlerFunc{},
-		ListTags:         []gin.HandlerFunc{},
-		GetTag:           []gin.HandlerFunc{},
-		DeleteTag:        []gin.HandlerFunc{},
-		CreateGroup:      []gin.HandlerFunc{},
-		ListGroups:       []gin.HandlerFunc{},
-		GetGroup:         []gin.HandlerFunc{},
-		DeleteGroup:      []gin.HandlerFunc{},
+		CreateRepository:           []gin.HandlerFunc{},
+		ListRepositories:           []gin.HandlerFunc{},
+		GetRepository:              []gin.HandlerFunc{},
+		DeleteRepository:           []gin.HandlerFunc{},
+		GetBundle:                  []gin.HandlerFunc{},
+		CreateTag:                  []gin.HandlerFunc{},
+		ListTags:                   []gin.HandlerFunc{},
+		GetTag:                     []gin.HandlerFunc{},
+		DeleteTag:                  []gin.HandlerFunc{},
+		GetFile:                    []gin.HandlerFunc{},
+		PutFile:                    []gin.HandlerFunc{},
+		DeleteFile:                 []gin.HandlerFunc{},
+		CreateCommit:               []gin.HandlerFunc{},
+		CreateMerge:                []gin.HandlerFunc{},
+		ListBranchProtectionRules:  []gin.HandlerFunc{},
+		CreateBranchProtectionRule: []gin.HandlerFunc{},
+		GetBranchProtectionRule:    []gin.HandlerFunc{},
+		UpdateBranchProtectionRule: []gin.HandlerFunc{},
+		DeleteBranchProtectionRule: []gin.HandlerFunc{},
+		ListTagProtectionRules:     []gin.HandlerFunc{},
+		CreateTagProtectionRule:    []gin.HandlerFunc{},
+		GetTagProtectionRule:       []gin.HandlerFunc{},
+		DeleteTagProtectionRule:    []gin.HandlerFunc{},
+		ListWebhooks:               []gin.HandlerFunc{},
+		CreateWebhook:              []gin.HandlerFunc{},
+		GetWebhook:                 []gin.HandlerFunc{},
+		UpdateWebhook:              []gin.HandlerFunc{},
+		DeleteWebhook:              []gin.HandlerFunc{},
+		ListWebhookDeliveries:      []gin.HandlerFunc{},
+		ListPermissions:            []gin.HandlerFunc{},
+		SetPermission:              []gin.HandlerFunc{},
+		DeletePermission:           []gin.HandlerFunc{},
+		ListPersonalTokens:         []gin.HandlerFunc{},
+		CreatePersonalToken:        []gin.HandlerFunc{},
+		DeletePersonalToken:        []gin.HandlerFunc{},
+		ListDeployTokens:           []gin.HandlerFunc{},
+		CreateDeployToken:          []gin.HandlerFunc{},
+		DeleteDeployToken:          []gin.HandlerFunc{},
+		ListUserKeys:               []gin.HandlerFunc{},
+		CreateUserKey:              []gin.HandlerFunc{},
+		DeleteUserKey:              []gin.HandlerFunc{},
+		ListDeployKeys:             []gin.HandlerFunc{},
+		CreateDeployKey:            []gin.HandlerFunc{},
+		DeleteDeployKey:            []gin.HandlerFunc{},
+		ListGPGKeys:                []gin.HandlerFunc{},
+		CreateGPGKey:               []gin.HandlerFunc{},
+		DeleteGPGKey:               []gin.HandlerFunc{},
+		GetFilterPolicy:            []gin.HandlerFunc{},
+		UpdateFilterPolicy:         []gin.HandlerFunc{},
+		GetQuota:                   []gin.HandlerFunc{},
+		UpdateQuota:                []gin.HandlerFunc{},
+		GetContentPolicy:           []gin.HandlerFunc{},
+		UpdateContentPolicy:        []gin.HandlerFunc{},
+		ListLFSObjects:             []gin.HandlerFunc{},
+		CreateGroup:                []gin.HandlerFunc{},
+		ListGroups:                 []gin.HandlerFunc{},
+		GetGroup:                   []gin.HandlerFunc{},
+		DeleteGroup:                []gin.HandlerFunc{},
+		ExportArchive:              []gin.HandlerFunc{},
+		ImportArchive:              []gin.HandlerFunc{},
+		ListAuditEntries:           []gin.HandlerFunc{},
 	}
 }
 
@@ -46,6 +170,7 @@ func (mc *MiddlewareConfig) Use(fn gin.HandlerFunc) {
 	mc.ListRepositories = append(mc.ListRepositories, fn)
 	mc.GetRepository = append(mc.GetRepository, fn)
 	mc.DeleteRepository = append(mc.DeleteRepository, fn)
+	mc.GetBundle = append(mc.GetBundle, fn)
 
 	// Append to all tag middleware slices
 	mc.CreateTag = append(mc.CreateTag, fn)
@@ -53,9 +178,83 @@ func (mc *MiddlewareConfig) Use(fn gin.HandlerFunc) {
 	mc.GetTag = append(mc.GetTag, fn)
 	mc.DeleteTag = append(mc.DeleteTag, fn)
 
+	// Append to all contents middleware slices
+	mc.GetFile = append(mc.GetFile, fn)
+	mc.PutFile = append(mc.PutFile, fn)
+	mc.DeleteFile = append(mc.DeleteFile, fn)
+	mc.CreateCommit = append(mc.CreateCommit, fn)
+	mc.CreateMerge = append(mc.CreateMerge, fn)
+
+	// Append to all protection middleware slices
+	mc.ListBranchProtectionRules = append(mc.ListBranchProtectionRules, fn)
+	mc.CreateBranchProtectionRule = append(mc.CreateBranchProtectionRule, fn)
+	mc.GetBranchProtectionRule = append(mc.GetBranchProtectionRule, fn)
+	mc.UpdateBranchProtectionRule = append(mc.UpdateBranchProtectionRule, fn)
+	mc.DeleteBranchProtectionRule = append(mc.DeleteBranchProtectionRule, fn)
+	mc.ListTagProtectionRules = append(mc.ListTagProtectionRules, fn)
+	mc.CreateTagProtectionRule = append(mc.CreateTagProtectionRule, fn)
+	mc.GetTagProtectionRule = append(mc.GetTagProtectionRule, fn)
+	mc.DeleteTagProtectionRule = append(mc.DeleteTagProtectionRule, fn)
+
+	// Append to all webhook middleware slices
+	mc.ListWebhooks = append(mc.ListWebhooks, fn)
+	mc.CreateWebhook = append(mc.CreateWebhook, fn)
+	mc.GetWebhook = append(mc.GetWebhook, fn)
+	mc.UpdateWebhook = append(mc.UpdateWebhook, fn)
+	mc.DeleteWebhook = append(mc.DeleteWebhook, fn)
+	mc.ListWebhookDeliveries = append(mc.ListWebhookDeliveries, fn)
+
+	// Append to all permission middleware slices
+	mc.ListPermissions = append(mc.ListPermissions, fn)
+	mc.SetPermission = append(mc.SetPermission, fn)
+	mc.DeletePermission = append(mc.DeletePermission, fn)
+
+	// Append to all token middleware slices
+	mc.ListPersonalTokens = append(mc.ListPersonalTokens, fn)
+	mc.CreatePersonalToken = append(mc.CreatePersonalToken, fn)
+	mc.DeletePersonalToken = append(mc.DeletePersonalToken, fn)
+	mc.ListDeployTokens = append(mc.ListDeployTokens, fn)
+	mc.CreateDeployToken = append(mc.CreateDeployToken, fn)
+	mc.DeleteDeployToken = append(mc.DeleteDeployToken, fn)
+
+	// Append to all SSH key middleware slices
+	mc.ListUserKeys = append(mc.ListUserKeys, fn)
+	mc.CreateUserKey = append(mc.CreateUserKey, fn)
+	mc.DeleteUserKey = append(mc.DeleteUserKey, fn)
+	mc.ListDeployKeys = append(mc.ListDeployKeys, fn)
+	mc.CreateDeployKey = append(mc.CreateDeployKey, fn)
+	mc.DeleteDeployKey = append(mc.DeleteDeployKey, fn)
+
+	// Append to all GPG key middleware slices
+	mc.ListGPGKeys = append(mc.ListGPGKeys, fn)
+	mc.CreateGPGKey = append(mc.CreateGPGKey, fn)
+	mc.DeleteGPGKey = append(mc.DeleteGPGKey, fn)
+
+	// Append to all filter policy middleware slices
+	mc.GetFilterPolicy = append(mc.GetFilterPolicy, fn)
+	mc.UpdateFilterPolicy = append(mc.UpdateFilterPolicy, fn)
+
+	// Append to all quota middleware slices
+	mc.GetQuota = append(mc.GetQuota, fn)
+	mc.UpdateQuota = append(mc.UpdateQuota, fn)
+
+	// Append to all content policy middleware slices
+	mc.GetContentPolicy = append(mc.GetContentPolicy, fn)
+	mc.UpdateContentPolicy = append(mc.UpdateContentPolicy, fn)
+
+	// Append to all Git LFS middleware slices
+	mc.ListLFSObjects = append(mc.ListLFSObjects, fn)
+
 	// Append to all group middleware slices
 	mc.CreateGroup = append(mc.CreateGroup, fn)
 	mc.ListGroups = append(mc.ListGroups, fn)
 	mc.GetGroup = append(mc.GetGroup, fn)
 	mc.DeleteGroup = append(mc.DeleteGroup, fn)
+
+	// Append to all backup middleware slices
+	mc.ExportArchive = append(mc.ExportArchive, fn)
+	mc.ImportArchive = append(mc.ImportArchive, fn)
+
+	// Append to all audit middleware slices
+	mc.ListAuditEntries = append(mc.ListAuditEntries, fn)
 }
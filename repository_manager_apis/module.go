@@ -7,7 +7,25 @@
 // @description This API provides comprehensive Git repository management capabilities including:
 // @description - Repository CRUD operations with multi-level path support
 // @description - Git tag management (lightweight and annotated tags)
+// @description - File content reads and commits with optimistic concurrency
+// @description - Atomic multi-file commits with compare-and-swap branch updates
+// @description - Server-side branch merges (fast-forward, merge commit, squash)
+// @description - Branch protection rules per repository or inherited from groups, enforced on push and on commits and merges made through this API
+// @description - Commit message, sign-off, email domain and signature rules on protected branches
+// @description - Protected, immutable tags per repository or inherited from groups
+// @description - Read, write, maintain and admin roles for users and teams, inherited from groups
+// @description - Personal access tokens and repository deploy tokens, accepted as bearer tokens
+// @description - SSH keys of users and read-only or read-write repository deploy keys
+// @description - GPG keys of users, verifying signed commits on protected branches
+// @description - Partial clone filter policies per repository, enforced by the git backend of git_http
+// @description - Storage quotas per repository and per group, enforced on push
+// @description - Push content policies: file size limit, forbidden paths and secret detection (commits and merges made through this API are exempt)
+// @description - Git LFS object listing and usage per repository
+// @description - Signed webhooks for push, branch, tag and repository events with delivery history
 // @description - Group/namespace management for organizing repositories
+// @description - Repository backup and restore as git bundles
+// @description - Whole-server backup and restore of all groups and repositories
+// @description - Queryable audit log of management and git operations
 // @description
 // @description All repository and group paths support multi-level hierarchies like "org/team/project"
 //
@@ -35,14 +53,25 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
 	"github.com/weedbox/common-modules/http_server"
+	"github.com/weedbox/git-modules/audit"
+	"github.com/weedbox/git-modules/auth"
+	"github.com/weedbox/git-modules/gpg_keys"
+	"github.com/weedbox/git-modules/lfs"
 	"github.com/weedbox/git-modules/repository_manager"
+	"github.com/weedbox/git-modules/ssh_keys"
+	"github.com/weedbox/git-modules/tokens"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
 
 const (
-	ModuleName       = "RepositoryManagerAPIs"
-	DefaultURLPrefix = "/apis/v1/repos"
+	ModuleName              = "RepositoryManagerAPIs"
+	DefaultURLPrefix        = "/apis/v1/repos"
+	DefaultBackupURLPrefix  = "/apis/v1/backup"
+	DefaultAuditURLPrefix   = "/apis/v1/audit"
+	DefaultTokensURLPrefix  = "/apis/v1/user/tokens"
+	DefaultKeysURLPrefix    = "/apis/v1/user/keys"
+	DefaultGPGKeysURLPrefix = "/apis/v1/user/gpg_keys"
 )
 
 type RepositoryManagerAPIs struct {
@@ -50,6 +79,9 @@ type RepositoryManagerAPIs struct {
 	logger           *zap.Logger
 	scope            string
 	middlewareConfig MiddlewareConfig
+	enforceRoles     bool
+	admins           []string
+	adminTeams       []string
 }
 
 type Params struct {
@@ -59,6 +91,21 @@ type Params struct {
 	Logger            *zap.Logger
 	RepositoryManager *repository_manager.RepositoryManager
 	HTTPServer        *http_server.HTTPServer
+
+	// AuditLog records management operations and serves the audit endpoint when provided
+	AuditLog *audit.AuditLog `optional:"true"`
+
+	// Tokens authenticates bearer access tokens and serves the token endpoints when provided
+	Tokens *tokens.TokenStore `optional:"true"`
+
+	// Keys serves the SSH key and deploy key endpoints when provided
+	Keys *ssh_keys.KeyStore `optional:"true"`
+
+	// GPGKeys serves the GPG key endpoints when provided
+	GPGKeys *gpg_keys.KeyStore `optional:"true"`
+
+	// LFS serves the Git LFS object listing of repositories when provided
+	LFS *lfs.Store `optional:"true"`
 }
 
 func Module(scope string) fx.Option {
@@ -95,9 +142,19 @@ func Module(scope string) fx.Option {
 func (m *RepositoryManagerAPIs) onStart(ctx context.Context) error {
 	m.logger.Info("Starting " + ModuleName)
 
+	m.enforceRoles = viper.GetBool(m.getConfigPath("enforce_roles"))
+	m.admins = viper.GetStringSlice(m.getConfigPath("admins"))
+	m.adminTeams = viper.GetStringSlice(m.getConfigPath("admin_teams"))
+
+	// Access tokens are authenticated before any other middleware runs
+	var authMiddlewares []gin.HandlerFunc
+	if m.params.Tokens != nil {
+		authMiddlewares = append(authMiddlewares, m.authenticateToken())
+	}
+
 	// Register routes
 	urlPrefix := viper.GetString(m.getConfigPath("url_prefix"))
-	router := m.params.HTTPServer.GetRouter().Group(urlPrefix)
+	router := m.params.HTTPServer.GetRouter().Group(urlPrefix, authMiddlewares...)
 
 	// Repository management routes
 	router.POST("", append(m.middlewareConfig.CreateRepository, m.handleCreateRepository)...)
@@ -107,8 +164,49 @@ func (m *RepositoryManagerAPIs) onStart(ctx context.Context) error {
 	// The middleware will check if path contains /tags/ and if repo exists
 	router.GET("/*name", m.tagsMiddleware(), m.dispatchGet())
 	router.POST("/*name", m.tagsMiddleware(), m.dispatchPost())
+	router.PUT("/*name", m.tagsMiddleware(), m.dispatchPut())
 	router.DELETE("/*name", m.tagsMiddleware(), m.dispatchDelete())
 
+	// Whole-server backup routes, restricted to server admins
+	backupURLPrefix := viper.GetString(m.getConfigPath("backup_url_prefix"))
+	backupRouter := m.params.HTTPServer.GetRouter().Group(backupURLPrefix, authMiddlewares...)
+	backupRouter.GET("", append(m.middlewareConfig.ExportArchive, m.requireServerAdmin(auth.OperationRead), m.handleExportArchive)...)
+	backupRouter.POST("", append(m.middlewareConfig.ImportArchive, m.requireServerAdmin(auth.OperationWrite), m.handleImportArchive)...)
+
+	// Audit log routes, restricted to server admins
+	if m.params.AuditLog != nil {
+		auditURLPrefix := viper.GetString(m.getConfigPath("audit_url_prefix"))
+		auditRouter := m.params.HTTPServer.GetRouter().Group(auditURLPrefix, authMiddlewares...)
+		auditRouter.GET("", append(m.middlewareConfig.ListAuditEntries, m.requireServerAdmin(auth.OperationRead), m.handleListAuditEntries)...)
+	}
+
+	// Personal access token routes
+	if m.params.Tokens != nil {
+		tokensURLPrefix := viper.GetString(m.getConfigPath("tokens_url_prefix"))
+		tokensRouter := m.params.HTTPServer.GetRouter().Group(tokensURLPrefix, authMiddlewares...)
+		tokensRouter.GET("", append(m.middlewareConfig.ListPersonalTokens, m.handleListPersonalTokens)...)
+		tokensRouter.POST("", append(m.middlewareConfig.CreatePersonalToken, m.handleCreatePersonalToken)...)
+		tokensRouter.DELETE("/:id", append(m.middlewareConfig.DeletePersonalToken, m.handleDeletePersonalToken)...)
+	}
+
+	// SSH key routes
+	if m.params.Keys != nil {
+		keysURLPrefix := viper.GetString(m.getConfigPath("keys_url_prefix"))
+		keysRouter := m.params.HTTPServer.GetRouter().Group(keysURLPrefix, authMiddlewares...)
+		keysRouter.GET("", append(m.middlewareConfig.ListUserKeys, m.handleListUserKeys)...)
+		keysRouter.POST("", append(m.middlewareConfig.CreateUserKey, m.handleCreateUserKey)...)
+		keysRouter.DELETE("/:id", append(m.middlewareConfig.DeleteUserKey, m.handleDeleteUserKey)...)
+	}
+
+	// GPG key routes
+	if m.params.GPGKeys != nil {
+		gpgKeysURLPrefix := viper.GetString(m.getConfigPath("gpg_keys_url_prefix"))
+		gpgKeysRouter := m.params.HTTPServer.GetRouter().Group(gpgKeysURLPrefix, authMiddlewares...)
+		gpgKeysRouter.GET("", append(m.middlewareConfig.ListGPGKeys, m.handleListGPGKeys)...)
+		gpgKeysRouter.POST("", append(m.middlewareConfig.CreateGPGKey, m.handleCreateGPGKey)...)
+		gpgKeysRouter.DELETE("/:id", append(m.middlewareConfig.DeleteGPGKey, m.handleDeleteGPGKey)...)
+	}
+
 	return nil
 }
 
@@ -123,6 +221,14 @@ func (m *RepositoryManagerAPIs) getConfigPath(key string) string {
 
 func (m *RepositoryManagerAPIs) initDefaultConfigs() {
 	viper.SetDefault(m.getConfigPath("url_prefix"), DefaultURLPrefix)
+	viper.SetDefault(m.getConfigPath("backup_url_prefix"), DefaultBackupURLPrefix)
+	viper.SetDefault(m.getConfigPath("audit_url_prefix"), DefaultAuditURLPrefix)
+	viper.SetDefault(m.getConfigPath("tokens_url_prefix"), DefaultTokensURLPrefix)
+	viper.SetDefault(m.getConfigPath("keys_url_prefix"), DefaultKeysURLPrefix)
+	viper.SetDefault(m.getConfigPath("gpg_keys_url_prefix"), DefaultGPGKeysURLPrefix)
+	viper.SetDefault(m.getConfigPath("enforce_roles"), false)
+	viper.SetDefault(m.getConfigPath("admins"), []string{})
+	viper.SetDefault(m.getConfigPath("admin_teams"), []string{})
 
 	// Default empty middleware config
 	mwcfg := NewMiddlewareConfig()
@@ -135,6 +241,52 @@ func (m *RepositoryManagerAPIs) SetupMiddleware(cfg MiddlewareConfig) {
 	m.middlewareConfig.ListRepositories = append([]gin.HandlerFunc{}, cfg.ListRepositories...)
 	m.middlewareConfig.GetRepository = append([]gin.HandlerFunc{}, cfg.GetRepository...)
 	m.middlewareConfig.DeleteRepository = append([]gin.HandlerFunc{}, cfg.DeleteRepository...)
+	m.middlewareConfig.GetBundle = append([]gin.HandlerFunc{}, cfg.GetBundle...)
+	m.middlewareConfig.GetFile = append([]gin.HandlerFunc{}, cfg.GetFile...)
+	m.middlewareConfig.PutFile = append([]gin.HandlerFunc{}, cfg.PutFile...)
+	m.middlewareConfig.DeleteFile = append([]gin.HandlerFunc{}, cfg.DeleteFile...)
+	m.middlewareConfig.CreateCommit = append([]gin.HandlerFunc{}, cfg.CreateCommit...)
+	m.middlewareConfig.CreateMerge = append([]gin.HandlerFunc{}, cfg.CreateMerge...)
+	m.middlewareConfig.ListBranchProtectionRules = append([]gin.HandlerFunc{}, cfg.ListBranchProtectionRules...)
+	m.middlewareConfig.CreateBranchProtectionRule = append([]gin.HandlerFunc{}, cfg.CreateBranchProtectionRule...)
+	m.middlewareConfig.GetBranchProtectionRule = append([]gin.HandlerFunc{}, cfg.GetBranchProtectionRule...)
+	m.middlewareConfig.UpdateBranchProtectionRule = append([]gin.HandlerFunc{}, cfg.UpdateBranchProtectionRule...)
+	m.middlewareConfig.DeleteBranchProtectionRule = append([]gin.HandlerFunc{}, cfg.DeleteBranchProtectionRule...)
+	m.middlewareConfig.ListTagProtectionRules = append([]gin.HandlerFunc{}, cfg.ListTagProtectionRules...)
+	m.middlewareConfig.CreateTagProtectionRule = append([]gin.HandlerFunc{}, cfg.CreateTagProtectionRule...)
+	m.middlewareConfig.GetTagProtectionRule = append([]gin.HandlerFunc{}, cfg.GetTagProtectionRule...)
+	m.middlewareConfig.DeleteTagProtectionRule = append([]gin.HandlerFunc{}, cfg.DeleteTagProtectionRule...)
+	m.middlewareConfig.ListWebhooks = append([]gin.HandlerFunc{}, cfg.ListWebhooks...)
+	m.middlewareConfig.CreateWebhook = append([]gin.HandlerFunc{}, cfg.CreateWebhook...)
+	m.middlewareConfig.GetWebhook = append([]gin.HandlerFunc{}, cfg.GetWebhook...)
+	m.middlewareConfig.UpdateWebhook = append([]gin.HandlerFunc{}, cfg.UpdateWebhook...)
+	m.middlewareConfig.DeleteWebhook = append([]gin.HandlerFunc{}, cfg.DeleteWebhook...)
+	m.middlewareConfig.ListWebhookDeliveries = append([]gin.HandlerFunc{}, cfg.ListWebhookDeliveries...)
+	m.middlewareConfig.ListPermissions = append([]gin.HandlerFunc{}, cfg.ListPermissions...)
+	m.middlewareConfig.SetPermission = append([]gin.HandlerFunc{}, cfg.SetPermission...)
+	m.middlewareConfig.DeletePermission = append([]gin.HandlerFunc{}, cfg.DeletePermission...)
+	m.middlewareConfig.ListPersonalTokens = append([]gin.HandlerFunc{}, cfg.ListPersonalTokens...)
+	m.middlewareConfig.CreatePersonalToken = append([]gin.HandlerFunc{}, cfg.CreatePersonalToken...)
+	m.middlewareConfig.DeletePersonalToken = append([]gin.HandlerFunc{}, cfg.DeletePersonalToken...)
+	m.middlewareConfig.ListDeployTokens = append([]gin.HandlerFunc{}, cfg.ListDeployTokens...)
+	m.middlewareConfig.CreateDeployToken = append([]gin.HandlerFunc{}, cfg.CreateDeployToken...)
+	m.middlewareConfig.DeleteDeployToken = append([]gin.HandlerFunc{}, cfg.DeleteDeployToken...)
+	m.middlewareConfig.ListUserKeys = append([]gin.HandlerFunc{}, cfg.ListUserKeys...)
+	m.middlewareConfig.CreateUserKey = append([]gin.HandlerFunc{}, cfg.CreateUserKey...)
+	m.middlewareConfig.DeleteUserKey = append([]gin.HandlerFunc{}, cfg.DeleteUserKey...)
+	m.middlewareConfig.ListDeployKeys = append([]gin.HandlerFunc{}, cfg.ListDeployKeys...)
+	m.middlewareConfig.CreateDeployKey = append([]gin.HandlerFunc{}, cfg.CreateDeployKey...)
+	m.middlewareConfig.DeleteDeployKey = append([]gin.HandlerFunc{}, cfg.DeleteDeployKey...)
+	m.middlewareConfig.ListGPGKeys = append([]gin.HandlerFunc{}, cfg.ListGPGKeys...)
+	m.middlewareConfig.CreateGPGKey = append([]gin.HandlerFunc{}, cfg.CreateGPGKey...)
+	m.middlewareConfig.DeleteGPGKey = append([]gin.HandlerFunc{}, cfg.DeleteGPGKey...)
+	m.middlewareConfig.GetFilterPolicy = append([]gin.HandlerFunc{}, cfg.GetFilterPolicy...)
+	m.middlewareConfig.UpdateFilterPolicy = append([]gin.HandlerFunc{}, cfg.UpdateFilterPolicy...)
+	m.middlewareConfig.GetQuota = append([]gin.HandlerFunc{}, cfg.GetQuota...)
+	m.middlewareConfig.UpdateQuota = append([]gin.HandlerFunc{}, cfg.UpdateQuota...)
+	m.middlewareConfig.GetContentPolicy = append([]gin.HandlerFunc{}, cfg.GetContentPolicy...)
+	m.middlewareConfig.UpdateContentPolicy = append([]gin.HandlerFunc{}, cfg.UpdateContentPolicy...)
+	m.middlewareConfig.ListLFSObjects = append([]gin.HandlerFunc{}, cfg.ListLFSObjects...)
 	m.middlewareConfig.CreateTag = append([]gin.HandlerFunc{}, cfg.CreateTag...)
 	m.middlewareConfig.ListTags = append([]gin.HandlerFunc{}, cfg.ListTags...)
 	m.middlewareConfig.GetTag = append([]gin.HandlerFunc{}, cfg.GetTag...)
@@ -143,6 +295,9 @@ func (m *RepositoryManagerAPIs) SetupMiddleware(cfg MiddlewareConfig) {
 	m.middlewareConfig.ListGroups = append([]gin.HandlerFunc{}, cfg.ListGroups...)
 	m.middlewareConfig.GetGroup = append([]gin.HandlerFunc{}, cfg.GetGroup...)
 	m.middlewareConfig.DeleteGroup = append([]gin.HandlerFunc{}, cfg.DeleteGroup...)
+	m.middlewareConfig.ExportArchive = append([]gin.HandlerFunc{}, cfg.ExportArchive...)
+	m.middlewareConfig.ImportArchive = append([]gin.HandlerFunc{}, cfg.ImportArchive...)
+	m.middlewareConfig.ListAuditEntries = append([]gin.HandlerFunc{}, cfg.ListAuditEntries...)
 }
 
 type pathKind int
@@ -152,15 +307,77 @@ const (
 	pathKindGroup
 	pathKindTagsRoot
 	pathKindTagItem
+	pathKindBundle
+	pathKindContents
+	pathKindCommits
+	pathKindMerges
+	pathKindBranchProtectionRoot
+	pathKindBranchProtectionItem
+	pathKindTagProtectionRoot
+	pathKindTagProtectionItem
+	pathKindWebhooksRoot
+	pathKindWebhookItem
+	pathKindWebhookDeliveries
+	pathKindPermissionsRoot
+	pathKindPermissionItem
+	pathKindDeployTokensRoot
+	pathKindDeployTokenItem
+	pathKindDeployKeysRoot
+	pathKindDeployKeyItem
+	pathKindFilters
+	pathKindQuota
+	pathKindContentPolicy
+	pathKindLFSObjects
 )
 
+// protectionPaths lists the path segments of protection rules with the path kinds of the rule list and of a single rule.
+// Protection rules exist on repositories and groups. They are matched in order.
+var protectionPaths = []struct {
+	segment string
+	root    pathKind
+	item    pathKind
+}{
+	{"/protected_branches", pathKindBranchProtectionRoot, pathKindBranchProtectionItem},
+	{"/protected_tags", pathKindTagProtectionRoot, pathKindTagProtectionItem},
+}
+
+// settingPaths lists the path suffixes of single settings of repositories and groups with their path kind.
+// They are matched in order.
+var settingPaths = []struct {
+	suffix string
+	kind   pathKind
+}{
+	{"/quota", pathKindQuota},
+	{"/content_policy", pathKindContentPolicy},
+}
+
+// repositoryActionPaths lists the path suffixes of repository actions with their path kind.
+// They are matched in order.
+var repositoryActionPaths = []struct {
+	suffix string
+	kind   pathKind
+}{
+	{"/bundle", pathKindBundle},
+	{"/commits", pathKindCommits},
+	{"/merges", pathKindMerges},
+	{"/filters", pathKindFilters},
+	{"/lfs_objects", pathKindLFSObjects},
+}
+
 const (
 	contextKeyPathKind = "path_kind"
 	contextKeyRepoName = "repo_name"
 	contextKeyTagName  = "tag_name"
+	contextKeyFilePath = "file_path"
+	contextKeyPattern  = "pattern"
+	contextKeyWebhook  = "webhook_id"
+	contextKeyKind     = "subject_kind"
+	contextKeySubject  = "subject"
+	contextKeyTokenID  = "token_id"
+	contextKeyKeyID    = "key_id"
 )
 
-// tagsMiddleware checks if the path is a tags operation and validates repository existence
+// tagsMiddleware checks if the path is a tags, contents or repository action operation and validates repository existence
 // Also differentiates between repositories and groups
 func (m *RepositoryManagerAPIs) tagsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -170,6 +387,129 @@ func (m *RepositoryManagerAPIs) tagsMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Check if path addresses a file in a repository (e.g. /repo/contents/path/to/file)
+		if repoName, filePath, ok := m.splitContentsPath(path); ok {
+			c.Set(contextKeyPathKind, pathKindContents)
+			c.Set(contextKeyRepoName, repoName)
+			c.Set(contextKeyFilePath, filePath)
+			c.Next()
+			return
+		}
+
+		// Check if path addresses protection rules (e.g. /org/protected_branches/release/*)
+		for _, protection := range protectionPaths {
+			if name, pattern, ok := m.splitSettingsPath(path, protection.segment); ok {
+				c.Set(contextKeyRepoName, name)
+				if pattern == "" {
+					c.Set(contextKeyPathKind, protection.root)
+				} else {
+					c.Set(contextKeyPathKind, protection.item)
+					c.Set(contextKeyPattern, pattern)
+				}
+				c.Next()
+				return
+			}
+		}
+
+		// Check if path addresses webhooks (e.g. /org/webhooks/{id}/deliveries)
+		if name, rest, ok := m.splitSettingsPath(path, "/webhooks"); ok {
+			c.Set(contextKeyRepoName, name)
+			switch id, deliveries := strings.CutSuffix(rest, "/deliveries"); {
+			case rest == "":
+				c.Set(contextKeyPathKind, pathKindWebhooksRoot)
+			case strings.Contains(id, "/"):
+				c.AbortWithStatus(http.StatusNotFound)
+				return
+			case deliveries:
+				c.Set(contextKeyPathKind, pathKindWebhookDeliveries)
+				c.Set(contextKeyWebhook, id)
+			default:
+				c.Set(contextKeyPathKind, pathKindWebhookItem)
+				c.Set(contextKeyWebhook, id)
+			}
+			c.Next()
+			return
+		}
+
+		// Check if path addresses permissions (e.g. /org/permissions/users/john)
+		if name, rest, ok := m.splitSettingsPath(path, "/permissions"); ok {
+			c.Set(contextKeyRepoName, name)
+			kind, subject, _ := strings.Cut(rest, "/")
+			switch {
+			case rest == "":
+				c.Set(contextKeyPathKind, pathKindPermissionsRoot)
+			case (kind == "users" || kind == "teams") && subject != "" && !strings.Contains(subject, "/"):
+				c.Set(contextKeyPathKind, pathKindPermissionItem)
+				c.Set(contextKeyKind, kind)
+				c.Set(contextKeySubject, subject)
+			default:
+				c.AbortWithStatus(http.StatusNotFound)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		// Check if path addresses deploy keys (e.g. /org/app/deploy_keys/{id})
+		if m.params.Keys != nil {
+			if name, id, ok := m.splitSettingsPath(path, "/deploy_keys"); ok && m.params.RepositoryManager.IsRepository(name) {
+				c.Set(contextKeyRepoName, name)
+				switch {
+				case id == "":
+					c.Set(contextKeyPathKind, pathKindDeployKeysRoot)
+				case strings.Contains(id, "/"):
+					c.AbortWithStatus(http.StatusNotFound)
+					return
+				default:
+					c.Set(contextKeyPathKind, pathKindDeployKeyItem)
+					c.Set(contextKeyKeyID, id)
+				}
+				c.Next()
+				return
+			}
+		}
+
+		// Check if path addresses deploy tokens (e.g. /org/app/deploy_tokens/{id})
+		if m.params.Tokens != nil {
+			if name, id, ok := m.splitSettingsPath(path, "/deploy_tokens"); ok && m.params.RepositoryManager.IsRepository(name) {
+				c.Set(contextKeyRepoName, name)
+				switch {
+				case id == "":
+					c.Set(contextKeyPathKind, pathKindDeployTokensRoot)
+				case strings.Contains(id, "/"):
+					c.AbortWithStatus(http.StatusNotFound)
+					return
+				default:
+					c.Set(contextKeyPathKind, pathKindDeployTokenItem)
+					c.Set(contextKeyTokenID, id)
+				}
+				c.Next()
+				return
+			}
+		}
+
+		// Check if path addresses a setting of a repository or group (e.g. /org/quota).
+		// Other paths may contain the segment, e.g. the repository org/quota/app.
+		for _, setting := range settingPaths {
+			name, ok := strings.CutSuffix(path, setting.suffix)
+			if ok && (m.params.RepositoryManager.IsRepository(name) || m.params.RepositoryManager.IsGroup(name)) {
+				c.Set(contextKeyPathKind, setting.kind)
+				c.Set(contextKeyRepoName, name)
+				c.Next()
+				return
+			}
+		}
+
+		// Check if path is a repository action (e.g. /repo/bundle or /repo/commits)
+		for _, action := range repositoryActionPaths {
+			if repoName, ok := strings.CutSuffix(path, action.suffix); ok && m.params.RepositoryManager.IsRepository(repoName) {
+				c.Set(contextKeyPathKind, action.kind)
+				c.Set(contextKeyRepoName, repoName)
+				c.Next()
+				return
+			}
+		}
+
 		// Check if path contains /tags/
 		idx := indexOfTagsSegment(path)
 		if idx < 0 {
@@ -246,19 +586,59 @@ func (m *RepositoryManagerAPIs) dispatchGet() gin.HandlerFunc {
 
 		repoName, _ := c.Get(contextKeyRepoName)
 		tagName, _ := c.Get(contextKeyTagName)
+		filePath, _ := c.Get(contextKeyFilePath)
+		pattern, _ := c.Get(contextKeyPattern)
+		webhookID, _ := c.Get(contextKeyWebhook)
 
 		setParam(c, "name", "/"+repoName.(string))
 
 		switch kind.(pathKind) {
 		case pathKindRepository:
-			m.invokeHandlers(c, m.middlewareConfig.GetRepository, m.handleGetRepository)
+			m.invokeHandlers(c, m.middlewareConfig.GetRepository, repository_manager.RoleRead, m.handleGetRepository)
 		case pathKindGroup:
-			m.invokeHandlers(c, m.middlewareConfig.GetGroup, m.handleGetGroup)
+			m.invokeHandlers(c, m.middlewareConfig.GetGroup, repository_manager.RoleRead, m.handleGetGroup)
 		case pathKindTagsRoot:
-			m.invokeHandlers(c, m.middlewareConfig.ListTags, m.handleListTags)
+			m.invokeHandlers(c, m.middlewareConfig.ListTags, repository_manager.RoleRead, m.handleListTags)
 		case pathKindTagItem:
 			setParam(c, "tag", "/"+tagName.(string))
-			m.invokeHandlers(c, m.middlewareConfig.GetTag, m.handleGetTag)
+			m.invokeHandlers(c, m.middlewareConfig.GetTag, repository_manager.RoleRead, m.handleGetTag)
+		case pathKindBundle:
+			m.invokeHandlers(c, m.middlewareConfig.GetBundle, repository_manager.RoleRead, m.handleGetBundle)
+		case pathKindContents:
+			setParam(c, "path", "/"+filePath.(string))
+			m.invokeHandlers(c, m.middlewareConfig.GetFile, repository_manager.RoleRead, m.handleGetFile)
+		case pathKindBranchProtectionRoot:
+			m.invokeHandlers(c, m.middlewareConfig.ListBranchProtectionRules, repository_manager.RoleRead, m.handleListBranchProtectionRules)
+		case pathKindBranchProtectionItem:
+			setParam(c, "pattern", "/"+pattern.(string))
+			m.invokeHandlers(c, m.middlewareConfig.GetBranchProtectionRule, repository_manager.RoleRead, m.handleGetBranchProtectionRule)
+		case pathKindTagProtectionRoot:
+			m.invokeHandlers(c, m.middlewareConfig.ListTagProtectionRules, repository_manager.RoleRead, m.handleListTagProtectionRules)
+		case pathKindTagProtectionItem:
+			setParam(c, "pattern", "/"+pattern.(string))
+			m.invokeHandlers(c, m.middlewareConfig.GetTagProtectionRule, repository_manager.RoleRead, m.handleGetTagProtectionRule)
+		case pathKindWebhooksRoot:
+			m.invokeHandlers(c, m.middlewareConfig.ListWebhooks, repository_manager.RoleMaintain, m.handleListWebhooks)
+		case pathKindWebhookItem:
+			setParam(c, "id", webhookID.(string))
+			m.invokeHandlers(c, m.middlewareConfig.GetWebhook, repository_manager.RoleMaintain, m.handleGetWebhook)
+		case pathKindWebhookDeliveries:
+			setParam(c, "id", webhookID.(string))
+			m.invokeHandlers(c, m.middlewareConfig.ListWebhookDeliveries, repository_manager.RoleMaintain, m.handleListWebhookDeliveries)
+		case pathKindPermissionsRoot:
+			m.invokeHandlers(c, m.middlewareConfig.ListPermissions, repository_manager.RoleMaintain, m.handleListPermissions)
+		case pathKindDeployTokensRoot:
+			m.invokeHandlers(c, m.middlewareConfig.ListDeployTokens, repository_manager.RoleMaintain, m.handleListDeployTokens)
+		case pathKindDeployKeysRoot:
+			m.invokeHandlers(c, m.middlewareConfig.ListDeployKeys, repository_manager.RoleMaintain, m.handleListDeployKeys)
+		case pathKindFilters:
+			m.invokeHandlers(c, m.middlewareConfig.GetFilterPolicy, repository_manager.RoleRead, m.handleGetFilterPolicy)
+		case pathKindQuota:
+			m.invokeHandlers(c, m.middlewareConfig.GetQuota, repository_manager.RoleRead, m.handleGetQuota)
+		case pathKindContentPolicy:
+			m.invokeHandlers(c, m.middlewareConfig.GetContentPolicy, repository_manager.RoleRead, m.handleGetContentPolicy)
+		case pathKindLFSObjects:
+			m.invokeHandlers(c, m.middlewareConfig.ListLFSObjects, repository_manager.RoleRead, m.handleListLFSObjects)
 		default:
 			c.AbortWithStatus(http.StatusNotFound)
 		}
@@ -278,7 +658,21 @@ func (m *RepositoryManagerAPIs) dispatchPost() gin.HandlerFunc {
 
 		switch kind.(pathKind) {
 		case pathKindTagsRoot:
-			m.invokeHandlers(c, m.middlewareConfig.CreateTag, m.handleCreateTag)
+			m.invokeHandlers(c, m.middlewareConfig.CreateTag, repository_manager.RoleWrite, m.handleCreateTag)
+		case pathKindCommits:
+			m.invokeHandlers(c, m.middlewareConfig.CreateCommit, repository_manager.RoleWrite, m.handleCreateCommit)
+		case pathKindMerges:
+			m.invokeHandlers(c, m.middlewareConfig.CreateMerge, repository_manager.RoleWrite, m.handleCreateMerge)
+		case pathKindBranchProtectionRoot:
+			m.invokeHandlers(c, m.middlewareConfig.CreateBranchProtectionRule, repository_manager.RoleMaintain, m.handleCreateBranchProtectionRule)
+		case pathKindTagProtectionRoot:
+			m.invokeHandlers(c, m.middlewareConfig.CreateTagProtectionRule, repository_manager.RoleMaintain, m.handleCreateTagProtectionRule)
+		case pathKindWebhooksRoot:
+			m.invokeHandlers(c, m.middlewareConfig.CreateWebhook, repository_manager.RoleMaintain, m.handleCreateWebhook)
+		case pathKindDeployTokensRoot:
+			m.invokeHandlers(c, m.middlewareConfig.CreateDeployToken, repository_manager.RoleMaintain, m.handleCreateDeployToken)
+		case pathKindDeployKeysRoot:
+			m.invokeHandlers(c, m.middlewareConfig.CreateDeployKey, repository_manager.RoleMaintain, m.handleCreateDeployKey)
 		default:
 			c.AbortWithStatus(http.StatusNotFound)
 		}
@@ -295,27 +689,95 @@ func (m *RepositoryManagerAPIs) dispatchDelete() gin.HandlerFunc {
 
 		repoName, _ := c.Get(contextKeyRepoName)
 		tagName, _ := c.Get(contextKeyTagName)
+		filePath, _ := c.Get(contextKeyFilePath)
+		pattern, _ := c.Get(contextKeyPattern)
+		webhookID, _ := c.Get(contextKeyWebhook)
 
 		setParam(c, "name", "/"+repoName.(string))
 
 		switch kind.(pathKind) {
 		case pathKindRepository:
-			m.invokeHandlers(c, m.middlewareConfig.DeleteRepository, m.handleDeleteRepository)
+			m.invokeHandlers(c, m.middlewareConfig.DeleteRepository, repository_manager.RoleAdmin, m.handleDeleteRepository)
 		case pathKindGroup:
-			m.invokeHandlers(c, m.middlewareConfig.DeleteGroup, m.handleDeleteGroup)
+			m.invokeHandlers(c, m.middlewareConfig.DeleteGroup, repository_manager.RoleAdmin, m.handleDeleteGroup)
 		case pathKindTagItem:
 			setParam(c, "tag", "/"+tagName.(string))
-			m.invokeHandlers(c, m.middlewareConfig.DeleteTag, m.handleDeleteTag)
+			m.invokeHandlers(c, m.middlewareConfig.DeleteTag, repository_manager.RoleMaintain, m.handleDeleteTag)
+		case pathKindContents:
+			setParam(c, "path", "/"+filePath.(string))
+			m.invokeHandlers(c, m.middlewareConfig.DeleteFile, repository_manager.RoleWrite, m.handleDeleteFile)
+		case pathKindBranchProtectionItem:
+			setParam(c, "pattern", "/"+pattern.(string))
+			m.invokeHandlers(c, m.middlewareConfig.DeleteBranchProtectionRule, repository_manager.RoleMaintain, m.handleDeleteBranchProtectionRule)
+		case pathKindTagProtectionItem:
+			setParam(c, "pattern", "/"+pattern.(string))
+			m.invokeHandlers(c, m.middlewareConfig.DeleteTagProtectionRule, repository_manager.RoleMaintain, m.handleDeleteTagProtectionRule)
+		case pathKindWebhookItem:
+			setParam(c, "id", webhookID.(string))
+			m.invokeHandlers(c, m.middlewareConfig.DeleteWebhook, repository_manager.RoleMaintain, m.handleDeleteWebhook)
+		case pathKindPermissionItem:
+			setPermissionParams(c)
+			m.invokeHandlers(c, m.middlewareConfig.DeletePermission, repository_manager.RoleAdmin, m.handleDeletePermission)
+		case pathKindDeployTokenItem:
+			tokenID, _ := c.Get(contextKeyTokenID)
+			setParam(c, "id", tokenID.(string))
+			m.invokeHandlers(c, m.middlewareConfig.DeleteDeployToken, repository_manager.RoleMaintain, m.handleDeleteDeployToken)
+		case pathKindDeployKeyItem:
+			keyID, _ := c.Get(contextKeyKeyID)
+			setParam(c, "id", keyID.(string))
+			m.invokeHandlers(c, m.middlewareConfig.DeleteDeployKey, repository_manager.RoleMaintain, m.handleDeleteDeployKey)
 		default:
 			c.AbortWithStatus(http.StatusNotFound)
 		}
 	}
 }
 
-func (m *RepositoryManagerAPIs) invokeHandlers(c *gin.Context, middlewares []gin.HandlerFunc, handler gin.HandlerFunc) {
-	chain := make(gin.HandlersChain, 0, len(middlewares)+1)
+func (m *RepositoryManagerAPIs) dispatchPut() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		kind, ok := c.Get(contextKeyPathKind)
+		if !ok {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+
+		repoName, _ := c.Get(contextKeyRepoName)
+		filePath, _ := c.Get(contextKeyFilePath)
+		pattern, _ := c.Get(contextKeyPattern)
+		webhookID, _ := c.Get(contextKeyWebhook)
+
+		setParam(c, "name", "/"+repoName.(string))
+
+		switch kind.(pathKind) {
+		case pathKindContents:
+			setParam(c, "path", "/"+filePath.(string))
+			m.invokeHandlers(c, m.middlewareConfig.PutFile, repository_manager.RoleWrite, m.handlePutFile)
+		case pathKindBranchProtectionItem:
+			setParam(c, "pattern", "/"+pattern.(string))
+			m.invokeHandlers(c, m.middlewareConfig.UpdateBranchProtectionRule, repository_manager.RoleMaintain, m.handleUpdateBranchProtectionRule)
+		case pathKindWebhookItem:
+			setParam(c, "id", webhookID.(string))
+			m.invokeHandlers(c, m.middlewareConfig.UpdateWebhook, repository_manager.RoleMaintain, m.handleUpdateWebhook)
+		case pathKindPermissionItem:
+			setPermissionParams(c)
+			m.invokeHandlers(c, m.middlewareConfig.SetPermission, repository_manager.RoleAdmin, m.handleSetPermission)
+		case pathKindFilters:
+			m.invokeHandlers(c, m.middlewareConfig.UpdateFilterPolicy, repository_manager.RoleMaintain, m.handleUpdateFilterPolicy)
+		case pathKindQuota:
+			m.invokeHandlers(c, m.middlewareConfig.UpdateQuota, repository_manager.RoleAdmin, m.handleUpdateQuota)
+		case pathKindContentPolicy:
+			m.invokeHandlers(c, m.middlewareConfig.UpdateContentPolicy, repository_manager.RoleMaintain, m.handleUpdateContentPolicy)
+		default:
+			c.AbortWithStatus(http.StatusNotFound)
+		}
+	}
+}
+
+// invokeHandlers runs the middlewares of an operation followed by the role check and the handler.
+// The role check runs last so middlewares can authenticate the request first.
+func (m *RepositoryManagerAPIs) invokeHandlers(c *gin.Context, middlewares []gin.HandlerFunc, role repository_manager.Role, handler gin.HandlerFunc) {
+	chain := make(gin.HandlersChain, 0, len(middlewares)+2)
 	chain = append(chain, middlewares...)
-	chain = append(chain, handler)
+	chain = append(chain, m.requireRole(role), handler)
 	runHandlerChain(c, chain)
 }
 
@@ -342,6 +804,14 @@ func setUnexportedField(field, value reflect.Value) {
 	reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem().Set(value)
 }
 
+// setPermissionParams sets the kind and subject parameters of a permission path
+func setPermissionParams(c *gin.Context) {
+	kind, _ := c.Get(contextKeyKind)
+	subject, _ := c.Get(contextKeySubject)
+	setParam(c, "kind", kind.(string))
+	setParam(c, "subject", subject.(string))
+}
+
 func setParam(c *gin.Context, key, value string) {
 	for i := range c.Params {
 		if c.Params[i].Key == key {
@@ -352,6 +822,50 @@ func setParam(c *gin.Context, key, value string) {
 	c.Params = append(c.Params, gin.Param{Key: key, Value: value})
 }
 
+// splitContentsPath splits a path like "org/repo/contents/dir/file" into repository name and file path.
+// The first "/contents/" segment preceded by an existing repository is used.
+func (m *RepositoryManagerAPIs) splitContentsPath(path string) (string, string, bool) {
+	const segment = "/contents/"
+
+	for offset := 0; ; {
+		idx := strings.Index(path[offset:], segment)
+		if idx < 0 {
+			return "", "", false
+		}
+
+		idx += offset
+		repoName := path[:idx]
+		filePath := path[idx+len(segment):]
+		if filePath != "" && m.params.RepositoryManager.IsRepository(repoName) {
+			return repoName, filePath, true
+		}
+
+		offset = idx + 1
+	}
+}
+
+// splitSettingsPath splits a path like "org/repo/protected_branches/release/*" into the
+// repository or group name and the remainder after the segment, e.g. a rule pattern,
+// which is empty for the list.
+func (m *RepositoryManagerAPIs) splitSettingsPath(path, segment string) (string, string, bool) {
+	for offset := 0; ; {
+		idx := strings.Index(path[offset:], segment)
+		if idx < 0 {
+			return "", "", false
+		}
+
+		idx += offset
+		name := path[:idx]
+		rest := path[idx+len(segment):]
+		if (rest == "" || strings.HasPrefix(rest, "/")) &&
+			(m.params.RepositoryManager.IsRepository(name) || m.params.RepositoryManager.IsGroup(name)) {
+			return name, strings.TrimPrefix(rest, "/"), true
+		}
+
+		offset = idx + 1
+	}
+}
+
 func indexOfTagsSegment(path string) int {
 	const segment = "/tags"
 
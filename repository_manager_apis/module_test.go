@@ -0,0 +1,234 @@
+package repository_manager_apis
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/weedbox/common-modules/http_server"
+	"github.com/weedbox/git-modules/audit"
+	"github.com/weedbox/git-modules/repository_manager"
+	"github.com/weedbox/git-modules/ssh_keys"
+	"github.com/weedbox/git-modules/tokens"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// testAPIs is a running API server with its repository manager and credential stores
+type testAPIs struct {
+	url     string
+	manager *repository_manager.RepositoryManager
+	tokens  *tokens.TokenStore
+	keys    *ssh_keys.KeyStore
+}
+
+// setupTestAPIs starts the APIs with the audit log, access tokens and SSH keys, alice being the only server admin.
+// It creates the repository org/app. opts are added to the app, such as a TeamResolver.
+func setupTestAPIs(t *testing.T, enforceRoles bool, opts ...fx.Option) *testAPIs {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	dir := t.TempDir()
+	viper.Set("http_server.host", "127.0.0.1")
+	viper.Set("http_server.port", port)
+	viper.Set("repository_manager.repos_path", filepath.Join(dir, "repos"))
+	viper.Set("audit.path", filepath.Join(dir, "audit.log"))
+	viper.Set("tokens.path", filepath.Join(dir, "tokens.json"))
+	viper.Set("ssh_keys.path", filepath.Join(dir, "ssh_keys.json"))
+	viper.Set("repository_manager_apis.enforce_roles", enforceRoles)
+	viper.Set("repository_manager_apis.admins", []string{"alice"})
+	t.Cleanup(func() {
+		viper.Set("repository_manager_apis.enforce_roles", false)
+		viper.Set("repository_manager_apis.admins", []string{})
+	})
+
+	s := &testAPIs{url: fmt.Sprintf("http://127.0.0.1:%d", port)}
+	app := fx.New(
+		fx.NopLogger,
+		fx.Provide(zap.NewNop),
+		http_server.Module("http_server"),
+		repository_manager.Module("repository_manager"),
+		audit.Module("audit"),
+		tokens.Module("tokens"),
+		ssh_keys.Module("ssh_keys"),
+		Module("repository_manager_apis"),
+		fx.Populate(&s.manager, &s.tokens, &s.keys),
+		fx.Options(opts...),
+	)
+	if err := app.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start app: %v", err)
+	}
+	t.Cleanup(func() { app.Stop(context.Background()) })
+
+	// The HTTP server listens in the background
+	for i := 0; i < 100; i++ {
+		if conn, err := net.Dial("tcp", listener.Addr().String()); err == nil {
+			conn.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := s.manager.CreateRepository("org/app", ""); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	return s
+}
+
+// personalToken creates a personal access token of a user and returns its secret
+func (s *testAPIs) personalToken(t *testing.T, user string, opts tokens.Options) string {
+	if opts.Name == "" {
+		opts.Name = "test"
+	}
+	token, err := s.tokens.CreatePersonalToken(user, opts)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	return token.Secret
+}
+
+// request sends a request with an optional bearer token and returns the status code
+func (s *testAPIs) request(t *testing.T, method, path, token string, body io.Reader) int {
+	req, err := http.NewRequest(method, s.url+path, body)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send %s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	return resp.StatusCode
+}
+
+// Test that enforced roles apply to repositories and that backups and the audit log need a server admin
+func TestEnforceRoles(t *testing.T) {
+	s := setupTestAPIs(t, true)
+	admin := s.personalToken(t, "alice", tokens.Options{Scope: tokens.ScopeWrite})
+	user := s.personalToken(t, "bob", tokens.Options{Scope: tokens.ScopeWrite})
+
+	for _, path := range []string{"/apis/v1/backup", "/apis/v1/audit"} {
+		if status := s.request(t, http.MethodGet, path, "", nil); status != http.StatusUnauthorized {
+			t.Errorf("GET %s: expected 401 for anonymous requests, got %d", path, status)
+		}
+		if status := s.request(t, http.MethodGet, path, user, nil); status != http.StatusForbidden {
+			t.Errorf("GET %s: expected 403 for users that are not admins, got %d", path, status)
+		}
+		if status := s.request(t, http.MethodGet, path, admin, nil); status != http.StatusOK {
+			t.Errorf("GET %s: expected 200 for admins, got %d", path, status)
+		}
+	}
+	if status := s.request(t, http.MethodPost, "/apis/v1/backup", user, nil); status != http.StatusForbidden {
+		t.Errorf("Expected backup import to be refused to users that are not admins, got %d", status)
+	}
+
+	if status := s.request(t, http.MethodGet, "/apis/v1/repos/org/app", "", nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for anonymous repository reads, got %d", status)
+	}
+	if status := s.request(t, http.MethodGet, "/apis/v1/repos/org/app", user, nil); status != http.StatusForbidden {
+		t.Errorf("Expected 403 without role, got %d", status)
+	}
+
+	if _, err := s.manager.SetPermission("org", repository_manager.Permission{User: "bob", Role: repository_manager.RoleRead}); err != nil {
+		t.Fatalf("Failed to grant role: %v", err)
+	}
+	if status := s.request(t, http.MethodGet, "/apis/v1/repos/org/app", user, nil); status != http.StatusOK {
+		t.Errorf("Expected the role inherited from the group to grant reads, got %d", status)
+	}
+	if status := s.request(t, http.MethodDelete, "/apis/v1/repos/org/app", user, nil); status != http.StatusForbidden {
+		t.Errorf("Expected deletion to need the admin role, got %d", status)
+	}
+}
+
+// Test that server-wide routes stay open when roles are not enforced
+func TestEnforceRoles_Disabled(t *testing.T) {
+	s := setupTestAPIs(t, false)
+
+	for _, path := range []string{"/apis/v1/backup", "/apis/v1/audit", "/apis/v1/repos/org/app"} {
+		if status := s.request(t, http.MethodGet, path, "", nil); status != http.StatusOK {
+			t.Errorf("GET %s: expected 200 without enforced roles, got %d", path, status)
+		}
+	}
+}
+
+// Test that settings segments only address settings at the end of a path
+func TestSettingPaths(t *testing.T) {
+	s := setupTestAPIs(t, false)
+	for _, name := range []string{"org/quota/app", "org/content_policy/app"} {
+		if _, err := s.manager.CreateRepository(name, ""); err != nil {
+			t.Fatalf("Failed to create repository: %v", err)
+		}
+	}
+
+	for _, path := range []string{
+		"/apis/v1/repos/org/quota/app",
+		"/apis/v1/repos/org/content_policy/app",
+		"/apis/v1/repos/org/quota/quota",
+		"/apis/v1/repos/org/quota/app/content_policy",
+		"/apis/v1/repos/org/content_policy/content_policy",
+	} {
+		if status := s.request(t, http.MethodGet, path, "", nil); status != http.StatusOK {
+			t.Errorf("GET %s: expected 200, got %d", path, status)
+		}
+	}
+
+	if status := s.request(t, http.MethodGet, "/apis/v1/repos/org/missing/quota", "", nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for the quota of a missing group, got %d", status)
+	}
+}
+
+// Test the status codes of bundle downloads and restores
+func TestBundleErrors(t *testing.T) {
+	s := setupTestAPIs(t, false)
+
+	if status := s.request(t, http.MethodGet, "/apis/v1/repos/org/missing/bundle", "", nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for the bundle of a missing repository, got %d", status)
+	}
+	if status := s.request(t, http.MethodGet, "/apis/v1/repos/org/app/bundle", "", nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for the bundle of an empty repository, got %d", status)
+	}
+
+	prerequisite := "# v2 git bundle\n-0123456789012345678901234567890123456789 base\n0123456789012345678901234567890123456789 refs/heads/main\n\n"
+	for _, tc := range []struct {
+		name   string
+		bundle string
+		status int
+	}{
+		{"org/app", "# v2 git bundle\n\n", http.StatusConflict},
+		{"org/restored", "hello world\n", http.StatusBadRequest},
+		{"org/restored", prerequisite, http.StatusBadRequest},
+		{"org/restored", "# v2 git bundle\n0123456789012345678901234567890123456789 refs/heads/main\n\nPACK", http.StatusBadRequest},
+		{"../restored", "# v2 git bundle\n\n", http.StatusBadRequest},
+	} {
+		resp, err := http.Post(s.url+"/apis/v1/repos?name="+tc.name, "application/x-git-bundle", strings.NewReader(tc.bundle))
+		if err != nil {
+			t.Fatalf("Failed to restore bundle: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != tc.status {
+			t.Errorf("POST bundle %q as %s: expected %d, got %d", tc.bundle, tc.name, tc.status, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package repository_manager_apis
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weedbox/git-modules/auth"
+	"github.com/weedbox/git-modules/repository_manager"
+	"go.uber.org/zap"
+)
+
+// handleListPermissions handles GET /apis/v1/repos/*name/permissions
+// @Summary List permissions
+// @Description List the roles granted on a repository or group. With effective=true the roles inherited from parent groups are included
+// @Tags Permissions
+// @Produce json
+// @Param name path string true "Repository or group name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param effective query bool false "Include roles inherited from parent groups" example:"true"
+// @Success 200 {array} repository_manager.Permission "List of permissions"
+// @Failure 404 {object} ErrorResponse "Repository or group not found"
+// @Failure 500 {object} ErrorResponse "Failed to list permissions"
+// @Router /apis/v1/repos/{name}/permissions [get]
+func (m *RepositoryManagerAPIs) handleListPermissions(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+
+	var permissions []repository_manager.Permission
+	var err error
+	if c.Query("effective") == "true" {
+		permissions, err = m.params.RepositoryManager.ListEffectivePermissions(name)
+	} else {
+		permissions, err = m.params.RepositoryManager.ListPermissions(name)
+	}
+	if err != nil {
+		m.logger.Error("Failed to list permissions", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, permissions)
+}
+
+// handleSetPermission handles PUT /apis/v1/repos/*name/permissions/{users|teams}/{subject}
+// @Summary Grant a role
+// @Description Grant a role to a user or team on a repository, or on a group and everything below it. The user "*" stands for everyone, including anonymous users
+// @Tags Permissions
+// @Accept json
+// @Produce json
+// @Param name path string true "Repository or group name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param kind path string true "Subject kind" enums(users,teams)
+// @Param subject path string true "User or team name" example:"john"
+// @Param body body SetPermissionRequest true "Role"
+// @Success 200 {object} repository_manager.Permission "Role granted"
+// @Failure 400 {object} ErrorResponse "Invalid request body or role"
+// @Failure 404 {object} ErrorResponse "Repository or group not found"
+// @Failure 500 {object} ErrorResponse "Failed to grant role"
+// @Router /apis/v1/repos/{name}/permissions/{kind}/{subject} [put]
+func (m *RepositoryManagerAPIs) handleSetPermission(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+
+	var req SetPermissionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	permission := permissionSubject(c)
+	permission.Role = req.Role
+
+	granted, err := m.params.RepositoryManager.SetPermission(name, permission)
+	if err != nil {
+		m.logger.Error("Failed to grant role", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, granted)
+}
+
+// handleDeletePermission handles DELETE /apis/v1/repos/*name/permissions/{users|teams}/{subject}
+// @Summary Revoke a role
+// @Description Revoke the role granted to a user or team on a repository or group. Roles inherited from parent groups are not affected
+// @Tags Permissions
+// @Produce json
+// @Param name path string true "Repository or group name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param kind path string true "Subject kind" enums(users,teams)
+// @Param subject path string true "User or team name" example:"john"
+// @Success 200 {object} MessageResponse "Role revoked"
+// @Failure 404 {object} ErrorResponse "Repository, group or permission not found"
+// @Failure 500 {object} ErrorResponse "Failed to revoke role"
+// @Router /apis/v1/repos/{name}/permissions/{kind}/{subject} [delete]
+func (m *RepositoryManagerAPIs) handleDeletePermission(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+
+	if err := m.params.RepositoryManager.DeletePermission(name, permissionSubject(c)); err != nil {
+		m.logger.Error("Failed to revoke role", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "Permission deleted successfully"})
+}
+
+// permissionSubject returns a permission naming the user or team addressed by the kind and subject path parameters
+func permissionSubject(c *gin.Context) repository_manager.Permission {
+	if c.Param("kind") == "teams" {
+		return repository_manager.Permission{Team: c.Param("subject")}
+	}
+	return repository_manager.Permission{User: c.Param("subject")}
+}
+
+// requireRole returns a middleware refusing requests whose identity does not hold a role on the
+// repository or group named by the name path parameter. Roles are only checked if enforce_roles is set,
+// otherwise only the scope of access tokens is. Anonymous requests are answered with 401
+// so clients can retry with credentials.
+func (m *RepositoryManagerAPIs) requireRole(role repository_manager.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := strings.TrimPrefix(c.Param("name"), "/")
+
+		var allowed bool
+		if m.enforceRoles {
+			allowed = m.authorize(c, name, role)
+		} else {
+			allowed = m.authorizeToken(c, name, role)
+		}
+		if !allowed {
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// requireServerAdmin returns a middleware refusing requests to server-wide endpoints, such as backups
+// and the audit log, unless their identity is a server admin: a user listed in admins or a member
+// of a team listed in admin_teams. Admins are only checked if enforce_roles is set, access tokens
+// limited to a repository or group are always refused.
+func (m *RepositoryManagerAPIs) requireServerAdmin(op auth.Operation) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !m.authorizeServerToken(c, op) {
+			c.Abort()
+			return
+		}
+
+		if !m.enforceRoles {
+			c.Next()
+			return
+		}
+
+		identity := auth.IdentityFromContext(c.Request.Context())
+		switch {
+		case identity == nil:
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{Error: auth.ErrUnauthenticated.Error()})
+			return
+		case !m.isServerAdmin(identity):
+			c.AbortWithStatusJSON(http.StatusForbidden, ErrorResponse{Error: "server admin access required"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// isServerAdmin reports whether an identity is listed in admins or belongs to a team listed in admin_teams
+func (m *RepositoryManagerAPIs) isServerAdmin(identity *auth.Identity) bool {
+	if slices.Contains(m.admins, identity.Name) {
+		return true
+	}
+
+	for _, team := range identity.Teams {
+		if slices.Contains(m.adminTeams, team) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// authorizeCreate checks that the identity of a request may create a repository or group.
+// Creating inside a group requires the maintain role on the nearest existing parent group.
+// Top-level repositories and groups are not covered by roles, the CreateRepository middlewares decide on them.
+func (m *RepositoryManagerAPIs) authorizeCreate(c *gin.Context, name string) bool {
+	if !m.enforceRoles {
+		return m.authorizeToken(c, name, repository_manager.RoleMaintain)
+	}
+
+	for parent := name; strings.Contains(parent, "/"); {
+		parent = parent[:strings.LastIndex(parent, "/")]
+		if m.params.RepositoryManager.IsGroup(parent) {
+			return m.authorize(c, parent, repository_manager.RoleMaintain)
+		}
+	}
+
+	return true
+}
+
+// authorize answers the request and returns false unless its identity holds a role on a repository or group
+func (m *RepositoryManagerAPIs) authorize(c *gin.Context, name string, role repository_manager.Role) bool {
+	identity := auth.IdentityFromContext(c.Request.Context())
+	err := m.params.RepositoryManager.Authorize(name, identity, role)
+	if err == nil {
+		return true
+	}
+
+	var forbidden *repository_manager.ForbiddenError
+	switch {
+	case errors.As(err, &forbidden) && identity == nil:
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: auth.ErrUnauthenticated.Error()})
+	case errors.As(err, &forbidden):
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: err.Error()})
+	default:
+		m.logger.Error("Failed to authorize request", zap.String("name", name), zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+	}
+
+	return false
+}
+
+// readableRepositories filters a repository list down to the repositories the identity of a request may read
+func (m *RepositoryManagerAPIs) readableRepositories(c *gin.Context, repos []repository_manager.Repository) []repository_manager.Repository {
+	if !m.enforceRoles {
+		return repos
+	}
+
+	identity := auth.IdentityFromContext(c.Request.Context())
+	readable := make([]repository_manager.Repository, 0, len(repos))
+	for _, repo := range repos {
+		if m.params.RepositoryManager.Authorize(repo.Name, identity, repository_manager.RoleRead) == nil {
+			readable = append(readable, repo)
+		}
+	}
+
+	return readable
+}
@@ -0,0 +1,274 @@
+package repository_manager_apis
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weedbox/git-modules/repository_manager"
+	"go.uber.org/zap"
+)
+
+// handleListBranchProtectionRules handles GET /apis/v1/repos/*name/protected_branches
+// @Summary List branch protection rules
+// @Description List the branch protection rules of a repository or group. With effective=true the rules inherited by a repository from its groups are included
+// @Tags Protection
+// @Produce json
+// @Param name path string true "Repository or group name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param effective query bool false "Include rules inherited from groups (repositories only)" example:"true"
+// @Success 200 {array} repository_manager.BranchProtectionRule "List of rules"
+// @Failure 404 {object} ErrorResponse "Repository or group not found"
+// @Failure 500 {object} ErrorResponse "Failed to list rules"
+// @Router /apis/v1/repos/{name}/protected_branches [get]
+func (m *RepositoryManagerAPIs) handleListBranchProtectionRules(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+
+	var rules []repository_manager.BranchProtectionRule
+	var err error
+	if c.Query("effective") == "true" {
+		rules, err = m.params.RepositoryManager.ListEffectiveBranchProtectionRules(name)
+	} else {
+		rules, err = m.params.RepositoryManager.ListBranchProtectionRules(name)
+	}
+	if err != nil {
+		m.logger.Error("Failed to list branch protection rules", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// handleCreateBranchProtectionRule handles POST /apis/v1/repos/*name/protected_branches
+// @Summary Create a branch protection rule
+// @Description Protect the branches matching a glob pattern of a repository, or of every repository in a group. Commit rules check the message, sign-off, email domains and signature of every pushed commit, signatures are verified against the GPG and SSH keys of users
+// @Tags Protection
+// @Accept json
+// @Produce json
+// @Param name path string true "Repository or group name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param body body repository_manager.BranchProtectionRule true "Branch protection rule"
+// @Success 201 {object} repository_manager.BranchProtectionRule "Rule created"
+// @Failure 400 {object} ErrorResponse "Invalid request body, pattern or commit rule"
+// @Failure 409 {object} ErrorResponse "A rule with this pattern already exists"
+// @Failure 500 {object} ErrorResponse "Failed to create rule"
+// @Router /apis/v1/repos/{name}/protected_branches [post]
+func (m *RepositoryManagerAPIs) handleCreateBranchProtectionRule(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+
+	var req repository_manager.BranchProtectionRule
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	rule, err := m.params.RepositoryManager.CreateBranchProtectionRule(name, req)
+	if err != nil {
+		m.logger.Error("Failed to create branch protection rule", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// handleGetBranchProtectionRule handles GET /apis/v1/repos/*name/protected_branches/*pattern
+// @Summary Get a branch protection rule
+// @Description Get the branch protection rule of a repository or group with the given pattern
+// @Tags Protection
+// @Produce json
+// @Param name path string true "Repository or group name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param pattern path string true "Branch pattern" example:"release/*"
+// @Success 200 {object} repository_manager.BranchProtectionRule "Rule"
+// @Failure 404 {object} ErrorResponse "Rule not found"
+// @Router /apis/v1/repos/{name}/protected_branches/{pattern} [get]
+func (m *RepositoryManagerAPIs) handleGetBranchProtectionRule(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+	pattern := strings.TrimPrefix(c.Param("pattern"), "/")
+
+	rule, err := m.params.RepositoryManager.GetBranchProtectionRule(name, pattern)
+	if err != nil {
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// handleUpdateBranchProtectionRule handles PUT /apis/v1/repos/*name/protected_branches/*pattern
+// @Summary Update a branch protection rule
+// @Description Replace the settings of the branch protection rule with the given pattern
+// @Tags Protection
+// @Accept json
+// @Produce json
+// @Param name path string true "Repository or group name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param pattern path string true "Branch pattern" example:"release/*"
+// @Param body body UpdateBranchProtectionRuleRequest true "Branch protection rule settings"
+// @Success 200 {object} repository_manager.BranchProtectionRule "Rule updated"
+// @Failure 400 {object} ErrorResponse "Invalid request body or commit rule"
+// @Failure 404 {object} ErrorResponse "Rule not found"
+// @Failure 500 {object} ErrorResponse "Failed to update rule"
+// @Router /apis/v1/repos/{name}/protected_branches/{pattern} [put]
+func (m *RepositoryManagerAPIs) handleUpdateBranchProtectionRule(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+	pattern := strings.TrimPrefix(c.Param("pattern"), "/")
+
+	var req UpdateBranchProtectionRuleRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	rule, err := m.params.RepositoryManager.UpdateBranchProtectionRule(name, pattern, repository_manager.BranchProtectionRule{
+		DenyForcePush:        req.DenyForcePush,
+		DenyDeletion:         req.DenyDeletion,
+		RequireLinearHistory: req.RequireLinearHistory,
+		CommitMessagePattern: req.CommitMessagePattern,
+		RequireSignoff:       req.RequireSignoff,
+		AllowedEmailDomains:  req.AllowedEmailDomains,
+		RequireSignedCommits: req.RequireSignedCommits,
+		AllowedPushers:       req.AllowedPushers,
+	})
+	if err != nil {
+		m.logger.Error("Failed to update branch protection rule", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// handleDeleteBranchProtectionRule handles DELETE /apis/v1/repos/*name/protected_branches/*pattern
+// @Summary Delete a branch protection rule
+// @Description Remove the branch protection rule with the given pattern from a repository or group
+// @Tags Protection
+// @Produce json
+// @Param name path string true "Repository or group name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param pattern path string true "Branch pattern" example:"release/*"
+// @Success 200 {object} MessageResponse "Rule deleted"
+// @Failure 404 {object} ErrorResponse "Rule not found"
+// @Failure 500 {object} ErrorResponse "Failed to delete rule"
+// @Router /apis/v1/repos/{name}/protected_branches/{pattern} [delete]
+func (m *RepositoryManagerAPIs) handleDeleteBranchProtectionRule(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+	pattern := strings.TrimPrefix(c.Param("pattern"), "/")
+
+	if err := m.params.RepositoryManager.DeleteBranchProtectionRule(name, pattern); err != nil {
+		m.logger.Error("Failed to delete branch protection rule", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "Branch protection rule deleted successfully"})
+}
+
+// handleListTagProtectionRules handles GET /apis/v1/repos/*name/protected_tags
+// @Summary List tag protection rules
+// @Description List the tag protection rules of a repository or group. With effective=true the rules inherited by a repository from its groups are included
+// @Tags Protection
+// @Produce json
+// @Param name path string true "Repository or group name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param effective query bool false "Include rules inherited from groups (repositories only)" example:"true"
+// @Success 200 {array} repository_manager.TagProtectionRule "List of rules"
+// @Failure 404 {object} ErrorResponse "Repository or group not found"
+// @Failure 500 {object} ErrorResponse "Failed to list rules"
+// @Router /apis/v1/repos/{name}/protected_tags [get]
+func (m *RepositoryManagerAPIs) handleListTagProtectionRules(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+
+	var rules []repository_manager.TagProtectionRule
+	var err error
+	if c.Query("effective") == "true" {
+		rules, err = m.params.RepositoryManager.ListEffectiveTagProtectionRules(name)
+	} else {
+		rules, err = m.params.RepositoryManager.ListTagProtectionRules(name)
+	}
+	if err != nil {
+		m.logger.Error("Failed to list tag protection rules", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// handleCreateTagProtectionRule handles POST /apis/v1/repos/*name/protected_tags
+// @Summary Create a tag protection rule
+// @Description Make the tags matching a glob pattern immutable in a repository, or in every repository of a group. Protected tags can be created but not moved or deleted
+// @Tags Protection
+// @Accept json
+// @Produce json
+// @Param name path string true "Repository or group name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param body body repository_manager.TagProtectionRule true "Tag protection rule"
+// @Success 201 {object} repository_manager.TagProtectionRule "Rule created"
+// @Failure 400 {object} ErrorResponse "Invalid request body or pattern"
+// @Failure 409 {object} ErrorResponse "A rule with this pattern already exists"
+// @Failure 500 {object} ErrorResponse "Failed to create rule"
+// @Router /apis/v1/repos/{name}/protected_tags [post]
+func (m *RepositoryManagerAPIs) handleCreateTagProtectionRule(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+
+	var req repository_manager.TagProtectionRule
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	rule, err := m.params.RepositoryManager.CreateTagProtectionRule(name, req)
+	if err != nil {
+		m.logger.Error("Failed to create tag protection rule", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// handleGetTagProtectionRule handles GET /apis/v1/repos/*name/protected_tags/*pattern
+// @Summary Get a tag protection rule
+// @Description Get the tag protection rule of a repository or group with the given pattern
+// @Tags Protection
+// @Produce json
+// @Param name path string true "Repository or group name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param pattern path string true "Tag pattern" example:"v*"
+// @Success 200 {object} repository_manager.TagProtectionRule "Rule"
+// @Failure 404 {object} ErrorResponse "Rule not found"
+// @Router /apis/v1/repos/{name}/protected_tags/{pattern} [get]
+func (m *RepositoryManagerAPIs) handleGetTagProtectionRule(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+	pattern := strings.TrimPrefix(c.Param("pattern"), "/")
+
+	rule, err := m.params.RepositoryManager.GetTagProtectionRule(name, pattern)
+	if err != nil {
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// handleDeleteTagProtectionRule handles DELETE /apis/v1/repos/*name/protected_tags/*pattern
+// @Summary Delete a tag protection rule
+// @Description Remove the tag protection rule with the given pattern from a repository or group
+// @Tags Protection
+// @Produce json
+// @Param name path string true "Repository or group name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param pattern path string true "Tag pattern" example:"v*"
+// @Success 200 {object} MessageResponse "Rule deleted"
+// @Failure 404 {object} ErrorResponse "Rule not found"
+// @Failure 500 {object} ErrorResponse "Failed to delete rule"
+// @Router /apis/v1/repos/{name}/protected_tags/{pattern} [delete]
+func (m *RepositoryManagerAPIs) handleDeleteTagProtectionRule(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+	pattern := strings.TrimPrefix(c.Param("pattern"), "/")
+
+	if err := m.params.RepositoryManager.DeleteTagProtectionRule(name, pattern); err != nil {
+		m.logger.Error("Failed to delete tag protection rule", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "Tag protection rule deleted successfully"})
+}
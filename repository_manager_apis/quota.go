@@ -0,0 +1,66 @@
+package repository_manager_apis
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weedbox/git-modules/repository_manager"
+	"go.uber.org/zap"
+)
+
+// handleGetQuota handles GET /apis/v1/repos/*name/quota
+// @Summary Get the quota
+// @Description Get the quota configured on a repository or group. A max_size of 0 means unlimited
+// @Tags Quotas
+// @Produce json
+// @Param name path string true "Repository or group name (supports multi-level paths)" example:"myorg/myrepo"
+// @Success 200 {object} repository_manager.Quota "Quota"
+// @Failure 404 {object} ErrorResponse "Repository or group not found"
+// @Failure 500 {object} ErrorResponse "Failed to get quota"
+// @Router /apis/v1/repos/{name}/quota [get]
+func (m *RepositoryManagerAPIs) handleGetQuota(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+
+	quota, err := m.params.RepositoryManager.GetQuota(name)
+	if err != nil {
+		m.logger.Error("Failed to get quota", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, quota)
+}
+
+// handleUpdateQuota handles PUT /apis/v1/repos/*name/quota
+// @Summary Update the quota
+// @Description Set the maximum disk space of a repository, or of all repositories of a group and its subgroups, in bytes. Pushes that would exceed the quota of the repository or of one of its groups are rejected. A max_size of 0 removes the quota
+// @Tags Quotas
+// @Accept json
+// @Produce json
+// @Param name path string true "Repository or group name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param body body repository_manager.Quota true "Quota"
+// @Success 200 {object} repository_manager.Quota "Quota updated"
+// @Failure 400 {object} ErrorResponse "Invalid request body or quota"
+// @Failure 404 {object} ErrorResponse "Repository or group not found"
+// @Failure 500 {object} ErrorResponse "Failed to update quota"
+// @Router /apis/v1/repos/{name}/quota [put]
+func (m *RepositoryManagerAPIs) handleUpdateQuota(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+
+	var req repository_manager.Quota
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	quota, err := m.params.RepositoryManager.SetQuota(name, req)
+	if err != nil {
+		m.logger.Error("Failed to update quota", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, quota)
+}
@@ -5,12 +5,13 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/weedbox/git-modules/audit"
 	"go.uber.org/zap"
 )
 
 // handleCreateRepository handles POST /apis/v1/repos
 // @Summary Create a repository or group
-// @Description Create a new Git repository or group/namespace
+// @Description Create a new Git repository or group/namespace. Repositories can be initialized with a README, .gitignore and license, or from a template repository, using the init field
 // @Tags Repositories
 // @Accept json
 // @Produce json
@@ -21,13 +22,30 @@ import (
 // @Failure 500 {object} ErrorResponse "Failed to create repository or group"
 // @Router /apis/v1/repos [post]
 func (m *RepositoryManagerAPIs) handleCreateRepository(c *gin.Context) {
+	// Restore repository from an uploaded bundle
+	if c.ContentType() == bundleContentType {
+		m.handleCreateRepositoryFromBundle(c)
+		return
+	}
+
 	var req CreateRepositoryRequest
+	defer func() {
+		action := audit.ActionRepositoryCreate
+		if req.Type == "group" {
+			action = audit.ActionGroupCreate
+		}
+		m.recordAudit(c, action, req.Name, "")
+	}()
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
 
+	if !m.authorizeCreate(c, req.Name) {
+		return
+	}
+
 	// Default to repository if type is not specified
 	if req.Type == "" {
 		req.Type = "repository"
@@ -45,6 +63,18 @@ func (m *RepositoryManagerAPIs) handleCreateRepository(c *gin.Context) {
 		return
 	}
 
+	// Create repository with initial content if requested
+	if req.Init != nil {
+		repo, err := m.params.RepositoryManager.CreateRepositoryWithOptions(req.Name, req.Description, *req.Init)
+		if err != nil {
+			m.logger.Error("Failed to create repository", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, repo)
+		return
+	}
+
 	// Create repository (default behavior)
 	repo, err := m.params.RepositoryManager.CreateRepository(req.Name, req.Description)
 	if err != nil {
@@ -58,7 +88,7 @@ func (m *RepositoryManagerAPIs) handleCreateRepository(c *gin.Context) {
 
 // handleListRepositories handles GET /apis/v1/repos
 // @Summary List all repositories
-// @Description Get a list of all Git repositories
+// @Description Get a list of all Git repositories. When roles are enforced only the repositories readable by the caller are listed
 // @Tags Repositories
 // @Produce json
 // @Success 200 {array} repository_manager.Repository "List of repositories"
@@ -72,17 +102,18 @@ func (m *RepositoryManagerAPIs) handleListRepositories(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, repos)
+	c.JSON(http.StatusOK, m.readableRepositories(c, repos))
 }
 
 // handleGetRepository handles GET /apis/v1/repos/*name
 // @Summary Get repository information
-// @Description Get detailed information about a specific repository. Supports multi-level paths like "username/repo" or "org/team/project"
+// @Description Get detailed information about a specific repository, including its disk usage and quota. Supports multi-level paths like "username/repo" or "org/team/project"
 // @Tags Repositories
 // @Produce json
 // @Param name path string true "Repository name (supports multi-level paths)" example:"myorg/myrepo"
 // @Success 200 {object} repository_manager.Repository "Repository information"
 // @Failure 404 {object} ErrorResponse "Repository not found"
+// @Failure 500 {object} ErrorResponse "Failed to compute repository usage"
 // @Router /apis/v1/repos/{name} [get]
 func (m *RepositoryManagerAPIs) handleGetRepository(c *gin.Context) {
 	// Extract repository name from path parameter
@@ -95,6 +126,14 @@ func (m *RepositoryManagerAPIs) handleGetRepository(c *gin.Context) {
 		return
 	}
 
+	usage, err := m.params.RepositoryManager.GetUsage(name)
+	if err != nil {
+		m.logger.Error("Failed to compute repository usage", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	repo.Usage = usage
+
 	c.JSON(http.StatusOK, repo)
 }
 
@@ -111,6 +150,7 @@ func (m *RepositoryManagerAPIs) handleDeleteRepository(c *gin.Context) {
 	// Extract repository name from path parameter
 	// c.Param("name") returns path with leading slash, e.g., "/username/repo"
 	name := strings.TrimPrefix(c.Param("name"), "/")
+	defer m.recordAudit(c, audit.ActionRepositoryDelete, name, "")
 
 	if err := m.params.RepositoryManager.DeleteRepository(name); err != nil {
 		m.logger.Error("Failed to delete repository", zap.Error(err))
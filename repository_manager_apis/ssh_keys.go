@@ -0,0 +1,178 @@
+package repository_manager_apis
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weedbox/git-modules/auth"
+	"github.com/weedbox/git-modules/ssh_keys"
+	"go.uber.org/zap"
+)
+
+// handleListUserKeys handles GET /apis/v1/user/keys
+// @Summary List SSH keys
+// @Description List the SSH public keys of the authenticated user
+// @Tags SSH Keys
+// @Produce json
+// @Success 200 {array} ssh_keys.Key "List of keys"
+// @Failure 401 {object} ErrorResponse "Authentication required"
+// @Router /apis/v1/user/keys [get]
+func (m *RepositoryManagerAPIs) handleListUserKeys(c *gin.Context) {
+	identity, ok := m.credentialsOwner(c, "SSH keys")
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, m.params.Keys.ListUserKeys(identity.Name))
+}
+
+// handleCreateUserKey handles POST /apis/v1/user/keys
+// @Summary Add an SSH key
+// @Description Register an SSH public key for the authenticated user. A key can only be registered once across all users and repositories
+// @Tags SSH Keys
+// @Accept json
+// @Produce json
+// @Param body body ssh_keys.Options true "Key options"
+// @Success 201 {object} ssh_keys.Key "Key added"
+// @Failure 400 {object} ErrorResponse "Invalid request body, key or title"
+// @Failure 401 {object} ErrorResponse "Authentication required"
+// @Failure 403 {object} ErrorResponse "Tokens cannot manage SSH keys"
+// @Failure 409 {object} ErrorResponse "Key already in use"
+// @Failure 500 {object} ErrorResponse "Failed to add key"
+// @Router /apis/v1/user/keys [post]
+func (m *RepositoryManagerAPIs) handleCreateUserKey(c *gin.Context) {
+	identity, ok := m.credentialsOwner(c, "SSH keys")
+	if !ok {
+		return
+	}
+
+	var req ssh_keys.Options
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	key, err := m.params.Keys.AddUserKey(identity.Name, req)
+	if err != nil {
+		m.logger.Error("Failed to add SSH key", zap.Error(err))
+		c.JSON(statusCodeForKeyError(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// handleDeleteUserKey handles DELETE /apis/v1/user/keys/{id}
+// @Summary Remove an SSH key
+// @Description Remove an SSH public key of the authenticated user
+// @Tags SSH Keys
+// @Produce json
+// @Param id path string true "Key ID" example:"9f86d081884c7d65"
+// @Success 200 {object} MessageResponse "Key removed"
+// @Failure 401 {object} ErrorResponse "Authentication required"
+// @Failure 404 {object} ErrorResponse "Key not found"
+// @Router /apis/v1/user/keys/{id} [delete]
+func (m *RepositoryManagerAPIs) handleDeleteUserKey(c *gin.Context) {
+	identity, ok := m.credentialsOwner(c, "SSH keys")
+	if !ok {
+		return
+	}
+
+	if err := m.params.Keys.DeleteUserKey(identity.Name, c.Param("id")); err != nil {
+		c.JSON(statusCodeForKeyError(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "SSH key removed successfully"})
+}
+
+// handleListDeployKeys handles GET /apis/v1/repos/*name/deploy_keys
+// @Summary List deploy keys
+// @Description List the deploy keys of a repository
+// @Tags SSH Keys
+// @Produce json
+// @Param name path string true "Repository name (supports multi-level paths)" example:"myorg/myrepo"
+// @Success 200 {array} ssh_keys.Key "List of keys"
+// @Failure 404 {object} ErrorResponse "Repository not found"
+// @Router /apis/v1/repos/{name}/deploy_keys [get]
+func (m *RepositoryManagerAPIs) handleListDeployKeys(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+
+	c.JSON(http.StatusOK, m.params.Keys.ListDeployKeys(name))
+}
+
+// handleCreateDeployKey handles POST /apis/v1/repos/*name/deploy_keys
+// @Summary Add a deploy key
+// @Description Register an SSH public key granting access to a single repository. Deploy keys are read-only unless write is set
+// @Tags SSH Keys
+// @Accept json
+// @Produce json
+// @Param name path string true "Repository name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param body body ssh_keys.Options true "Key options"
+// @Success 201 {object} ssh_keys.Key "Key added"
+// @Failure 400 {object} ErrorResponse "Invalid request body, key or title"
+// @Failure 403 {object} ErrorResponse "Deploy credentials cannot add keys"
+// @Failure 404 {object} ErrorResponse "Repository not found"
+// @Failure 409 {object} ErrorResponse "Key already in use"
+// @Failure 500 {object} ErrorResponse "Failed to add key"
+// @Router /apis/v1/repos/{name}/deploy_keys [post]
+func (m *RepositoryManagerAPIs) handleCreateDeployKey(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+	if m.refuseDeployCredentials(c, "deploy keys") {
+		return
+	}
+
+	var req ssh_keys.Options
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	key, err := m.params.Keys.AddDeployKey(name, req)
+	if err != nil {
+		m.logger.Error("Failed to add deploy key", zap.Error(err))
+		c.JSON(statusCodeForKeyError(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// handleDeleteDeployKey handles DELETE /apis/v1/repos/*name/deploy_keys/{id}
+// @Summary Remove a deploy key
+// @Description Remove a deploy key of a repository
+// @Tags SSH Keys
+// @Produce json
+// @Param name path string true "Repository name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param id path string true "Key ID" example:"9f86d081884c7d65"
+// @Success 200 {object} MessageResponse "Key removed"
+// @Failure 404 {object} ErrorResponse "Repository or key not found"
+// @Router /apis/v1/repos/{name}/deploy_keys/{id} [delete]
+func (m *RepositoryManagerAPIs) handleDeleteDeployKey(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+
+	if err := m.params.Keys.DeleteDeployKey(name, c.Param("id")); err != nil {
+		c.JSON(statusCodeForKeyError(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "SSH key removed successfully"})
+}
+
+// statusCodeForKeyError maps SSH key store errors to HTTP status codes
+func statusCodeForKeyError(err error) int {
+	switch {
+	case errors.Is(err, ssh_keys.ErrKeyNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ssh_keys.ErrKeyDuplicate):
+		return http.StatusConflict
+	case errors.Is(err, ssh_keys.ErrKeyInvalid), errors.Is(err, ssh_keys.ErrKeyWeak), errors.Is(err, ssh_keys.ErrTitleEmpty):
+		return http.StatusBadRequest
+	case errors.Is(err, auth.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/weedbox/git-modules/audit"
 	"go.uber.org/zap"
 )
 
@@ -18,6 +19,7 @@ import (
 // @Param body body CreateTagRequest true "Tag creation request"
 // @Success 201 {object} repository_manager.Tag "Tag created successfully"
 // @Failure 400 {object} ErrorResponse "Invalid request body"
+// @Failure 403 {object} ErrorResponse "Tag is protected and already exists"
 // @Failure 500 {object} ErrorResponse "Failed to create tag"
 // @Router /apis/v1/repos/{name}/tags [post]
 func (m *RepositoryManagerAPIs) handleCreateTag(c *gin.Context) {
@@ -26,6 +28,7 @@ func (m *RepositoryManagerAPIs) handleCreateTag(c *gin.Context) {
 	repoName := strings.TrimPrefix(c.Param("name"), "/")
 
 	var req CreateTagRequest
+	defer func() { m.recordAudit(c, audit.ActionTagCreate, repoName, req.TagName) }()
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
@@ -35,7 +38,7 @@ func (m *RepositoryManagerAPIs) handleCreateTag(c *gin.Context) {
 	tag, err := m.params.RepositoryManager.CreateTag(repoName, req.TagName, req.CommitHash, req.Message, req.Tagger)
 	if err != nil {
 		m.logger.Error("Failed to create tag", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
 		return
 	}
 
@@ -102,6 +105,7 @@ func (m *RepositoryManagerAPIs) handleGetTag(c *gin.Context) {
 // @Param name path string true "Repository name (supports multi-level paths)" example:"myorg/myrepo"
 // @Param tag path string true "Tag name (supports multi-level paths)" example:"release/v1.0.0"
 // @Success 200 {object} MessageResponse "Tag deleted successfully"
+// @Failure 403 {object} ErrorResponse "Tag is protected"
 // @Failure 500 {object} ErrorResponse "Failed to delete tag"
 // @Router /apis/v1/repos/{name}/tags/{tag} [delete]
 func (m *RepositoryManagerAPIs) handleDeleteTag(c *gin.Context) {
@@ -112,10 +116,11 @@ func (m *RepositoryManagerAPIs) handleDeleteTag(c *gin.Context) {
 	// Extract tag name from path parameter
 	// c.Param("tag") returns path with leading slash, e.g., "/v1.0.0"
 	tagName := strings.TrimPrefix(c.Param("tag"), "/")
+	defer m.recordAudit(c, audit.ActionTagDelete, repoName, tagName)
 
 	if err := m.params.RepositoryManager.DeleteTag(repoName, tagName); err != nil {
 		m.logger.Error("Failed to delete tag", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
 		return
 	}
 
@@ -0,0 +1,280 @@
+package repository_manager_apis
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weedbox/git-modules/auth"
+	"github.com/weedbox/git-modules/repository_manager"
+	"github.com/weedbox/git-modules/tokens"
+	"go.uber.org/zap"
+)
+
+// authenticateToken returns a middleware authenticating requests that carry an access token
+// as bearer token. Requests without token are passed on unchanged for other middlewares to authenticate.
+func (m *RepositoryManagerAPIs) authenticateToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		creds := auth.CredentialsFromRequest(c.Request)
+		if creds.Token == "" || !tokens.IsToken(creds.Token) {
+			c.Next()
+			return
+		}
+
+		token, err := m.params.Tokens.Verify(creds.Token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{Error: err.Error()})
+			return
+		}
+
+		identity, err := m.params.Tokens.ResolveIdentity(c.Request.Context(), token)
+		if err != nil {
+			m.logger.Error("Failed to resolve token identity", zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+			return
+		}
+
+		c.Request = c.Request.WithContext(auth.WithIdentity(c.Request.Context(), identity))
+		c.Next()
+	}
+}
+
+// authorizeToken answers the request and returns false if it was authenticated with an access token
+// that does not cover a repository or group, or that is read-only while the operation requires more than read access.
+// Deploy credentials never hold more than the write role, as with enforced roles.
+// It applies when roles are not enforced, enforced roles already account for the token scope.
+func (m *RepositoryManagerAPIs) authorizeToken(c *gin.Context, name string, role repository_manager.Role) bool {
+	identity := auth.IdentityFromContext(c.Request.Context())
+	if identity == nil || identity.Token == nil {
+		return true
+	}
+
+	op := auth.OperationRead
+	if role != repository_manager.RoleRead {
+		op = auth.OperationWrite
+	}
+
+	if !identity.Token.Covers(name) || !identity.Token.Allows(op) {
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: "access token does not grant " + string(op) + " access to " + name})
+		return false
+	}
+
+	if identity.Token.Deploy && !repository_manager.RoleWrite.Includes(role) {
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: "deploy credentials do not grant the " + string(role) + " role on " + name})
+		return false
+	}
+
+	return true
+}
+
+// authorizeServerToken answers the request and returns false if it was authenticated with an access token
+// that cannot be used on server-wide endpoints: deploy credentials and tokens limited to a group,
+// and read-only tokens for operations that require write access.
+func (m *RepositoryManagerAPIs) authorizeServerToken(c *gin.Context, op auth.Operation) bool {
+	identity := auth.IdentityFromContext(c.Request.Context())
+	if identity == nil || identity.Token == nil {
+		return true
+	}
+
+	switch {
+	case identity.Token.Deploy || identity.Token.Prefix != "":
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: "access tokens limited to a repository or group cannot access server-wide endpoints"})
+		return false
+	case !identity.Token.Allows(op):
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: "access token does not grant " + string(op) + " access"})
+		return false
+	}
+
+	return true
+}
+
+// refuseDeployCredentials answers the request and returns true if it was authenticated with deploy
+// credentials, which cannot create other credentials
+func (m *RepositoryManagerAPIs) refuseDeployCredentials(c *gin.Context, credentials string) bool {
+	identity := auth.IdentityFromContext(c.Request.Context())
+	if identity == nil || identity.Token == nil || !identity.Token.Deploy {
+		return false
+	}
+
+	c.JSON(http.StatusForbidden, ErrorResponse{Error: credentials + " cannot be created with deploy credentials"})
+	return true
+}
+
+// handleListPersonalTokens handles GET /apis/v1/user/tokens
+// @Summary List personal access tokens
+// @Description List the personal access tokens of the authenticated user. Secrets are never returned
+// @Tags Tokens
+// @Produce json
+// @Success 200 {array} tokens.Token "List of tokens"
+// @Failure 401 {object} ErrorResponse "Authentication required"
+// @Router /apis/v1/user/tokens [get]
+func (m *RepositoryManagerAPIs) handleListPersonalTokens(c *gin.Context) {
+	identity, ok := m.credentialsOwner(c, "personal access tokens")
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, m.params.Tokens.ListPersonalTokens(identity.Name))
+}
+
+// handleCreatePersonalToken handles POST /apis/v1/user/tokens
+// @Summary Create a personal access token
+// @Description Create a token acting on behalf of the authenticated user, limited to read or write access and optionally to a group. The secret is only returned in this response
+// @Tags Tokens
+// @Accept json
+// @Produce json
+// @Param body body tokens.Options true "Token options"
+// @Success 201 {object} tokens.CreatedToken "Token created"
+// @Failure 400 {object} ErrorResponse "Invalid request body, scope or expiry"
+// @Failure 401 {object} ErrorResponse "Authentication required"
+// @Failure 403 {object} ErrorResponse "Tokens cannot create tokens"
+// @Failure 500 {object} ErrorResponse "Failed to create token"
+// @Router /apis/v1/user/tokens [post]
+func (m *RepositoryManagerAPIs) handleCreatePersonalToken(c *gin.Context) {
+	identity, ok := m.credentialsOwner(c, "personal access tokens")
+	if !ok {
+		return
+	}
+
+	var req tokens.Options
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	token, err := m.params.Tokens.CreatePersonalToken(identity.Name, req)
+	if err != nil {
+		m.logger.Error("Failed to create personal access token", zap.Error(err))
+		c.JSON(statusCodeForTokenError(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, token)
+}
+
+// handleDeletePersonalToken handles DELETE /apis/v1/user/tokens/{id}
+// @Summary Revoke a personal access token
+// @Description Revoke a personal access token of the authenticated user
+// @Tags Tokens
+// @Produce json
+// @Param id path string true "Token ID" example:"9f86d081884c7d65"
+// @Success 200 {object} MessageResponse "Token revoked"
+// @Failure 401 {object} ErrorResponse "Authentication required"
+// @Failure 404 {object} ErrorResponse "Token not found"
+// @Router /apis/v1/user/tokens/{id} [delete]
+func (m *RepositoryManagerAPIs) handleDeletePersonalToken(c *gin.Context) {
+	identity, ok := m.credentialsOwner(c, "personal access tokens")
+	if !ok {
+		return
+	}
+
+	if err := m.params.Tokens.RevokePersonalToken(identity.Name, c.Param("id")); err != nil {
+		c.JSON(statusCodeForTokenError(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "Token revoked successfully"})
+}
+
+// handleListDeployTokens handles GET /apis/v1/repos/*name/deploy_tokens
+// @Summary List deploy tokens
+// @Description List the deploy tokens of a repository. Secrets are never returned
+// @Tags Tokens
+// @Produce json
+// @Param name path string true "Repository name (supports multi-level paths)" example:"myorg/myrepo"
+// @Success 200 {array} tokens.Token "List of tokens"
+// @Failure 404 {object} ErrorResponse "Repository not found"
+// @Router /apis/v1/repos/{name}/deploy_tokens [get]
+func (m *RepositoryManagerAPIs) handleListDeployTokens(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+
+	c.JSON(http.StatusOK, m.params.Tokens.ListDeployTokens(name))
+}
+
+// handleCreateDeployToken handles POST /apis/v1/repos/*name/deploy_tokens
+// @Summary Create a deploy token
+// @Description Create a token granting read or write access to a single repository, e.g. for CI. The secret is only returned in this response
+// @Tags Tokens
+// @Accept json
+// @Produce json
+// @Param name path string true "Repository name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param body body tokens.Options true "Token options"
+// @Success 201 {object} tokens.CreatedToken "Token created"
+// @Failure 400 {object} ErrorResponse "Invalid request body, scope or expiry"
+// @Failure 403 {object} ErrorResponse "Deploy credentials cannot create tokens"
+// @Failure 404 {object} ErrorResponse "Repository not found"
+// @Failure 500 {object} ErrorResponse "Failed to create token"
+// @Router /apis/v1/repos/{name}/deploy_tokens [post]
+func (m *RepositoryManagerAPIs) handleCreateDeployToken(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+	if m.refuseDeployCredentials(c, "deploy tokens") {
+		return
+	}
+
+	var req tokens.Options
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	token, err := m.params.Tokens.CreateDeployToken(name, req)
+	if err != nil {
+		m.logger.Error("Failed to create deploy token", zap.Error(err))
+		c.JSON(statusCodeForTokenError(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, token)
+}
+
+// handleDeleteDeployToken handles DELETE /apis/v1/repos/*name/deploy_tokens/{id}
+// @Summary Revoke a deploy token
+// @Description Revoke a deploy token of a repository
+// @Tags Tokens
+// @Produce json
+// @Param name path string true "Repository name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param id path string true "Token ID" example:"9f86d081884c7d65"
+// @Success 200 {object} MessageResponse "Token revoked"
+// @Failure 404 {object} ErrorResponse "Repository or token not found"
+// @Router /apis/v1/repos/{name}/deploy_tokens/{id} [delete]
+func (m *RepositoryManagerAPIs) handleDeleteDeployToken(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+
+	if err := m.params.Tokens.RevokeDeployToken(name, c.Param("id")); err != nil {
+		c.JSON(statusCodeForTokenError(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "Token revoked successfully"})
+}
+
+// credentialsOwner returns the user managing their personal access tokens or SSH keys,
+// answering the request if it is anonymous or itself authenticated with a token
+func (m *RepositoryManagerAPIs) credentialsOwner(c *gin.Context, credentials string) (*auth.Identity, bool) {
+	identity := auth.IdentityFromContext(c.Request.Context())
+	switch {
+	case identity == nil || identity.Name == "":
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: auth.ErrUnauthenticated.Error()})
+		return nil, false
+	case identity.Token != nil:
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: credentials + " cannot be managed with a token"})
+		return nil, false
+	}
+
+	return identity, true
+}
+
+// statusCodeForTokenError maps token store errors to HTTP status codes
+func statusCodeForTokenError(err error) int {
+	switch {
+	case errors.Is(err, tokens.ErrTokenNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, tokens.ErrNameEmpty), errors.Is(err, tokens.ErrScopeInvalid), errors.Is(err, tokens.ErrExpiryInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, auth.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
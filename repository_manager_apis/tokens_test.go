@@ -0,0 +1,118 @@
+package repository_manager_apis
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/weedbox/git-modules/auth"
+	"github.com/weedbox/git-modules/repository_manager"
+	"github.com/weedbox/git-modules/tokens"
+	"go.uber.org/fx"
+)
+
+// Test that access tokens are limited to their scope on repository and server-wide routes
+func TestTokenScopes(t *testing.T) {
+	s := setupTestAPIs(t, false)
+
+	deploy, err := s.tokens.CreateDeployToken("org/app", tokens.Options{Name: "ci", Scope: tokens.ScopeWrite})
+	if err != nil {
+		t.Fatalf("Failed to create deploy token: %v", err)
+	}
+	group := s.personalToken(t, "alice", tokens.Options{Scope: tokens.ScopeWrite, Group: "org"})
+	readOnly := s.personalToken(t, "alice", tokens.Options{Scope: tokens.ScopeRead})
+
+	// Deploy tokens write to their repository but hold no more than the write role
+	file := `{"message":"Update","content":"aGVsbG8K"}`
+	if status := s.request(t, http.MethodPut, "/apis/v1/repos/org/app/contents/README.md", deploy.Secret, strings.NewReader(file)); status != http.StatusCreated && status != http.StatusOK {
+		t.Errorf("Expected write deploy token to commit files, got %d", status)
+	}
+	if status := s.request(t, http.MethodGet, "/apis/v1/repos/org/app/deploy_tokens", deploy.Secret, nil); status != http.StatusForbidden {
+		t.Errorf("Expected deploy token not to list deploy tokens, got %d", status)
+	}
+
+	// Deploy credentials never create other credentials
+	for _, path := range []string{"/apis/v1/repos/org/app/deploy_tokens", "/apis/v1/repos/org/app/deploy_keys"} {
+		body := `{"name":"escalate","scope":"write","title":"escalate","key":"ssh-ed25519 AAAA","write":true}`
+		if status := s.request(t, http.MethodPost, path, deploy.Secret, strings.NewReader(body)); status != http.StatusForbidden {
+			t.Errorf("POST %s: expected deploy token to be refused, got %d", path, status)
+		}
+	}
+	if got := len(s.tokens.ListDeployTokens("org/app")); got != 1 {
+		t.Errorf("Expected 1 deploy token, got %d", got)
+	}
+
+	// Tokens limited to a repository or group cannot reach server-wide routes
+	for _, token := range []string{deploy.Secret, group} {
+		for _, path := range []string{"/apis/v1/backup", "/apis/v1/audit"} {
+			if status := s.request(t, http.MethodGet, path, token, nil); status != http.StatusForbidden {
+				t.Errorf("GET %s: expected scoped token to be refused, got %d", path, status)
+			}
+		}
+	}
+
+	// Read-only tokens export backups but do not import them
+	if status := s.request(t, http.MethodGet, "/apis/v1/backup", readOnly, nil); status != http.StatusOK {
+		t.Errorf("Expected read-only token to export backups, got %d", status)
+	}
+	if status := s.request(t, http.MethodPost, "/apis/v1/backup", readOnly, nil); status != http.StatusForbidden {
+		t.Errorf("Expected read-only token not to import backups, got %d", status)
+	}
+	if status := s.request(t, http.MethodDelete, "/apis/v1/repos/org/app", readOnly, nil); status != http.StatusForbidden {
+		t.Errorf("Expected read-only token not to delete repositories, got %d", status)
+	}
+
+	if status := s.request(t, http.MethodGet, "/apis/v1/repos/org/app", "gitpat_invalid", nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected unknown token to be refused with 401, got %d", status)
+	}
+}
+
+// Test that deploy tokens keep their limits when roles are enforced
+func TestTokenScopes_EnforceRoles(t *testing.T) {
+	s := setupTestAPIs(t, true)
+
+	deploy, err := s.tokens.CreateDeployToken("org/app", tokens.Options{Name: "ci", Scope: tokens.ScopeRead})
+	if err != nil {
+		t.Fatalf("Failed to create deploy token: %v", err)
+	}
+
+	if status := s.request(t, http.MethodGet, "/apis/v1/repos/org/app", deploy.Secret, nil); status != http.StatusOK {
+		t.Errorf("Expected deploy token to read its repository, got %d", status)
+	}
+	for _, path := range []string{"/apis/v1/backup", "/apis/v1/audit"} {
+		if status := s.request(t, http.MethodGet, path, deploy.Secret, nil); status != http.StatusForbidden {
+			t.Errorf("GET %s: expected deploy token to be refused, got %d", path, status)
+		}
+	}
+
+	// Scoped tokens of admins do not reach server-wide routes either
+	group := s.personalToken(t, "alice", tokens.Options{Scope: tokens.ScopeWrite, Group: "org"})
+	if status := s.request(t, http.MethodGet, "/apis/v1/backup", group, nil); status != http.StatusForbidden {
+		t.Errorf("Expected group token of an admin to be refused, got %d", status)
+	}
+}
+
+// Test that roles granted to teams apply to the personal access tokens of their members
+func TestTokenTeamRoles(t *testing.T) {
+	teams := auth.TeamResolverFunc(func(ctx context.Context, user string) ([]string, error) {
+		if user == "carol" {
+			return []string{"readers"}, nil
+		}
+		return nil, nil
+	})
+	s := setupTestAPIs(t, true, fx.Supply(fx.Annotate(teams, fx.As(new(auth.TeamResolver)))))
+	if _, err := s.manager.SetPermission("org", repository_manager.Permission{Team: "readers", Role: repository_manager.RoleRead}); err != nil {
+		t.Fatalf("Failed to grant role: %v", err)
+	}
+
+	carol := s.personalToken(t, "carol", tokens.Options{Scope: tokens.ScopeRead})
+	if status := s.request(t, http.MethodGet, "/apis/v1/repos/org/app", carol, nil); status != http.StatusOK {
+		t.Errorf("Expected team member to read the repository, got %d", status)
+	}
+
+	dave := s.personalToken(t, "dave", tokens.Options{Scope: tokens.ScopeRead})
+	if status := s.request(t, http.MethodGet, "/apis/v1/repos/org/app", dave, nil); status != http.StatusForbidden {
+		t.Errorf("Expected user outside the team to be refused, got %d", status)
+	}
+}
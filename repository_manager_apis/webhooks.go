@@ -0,0 +1,188 @@
+package repository_manager_apis
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weedbox/git-modules/repository_manager"
+	"go.uber.org/zap"
+)
+
+// handleListWebhooks handles GET /apis/v1/repos/*name/webhooks
+// @Summary List webhooks
+// @Description List the webhooks of a repository or group. With effective=true the webhooks inherited by a repository from its groups are included. Secrets are never returned
+// @Tags Webhooks
+// @Produce json
+// @Param name path string true "Repository or group name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param effective query bool false "Include webhooks inherited from groups (repositories only)" example:"true"
+// @Success 200 {array} repository_manager.Webhook "List of webhooks"
+// @Failure 404 {object} ErrorResponse "Repository or group not found"
+// @Failure 500 {object} ErrorResponse "Failed to list webhooks"
+// @Router /apis/v1/repos/{name}/webhooks [get]
+func (m *RepositoryManagerAPIs) handleListWebhooks(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+
+	var webhooks []repository_manager.Webhook
+	var err error
+	if c.Query("effective") == "true" {
+		webhooks, err = m.params.RepositoryManager.ListEffectiveWebhooks(name)
+	} else {
+		webhooks, err = m.params.RepositoryManager.ListWebhooks(name)
+	}
+	if err != nil {
+		m.logger.Error("Failed to list webhooks", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// handleCreateWebhook handles POST /apis/v1/repos/*name/webhooks
+// @Summary Create a webhook
+// @Description Register a webhook notified of the events of a repository, or of every repository in a group. Payloads are signed with HMAC-SHA256 of the secret in the X-Hub-Signature-256 header. Loopback and link-local addresses are refused unless their host is listed in repository_manager.webhook_allowed_hosts
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param name path string true "Repository or group name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param body body WebhookRequest true "Webhook"
+// @Success 201 {object} repository_manager.Webhook "Webhook created"
+// @Failure 400 {object} ErrorResponse "Invalid request body, URL or event"
+// @Failure 404 {object} ErrorResponse "Repository or group not found"
+// @Failure 500 {object} ErrorResponse "Failed to create webhook"
+// @Router /apis/v1/repos/{name}/webhooks [post]
+func (m *RepositoryManagerAPIs) handleCreateWebhook(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+
+	var req WebhookRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	webhook, err := m.params.RepositoryManager.CreateWebhook(name, repository_manager.Webhook{
+		URL:    req.URL,
+		Secret: req.Secret,
+		Events: req.Events,
+	})
+	if err != nil {
+		m.logger.Error("Failed to create webhook", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// handleGetWebhook handles GET /apis/v1/repos/*name/webhooks/:id
+// @Summary Get a webhook
+// @Description Get the webhook of a repository or group with the given ID
+// @Tags Webhooks
+// @Produce json
+// @Param name path string true "Repository or group name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param id path string true "Webhook ID" example:"9f86d081884c7d65"
+// @Success 200 {object} repository_manager.Webhook "Webhook"
+// @Failure 404 {object} ErrorResponse "Webhook not found"
+// @Router /apis/v1/repos/{name}/webhooks/{id} [get]
+func (m *RepositoryManagerAPIs) handleGetWebhook(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+	id := c.Param("id")
+
+	webhook, err := m.params.RepositoryManager.GetWebhook(name, id)
+	if err != nil {
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// handleUpdateWebhook handles PUT /apis/v1/repos/*name/webhooks/:id
+// @Summary Update a webhook
+// @Description Replace the URL and event filter of a webhook. The secret is only replaced if a new one is given
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param name path string true "Repository or group name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param id path string true "Webhook ID" example:"9f86d081884c7d65"
+// @Param body body WebhookRequest true "Webhook"
+// @Success 200 {object} repository_manager.Webhook "Webhook updated"
+// @Failure 400 {object} ErrorResponse "Invalid request body, URL or event"
+// @Failure 404 {object} ErrorResponse "Webhook not found"
+// @Failure 500 {object} ErrorResponse "Failed to update webhook"
+// @Router /apis/v1/repos/{name}/webhooks/{id} [put]
+func (m *RepositoryManagerAPIs) handleUpdateWebhook(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+	id := c.Param("id")
+
+	var req WebhookRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	webhook, err := m.params.RepositoryManager.UpdateWebhook(name, id, repository_manager.Webhook{
+		URL:    req.URL,
+		Secret: req.Secret,
+		Events: req.Events,
+	})
+	if err != nil {
+		m.logger.Error("Failed to update webhook", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// handleDeleteWebhook handles DELETE /apis/v1/repos/*name/webhooks/:id
+// @Summary Delete a webhook
+// @Description Remove a webhook and its delivery history from a repository or group
+// @Tags Webhooks
+// @Produce json
+// @Param name path string true "Repository or group name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param id path string true "Webhook ID" example:"9f86d081884c7d65"
+// @Success 200 {object} MessageResponse "Webhook deleted"
+// @Failure 404 {object} ErrorResponse "Webhook not found"
+// @Failure 500 {object} ErrorResponse "Failed to delete webhook"
+// @Router /apis/v1/repos/{name}/webhooks/{id} [delete]
+func (m *RepositoryManagerAPIs) handleDeleteWebhook(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+	id := c.Param("id")
+
+	if err := m.params.RepositoryManager.DeleteWebhook(name, id); err != nil {
+		m.logger.Error("Failed to delete webhook", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "Webhook deleted successfully"})
+}
+
+// handleListWebhookDeliveries handles GET /apis/v1/repos/*name/webhooks/:id/deliveries
+// @Summary List webhook deliveries
+// @Description List the recent deliveries of a webhook with their response codes, newest first
+// @Tags Webhooks
+// @Produce json
+// @Param name path string true "Repository or group name (supports multi-level paths)" example:"myorg/myrepo"
+// @Param id path string true "Webhook ID" example:"9f86d081884c7d65"
+// @Success 200 {array} repository_manager.WebhookDelivery "List of deliveries"
+// @Failure 404 {object} ErrorResponse "Webhook not found"
+// @Failure 500 {object} ErrorResponse "Failed to list deliveries"
+// @Router /apis/v1/repos/{name}/webhooks/{id}/deliveries [get]
+func (m *RepositoryManagerAPIs) handleListWebhookDeliveries(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+	id := c.Param("id")
+
+	deliveries, err := m.params.RepositoryManager.ListWebhookDeliveries(name, id)
+	if err != nil {
+		m.logger.Error("Failed to list webhook deliveries", zap.Error(err))
+		c.JSON(statusCodeForError(err, http.StatusInternalServerError), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
@@ -0,0 +1,35 @@
+package ssh_keys
+
+import "time"
+
+const (
+	// KindUser marks SSH keys of users, which act on behalf of the user
+	KindUser = "user"
+
+	// KindDeploy marks deploy keys, which grant access to a single repository
+	KindDeploy = "deploy"
+)
+
+// Key describes a registered SSH public key
+// @Description SSH public key of a user or deploy key of a repository
+type Key struct {
+	ID          string     `json:"id" example:"9f86d081884c7d65"`
+	Kind        string     `json:"kind" example:"user" enums:"user,deploy"`
+	Title       string     `json:"title" example:"laptop"`
+	User        string     `json:"user,omitempty" example:"john"`
+	Repository  string     `json:"repository,omitempty" example:"myorg/myrepo"`
+	Write       bool       `json:"write" example:"false"`
+	Fingerprint string     `json:"fingerprint" example:"SHA256:nThbg6kXUpJWGl7E1IGOCspRomTxdCARLviKw6E5SY8"`
+	PublicKey   string     `json:"public_key" example:"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGv0R0j8tRJ0GpnbxDwL1x0kR9t4v4nYgHjB0m2k8Y3e"`
+	CreatedAt   time.Time  `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty" example:"2024-06-01T00:00:00Z"`
+} // @name SSHKey
+
+// Options describes a key to register
+// @Description SSH key registration request. The title defaults to the comment of the key.
+// @Description Write allows a deploy key to push, deploy keys are read-only by default
+type Options struct {
+	Title string `json:"title" example:"laptop"`
+	Key   string `json:"key" binding:"required" example:"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGv0R0j8tRJ0GpnbxDwL1x0kR9t4v4nYgHjB0m2k8Y3e john@laptop"`
+	Write bool   `json:"write" example:"false"`
+} // @name SSHKeyOptions
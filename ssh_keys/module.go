@@ -0,0 +1,135 @@
+// Package ssh_keys stores the SSH public keys of users and the deploy keys of repositories.
+//
+// User keys act on behalf of their user. Deploy keys grant read-only or, if allowed,
+// read-write access to a single repository. A key can only be registered once across all
+// users and repositories, so every key resolves to exactly one identity.
+//
+// The KeyStore implements git_ssh.KeyStore: keys are looked up by their SHA-256 fingerprint
+// during the SSH handshake and their last-used time is recorded once the client has authenticated.
+// User keys carry no team memberships, an auth.TeamResolver provides them so that roles granted
+// to teams apply over SSH. Keys are managed through repository_manager_apis when the module
+// is part of the application.
+package ssh_keys
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/spf13/viper"
+	"github.com/weedbox/git-modules/auth"
+	"github.com/weedbox/git-modules/events"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	ModuleName  = "SSHKeyStore"
+	DefaultPath = "./git/ssh_keys.json"
+)
+
+type KeyStore struct {
+	params        Params
+	logger        *zap.Logger
+	scope         string
+	path          string
+	mu            sync.Mutex
+	keys          map[string]*Key
+	byFingerprint map[string]*Key
+	unsubscribe   func()
+}
+
+type Params struct {
+	fx.In
+
+	Lifecycle fx.Lifecycle
+	Logger    *zap.Logger
+
+	// Bus removes the deploy keys of deleted repositories when provided
+	Bus *events.Bus `optional:"true"`
+
+	// Teams resolves the teams of the users behind user keys when provided
+	Teams auth.TeamResolver `optional:"true"`
+}
+
+func Module(scope string) fx.Option {
+
+	var m *KeyStore
+
+	return fx.Module(
+		scope,
+		fx.Provide(func(p Params) *KeyStore {
+			s := &KeyStore{
+				params: p,
+				logger: p.Logger.Named(scope),
+				scope:  scope,
+			}
+
+			s.initDefaultConfigs()
+
+			return s
+		}),
+		fx.Populate(&m),
+		fx.Invoke(func(p Params) {
+
+			p.Lifecycle.Append(
+				fx.Hook{
+					OnStart: m.onStart,
+					OnStop:  m.onStop,
+				},
+			)
+		}),
+	)
+
+}
+
+func (m *KeyStore) onStart(ctx context.Context) error {
+	m.logger.Info("Starting " + ModuleName)
+
+	m.path = viper.GetString(m.getConfigPath("path"))
+
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return fmt.Errorf("failed to create SSH key store directory: %w", err)
+	}
+
+	m.mu.Lock()
+	err := m.load()
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	m.unsubscribe = m.params.Bus.Subscribe(func(event events.Event) {
+		if e, ok := event.(events.RepositoryDeleted); ok {
+			m.removeDeployKeys(e.Repository)
+		}
+	})
+
+	return nil
+}
+
+func (m *KeyStore) onStop(ctx context.Context) error {
+	if m.unsubscribe != nil {
+		m.unsubscribe()
+	}
+
+	// Persist last-used times recorded since the last save
+	m.mu.Lock()
+	if err := m.save(); err != nil {
+		m.logger.Warn("Failed to save SSH keys", zap.Error(err))
+	}
+	m.mu.Unlock()
+
+	m.logger.Info("Stopped " + ModuleName)
+	return nil
+}
+
+func (m *KeyStore) getConfigPath(key string) string {
+	return fmt.Sprintf("%s.%s", m.scope, key)
+}
+
+func (m *KeyStore) initDefaultConfigs() {
+	viper.SetDefault(m.getConfigPath("path"), DefaultPath)
+}
@@ -0,0 +1,129 @@
+package ssh_keys
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// signatureMagic starts SSH signatures and the data they sign, see PROTOCOL.sshsig of OpenSSH
+	signatureMagic = "SSHSIG"
+
+	// signatureNamespace is the namespace git signs commits and tags in
+	signatureNamespace = "git"
+
+	signatureBegin = "-----BEGIN SSH SIGNATURE-----"
+	signatureEnd   = "-----END SSH SIGNATURE-----"
+)
+
+// ErrSignatureUnverified indicates a signature that is malformed or was not made by a registered user key
+var ErrSignatureUnverified = errors.New("signature was not made by a registered SSH key")
+
+// sshSignature is an SSH signature following the magic preamble
+type sshSignature struct {
+	Version       uint32
+	PublicKey     []byte
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Signature     []byte
+}
+
+// signedData is the data an SSH signature is computed over, following the magic preamble
+type signedData struct {
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Hash          []byte
+}
+
+// VerifyCommitSignature checks an armored SSH signature of a commit payload, as made by
+// git with gpg.format=ssh, and returns the user who registered the signing key. SSH keys have no emails.
+// Deploy keys do not sign commits. Other signatures yield ErrSignatureUnverified.
+func (m *KeyStore) VerifyCommitSignature(signature string, payload []byte) (string, []string, error) {
+	sig, err := parseSSHSignature(signature)
+	if err != nil || sig.Namespace != signatureNamespace {
+		return "", nil, ErrSignatureUnverified
+	}
+
+	pub, err := ssh.ParsePublicKey(sig.PublicKey)
+	if err != nil {
+		return "", nil, ErrSignatureUnverified
+	}
+
+	m.mu.Lock()
+	stored, ok := m.byFingerprint[ssh.FingerprintSHA256(pub)]
+	user := ""
+	if ok && stored.Kind == KindUser {
+		user = stored.User
+	}
+	m.mu.Unlock()
+	if user == "" {
+		return "", nil, ErrSignatureUnverified
+	}
+
+	var h hash.Hash
+	switch sig.HashAlgorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return "", nil, ErrSignatureUnverified
+	}
+	h.Write(payload)
+
+	var blob ssh.Signature
+	if err := ssh.Unmarshal(sig.Signature, &blob); err != nil {
+		return "", nil, ErrSignatureUnverified
+	}
+
+	data := append([]byte(signatureMagic), ssh.Marshal(signedData{
+		Namespace:     sig.Namespace,
+		Reserved:      sig.Reserved,
+		HashAlgorithm: sig.HashAlgorithm,
+		Hash:          h.Sum(nil),
+	})...)
+	if err := pub.Verify(data, &blob); err != nil {
+		return "", nil, ErrSignatureUnverified
+	}
+
+	return user, nil, nil
+}
+
+// parseSSHSignature decodes an armored SSH signature
+func parseSSHSignature(armored string) (*sshSignature, error) {
+	armored = strings.TrimSpace(armored)
+	body, ok := strings.CutPrefix(armored, signatureBegin)
+	if !ok {
+		return nil, ErrSignatureUnverified
+	}
+	body, ok = strings.CutSuffix(body, signatureEnd)
+	if !ok {
+		return nil, ErrSignatureUnverified
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(body), ""))
+	if err != nil {
+		return nil, ErrSignatureUnverified
+	}
+
+	rest, ok := bytes.CutPrefix(raw, []byte(signatureMagic))
+	if !ok {
+		return nil, ErrSignatureUnverified
+	}
+
+	sig := &sshSignature{}
+	if err := ssh.Unmarshal(rest, sig); err != nil || sig.Version != 1 {
+		return nil, ErrSignatureUnverified
+	}
+
+	return sig, nil
+}
@@ -0,0 +1,87 @@
+package ssh_keys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// signTestPayload signs a payload like ssh-keygen -Y sign -n git does
+func signTestPayload(t *testing.T, signer ssh.Signer, payload []byte) string {
+	hash := sha512.Sum512(payload)
+	data := append([]byte(signatureMagic), ssh.Marshal(signedData{
+		Namespace:     signatureNamespace,
+		HashAlgorithm: "sha512",
+		Hash:          hash[:],
+	})...)
+
+	sig, err := signer.Sign(rand.Reader, data)
+	if err != nil {
+		t.Fatalf("Failed to sign payload: %v", err)
+	}
+
+	raw := append([]byte(signatureMagic), ssh.Marshal(sshSignature{
+		Version:       1,
+		PublicKey:     signer.PublicKey().Marshal(),
+		Namespace:     signatureNamespace,
+		HashAlgorithm: "sha512",
+		Signature:     ssh.Marshal(sig),
+	})...)
+
+	encoded := base64.StdEncoding.EncodeToString(raw)
+	lines := make([]string, 0)
+	for len(encoded) > 70 {
+		lines = append(lines, encoded[:70])
+		encoded = encoded[70:]
+	}
+	lines = append(lines, encoded)
+
+	return signatureBegin + "\n" + strings.Join(lines, "\n") + "\n" + signatureEnd + "\n"
+}
+
+// Test that commit signatures resolve to the user who registered the signing key
+func TestVerifyCommitSignature(t *testing.T) {
+	m := setupTestKeyStore(t)
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	payload := []byte("tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n\nInitial commit\n")
+	signature := signTestPayload(t, signer, payload)
+
+	// Unregistered keys are not trusted
+	if _, _, err := m.VerifyCommitSignature(signature, payload); !errors.Is(err, ErrSignatureUnverified) {
+		t.Errorf("Expected ErrSignatureUnverified for unregistered key, got %v", err)
+	}
+
+	if _, err := m.AddUserKey("john", Options{Title: "signing", Key: string(ssh.MarshalAuthorizedKey(signer.PublicKey()))}); err != nil {
+		t.Fatalf("Failed to add key: %v", err)
+	}
+
+	user, _, err := m.VerifyCommitSignature(signature, payload)
+	if err != nil {
+		t.Fatalf("Failed to verify signature: %v", err)
+	}
+	if user != "john" {
+		t.Errorf("Expected signature by john, got %q", user)
+	}
+
+	if _, _, err := m.VerifyCommitSignature(signature, append(payload, '!')); !errors.Is(err, ErrSignatureUnverified) {
+		t.Errorf("Expected ErrSignatureUnverified for modified payload, got %v", err)
+	}
+	if _, _, err := m.VerifyCommitSignature("-----BEGIN SSH SIGNATURE-----\ngarbage\n-----END SSH SIGNATURE-----", payload); !errors.Is(err, ErrSignatureUnverified) {
+		t.Errorf("Expected ErrSignatureUnverified for malformed signature, got %v", err)
+	}
+}
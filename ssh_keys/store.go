@@ -0,0 +1,349 @@
+package ssh_keys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/weedbox/git-modules/auth"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// minRSABits is the smallest RSA key size accepted
+	minRSABits = 2048
+
+	// lastUsedInterval limits how often the last-used time of a key is written to disk
+	lastUsedInterval = time.Minute
+)
+
+var (
+	// ErrKeyUnknown indicates a public key that is not registered
+	ErrKeyUnknown = fmt.Errorf("unknown SSH key: %w", auth.ErrUnauthenticated)
+
+	// ErrKeyInvalid indicates a key that is not a single public key in authorized_keys format
+	ErrKeyInvalid = errors.New("invalid SSH key: expected a single public key in authorized_keys format")
+
+	// ErrKeyWeak indicates a DSA key or an RSA key shorter than 2048 bits
+	ErrKeyWeak = errors.New("SSH key too weak: DSA keys and RSA keys shorter than 2048 bits are not accepted")
+
+	// ErrKeyDuplicate indicates a key already registered by a user or as deploy key
+	ErrKeyDuplicate = errors.New("SSH key is already in use")
+
+	// ErrKeyNotFound indicates a key that does not exist or belongs to another user or repository
+	ErrKeyNotFound = errors.New("SSH key not found")
+
+	// ErrTitleEmpty indicates a key without title and without comment to take the title from
+	ErrTitleEmpty = errors.New("SSH key title cannot be empty")
+)
+
+// AddUserKey registers a public key acting on behalf of a user
+func (m *KeyStore) AddUserKey(user string, opts Options) (*Key, error) {
+	if user == "" {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	return m.add(Key{Kind: KindUser, User: user}, opts)
+}
+
+// AddDeployKey registers a public key granting access to a single repository.
+// Deploy keys are read-only unless the write option is set.
+func (m *KeyStore) AddDeployKey(repository string, opts Options) (*Key, error) {
+	return m.add(Key{Kind: KindDeploy, Repository: repository, Write: opts.Write}, opts)
+}
+
+// ListUserKeys returns the keys of a user, oldest first
+func (m *KeyStore) ListUserKeys(user string) []Key {
+	return m.list(func(k *Key) bool { return k.Kind == KindUser && k.User == user })
+}
+
+// ListDeployKeys returns the deploy keys of a repository, oldest first
+func (m *KeyStore) ListDeployKeys(repository string) []Key {
+	return m.list(func(k *Key) bool { return k.Kind == KindDeploy && k.Repository == repository })
+}
+
+// DeleteUserKey removes a key of a user
+func (m *KeyStore) DeleteUserKey(user, id string) error {
+	return m.remove(id, func(k *Key) bool { return k.Kind == KindUser && k.User == user })
+}
+
+// DeleteDeployKey removes a deploy key of a repository
+func (m *KeyStore) DeleteDeployKey(repository, id string) error {
+	return m.remove(id, func(k *Key) bool { return k.Kind == KindDeploy && k.Repository == repository })
+}
+
+// LookupPublicKey implements git_ssh.KeyStore. It returns the identity of the registered key
+// with the fingerprint of key, including the teams of its user when a TeamResolver is provided.
+// Unregistered keys yield ErrKeyUnknown. The key is looked up before the client proved it holds
+// the private key, so its use is recorded separately by RecordUse.
+func (m *KeyStore) LookupPublicKey(ctx context.Context, key ssh.PublicKey) (*auth.Identity, error) {
+	m.mu.Lock()
+	stored, ok := m.byFingerprint[ssh.FingerprintSHA256(key)]
+	var identity *auth.Identity
+	if ok {
+		identity = Identity(stored)
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return nil, ErrKeyUnknown
+	}
+
+	if stored.Kind == KindUser && m.params.Teams != nil {
+		teams, err := m.params.Teams.Teams(ctx, identity.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve teams of %s: %w", identity.Name, err)
+		}
+		identity.Teams = teams
+	}
+
+	return identity, nil
+}
+
+// RecordUse sets the last-used time of the key with a fingerprint, once a client authenticated with it.
+// Unknown fingerprints, e.g. of keys resolved by another KeyStore, are ignored.
+func (m *KeyStore) RecordUse(fingerprint string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.byFingerprint[fingerprint]
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	persist := stored.LastUsedAt == nil || now.Sub(*stored.LastUsedAt) >= lastUsedInterval
+	stored.LastUsedAt = &now
+	if persist {
+		if err := m.save(); err != nil {
+			m.logger.Warn("Failed to record SSH key use", zap.String("id", stored.ID), zap.Error(err))
+		}
+	}
+}
+
+// Identity returns the identity connections authenticated with a key act as.
+// Deploy keys are limited to their repository like deploy tokens.
+func Identity(key *Key) *auth.Identity {
+	if key.Kind == KindDeploy {
+		return &auth.Identity{
+			Name:  "deploy-key:" + key.Title,
+			Token: &auth.TokenScope{Write: key.Write, Prefix: key.Repository, Deploy: true},
+		}
+	}
+
+	return &auth.Identity{Name: key.User}
+}
+
+// add validates and stores a new key
+func (m *KeyStore) add(key Key, opts Options) (*Key, error) {
+	pub, comment, _, rest, err := ssh.ParseAuthorizedKey([]byte(opts.Key))
+	if err != nil || strings.TrimSpace(string(rest)) != "" {
+		return nil, ErrKeyInvalid
+	}
+	if _, ok := pub.(*ssh.Certificate); ok {
+		return nil, ErrKeyInvalid
+	}
+	if err := checkKeyStrength(pub); err != nil {
+		return nil, err
+	}
+
+	key.Title = strings.TrimSpace(opts.Title)
+	if key.Title == "" {
+		key.Title = strings.TrimSpace(comment)
+	}
+	if key.Title == "" {
+		return nil, ErrTitleEmpty
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	key.ID = id
+	key.Fingerprint = ssh.FingerprintSHA256(pub)
+	key.PublicKey = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub)))
+	key.CreatedAt = time.Now().UTC()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.byFingerprint[key.Fingerprint]; ok {
+		return nil, ErrKeyDuplicate
+	}
+
+	stored := &key
+	m.keys[stored.ID] = stored
+	m.byFingerprint[stored.Fingerprint] = stored
+	if err := m.save(); err != nil {
+		delete(m.keys, stored.ID)
+		delete(m.byFingerprint, stored.Fingerprint)
+		return nil, err
+	}
+
+	m.logger.Info("SSH key added",
+		zap.String("id", key.ID),
+		zap.String("kind", key.Kind),
+		zap.String("user", key.User),
+		zap.String("repository", key.Repository),
+		zap.String("fingerprint", key.Fingerprint),
+	)
+
+	created := key
+	return &created, nil
+}
+
+// list returns the keys matching a predicate, oldest first
+func (m *KeyStore) list(match func(k *Key) bool) []Key {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]Key, 0)
+	for _, k := range m.keys {
+		if match(k) {
+			keys = append(keys, *k)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys
+}
+
+// remove deletes a key if it matches a predicate
+func (m *KeyStore) remove(id string, match func(k *Key) bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.keys[id]
+	if !ok || !match(stored) {
+		return ErrKeyNotFound
+	}
+
+	delete(m.keys, id)
+	delete(m.byFingerprint, stored.Fingerprint)
+	if err := m.save(); err != nil {
+		m.keys[id] = stored
+		m.byFingerprint[stored.Fingerprint] = stored
+		return err
+	}
+
+	m.logger.Info("SSH key removed", zap.String("id", id), zap.String("kind", stored.Kind))
+	return nil
+}
+
+// removeDeployKeys deletes the deploy keys of a deleted repository,
+// so that they do not grant access to a new repository created with the same name
+func (m *KeyStore) removeDeployKeys(repository string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := 0
+	for id, k := range m.keys {
+		if k.Kind == KindDeploy && k.Repository == repository {
+			delete(m.keys, id)
+			delete(m.byFingerprint, k.Fingerprint)
+			removed++
+		}
+	}
+
+	if removed == 0 {
+		return
+	}
+
+	if err := m.save(); err != nil {
+		m.logger.Error("Failed to remove deploy keys", zap.String("repository", repository), zap.Error(err))
+		return
+	}
+
+	m.logger.Info("Deploy keys removed", zap.String("repository", repository), zap.Int("count", removed))
+}
+
+// load reads the key file, a missing file yields an empty store
+func (m *KeyStore) load() error {
+	m.keys = make(map[string]*Key)
+	m.byFingerprint = make(map[string]*Key)
+
+	data, err := os.ReadFile(m.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read SSH keys: %w", err)
+	}
+
+	var stored []*Key
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("failed to read SSH keys: %w", err)
+	}
+
+	for _, k := range stored {
+		m.keys[k.ID] = k
+		m.byFingerprint[k.Fingerprint] = k
+	}
+
+	return nil
+}
+
+// save atomically replaces the key file
+func (m *KeyStore) save() error {
+	stored := make([]*Key, 0, len(m.keys))
+	for _, k := range m.keys {
+		stored = append(stored, k)
+	}
+	sort.Slice(stored, func(i, j int) bool { return stored[i].CreatedAt.Before(stored[j].CreatedAt) })
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to write SSH keys: %w", err)
+	}
+
+	tmpPath := m.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write SSH keys: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, m.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write SSH keys: %w", err)
+	}
+
+	return nil
+}
+
+// checkKeyStrength rejects DSA keys and short RSA keys
+func checkKeyStrength(pub ssh.PublicKey) error {
+	switch pub.Type() {
+	case ssh.KeyAlgoDSA:
+		return ErrKeyWeak
+
+	case ssh.KeyAlgoRSA:
+		cryptoKey, ok := pub.(ssh.CryptoPublicKey)
+		if !ok {
+			return ErrKeyInvalid
+		}
+		rsaKey, ok := cryptoKey.CryptoPublicKey().(*rsa.PublicKey)
+		if !ok || rsaKey.N.BitLen() < minRSABits {
+			return ErrKeyWeak
+		}
+	}
+
+	return nil
+}
+
+// newID returns a random key ID
+func newID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate key ID: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
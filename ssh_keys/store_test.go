@@ -0,0 +1,187 @@
+package ssh_keys
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/weedbox/git-modules/auth"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+)
+
+func setupTestKeyStore(t *testing.T) *KeyStore {
+	m := &KeyStore{
+		logger: zap.NewNop(),
+		path:   filepath.Join(t.TempDir(), "ssh_keys.json"),
+	}
+	if err := m.load(); err != nil {
+		t.Fatalf("Failed to load keys: %v", err)
+	}
+
+	return m
+}
+
+func generateTestKey(t *testing.T) (ssh.PublicKey, string) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("Failed to convert key: %v", err)
+	}
+
+	return key, string(ssh.MarshalAuthorizedKey(key))
+}
+
+// Test that user keys resolve to their user and cannot be registered twice
+func TestUserKeys(t *testing.T) {
+	m := setupTestKeyStore(t)
+	pub, authorizedKey := generateTestKey(t)
+
+	key, err := m.AddUserKey("john", Options{Key: strings.TrimSpace(authorizedKey) + " john@laptop"})
+	if err != nil {
+		t.Fatalf("Failed to add key: %v", err)
+	}
+	if key.Title != "john@laptop" || key.Fingerprint != ssh.FingerprintSHA256(pub) {
+		t.Errorf("Unexpected key: %+v", key)
+	}
+
+	if _, err := m.AddUserKey("jane", Options{Title: "copy", Key: authorizedKey}); !errors.Is(err, ErrKeyDuplicate) {
+		t.Errorf("Expected ErrKeyDuplicate, got %v", err)
+	}
+	if _, err := m.AddDeployKey("org/app", Options{Title: "copy", Key: authorizedKey}); !errors.Is(err, ErrKeyDuplicate) {
+		t.Errorf("Expected ErrKeyDuplicate for deploy key, got %v", err)
+	}
+
+	// Keys survive a reload
+	if err := m.load(); err != nil {
+		t.Fatalf("Failed to reload keys: %v", err)
+	}
+
+	identity, err := m.LookupPublicKey(context.Background(), pub)
+	if err != nil {
+		t.Fatalf("Failed to look up key: %v", err)
+	}
+	if identity.Name != "john" || identity.Token != nil {
+		t.Errorf("Unexpected identity: %+v", identity)
+	}
+	if identity.Teams != nil {
+		t.Errorf("Expected no teams without a TeamResolver, got %v", identity.Teams)
+	}
+
+	// Looking a key up does not count as use, the client may not hold the private key
+	if keys := m.ListUserKeys("john"); len(keys) != 1 || keys[0].LastUsedAt != nil {
+		t.Errorf("Expected one key without last-used time after lookup, got %v", keys)
+	}
+	m.RecordUse(ssh.FingerprintSHA256(pub))
+	m.RecordUse("SHA256:unknown")
+	if keys := m.ListUserKeys("john"); len(keys) != 1 || keys[0].LastUsedAt == nil {
+		t.Errorf("Expected one key with last-used time, got %v", keys)
+	}
+
+	// Teams of the user are resolved for user keys only
+	m.params.Teams = auth.TeamResolverFunc(func(ctx context.Context, user string) ([]string, error) {
+		if user != "john" {
+			return nil, errors.New("unexpected user " + user)
+		}
+		return []string{"developers"}, nil
+	})
+	identity, err = m.LookupPublicKey(context.Background(), pub)
+	if err != nil || len(identity.Teams) != 1 || identity.Teams[0] != "developers" {
+		t.Errorf("Expected identity with team developers, got %+v, %v", identity, err)
+	}
+	deployPub, deployKey := generateTestKey(t)
+	if _, err := m.AddDeployKey("org/app", Options{Title: "ci", Key: deployKey}); err != nil {
+		t.Fatalf("Failed to add deploy key: %v", err)
+	}
+	if identity, err := m.LookupPublicKey(context.Background(), deployPub); err != nil || identity.Teams != nil {
+		t.Errorf("Expected deploy key without teams, got %+v, %v", identity, err)
+	}
+	m.params.Teams = auth.TeamResolverFunc(func(ctx context.Context, user string) ([]string, error) {
+		return nil, errors.New("directory unavailable")
+	})
+	if _, err := m.LookupPublicKey(context.Background(), pub); err == nil {
+		t.Error("Expected lookup to fail when teams cannot be resolved")
+	}
+	m.params.Teams = nil
+
+	if err := m.DeleteUserKey("jane", key.ID); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound when deleting another user's key, got %v", err)
+	}
+	if err := m.DeleteUserKey("john", key.ID); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+	if _, err := m.LookupPublicKey(context.Background(), pub); !errors.Is(err, auth.ErrUnauthenticated) {
+		t.Errorf("Expected deleted key to be rejected, got %v", err)
+	}
+}
+
+// Test deploy key scopes and key validation
+func TestDeployKeys(t *testing.T) {
+	m := setupTestKeyStore(t)
+
+	readPub, readKey := generateTestKey(t)
+	writePub, writeKey := generateTestKey(t)
+
+	if _, err := m.AddDeployKey("org/app", Options{Title: "mirror", Key: readKey}); err != nil {
+		t.Fatalf("Failed to add deploy key: %v", err)
+	}
+	if _, err := m.AddDeployKey("org/app", Options{Title: "ci", Key: writeKey, Write: true}); err != nil {
+		t.Fatalf("Failed to add deploy key: %v", err)
+	}
+
+	identity, err := m.LookupPublicKey(context.Background(), readPub)
+	if err != nil {
+		t.Fatalf("Failed to look up key: %v", err)
+	}
+	if identity.Token == nil || identity.Token.Allows(auth.OperationWrite) || !identity.Token.Covers("org/app") || identity.Token.Covers("org/lib") {
+		t.Errorf("Unexpected read-only deploy key scope: %+v", identity.Token)
+	}
+
+	identity, err = m.LookupPublicKey(context.Background(), writePub)
+	if err != nil {
+		t.Fatalf("Failed to look up key: %v", err)
+	}
+	if identity.Token == nil || !identity.Token.Allows(auth.OperationWrite) {
+		t.Errorf("Unexpected read-write deploy key scope: %+v", identity.Token)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	weak, err := ssh.NewPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("Failed to convert RSA key: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+		err  error
+	}{
+		{"garbage", Options{Title: "x", Key: "not a key"}, ErrKeyInvalid},
+		{"two keys", Options{Title: "x", Key: readKey + writeKey}, ErrKeyInvalid},
+		{"short RSA", Options{Title: "x", Key: string(ssh.MarshalAuthorizedKey(weak))}, ErrKeyWeak},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := m.AddDeployKey("org/app", tt.opts); !errors.Is(err, tt.err) {
+				t.Errorf("Expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+
+	m.removeDeployKeys("org/app")
+	if keys := m.ListDeployKeys("org/app"); len(keys) != 0 {
+		t.Errorf("Expected deploy keys of a deleted repository to be removed, got %v", keys)
+	}
+}
@@ -0,0 +1,56 @@
+package tokens
+
+import "time"
+
+const (
+	// KindPersonal marks personal access tokens, which act on behalf of a user
+	KindPersonal = "personal"
+
+	// KindDeploy marks deploy tokens, which grant access to a single repository
+	KindDeploy = "deploy"
+)
+
+const (
+	// ScopeRead allows cloning, fetching and reading
+	ScopeRead = "read"
+
+	// ScopeWrite additionally allows pushing and other changes
+	ScopeWrite = "write"
+)
+
+// Token describes an access token. The secret is only returned once, when the token is created.
+// @Description Personal access token or deploy token
+type Token struct {
+	ID         string     `json:"id" example:"9f86d081884c7d65"`
+	Kind       string     `json:"kind" example:"personal" enums:"personal,deploy"`
+	Name       string     `json:"name" example:"ci"`
+	User       string     `json:"user,omitempty" example:"john"`
+	Repository string     `json:"repository,omitempty" example:"myorg/myrepo"`
+	Group      string     `json:"group,omitempty" example:"myorg"`
+	Scope      string     `json:"scope" example:"read" enums:"read,write"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" example:"2025-01-01T00:00:00Z"`
+	CreatedAt  time.Time  `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" example:"2024-06-01T00:00:00Z"`
+} // @name Token
+
+// CreatedToken is a newly created token together with its secret
+// @Description Newly created token. The token field holds the secret, which cannot be retrieved again
+type CreatedToken struct {
+	Token
+	Secret string `json:"token" example:"gitpat_3f7a9c0e5b1d4f2a8c6e0b9d7f5a3c1e2b4d6f8a"`
+} // @name CreatedToken
+
+// Options describes a token to create
+// @Description Token creation request
+type Options struct {
+	Name      string     `json:"name" binding:"required" example:"ci"`
+	Scope     string     `json:"scope" example:"read" enums:"read,write"`
+	Group     string     `json:"group,omitempty" example:"myorg"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2025-01-01T00:00:00Z"`
+} // @name TokenOptions
+
+// storedToken is a token as persisted, with the hash of its secret
+type storedToken struct {
+	Token
+	Hash string `json:"hash"`
+}
@@ -0,0 +1,134 @@
+// Package tokens issues and verifies personal access tokens and repository deploy tokens.
+//
+// Personal access tokens act on behalf of a user, limited to read or write access and
+// optionally to a group. Deploy tokens grant read or write access to a single repository.
+// Only SHA-256 hashes of the token secrets are stored, the secrets are returned once on creation.
+//
+// Tokens are accepted by git_http as Basic auth passwords or bearer tokens, and by
+// repository_manager_apis as bearer tokens, when the module is part of the application.
+// Personal access tokens carry no team memberships, an auth.TeamResolver provides them so
+// that roles granted to teams apply to the tokens of their members.
+package tokens
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/spf13/viper"
+	"github.com/weedbox/git-modules/auth"
+	"github.com/weedbox/git-modules/events"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	ModuleName  = "TokenStore"
+	DefaultPath = "./git/tokens.json"
+)
+
+type TokenStore struct {
+	params      Params
+	logger      *zap.Logger
+	scope       string
+	path        string
+	mu          sync.Mutex
+	tokens      map[string]*storedToken
+	byHash      map[string]*storedToken
+	unsubscribe func()
+}
+
+type Params struct {
+	fx.In
+
+	Lifecycle fx.Lifecycle
+	Logger    *zap.Logger
+
+	// Bus revokes the deploy tokens of deleted repositories when provided
+	Bus *events.Bus `optional:"true"`
+
+	// Teams resolves the teams of the users behind personal access tokens when provided
+	Teams auth.TeamResolver `optional:"true"`
+}
+
+func Module(scope string) fx.Option {
+
+	var m *TokenStore
+
+	return fx.Module(
+		scope,
+		fx.Provide(func(p Params) *TokenStore {
+			s := &TokenStore{
+				params: p,
+				logger: p.Logger.Named(scope),
+				scope:  scope,
+			}
+
+			s.initDefaultConfigs()
+
+			return s
+		}),
+		fx.Populate(&m),
+		fx.Invoke(func(p Params) {
+
+			p.Lifecycle.Append(
+				fx.Hook{
+					OnStart: m.onStart,
+					OnStop:  m.onStop,
+				},
+			)
+		}),
+	)
+
+}
+
+func (m *TokenStore) onStart(ctx context.Context) error {
+	m.logger.Info("Starting " + ModuleName)
+
+	m.path = viper.GetString(m.getConfigPath("path"))
+
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return fmt.Errorf("failed to create token store directory: %w", err)
+	}
+
+	m.mu.Lock()
+	err := m.load()
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	m.unsubscribe = m.params.Bus.Subscribe(func(event events.Event) {
+		if e, ok := event.(events.RepositoryDeleted); ok {
+			m.revokeDeployTokens(e.Repository)
+		}
+	})
+
+	return nil
+}
+
+func (m *TokenStore) onStop(ctx context.Context) error {
+	if m.unsubscribe != nil {
+		m.unsubscribe()
+	}
+
+	// Persist last-used times recorded since the last save
+	m.mu.Lock()
+	if err := m.save(); err != nil {
+		m.logger.Warn("Failed to save tokens", zap.Error(err))
+	}
+	m.mu.Unlock()
+
+	m.logger.Info("Stopped " + ModuleName)
+	return nil
+}
+
+func (m *TokenStore) getConfigPath(key string) string {
+	return fmt.Sprintf("%s.%s", m.scope, key)
+}
+
+func (m *TokenStore) initDefaultConfigs() {
+	viper.SetDefault(m.getConfigPath("path"), DefaultPath)
+}
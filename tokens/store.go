@@ -0,0 +1,380 @@
+package tokens
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/weedbox/git-modules/auth"
+	"go.uber.org/zap"
+)
+
+const (
+	// personalPrefix and deployPrefix start the secrets of personal access tokens and deploy tokens,
+	// which tells them apart from passwords and lets secret scanners recognize them
+	personalPrefix = "gitpat_"
+	deployPrefix   = "gitdt_"
+
+	// lastUsedInterval limits how often the last-used time of a token is written to disk
+	lastUsedInterval = time.Minute
+)
+
+var (
+	// ErrTokenInvalid indicates an unknown or revoked token
+	ErrTokenInvalid = fmt.Errorf("invalid or revoked access token: %w", auth.ErrUnauthenticated)
+
+	// ErrTokenExpired indicates a token past its expiry time
+	ErrTokenExpired = fmt.Errorf("access token expired: %w", auth.ErrUnauthenticated)
+
+	// ErrTokenNotFound indicates a token that does not exist or belongs to another user or repository
+	ErrTokenNotFound = errors.New("token not found")
+
+	// ErrNameEmpty indicates a token without name
+	ErrNameEmpty = errors.New("token name cannot be empty")
+
+	// ErrScopeInvalid indicates an unknown token scope
+	ErrScopeInvalid = errors.New("invalid token scope: must be read or write")
+
+	// ErrExpiryInvalid indicates an expiry time in the past
+	ErrExpiryInvalid = errors.New("invalid token expiry: must be in the future")
+)
+
+// IsToken reports whether a secret has the format of an access token
+func IsToken(secret string) bool {
+	return strings.HasPrefix(secret, personalPrefix) || strings.HasPrefix(secret, deployPrefix)
+}
+
+// SecretFromCredentials returns the token sent with a request,
+// either as bearer token or as password of Basic authentication
+func SecretFromCredentials(creds auth.Credentials) string {
+	if creds.Token != "" {
+		return creds.Token
+	}
+	return creds.Password
+}
+
+// CreatePersonalToken issues a token acting on behalf of a user.
+// The token is limited to its scope and, if set, to a group and everything below it.
+func (m *TokenStore) CreatePersonalToken(user string, opts Options) (*CreatedToken, error) {
+	if user == "" {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	return m.create(Token{
+		Kind:  KindPersonal,
+		User:  user,
+		Group: strings.Trim(opts.Group, "/"),
+	}, opts, personalPrefix)
+}
+
+// CreateDeployToken issues a token granting access to a single repository.
+// The group option does not apply to deploy tokens.
+func (m *TokenStore) CreateDeployToken(repository string, opts Options) (*CreatedToken, error) {
+	return m.create(Token{
+		Kind:       KindDeploy,
+		Repository: repository,
+	}, opts, deployPrefix)
+}
+
+// ListPersonalTokens returns the personal access tokens of a user, oldest first
+func (m *TokenStore) ListPersonalTokens(user string) []Token {
+	return m.list(func(t *storedToken) bool { return t.Kind == KindPersonal && t.User == user })
+}
+
+// ListDeployTokens returns the deploy tokens of a repository, oldest first
+func (m *TokenStore) ListDeployTokens(repository string) []Token {
+	return m.list(func(t *storedToken) bool { return t.Kind == KindDeploy && t.Repository == repository })
+}
+
+// RevokePersonalToken deletes a personal access token of a user
+func (m *TokenStore) RevokePersonalToken(user, id string) error {
+	return m.revoke(id, func(t *storedToken) bool { return t.Kind == KindPersonal && t.User == user })
+}
+
+// RevokeDeployToken deletes a deploy token of a repository
+func (m *TokenStore) RevokeDeployToken(repository, id string) error {
+	return m.revoke(id, func(t *storedToken) bool { return t.Kind == KindDeploy && t.Repository == repository })
+}
+
+// Verify returns the token with the given secret and records its use.
+// Unknown, revoked and expired tokens yield errors wrapping auth.ErrUnauthenticated.
+func (m *TokenStore) Verify(secret string) (*Token, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.byHash[hashSecret(secret)]
+	if !ok {
+		return nil, ErrTokenInvalid
+	}
+
+	now := time.Now().UTC()
+	if stored.ExpiresAt != nil && now.After(*stored.ExpiresAt) {
+		return nil, ErrTokenExpired
+	}
+
+	persist := stored.LastUsedAt == nil || now.Sub(*stored.LastUsedAt) >= lastUsedInterval
+	stored.LastUsedAt = &now
+	if persist {
+		if err := m.save(); err != nil {
+			m.logger.Warn("Failed to record token use", zap.String("id", stored.ID), zap.Error(err))
+		}
+	}
+
+	token := stored.Token
+	return &token, nil
+}
+
+// Identity returns the identity requests authenticated with a token act as
+func Identity(token *Token) *auth.Identity {
+	scope := &auth.TokenScope{Write: token.Scope == ScopeWrite}
+
+	if token.Kind == KindDeploy {
+		scope.Deploy = true
+		scope.Prefix = token.Repository
+		return &auth.Identity{Name: "deploy-token:" + token.Name, Token: scope}
+	}
+
+	scope.Prefix = token.Group
+	return &auth.Identity{Name: token.User, Token: scope}
+}
+
+// ResolveIdentity returns the identity requests authenticated with a token act as,
+// including the teams of the user of a personal access token when a TeamResolver is provided
+func (m *TokenStore) ResolveIdentity(ctx context.Context, token *Token) (*auth.Identity, error) {
+	identity := Identity(token)
+
+	if token.Kind == KindPersonal && m.params.Teams != nil {
+		teams, err := m.params.Teams.Teams(ctx, identity.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve teams of %s: %w", identity.Name, err)
+		}
+		identity.Teams = teams
+	}
+
+	return identity, nil
+}
+
+// Authenticate implements auth.Authenticator for requests carrying a token.
+// Requests without credentials are anonymous. Tokens are refused with auth.ErrForbidden
+// for repositories outside their prefix and for writes with read-only tokens.
+func (m *TokenStore) Authenticate(ctx context.Context, req auth.Request) (*auth.Identity, error) {
+	secret := SecretFromCredentials(req.Credentials)
+	if secret == "" {
+		return nil, nil
+	}
+
+	token, err := m.Verify(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := m.ResolveIdentity(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if !identity.Token.Covers(req.Repository) || !identity.Token.Allows(req.Operation) {
+		return nil, auth.ErrForbidden
+	}
+
+	return identity, nil
+}
+
+// create validates and stores a new token
+func (m *TokenStore) create(token Token, opts Options, prefix string) (*CreatedToken, error) {
+	if strings.TrimSpace(opts.Name) == "" {
+		return nil, ErrNameEmpty
+	}
+
+	if opts.Scope == "" {
+		opts.Scope = ScopeRead
+	}
+	if opts.Scope != ScopeRead && opts.Scope != ScopeWrite {
+		return nil, ErrScopeInvalid
+	}
+
+	now := time.Now().UTC()
+	if opts.ExpiresAt != nil {
+		if !opts.ExpiresAt.After(now) {
+			return nil, ErrExpiryInvalid
+		}
+		expiresAt := opts.ExpiresAt.UTC()
+		token.ExpiresAt = &expiresAt
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomHex(20)
+	if err != nil {
+		return nil, err
+	}
+
+	token.ID = id
+	token.Name = opts.Name
+	token.Scope = opts.Scope
+	token.CreatedAt = now
+	secret = prefix + secret
+
+	stored := &storedToken{Token: token, Hash: hashSecret(secret)}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tokens[stored.ID] = stored
+	m.byHash[stored.Hash] = stored
+	if err := m.save(); err != nil {
+		delete(m.tokens, stored.ID)
+		delete(m.byHash, stored.Hash)
+		return nil, err
+	}
+
+	m.logger.Info("Token created",
+		zap.String("id", token.ID),
+		zap.String("kind", token.Kind),
+		zap.String("user", token.User),
+		zap.String("repository", token.Repository),
+	)
+	return &CreatedToken{Token: token, Secret: secret}, nil
+}
+
+// list returns the tokens matching a predicate, oldest first
+func (m *TokenStore) list(match func(t *storedToken) bool) []Token {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tokens := make([]Token, 0)
+	for _, t := range m.tokens {
+		if match(t) {
+			tokens = append(tokens, t.Token)
+		}
+	}
+
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].CreatedAt.Before(tokens[j].CreatedAt) })
+	return tokens
+}
+
+// revoke deletes a token if it matches a predicate
+func (m *TokenStore) revoke(id string, match func(t *storedToken) bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.tokens[id]
+	if !ok || !match(stored) {
+		return ErrTokenNotFound
+	}
+
+	delete(m.tokens, id)
+	delete(m.byHash, stored.Hash)
+	if err := m.save(); err != nil {
+		m.tokens[id] = stored
+		m.byHash[stored.Hash] = stored
+		return err
+	}
+
+	m.logger.Info("Token revoked", zap.String("id", id), zap.String("kind", stored.Kind))
+	return nil
+}
+
+// revokeDeployTokens deletes the deploy tokens of a deleted repository,
+// so that they do not grant access to a new repository created with the same name
+func (m *TokenStore) revokeDeployTokens(repository string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	revoked := 0
+	for id, t := range m.tokens {
+		if t.Kind == KindDeploy && t.Repository == repository {
+			delete(m.tokens, id)
+			delete(m.byHash, t.Hash)
+			revoked++
+		}
+	}
+
+	if revoked == 0 {
+		return
+	}
+
+	if err := m.save(); err != nil {
+		m.logger.Error("Failed to revoke deploy tokens", zap.String("repository", repository), zap.Error(err))
+		return
+	}
+
+	m.logger.Info("Deploy tokens revoked", zap.String("repository", repository), zap.Int("count", revoked))
+}
+
+// load reads the token file, a missing file yields an empty store
+func (m *TokenStore) load() error {
+	m.tokens = make(map[string]*storedToken)
+	m.byHash = make(map[string]*storedToken)
+
+	data, err := os.ReadFile(m.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read tokens: %w", err)
+	}
+
+	var stored []*storedToken
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("failed to read tokens: %w", err)
+	}
+
+	for _, t := range stored {
+		m.tokens[t.ID] = t
+		m.byHash[t.Hash] = t
+	}
+
+	return nil
+}
+
+// save atomically replaces the token file
+func (m *TokenStore) save() error {
+	stored := make([]*storedToken, 0, len(m.tokens))
+	for _, t := range m.tokens {
+		stored = append(stored, t)
+	}
+	sort.Slice(stored, func(i, j int) bool { return stored[i].CreatedAt.Before(stored[j].CreatedAt) })
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to write tokens: %w", err)
+	}
+
+	tmpPath := m.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write tokens: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, m.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write tokens: %w", err)
+	}
+
+	return nil
+}
+
+// hashSecret returns the hex encoded SHA-256 hash a token secret is stored as
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomHex returns n random bytes, hex encoded
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
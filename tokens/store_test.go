@@ -0,0 +1,194 @@
+package tokens
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/weedbox/git-modules/auth"
+	"go.uber.org/zap"
+)
+
+func setupTestTokenStore(t *testing.T) *TokenStore {
+	m := &TokenStore{
+		logger: zap.NewNop(),
+		path:   filepath.Join(t.TempDir(), "tokens.json"),
+	}
+	if err := m.load(); err != nil {
+		t.Fatalf("Failed to load tokens: %v", err)
+	}
+
+	return m
+}
+
+// Test that personal access tokens are limited to their scope and group
+func TestPersonalTokens(t *testing.T) {
+	m := setupTestTokenStore(t)
+
+	created, err := m.CreatePersonalToken("john", Options{Name: "laptop", Scope: ScopeRead, Group: "org"})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	if !IsToken(created.Secret) || !strings.HasPrefix(created.Secret, personalPrefix) {
+		t.Errorf("Unexpected token secret format: %s", created.Secret)
+	}
+
+	// Only the hash of the secret is stored
+	data, err := os.ReadFile(m.path)
+	if err != nil {
+		t.Fatalf("Failed to read token file: %v", err)
+	}
+	if strings.Contains(string(data), created.Secret) {
+		t.Error("Token file contains the token secret")
+	}
+
+	tests := []struct {
+		name       string
+		repository string
+		op         auth.Operation
+		err        error
+	}{
+		{"read in group", "org/app", auth.OperationRead, nil},
+		{"write with read scope", "org/app", auth.OperationWrite, auth.ErrForbidden},
+		{"outside group", "other/app", auth.OperationRead, auth.ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := m.Authenticate(context.Background(), auth.Request{
+				Repository:  tt.repository,
+				Operation:   tt.op,
+				Credentials: auth.Credentials{Username: "john", Password: created.Secret},
+			})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if err == nil && identity.Name != "john" {
+				t.Errorf("Expected identity john, got %s", identity.Name)
+			}
+		})
+	}
+
+	tokens := m.ListPersonalTokens("john")
+	if len(tokens) != 1 || tokens[0].LastUsedAt == nil {
+		t.Errorf("Expected one token with last-used time, got %v", tokens)
+	}
+
+	if err := m.RevokePersonalToken("jane", created.ID); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("Expected ErrTokenNotFound when revoking another user's token, got %v", err)
+	}
+	if err := m.RevokePersonalToken("john", created.ID); err != nil {
+		t.Fatalf("Failed to revoke token: %v", err)
+	}
+	if _, err := m.Verify(created.Secret); !errors.Is(err, auth.ErrUnauthenticated) {
+		t.Errorf("Expected revoked token to be rejected, got %v", err)
+	}
+}
+
+// Test deploy tokens, expiry and persistence
+func TestDeployTokens(t *testing.T) {
+	m := setupTestTokenStore(t)
+
+	created, err := m.CreateDeployToken("org/app", Options{Name: "ci", Scope: ScopeWrite})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	expiresAt := time.Now().Add(time.Hour)
+	expiring, err := m.CreateDeployToken("org/app", Options{Name: "old", ExpiresAt: &expiresAt})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	// Tokens survive a reload
+	if err := m.load(); err != nil {
+		t.Fatalf("Failed to reload tokens: %v", err)
+	}
+
+	identity, err := m.Authenticate(context.Background(), auth.Request{
+		Repository:  "org/app",
+		Operation:   auth.OperationWrite,
+		Credentials: auth.Credentials{Token: created.Secret},
+	})
+	if err != nil {
+		t.Fatalf("Failed to authenticate deploy token: %v", err)
+	}
+	if identity.Token == nil || !identity.Token.Deploy || identity.Token.Covers("org/lib") {
+		t.Errorf("Unexpected deploy token identity: %+v", identity.Token)
+	}
+
+	m.tokens[expiring.ID].ExpiresAt = &time.Time{}
+	if _, err := m.Verify(expiring.Secret); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Expected ErrTokenExpired, got %v", err)
+	}
+
+	if _, err := m.CreateDeployToken("org/app", Options{Name: "ci", Scope: "admin"}); !errors.Is(err, ErrScopeInvalid) {
+		t.Errorf("Expected ErrScopeInvalid, got %v", err)
+	}
+
+	m.revokeDeployTokens("org/app")
+	if tokens := m.ListDeployTokens("org/app"); len(tokens) != 0 {
+		t.Errorf("Expected deploy tokens of a deleted repository to be revoked, got %v", tokens)
+	}
+}
+
+// Test that personal access tokens carry the teams of their user
+func TestTokenTeams(t *testing.T) {
+	m := setupTestTokenStore(t)
+
+	var fail bool
+	m.params.Teams = auth.TeamResolverFunc(func(ctx context.Context, user string) ([]string, error) {
+		if fail {
+			return nil, errors.New("directory unavailable")
+		}
+		if user == "carol" {
+			return []string{"readers"}, nil
+		}
+		return nil, nil
+	})
+
+	personal, err := m.CreatePersonalToken("carol", Options{Name: "laptop"})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	deploy, err := m.CreateDeployToken("org/app", Options{Name: "ci"})
+	if err != nil {
+		t.Fatalf("Failed to create deploy token: %v", err)
+	}
+
+	authenticate := func(secret string) (*auth.Identity, error) {
+		return m.Authenticate(context.Background(), auth.Request{
+			Repository:  "org/app",
+			Operation:   auth.OperationRead,
+			Credentials: auth.Credentials{Token: secret},
+		})
+	}
+
+	identity, err := authenticate(personal.Secret)
+	if err != nil {
+		t.Fatalf("Failed to authenticate: %v", err)
+	}
+	if len(identity.Teams) != 1 || identity.Teams[0] != "readers" {
+		t.Errorf("Expected teams of carol, got %v", identity.Teams)
+	}
+
+	// Deploy tokens act for no user and belong to no team
+	identity, err = authenticate(deploy.Secret)
+	if err != nil {
+		t.Fatalf("Failed to authenticate deploy token: %v", err)
+	}
+	if len(identity.Teams) != 0 {
+		t.Errorf("Expected deploy token without teams, got %v", identity.Teams)
+	}
+
+	fail = true
+	if _, err := authenticate(personal.Secret); err == nil {
+		t.Error("Expected authentication to fail when teams cannot be resolved")
+	}
+	if _, err := authenticate(deploy.Secret); err != nil {
+		t.Errorf("Expected deploy token without team resolution, got %v", err)
+	}
+}